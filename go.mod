@@ -1,5 +1,5 @@
 module github.com/mistsys/protobuf3
 
-go 1.18
+go 1.20
 
 require github.com/nsd20463/cpuendian v1.1.4
@@ -881,6 +881,63 @@ func BenchmarkUnmarshalImmutableBytesMsg(b *testing.B) {
 	}
 }
 
+// stringsMsgBytes returns the encoding of the StringsMsg which the string benchmarks decode
+func stringsMsgBytes(b *testing.B) []byte {
+	s := "pointed to string"
+
+	m := StringsMsg{
+		S:  "a direct string",
+		P:  &s,
+		SS: []string{"the first string of a slice", "the second string of a slice"},
+		A:  [2]string{"the first string of an array", "the second string of an array"},
+		M:  map[string]string{"a key": "its value", "another key": "another value"},
+	}
+
+	pb, err := protobuf3.Marshal(&m)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return pb
+}
+
+func BenchmarkUnmarshalStringsMsg(b *testing.B) {
+	pb := stringsMsgBytes(b)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var m StringsMsg
+		protobuf3.Unmarshal(pb, &m)
+	}
+}
+
+func BenchmarkUnmarshalInternedStringsMsg(b *testing.B) {
+	pb := stringsMsgBytes(b)
+
+	buf := protobuf3.NewBuffer(pb)
+	buf.Interner = protobuf3.NewInterner(1000, 64)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var m StringsMsg
+		buf.Rewind()
+		buf.Unmarshal(&m)
+	}
+}
+
+func BenchmarkUnmarshalImmutableStringsMsg(b *testing.B) {
+	pb := stringsMsgBytes(b)
+
+	buf := protobuf3.NewBuffer(pb)
+	buf.Immutable = true
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var m StringsMsg
+		buf.Rewind()
+		buf.Unmarshal(&m)
+	}
+}
+
 func BenchmarkUnmarshalOldBytesMsg(b *testing.B) {
 	s := "str"
 
@@ -0,0 +1,112 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// protobuf3-gen generates reflection-free AppendProtobuf3 and UnmarshalProtobuf3 methods for struct types from their
+// protobuf struct tags. The generated methods encode and decode exactly as the reflective code in protobuf3 does. See
+// package codegen.
+//
+// Usage:
+//
+//	protobuf3-gen [-o file] [-test] [-xxxhack] package type...
+//
+// The package is named the same way as for the go command. The output is written to stdout unless -o is used.
+// With -test, a test which cross-checks the generated methods against the reflective encoder and decoder is also
+// written, to the output file name with _test appended (which requires -o).
+//
+// Since the package is type checked, a previously generated file which no longer compiles because the types have
+// changed must be deleted before the methods can be regenerated.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mistsys/protobuf3/protobuf3/codegen"
+	"github.com/mistsys/protobuf3/protobuf3/internal/gosrc"
+)
+
+func main() {
+	var opts codegen.Options
+	output := flag.String("o", "", "write the output to `file` rather than stdout")
+	test := flag.Bool("test", false, "also generate a test which cross-checks the generated methods")
+	flag.BoolVar(&opts.XXXHack, "xxxhack", false, "ignore untagged fields whose names start with XXX_, like protobuf3.XXXHack")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] package type...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 2 || (*test && *output == "") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	pkgs, err := gosrc.Load(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if len(pkgs) != 1 {
+		fmt.Fprintf(os.Stderr, "%s matches %d packages. It must match exactly one\n", flag.Arg(0), len(pkgs))
+		os.Exit(2)
+	}
+	names := flag.Args()[1:]
+
+	src, err := codegen.Generate(pkgs[0], opts, names...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *output == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	if err := os.WriteFile(*output, src, 0666); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if *test {
+		src, err := codegen.GenerateTest(pkgs[0], opts, names...)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		name := strings.TrimSuffix(*output, ".go") + "_test.go"
+		if err := os.WriteFile(name, src, 0666); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+}
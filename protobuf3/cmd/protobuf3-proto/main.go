@@ -0,0 +1,146 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// protobuf3-proto generates the .proto definitions of Go types from their source, producing the same output as
+// protobuf3.AsProtobufFull2() would, plus the Go comments of the types and their fields. See package protogen.
+//
+// Usage:
+//
+//	protobuf3-proto [-o file] [-header line]... [-option name=value]... [-nocomments] [-xxxhack] package [type...]
+//
+// The package is named the same way as for the go command. If no types are named then all the struct types declared
+// in the package which have protobuf tags are generated. The output is written to stdout unless -o is used.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/types"
+	"os"
+	"strings"
+
+	"github.com/mistsys/protobuf3/protobuf3/internal/gosrc"
+	"github.com/mistsys/protobuf3/protobuf3/protogen"
+)
+
+// a flag which can be repeated
+type stringsFlag []string
+
+func (s *stringsFlag) String() string     { return strings.Join(*s, "\n") }
+func (s *stringsFlag) Set(v string) error { *s = append(*s, v); return nil }
+
+// a flag of name=value pairs which can be repeated
+type optionsFlag map[string]string
+
+func (o optionsFlag) String() string {
+	var pairs []string
+	for name, value := range o {
+		pairs = append(pairs, name+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (o optionsFlag) Set(v string) error {
+	name, value, ok := strings.Cut(v, "=")
+	if !ok {
+		return fmt.Errorf("%q is not name=value", v)
+	}
+	o[name] = value
+	return nil
+}
+
+func main() {
+	var opts protogen.Options
+	output := flag.String("o", "", "write the output to `file` rather than stdout")
+	flag.Var((*stringsFlag)(&opts.ExtraHeaders), "header", "a `line` to insert after the package line. Can be repeated")
+	opts.FileOptions = make(map[string]string)
+	flag.Var(optionsFlag(opts.FileOptions), "option", "a string valued file option, such as go_package, given as `name=value`. Can be repeated")
+	flag.BoolVar(&opts.NoComments, "nocomments", false, "omit the Go comments")
+	flag.BoolVar(&opts.XXXHack, "xxxhack", false, "ignore untagged fields whose names start with XXX_, like protobuf3.XXXHack")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] package [type...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	pkgs, err := gosrc.Load(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if len(pkgs) != 1 {
+		fmt.Fprintf(os.Stderr, "%s matches %d packages. It must match exactly one\n", flag.Arg(0), len(pkgs))
+		os.Exit(2)
+	}
+
+	var named []types.Type
+	if flag.NArg() > 1 {
+		for _, name := range flag.Args()[1:] {
+			t, err := protogen.Lookup(pkgs, name)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(2)
+			}
+			named = append(named, t)
+		}
+	} else {
+		for _, t := range protogen.Structs(pkgs[0]) {
+			named = append(named, t)
+		}
+		if len(named) == 0 {
+			fmt.Fprintf(os.Stderr, "%s has no struct types with protobuf tags\n", flag.Arg(0))
+			os.Exit(2)
+		}
+	}
+
+	proto, err := protogen.Generate(pkgs, opts, named[0], named[1:]...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		// and write the output anyway. like AsProtobufFull it contains the errors, which will cause protoc to complain if it is used
+	}
+	if *output != "" {
+		if werr := os.WriteFile(*output, []byte(proto+"\n"), 0666); werr != nil {
+			fmt.Fprintln(os.Stderr, werr)
+			os.Exit(2)
+		}
+	} else {
+		fmt.Println(proto)
+	}
+	if err != nil {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,113 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// protobuf3-tags adds protobuf tags to the untagged fields of the Go structs which already have some protobuf tags,
+// choosing each field's wiretype from its Go type and the next free tag id. See package tagassign for the details.
+//
+// Usage:
+//
+//	protobuf3-tags [-w] [-l] [-xxxhack] [packages]
+//
+// The packages are named the same way as for the go command, and default to ".". By default the rewritten files
+// are printed to stdout. Warnings about fields which could not be tagged are printed to stderr, and make the exit
+// status 1.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+
+	"github.com/mistsys/protobuf3/protobuf3/internal/gosrc"
+	"github.com/mistsys/protobuf3/protobuf3/tagassign"
+)
+
+func main() {
+	write := flag.Bool("w", false, "write the result back to the source files instead of stdout")
+	list := flag.Bool("l", false, "list the files which would be changed")
+	flag.BoolVar(&tagassign.XXXHack, "xxxhack", false, "leave untagged fields whose names start with XXX_ alone, like protobuf3.XXXHack")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] [packages]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	patterns := flag.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+
+	pkgs, err := gosrc.Load(patterns...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	status := 0
+	for _, pkg := range pkgs {
+		for _, f := range pkg.Files {
+			n, warnings := tagassign.Assign(pkg, f)
+			for _, w := range warnings {
+				fmt.Fprintln(os.Stderr, w)
+				status = 1
+			}
+			if n == 0 {
+				continue
+			}
+
+			filename := pkg.Fset.Position(f.Pos()).Filename
+			var buf bytes.Buffer
+			if err := format.Node(&buf, pkg.Fset, f); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", filename, err)
+				os.Exit(2)
+			}
+			switch {
+			case *list:
+				fmt.Println(filename)
+			case *write:
+				info, err := os.Stat(filename)
+				if err == nil {
+					err = os.WriteFile(filename, buf.Bytes(), info.Mode().Perm())
+				}
+				if err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					os.Exit(2)
+				}
+			default:
+				os.Stdout.Write(buf.Bytes())
+			}
+		}
+	}
+	os.Exit(status)
+}
@@ -0,0 +1,77 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// protobuf3-vet statically checks the protobuf, protobuf_key and protobuf_val struct tags in Go packages,
+// reporting the mistakes which protobuf3 would otherwise only report at runtime. See package tagcheck for
+// the list of checks.
+//
+// Usage:
+//
+//	protobuf3-vet [-xxxhack] [packages]
+//
+// The packages are named the same way as for the go command, and default to ".". The exit status is 0 if
+// no problems are found, 1 if some are, and 2 if an error occurred.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mistsys/protobuf3/protobuf3/tagcheck"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [packages]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.BoolVar(&tagcheck.XXXHack, "xxxhack", false, "ignore untagged fields whose names start with XXX_, like protobuf3.XXXHack")
+	flag.Parse()
+	patterns := flag.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+
+	diags, err := tagcheck.CheckPackages(patterns...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	for _, d := range diags {
+		fmt.Println(d)
+	}
+	if len(diags) != 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,360 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package protobuf3
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+// Codec encodes and decodes values of a type which cannot implement Appender or Marshaler itself, typically
+// because it is declared in another package (big.Int, sql.NullString, ...). See RegisterCodec.
+type Codec struct {
+	// Wire is the wiretype of the encoded value. Fields of the type must be tagged with it.
+	Wire WireType
+
+	// Append appends the encoding of *v, where v is a pointer to a value of the registered type, to b and
+	// returns the result. Like Appender.AppendProtobuf3, it does not append the tag, nor the length of a
+	// WireBytes value. A value which appends nothing is omitted, unless it is an element of a slice or array.
+	Append func(b []byte, v interface{}) ([]byte, error)
+
+	// Unmarshal decodes data into *v, reversing what Append produced. data does not include the length of a
+	// WireBytes value.
+	Unmarshal func(data []byte, v interface{}) error
+
+	// Name is the protobuf type of fields of the registered type in the output of AsProtobuf[Full](). If Name
+	// is a message type then Definition should hold its definition, and Imports any .proto files it needs.
+	Name       string
+	Definition string
+	Imports    []string
+
+	// MapKey, if not nil, encodes the type when it is used as a map key. protobuf map keys must be integers or
+	// strings, so a type encoded as bytes or as a message needs another encoding there. MapKey must have the same Wire.
+	MapKey *Codec
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = make(map[reflect.Type]*Codec)
+	builtins = make(map[reflect.Type]bool) // the types whose codecs this package registered, which RegisterCodec can replace
+)
+
+// RegisterCodec registers the functions which encode and decode type t. Fields of type t, and pointers, slices
+// and arrays of t, as well as map keys and values of type t, are encoded with c, and AsProtobufFull() uses
+// c's protobuf type name and definition. A registered codec takes precedence over any methods of t.
+//
+// RegisterCodec must be called before the properties of any struct using t are computed, so call it from init().
+// It panics if c (or c.MapKey) lacks Append, Unmarshal or Name, or if t is already registered, except that it
+// replaces the codecs this package registers for the net/netip and net types.
+//
+// Registrations happen at run time, so the tools which work from source (protobuf3-vet, protobuf3-proto and
+// protobuf3-gen) do not know about them, and reject fields of registered types as they always have.
+func RegisterCodec(t reflect.Type, c Codec) {
+	if c.Append == nil || c.Unmarshal == nil {
+		panic(fmt.Sprintf("protobuf3: RegisterCodec(%s): Append and Unmarshal are required", t))
+	}
+	if c.Name == "" {
+		panic(fmt.Sprintf("protobuf3: RegisterCodec(%s): Name is required", t))
+	}
+	if k := c.MapKey; k != nil && (k.Append == nil || k.Unmarshal == nil || k.Name == "" || k.Wire != c.Wire) {
+		panic(fmt.Sprintf("protobuf3: RegisterCodec(%s): MapKey must be complete, and have the same Wire", t))
+	}
+
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	if _, ok := codecs[t]; ok && !builtins[t] {
+		panic(fmt.Sprintf("protobuf3: RegisterCodec(%s): type is already registered", t))
+	}
+	delete(builtins, t)
+	codecs[t] = &c
+}
+
+// registerBuiltinCodec registers one of the codecs of this package, which RegisterCodec can replace
+func registerBuiltinCodec(t reflect.Type, c Codec) {
+	RegisterCodec(t, c)
+	codecsMu.Lock()
+	builtins[t] = true
+	codecsMu.Unlock()
+}
+
+// returns the registered codec of type t, or nil
+func lookupCodec(t reflect.Type) *Codec {
+	codecsMu.RLock()
+	c := codecs[t]
+	codecsMu.RUnlock()
+	return c
+}
+
+// returns the registered codec used by a field of type t, and the type it encodes, or nil
+func fieldCodec(t reflect.Type) (reflect.Type, *Codec) {
+	if c := lookupCodec(t); c != nil {
+		return t, c
+	}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return t.Elem(), lookupCodec(t.Elem())
+	}
+	return nil, nil
+}
+
+// setCodecEncAndDec is the part of setEncAndDec which handles fields encoded by a codec. elem is the type c
+// encodes, which is t1 itself or the element type of pointer, slice or array t1.
+func (p *Properties) setCodecEncAndDec(t1, elem reflect.Type, c *Codec, wire WireType, name string) error {
+	if wire != c.Wire {
+		return fmt.Errorf("protobuf3: %q %s cannot have wiretype %s", name, t1, wire)
+	}
+	p.codec = c
+	p.asProtobuf = c.Name
+
+	if t1 == elem {
+		p.stype = t1
+		p.enc = (*Buffer).enc_codec
+		p.dec = (*Buffer).dec_codec
+		return nil
+	}
+
+	p.stype = t1.Elem()
+	switch t1.Kind() {
+	case reflect.Ptr:
+		p.enc = (*Buffer).enc_ptr_codec
+		p.dec = (*Buffer).dec_ptr_codec
+	case reflect.Slice:
+		p.enc = (*Buffer).enc_slice_codec
+		p.dec = (*Buffer).dec_slice_codec
+		p.asProtobuf = "repeated " + c.Name
+	case reflect.Array:
+		p.length = uint(t1.Len())
+		if p.length == 0 {
+			// save ourselves some work, and encode nothing
+			p.enc = (*Buffer).enc_nothing
+			p.dec = (*Buffer).dec_nothing
+		} else {
+			p.enc = (*Buffer).enc_array_codec
+			p.dec = (*Buffer).dec_array_codec
+		}
+		p.asProtobuf = "repeated " + c.Name
+	}
+	return nil
+}
+
+// encode_codec appends the value at ptr using p.codec. If must_encode is false then a value which
+// appends nothing is omitted
+func (o *Buffer) encode_codec(p *Properties, ptr unsafe.Pointer, must_encode bool) error {
+	b, err := appendCodec(o.buf, p.tagcode, p.WireType, p.codec, reflect.NewAt(p.stype, ptr).Interface(), must_encode)
+	if err != nil {
+		o.noteError(err)
+		return err
+	}
+	o.buf = b
+	return nil
+}
+
+// appendCodec appends the tagcode and the encoding of *v by c, the way AppendAppender appends an Appender
+func appendCodec(b []byte, tagcode string, wt WireType, c *Codec, v interface{}, must_encode bool) ([]byte, error) {
+	n1 := len(b)
+	b = append(b, tagcode...)
+	if wt == WireBytes {
+		b = append(b, 0) // placeholder for the length
+	}
+	n2 := len(b)
+
+	b2, err := c.Append(b, v)
+	if err != nil {
+		return b[:n1], err
+	}
+	if len(b2) < n2 {
+		return b[:n1], fmt.Errorf("protobuf3: buggy codec for %T returned []byte len %d", v, len(b2))
+	}
+	b = b2
+
+	if !must_encode && len(b) == n2 {
+		// *v encoded to nothing; remove the tagcode and length placeholder
+		return b[:n1], nil
+	}
+	if wt == WireBytes {
+		b = FixupLength(b, n2)
+	}
+	return b, nil
+}
+
+// returns the codec registered for *v, or for the map keys of type *v if key is true
+func codecOf(v interface{}, key bool) (*Codec, error) {
+	t := reflect.TypeOf(v).Elem()
+	c := lookupCodec(t)
+	if c == nil {
+		return nil, fmt.Errorf("protobuf3: no codec is registered for %s", t)
+	}
+	if key && c.MapKey != nil {
+		c = c.MapKey
+	}
+	return c, nil
+}
+
+// Encode a field of a registered codec type
+func (o *Buffer) enc_codec(p *Properties, base unsafe.Pointer) {
+	o.encode_codec(p, unsafe.Pointer(uintptr(base)+p.offset), false)
+}
+
+// Encode a pointer to a registered codec type
+func (o *Buffer) enc_ptr_codec(p *Properties, base unsafe.Pointer) {
+	ptr := *(*unsafe.Pointer)(unsafe.Pointer(uintptr(base) + p.offset))
+	if ptr == nil {
+		return
+	}
+	o.encode_codec(p, ptr, false)
+}
+
+// Encode a slice of a registered codec type
+func (o *Buffer) enc_slice_codec(p *Properties, base unsafe.Pointer) {
+	s := *(*[]byte)(unsafe.Pointer(uintptr(base) + p.offset)) // note this could just as well be (*[]int) or anything
+	n := len(s)                                               // note this is the # of elements, not the # of bytes
+	if n == 0 {
+		return
+	}
+	enc_codecs(o, p, unsafe.Pointer(&s[0]), uint(n))
+}
+
+// Encode an array of a registered codec type
+func (o *Buffer) enc_array_codec(p *Properties, base unsafe.Pointer) {
+	enc_codecs(o, p, unsafe.Pointer(uintptr(base)+p.offset), p.length)
+}
+
+// utility function to encode a series of 'n' values in a line in memory (from a slice or from an array)
+func enc_codecs(o *Buffer, p *Properties, base unsafe.Pointer, n uint) {
+	sz := p.stype.Size()
+	nb := uintptr(n) * sz
+	for i := uintptr(0); i < nb; i += sz {
+		// note in a slice we always encode the value, even if it is empty, in order to preserve indexing of the slice
+		if o.encode_codec(p, unsafe.Pointer(uintptr(base)+i), true) != nil {
+			// err is already noted
+			return
+		}
+	}
+}
+
+// Decode a field of a registered codec type
+func (o *Buffer) dec_codec(p *Properties, base unsafe.Pointer) error {
+	raw, err := o.get(p.stype, p.WireType)
+	if err != nil {
+		return err
+	}
+	if err := o.addBytes(len(raw), false); err != nil { // codecs copy what they need from raw
+		return err
+	}
+	ptr := unsafe.Pointer(uintptr(base) + p.offset)
+	return p.codec.Unmarshal(raw, reflect.NewAt(p.stype, ptr).Interface())
+}
+
+// Decode a pointer to a registered codec type
+func (o *Buffer) dec_ptr_codec(p *Properties, base unsafe.Pointer) error {
+	raw, err := o.get(p.stype, p.WireType)
+	if err != nil {
+		return err
+	}
+	if err := o.addBytes(len(raw), false); err != nil {
+		return err
+	}
+
+	pptr := (*unsafe.Pointer)(unsafe.Pointer(uintptr(base) + p.offset))
+	var val reflect.Value
+	if *pptr == nil {
+		if err := o.allocate(p.stype.Size()); err != nil {
+			return err
+		}
+		val = reflect.New(p.stype)
+		*pptr = unsafe.Pointer(val.Pointer())
+	} else {
+		// else the value is already allocated and we merge into it
+		val = reflect.NewAt(p.stype, *pptr)
+	}
+	return p.codec.Unmarshal(raw, val.Interface())
+}
+
+// Decode into a slice of a registered codec type
+func (o *Buffer) dec_slice_codec(p *Properties, base unsafe.Pointer) error {
+	raw, err := o.get(p.stype, p.WireType)
+	if err != nil {
+		return err
+	}
+	if err := o.addBytes(len(raw), false); err != nil {
+		return err
+	}
+
+	// build a reflect.Value of the slice
+	ptr := unsafe.Pointer(uintptr(base) + p.offset)
+	slice := reflect.NewAt(reflect.SliceOf(p.stype), ptr).Elem()
+
+	if slice.IsNil() {
+		// preallocate what is probably the right sized slice immediately (if it isn't then we'll append later)
+		slice.Set(reflect.MakeSlice(slice.Type(), 0, o.countAhead(p, p.stype.Size())))
+	}
+	if err := o.addRepeated(slice.Len()+1, 1, p.stype.Size()); err != nil {
+		return err
+	}
+
+	n := slice.Len()
+	if n < slice.Cap() {
+		slice.SetLen(n + 1)
+		slice.Index(n).Set(reflect.Zero(p.stype)) // the spare capacity might hold an old value
+	} else {
+		slice.Set(reflect.Append(slice, reflect.Zero(p.stype)))
+	}
+
+	return p.codec.Unmarshal(raw, slice.Index(n).Addr().Interface())
+}
+
+// Decode into an array of a registered codec type
+func (o *Buffer) dec_array_codec(p *Properties, base unsafe.Pointer) error {
+	raw, err := o.get(p.stype, p.WireType)
+	if err != nil {
+		return err
+	}
+	if err := o.addBytes(len(raw), false); err != nil {
+		return err
+	}
+
+	ptr := unsafe.Pointer(uintptr(base) + p.offset)
+	n := p.length
+	i := o.array_indexes[ptr]
+	if i < n {
+		// address of element i
+		ptr_elem := unsafe.Pointer(uintptr(ptr) + uintptr(i)*p.stype.Size())
+		err = p.codec.Unmarshal(raw, reflect.NewAt(p.stype, ptr_elem).Interface())
+		i++
+		o.saveIndex(ptr, i)
+	}
+	return err
+}
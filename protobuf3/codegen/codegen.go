@@ -0,0 +1,1800 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+/*
+Package codegen generates reflection-free AppendProtobuf3 and UnmarshalProtobuf3 methods for struct types from
+their protobuf struct tags.
+
+The generated methods make the types implement protobuf3.Generated (and so protobuf3.Appender), and produce the
+same bytes as the reflective encoder would have produced for the same values (with the usual exception that the
+entries of maps with more than one entry are encoded in random order), and decode the same as the reflective decoder.
+Because the generated methods are an Appender, the reflective encoder calls them when the types are used as fields of
+other types, and Marshal calls them directly. Unmarshal likewise calls the generated DecodeProtobuf3 method, which
+decodes with the Buffer's settings, and nested messages with generated methods are decoded with those same settings.
+
+The struct types declared in the same package which are used by the fields of the named types have methods generated
+for them too, since once a field's type is an Appender its encoding can change (a pointer to an Appender which encodes
+to nothing is omitted, while a pointer to an ordinary struct is not). Anonymous struct types get unexported helper
+functions. Named struct types from other packages must already implement Appender or Marshaler.
+
+GenerateTest generates tests which use package crosscheck to compare the generated methods of each type against the
+reflective encoder and decoder on random values.
+
+See cmd/protobuf3-gen for a command line wrapper.
+*/
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/types"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mistsys/protobuf3/protobuf3/internal/gosrc"
+)
+
+// Header is the first line of the generated files
+const Header = "// Code generated by protobuf3-gen. DO NOT EDIT."
+
+// Options modify the generated code
+type Options struct {
+	XXXHack bool // same as protobuf3.XXXHack
+}
+
+// Generate returns the source of a Go file, in package pkg, declaring the methods of the named struct types,
+// and of the struct types declared in pkg which they use.
+func Generate(pkg *gosrc.Package, opts Options, names ...string) ([]byte, error) {
+	g, err := newGenerator(pkg, opts, names)
+	if err != nil {
+		return nil, err
+	}
+	return g.generate()
+}
+
+// GenerateTest returns the source of a Go test file, in package pkg, which cross-checks the methods Generate
+// generates for the same arguments, type by type, against the reflective encoder and decoder.
+func GenerateTest(pkg *gosrc.Package, opts Options, names ...string) ([]byte, error) {
+	g, err := newGenerator(pkg, opts, names)
+	if err != nil {
+		return nil, err
+	}
+	return g.generateTest()
+}
+
+// how a field's value is laid out
+type mode int
+
+const (
+	modeNothing  mode = iota // a zero-length array, which encodes as nothing
+	modeValue                // T
+	modePtr                  // *T
+	modePacked               // []T or [N]T of scalars, packed into one WireBytes
+	modeRepeated             // []T, [N]T, []*T or [N]*T, each element encoded separately
+	modeMap                  // map[K]V
+	modeOptional             // protobuf3.Optional[T] of a scalar, string or []byte T
+	modeSet                  // map[K]struct{} or map[K]bool set, encoded like a []K
+)
+
+// the kind of T
+type kind int
+
+const (
+	kindBool kind = iota
+	kindInt       // any integer type
+	kindFloat32
+	kindFloat64
+	kindString
+	kindBytes     // []byte, or [N]byte when array is set
+	kindByteArray // a [N]byte element of a repeated field
+	kindDuration
+	kindTime
+	kindStruct    // an anonymous struct type
+	kindGenerated // a struct type with methods generated by protobuf3-gen, now or earlier
+	kindAppender
+	kindMarshaler
+	kindCodec  // a net/netip or net type, encoded by the codec protobuf3 registers for it
+	kindBinary // a type with the binary option, encoded by its encoding.BinaryMarshaler methods
+	kindText   // a type with the text option, encoded by its encoding.TextMarshaler methods
+)
+
+// codec describes how to encode and decode a field
+type codec struct {
+	mode     mode
+	kind     kind
+	array    bool       // the field is an array
+	ptrElem  bool       // the elements of the modeRepeated field are pointers
+	typ      types.Type // the type of the field
+	elem     types.Type // T in the comments above
+	wire     string     // the wiretype in the protobuf tag
+	anon     *anon      // for kindStruct
+	time     string     // the time format option of a time.Time encoded as a kindInt or a kindString
+	mapKey   bool       // a kindCodec map key, which has its own encoding
+	resize   bool       // the resize option of a kindByteArray, which fits elements of the wrong length
+	key, val *codec     // for modeMap
+	set      *codec     // for modeSet, the codec of a []K of the elements
+}
+
+// a field of a struct
+type field struct {
+	name   string // the Go name of the field
+	path   string // the selector of the field, which differs from name for fields of embedded structs
+	id     uint32
+	c      *codec
+	embeds []embed // the structs embedded by pointer which the field is in, outermost first
+}
+
+// a struct embedded by pointer
+type embed struct {
+	path string     // the selector of the pointer
+	typ  types.Type // the struct type
+}
+
+// expr returns the expression of the field in the method or function of its message m
+func (f *field) expr() string {
+	if f.path == "" {
+		return "(*m)" // the Items field of a wrapper message is the wrapped value itself
+	}
+	return "m." + f.path
+}
+
+// an anonymous struct type, or a list or map encoded as a wrapper message, for which we generate
+// functions rather than methods
+type anon struct {
+	name    string     // the suffix of the names of the functions
+	typ     types.Type // a *types.Struct, or the slice or array type of a list
+	wrapper bool       // typ is a list or map encoded as a wrapper message; its single field is typ itself
+	fields  []field
+}
+
+// a named struct type, for which we generate methods
+type message struct {
+	typ    *types.Named
+	fields []field
+}
+
+type generator struct {
+	pkg     *gosrc.Package
+	opts    Options
+	imports map[string]string // path -> name of the packages used by the generated code
+	named   map[*types.Named]*message
+	todo    []*types.Named
+	anons   map[string]*anon // by the anonymous type's string
+	order   []*anon
+	out     *bytes.Buffer
+	usesErr bool // the code being generated uses the err variable
+	vars    []string
+}
+
+func newGenerator(pkg *gosrc.Package, opts Options, names []string) (*generator, error) {
+	g := &generator{
+		pkg:     pkg,
+		opts:    opts,
+		imports: make(map[string]string),
+		named:   make(map[*types.Named]*message),
+		anons:   make(map[string]*anon),
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("codegen: no types named")
+	}
+	for _, name := range names {
+		obj, ok := pkg.Types.Scope().Lookup(name).(*types.TypeName)
+		if !ok {
+			return nil, fmt.Errorf("codegen: type %s not found in package %s", name, pkg.Path)
+		}
+		n, ok := obj.Type().(*types.Named)
+		if !ok || !isStruct(n) {
+			return nil, fmt.Errorf("codegen: %s is not a named struct type", name)
+		}
+		g.add(n)
+	}
+
+	// prepare all the types, which can discover more types
+	for len(g.todo) != 0 {
+		n := g.todo[0]
+		g.todo = g.todo[1:]
+		if n.TypeParams().Len() != 0 {
+			return nil, fmt.Errorf("codegen: %s is a generic type", n.Obj().Name())
+		}
+		fields, err := g.fields(n.Underlying().(*types.Struct), n.Obj().Name(), "")
+		if err != nil {
+			return nil, err
+		}
+		g.named[n].fields = fields
+	}
+	return g, nil
+}
+
+// add named type n to the set of types whose methods we generate
+func (g *generator) add(n *types.Named) {
+	if _, ok := g.named[n]; !ok {
+		g.named[n] = &message{typ: n}
+		g.todo = append(g.todo, n)
+	}
+}
+
+// fields returns the fields of s in tag order, the way getPropertiesLocked finds them
+func (g *generator) fields(s *types.Struct, tname, prefix string) ([]field, error) {
+	var fields []field
+	var reserved []uint32
+	for i := 0; i < s.NumFields(); i++ {
+		f := s.Field(i)
+		name := f.Name()
+		stag := reflect.StructTag(s.Tag(i))
+		tag := stag.Get("protobuf")
+
+		if !f.Exported() && f.Pkg() != g.pkg.Types {
+			if tag != "" && tag != "-" {
+				return nil, fmt.Errorf("codegen: %s.%s is unexported and declared in another package", tname, name)
+			}
+			continue
+		}
+
+		if tag == "embedded" && f.Anonymous() {
+			es, ok := gosrc.EmbeddedStruct(f.Type())
+			if !ok {
+				return nil, fmt.Errorf("codegen: embedded field %s of %s is not a struct or a pointer to a struct", name, tname)
+			}
+			efields, err := g.fields(es, tname, prefix+name+".")
+			if err != nil {
+				return nil, err
+			}
+			if p, ok := f.Type().(*types.Pointer); ok {
+				// like embedPtr(), the fields are encoded only when the pointer isn't nil
+				for i := range efields {
+					efields[i].embeds = append([]embed{{prefix + name, p.Elem()}}, efields[i].embeds...)
+				}
+			}
+			fields = append(fields, efields...)
+			_, ids := gosrc.StructIDs(es) // the ids reserved by the embedded struct are reserved here too
+			reserved = append(reserved, ids...)
+			continue
+		}
+
+		if gosrc.IsReserved(f.Type()) {
+			ids, err := gosrc.ParseReserved(tag)
+			if err != nil {
+				return nil, fmt.Errorf("codegen: error parsing protobuf3.Reserved field %q of type %q: %v", name, tname, err)
+			}
+			reserved = append(reserved, ids...)
+			continue
+		}
+
+		if tag == "" {
+			if g.opts.XXXHack && strings.HasPrefix(name, "XXX_") {
+				continue
+			}
+			return nil, fmt.Errorf("codegen: %s.%s (%s) lacks a protobuf tag", tname, name, f.Type())
+		}
+		pt, skip, err := gosrc.ParseTag(tag)
+		if err != nil {
+			return nil, fmt.Errorf("codegen: error preparing field %q of type %q: %v", name, tname, err)
+		}
+		if skip {
+			continue
+		}
+
+		c, err := g.analyzeTag(f.Type(), pt, stag, tname+"_"+name)
+		if err != nil {
+			return nil, fmt.Errorf("codegen: error preparing field %q of type %q: %v", name, tname, err)
+		}
+		fields = append(fields, field{name: name, path: prefix + name, id: pt.ID, c: c})
+	}
+
+	sort.SliceStable(fields, func(i, j int) bool { return fields[i].id < fields[j].id })
+	for i := range fields {
+		f := &fields[i]
+		if i > 0 && fields[i-1].id == f.id {
+			return nil, fmt.Errorf("codegen: error duplicate tag id %d assigned to %s.%s", f.id, tname, f.name)
+		}
+		for _, r := range reserved {
+			if r == f.id {
+				return nil, fmt.Errorf("codegen: error reserved tag id %d assigned to %s.%s", f.id, tname, f.name)
+			}
+		}
+	}
+	return fields, nil
+}
+
+// analyzeTag works out the codec of a field of type t with tag pt
+func (g *generator) analyzeTag(t types.Type, pt gosrc.Tag, stag reflect.StructTag, hint string) (*codec, error) {
+	tf, err := pt.TimeFormat()
+	if err != nil {
+		return nil, err
+	}
+	if tf != "" {
+		c, err := analyzeTime(t, pt.Wire, tf)
+		if err != nil {
+			if t2, ok := gosrc.OptionListElem(t); ok {
+				// like setOptionListEncAndDec(), the option applies to the items of the lists
+				return g.analyzeOptionList(t, t2, pt, hint)
+			}
+		}
+		return c, err
+	}
+	mf, err := pt.MarshalFormat()
+	if err != nil {
+		return nil, err
+	}
+	if mf != "" {
+		c, err := analyzeEncoding(t, pt.Wire, mf)
+		if err != nil {
+			if t2, ok := gosrc.OptionListElem(t); ok {
+				return g.analyzeOptionList(t, t2, pt, hint)
+			}
+		}
+		return c, err
+	}
+	if gosrc.IsSet(t, pt, stag) {
+		return g.analyzeSet(t, pt.Wire, hint)
+	}
+	c, err := g.analyze(t, pt.Wire, stag, hint)
+	if err != nil {
+		return nil, err
+	}
+	c.resize = pt.HasOption("resize")
+	return c, nil
+}
+
+// analyzeOptionList works out the codec of a slice or array t of lists of type t2 whose tag pt has a time format or
+// marshal format option for the items of the lists
+func (g *generator) analyzeOptionList(t, t2 types.Type, pt gosrc.Tag, hint string) (*codec, error) {
+	c := &codec{typ: t, elem: t2, wire: pt.Wire, mode: modeRepeated}
+	_, c.array = t.Underlying().(*types.Array)
+	return c, g.list(c, t2, pt, hint)
+}
+
+// analyzeEncoding works out the codec of a field with marshal format option mf, mirroring setMarshalEncAndDec()
+func analyzeEncoding(t types.Type, wire, mf string) (*codec, error) {
+	elem, _, ok := gosrc.EncodingElem(t, mf)
+	if !ok {
+		return nil, fmt.Errorf("%s with option %s does not implement the encoding interfaces", t, mf)
+	}
+	if wire != "bytes" {
+		return nil, fmt.Errorf("%s cannot have wiretype %s", t, wire)
+	}
+	c := &codec{typ: t, elem: elem, wire: wire, mode: modeValue, kind: kindBinary}
+	if mf == "text" {
+		c.kind = kindText
+	}
+	c.elemMode(t)
+	return c, nil
+}
+
+// elemMode sets the mode of a codec which encodes c.elem, when t is c.elem or a pointer, slice or array of it,
+// the way setCodecEncAndDec() does
+func (c *codec) elemMode(t types.Type) {
+	if t == c.elem {
+		return
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Pointer:
+		c.mode = modePtr
+	case *types.Slice:
+		c.mode = modeRepeated
+	case *types.Array:
+		c.mode = modeRepeated
+		c.array = true
+		if u.Len() == 0 {
+			c.mode = modeNothing
+		}
+	}
+}
+
+// analyzeSet works out the codec of a set, mirroring setSetEncAndDec()
+func (g *generator) analyzeSet(t types.Type, wire, hint string) (*codec, error) {
+	elem, ok := gosrc.SetElem(t)
+	if !ok {
+		return nil, fmt.Errorf("%s: the set option requires a map[K]struct{} or map[K]bool", t)
+	}
+	sc, err := g.analyze(types.NewSlice(elem), wire, "", hint)
+	if err != nil {
+		return nil, err
+	}
+	return &codec{typ: t, elem: t.Underlying().(*types.Map).Elem(), wire: wire, mode: modeSet, set: sc}, nil
+}
+
+// analyzeTime works out the codec of a time.Time field with time format option tf, mirroring setTimeEncAndDec()
+func analyzeTime(t types.Type, wire, tf string) (*codec, error) {
+	c := &codec{typ: t, elem: t, wire: wire, mode: modeValue, time: tf}
+	switch u := t.(type) {
+	case *types.Pointer:
+		c.mode = modePtr
+		c.elem = u.Elem()
+	case *types.Slice:
+		c.mode = modePacked
+		c.elem = u.Elem()
+	}
+	if !gosrc.IsTime(c.elem) {
+		return nil, fmt.Errorf("%s cannot have option %s, which only time.Time, *time.Time and []time.Time can have", t, tf)
+	}
+
+	if tf == "rfc3339" {
+		if wire != "bytes" {
+			return nil, fmt.Errorf("time.Time with option %s cannot have wiretype %s", tf, wire)
+		}
+		c.kind = kindString
+		if c.mode == modePacked {
+			c.mode = modeRepeated
+		}
+		return c, nil
+	}
+	switch wire {
+	case "varint", "fixed64", "zigzag64":
+	default:
+		return nil, fmt.Errorf("time.Time with option %s cannot have wiretype %s", tf, wire)
+	}
+	c.kind = kindInt
+	return c, nil
+}
+
+// analyze works out the codec of a field of type t, mirroring setEncAndDec(). hint names anonymous struct types.
+func (g *generator) analyze(t types.Type, wire string, stag reflect.StructTag, hint string) (*codec, error) {
+	c := &codec{typ: t, elem: t, wire: wire, mode: modeValue}
+	if elem, ok := gosrc.OptionalElem(t); ok {
+		// like setOptionalEncAndDec(), T is encoded as usual, but whenever Set is true
+		if !gosrc.IsOptionalElem(elem) {
+			return nil, fmt.Errorf("%s: Optional supports only scalar, string and []byte types", t)
+		}
+		ec, err := g.analyze(elem, wire, stag, hint)
+		if err != nil {
+			return nil, err
+		}
+		ec.mode = modeOptional
+		ec.typ = t
+		return ec, nil
+	}
+	if elem, _, ok := gosrc.NetElem(t); ok {
+		// mirrors setCodecEncAndDec()
+		if wire != "bytes" {
+			return nil, fmt.Errorf("%s cannot have wiretype %s", t, wire)
+		}
+		c.kind = kindCodec
+		c.elem = elem
+		c.elemMode(t)
+		return c, nil
+	}
+	if k, ok := g.custom(t); ok {
+		c.kind = k
+		return c, nil
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		return c, g.scalar(c, t, wire)
+
+	case *types.Struct:
+		return c, g.message(c, t, wire, hint)
+
+	case *types.Pointer:
+		c.mode = modePtr
+		t2 := u.Elem()
+		c.elem = t2
+		if k, ok := g.custom(t2); ok {
+			c.kind = k
+			return c, nil
+		}
+		switch t2.Underlying().(type) {
+		case *types.Basic:
+			return c, g.scalar(c, t2, wire)
+		case *types.Struct:
+			return c, g.message(c, t2, wire, hint)
+		}
+		return nil, fmt.Errorf("no encoder function for %s", t)
+
+	case *types.Slice:
+		if isByte(u.Elem()) {
+			c.kind = kindBytes
+			c.wire = "bytes" // like setEncAndDec(), whatever the tag's wiretype
+			return c, nil
+		}
+		c.elem = u.Elem()
+		return c, g.repeated(c, u.Elem(), wire, hint)
+
+	case *types.Array:
+		if u.Len() == 0 {
+			c.mode = modeNothing
+			return c, nil
+		}
+		c.array = true
+		if isByte(u.Elem()) {
+			c.kind = kindBytes
+			c.wire = "bytes" // like setEncAndDec(), whatever the tag's wiretype
+			return c, nil
+		}
+		c.elem = u.Elem()
+		return c, g.repeated(c, u.Elem(), wire, hint)
+
+	case *types.Map:
+		if wire != "bytes" {
+			return nil, fmt.Errorf("map %s wiretype is not \"bytes\"", t)
+		}
+		c.mode = modeMap
+		for _, kv := range []struct {
+			c    **codec
+			name string
+			id   uint32
+			typ  types.Type
+		}{
+			{&c.key, "protobuf_key", 1, u.Key()},
+			{&c.val, "protobuf_val", 2, u.Elem()},
+		} {
+			tag := stag.Get(kv.name)
+			pt, skip, err := gosrc.ParseTag(tag)
+			if err != nil || skip || pt.ID != kv.id {
+				return nil, fmt.Errorf("bad %s tag %q", kv.name, tag)
+			}
+			if mf, _ := pt.MarshalFormat(); mf != "" && kv.id == 1 {
+				return nil, fmt.Errorf("%s tag cannot have option %s", kv.name, mf)
+			}
+			var kvc *codec
+			if kv.id == 2 && gosrc.IsMapWrapped(kv.typ) {
+				kvc, err = g.mapValue(kv.typ, pt, stag, hint+"_Value")
+			} else {
+				kvc, err = g.analyzeTag(kv.typ, pt, "", hint)
+			}
+			if err != nil {
+				return nil, err
+			}
+			if kvc.mode == modeRepeated && kvc.array {
+				return nil, fmt.Errorf("map %s of arrays of messages is not supported", t)
+			}
+			if kv.id == 1 && kvc.kind == kindCodec {
+				kvc.mapKey = gosrc.IsNetKey(kv.typ)
+			}
+			*kv.c = kvc
+		}
+		return c, nil
+	}
+
+	return nil, fmt.Errorf("no encoder/decoder for type %s", t)
+}
+
+// custom returns the kind of t if it is, or will be, an Appender or a Marshaler
+func (g *generator) custom(t types.Type) (kind, bool) {
+	if n, ok := t.(*types.Named); ok && n.Obj().Pkg() == g.pkg.Types && isStruct(n) && !gosrc.IsTime(n) {
+		if _, ok := g.named[n]; ok || gosrc.IsGenerated(types.NewPointer(n)) || !isCustom(types.NewPointer(n)) {
+			// a struct type of ours. it gets generated methods too
+			g.add(n)
+			return kindGenerated, true
+		}
+	}
+	ptr_t := types.NewPointer(t)
+	switch {
+	case gosrc.IsGenerated(ptr_t):
+		return kindGenerated, true
+	case gosrc.IsAppender(ptr_t):
+		return kindAppender, true
+	case gosrc.IsMarshaler(ptr_t):
+		return kindMarshaler, true
+	}
+	return 0, false
+}
+
+// scalar sets the kind of the basic type t
+func (g *generator) scalar(c *codec, t types.Type, wire string) error {
+	b := t.Underlying().(*types.Basic)
+	numeric := wire != "bytes"
+	switch {
+	case b.Kind() == types.Bool:
+		c.kind = kindBool
+	case b.Kind() == types.Int64 && wire == "bytes" && gosrc.IsDuration(t):
+		c.kind = kindDuration
+		numeric = true
+	case b.Info()&types.IsInteger != 0 && b.Kind() != types.Uintptr:
+		c.kind = kindInt
+	case b.Kind() == types.Float32:
+		c.kind = kindFloat32
+		numeric = wire == "fixed32"
+	case b.Kind() == types.Float64:
+		c.kind = kindFloat64
+		numeric = wire == "fixed64"
+	case b.Kind() == types.String:
+		c.kind = kindString
+		numeric = wire == "bytes"
+	default:
+		return fmt.Errorf("no encoder/decoder for type %s", t)
+	}
+	if !numeric {
+		return fmt.Errorf("%s cannot have wiretype %s", t, wire)
+	}
+	return nil
+}
+
+// message sets the kind of the struct type t
+func (g *generator) message(c *codec, t types.Type, wire string, hint string) error {
+	if wire != "bytes" {
+		return fmt.Errorf("%s cannot have wiretype %s", t, wire)
+	}
+	if gosrc.IsTime(t) {
+		c.kind = kindTime
+		return nil
+	}
+	s, ok := t.(*types.Struct)
+	if !ok {
+		return fmt.Errorf("%s implements neither protobuf3.Appender nor protobuf3.Marshaler; generate its methods first", t)
+	}
+	c.kind = kindStruct
+	key := g.typeString(s)
+	if a, ok := g.anons[key]; ok {
+		c.anon = a
+		return nil
+	}
+	a := &anon{name: hint, typ: s}
+	g.anons[key] = a
+	g.order = append(g.order, a)
+	c.anon = a
+	fields, err := g.fields(s, hint, "")
+	if err != nil {
+		return err
+	}
+	a.fields = fields
+	return nil
+}
+
+// list sets the kind of the slice or array type t2 of the elements of a slice or array with protobuf tag pt. Like
+// setListEncAndDec(), each element is encoded as a message with the elements of t2 in its field 1.
+func (g *generator) list(c *codec, t2 types.Type, pt gosrc.Tag, hint string) error {
+	c.wire = "bytes" // the wrapper messages are bytes; pt applies to the elements of t2
+	a, ok := g.wrapper(c, t2, strings.Join(append([]string{pt.Wire}, pt.Options...), ","), hint)
+	if ok {
+		return nil
+	}
+	pt.ID = 1
+	ic, err := g.analyzeTag(t2, pt, "", hint+"_Items")
+	if err != nil {
+		return err
+	}
+	a.fields = []field{{name: "Items", id: 1, c: ic}}
+	return nil
+}
+
+// mapValue works out the codec of the value of type t of a map, when t is a list or a map, with protobuf_val tag pt
+// in struct tag stag. Like getMapValueWrapperLocked(), the value is encoded as a message with t in its field 1.
+func (g *generator) mapValue(t types.Type, pt gosrc.Tag, stag reflect.StructTag, hint string) (*codec, error) {
+	c := &codec{typ: t, elem: t, wire: "bytes", mode: modeValue}
+	pt.ID = 1
+	vtag := gosrc.MapValueTag(stag, t)
+	a, ok := g.wrapper(c, t, strings.Join(append([]string{pt.Wire, "1"}, pt.Options...), ",")+" "+string(vtag), hint)
+	if ok {
+		return c, nil
+	}
+	ic, err := g.analyzeTag(t, pt, vtag, hint+"_Items")
+	if err != nil {
+		return nil, err
+	}
+	a.fields = []field{{name: "Items", id: 1, c: ic}}
+	return c, nil
+}
+
+// wrapper sets c to encode values of list or map type t as wrapper messages, whose field 1 has tag tag, and
+// returns the wrapper, and true if the wrapper's fields are already known
+func (g *generator) wrapper(c *codec, t types.Type, tag string, hint string) (*anon, bool) {
+	c.kind = kindStruct
+	key := g.typeString(t) + " " + tag
+	if a, ok := g.anons[key]; ok {
+		c.anon = a
+		return a, true
+	}
+	a := &anon{name: hint, typ: t, wrapper: true}
+	g.anons[key] = a
+	g.order = append(g.order, a)
+	c.anon = a
+	return a, false
+}
+
+// repeated sets the mode and kind of a slice or array with elements of type t2
+func (g *generator) repeated(c *codec, t2 types.Type, wire string, hint string) error {
+	c.mode = modeRepeated
+	if k, ok := g.custom(t2); ok {
+		c.kind = k
+		return nil
+	}
+	if gosrc.IsList(t2) {
+		return g.list(c, t2, gosrc.Tag{Wire: wire}, hint)
+	}
+	switch u := t2.Underlying().(type) {
+	case *types.Basic:
+		if err := g.scalar(c, t2, wire); err != nil {
+			return err
+		}
+		switch c.kind {
+		case kindString, kindDuration:
+		default:
+			c.mode = modePacked
+		}
+		return nil
+
+	case *types.Struct:
+		return g.message(c, t2, wire, hint)
+
+	case *types.Pointer:
+		t3 := u.Elem()
+		if gosrc.IsByteArray(t3) && !c.array {
+			// like enc_slice_ptr_array_byte()
+			c.ptrElem = true
+			c.elem = t3
+			c.kind = kindByteArray
+			if wire != "bytes" {
+				return fmt.Errorf("%s cannot have wiretype %s", c.typ, wire)
+			}
+			return nil
+		}
+		if !isStruct(t3) {
+			break
+		}
+		c.ptrElem = true
+		c.elem = t3
+		if wire != "bytes" {
+			return fmt.Errorf("%s cannot have wiretype %s", c.typ, wire)
+		}
+		if k, ok := g.custom(t3); ok {
+			c.kind = k
+			return nil
+		}
+		return g.message(c, t3, wire, hint)
+
+	case *types.Slice:
+		if !c.array && isByte(u.Elem()) {
+			c.kind = kindBytes
+			if wire != "bytes" {
+				return fmt.Errorf("%s cannot have wiretype %s", c.typ, wire)
+			}
+			return nil
+		}
+
+	case *types.Array:
+		if isByte(u.Elem()) {
+			c.kind = kindByteArray
+			if wire != "bytes" {
+				return fmt.Errorf("%s cannot have wiretype %s", c.typ, wire)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("no encoder/decoder for type %s", c.typ)
+}
+
+// the wiretype on the wire, which is WireBytes for packed fields
+func (c *codec) wireType() string {
+	if c.mode == modeSet {
+		return c.set.wireType()
+	}
+	if c.mode == modePacked {
+		return "protobuf3.WireBytes"
+	}
+	return wireConst(c.wire)
+}
+
+func wireConst(wire string) string {
+	switch wire {
+	case "fixed32":
+		return "protobuf3.WireFixed32"
+	case "fixed64":
+		return "protobuf3.WireFixed64"
+	case "bytes":
+		return "protobuf3.WireBytes"
+	}
+	return "protobuf3.WireVarint"
+}
+
+// the tagcode of field id, as a Go string literal
+func (c *codec) tagcode(id uint32) string {
+	var wt uint32
+	switch c.wireType() {
+	case "protobuf3.WireFixed32":
+		wt = 5
+	case "protobuf3.WireFixed64":
+		wt = 1
+	case "protobuf3.WireBytes":
+		wt = 2
+	}
+	x := id<<3 | wt
+	var b strings.Builder
+	b.WriteByte('"')
+	for x > 127 {
+		fmt.Fprintf(&b, "\\x%02x", 0x80|uint8(x))
+		x >>= 7
+	}
+	fmt.Fprintf(&b, "\\x%02x\"", uint8(x))
+	return b.String()
+}
+
+// the names of the functions and methods which encode, decode and count the wiretype
+func (c *codec) valFuncs() (enc, dec, cnt string) {
+	switch c.wire {
+	case "fixed32":
+		return "protobuf3.AppendFixed32", "DecodeFixed32", "CountFixed32s"
+	case "fixed64":
+		return "protobuf3.AppendFixed64", "DecodeFixed64", "CountFixed64s"
+	case "zigzag32":
+		return "protobuf3.AppendZigzag32", "DecodeZigzag32", "CountVarints"
+	case "zigzag64":
+		return "protobuf3.AppendZigzag64", "DecodeZigzag64", "CountVarints"
+	}
+	return "protobuf3.AppendVarint", "DecodeVarint", "CountVarints"
+}
+
+// generation of the source
+
+func (g *generator) p(format string, args ...interface{}) {
+	fmt.Fprintf(g.out, format, args...)
+	g.out.WriteByte('\n')
+}
+
+// typeString returns the Go source of type t, importing the packages it needs
+func (g *generator) typeString(t types.Type) string {
+	return types.TypeString(t, func(pkg *types.Package) string {
+		if pkg == g.pkg.Types {
+			return ""
+		}
+		g.imports[pkg.Path()] = pkg.Name()
+		return pkg.Name()
+	})
+}
+
+func (g *generator) use(path string) {
+	g.imports[path] = path[strings.LastIndexByte(path, '/')+1:]
+}
+
+// conv returns expr converted to type t, unless it already is of type basic
+func (g *generator) conv(t types.Type, basic types.BasicKind, expr string) string {
+	if types.Identical(t, types.Typ[basic]) {
+		return expr
+	}
+	return g.typeString(t) + "(" + expr + ")"
+}
+
+// addr returns the address of the addressable expression x
+func addr(x string) string {
+	if strings.HasPrefix(x, "(*") && strings.HasSuffix(x, ")") {
+		return x[2 : len(x)-1]
+	}
+	return "&" + x
+}
+
+func (g *generator) generate() ([]byte, error) {
+	var body bytes.Buffer
+	g.out = &body
+
+	g.use("github.com/mistsys/protobuf3/protobuf3")
+	for _, m := range g.messages() {
+		name := m.typ.Obj().Name()
+		g.p("")
+		g.p("var _ protobuf3.Generated = (*%s)(nil)", name)
+		g.p("")
+		g.p("// AppendProtobuf3 appends the protobuf encoding of m to b. It implements protobuf3.Appender.")
+		g.p("func (m *%s) AppendProtobuf3(b []byte) ([]byte, error) {", name)
+		g.appendBody(m.fields)
+		g.p("}")
+		g.p("")
+		g.p("// UnmarshalProtobuf3 decodes buf into m, merging with the existing contents of m. It implements protobuf3.Appender.")
+		g.p("func (m *%s) UnmarshalProtobuf3(buf []byte) error {", name)
+		g.p("b := protobuf3.MakeBuffer(buf)")
+		g.p("return m.DecodeProtobuf3(&b)")
+		g.p("}")
+		g.p("")
+		g.p("// DecodeProtobuf3 decodes the rest of b into m, merging with the existing contents of m. It implements protobuf3.Generated.")
+		g.p("func (m *%s) DecodeProtobuf3(b *protobuf3.Buffer) error {", name)
+		g.unmarshalBody(m.fields, g.pkg.Name+"."+name)
+		g.p("}")
+		g.p("")
+		g.p("// Protobuf3Generated marks %s as implementing protobuf3.Generated", name)
+		g.p("func (*%s) Protobuf3Generated() {}", name)
+	}
+
+	for _, a := range g.order {
+		ts := g.typeString(a.typ)
+		what := "an anonymous struct"
+		if a.wrapper {
+			what = "a list or map encoded as a message"
+		}
+		g.p("")
+		g.p("// protobuf3Append_%s appends the protobuf encoding of m, %s, to b", a.name, what)
+		g.p("func protobuf3Append_%s(b []byte, m *%s) ([]byte, error) {", a.name, ts)
+		g.appendBody(a.fields)
+		g.p("}")
+		g.p("")
+		g.p("// protobuf3Unmarshal_%s decodes the rest of b into m, %s", a.name, what)
+		g.p("func protobuf3Unmarshal_%s(b *protobuf3.Buffer, m *%s) error {", a.name, ts)
+		g.unmarshalBody(a.fields, a.name)
+		g.p("}")
+	}
+
+	var out bytes.Buffer
+	g.out = &out
+	g.p("%s", Header)
+	g.p("")
+	g.p("package %s", g.pkg.Name)
+	g.imp()
+	out.Write(body.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return out.Bytes(), fmt.Errorf("codegen: generated code does not parse: %v", err)
+	}
+	return src, nil
+}
+
+// imp writes the import declaration
+func (g *generator) imp() {
+	var std, other []string
+	for path := range g.imports {
+		if strings.Contains(path, ".") {
+			other = append(other, path)
+		} else {
+			std = append(std, path)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(other)
+	g.p("")
+	g.p("import (")
+	for _, path := range std {
+		g.p("%q", path)
+	}
+	if len(std) != 0 && len(other) != 0 {
+		g.p("")
+	}
+	for _, path := range other {
+		if name := g.imports[path]; name != path[strings.LastIndexByte(path, '/')+1:] {
+			g.p("%s %q", name, path)
+		} else {
+			g.p("%q", path)
+		}
+	}
+	g.p(")")
+}
+
+// shadow returns the name of the shadow type of struct type t, adding its declaration, and those of the shadow types
+// it uses, to decls. Usually the shadow type is declared as `type reflectT T`. But a struct embedding a type with
+// methods inherits the methods, so then the shadow type is a struct with t's fields, embedding the shadow type of
+// the embedded type in its place.
+func (g *generator) shadow(t *types.Named, decls map[string]string) (string, error) {
+	name := "reflect" + t.Obj().Name()
+	if _, ok := decls[name]; ok {
+		return name, nil
+	}
+	decls[name] = fmt.Sprintf("type %s %s // same fields, without the methods", name, g.typeString(t))
+
+	st := t.Underlying().(*types.Struct)
+	var fields []string
+	promoted := false
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		ft := g.typeString(f.Type())
+		if f.Embedded() {
+			et, ptr := f.Type(), ""
+			if p, ok := et.(*types.Pointer); ok {
+				et, ptr = p.Elem(), "*"
+			}
+			if n, ok := et.(*types.Named); ok && g.promotes(n, nil) {
+				// the shadow of the embedded type is encoded the same way, but by reflection
+				tag, _ := reflect.StructTag(st.Tag(i)).Lookup("protobuf")
+				if _, ok := g.named[n]; n.Obj().Pkg() != g.pkg.Types || (tag != "embedded" && !ok) {
+					return "", fmt.Errorf("codegen: %s embeds %s, whose methods the reflective encoder would use in place of %s's", t.Obj().Name(), g.typeString(n), t.Obj().Name())
+				}
+				s, err := g.shadow(n, decls)
+				if err != nil {
+					return "", err
+				}
+				ft = ptr + s
+				promoted = true
+			}
+		} else {
+			ft = f.Name() + " " + ft
+		}
+		if tag := st.Tag(i); tag != "" {
+			if strings.Contains(tag, "`") {
+				ft += " " + strconv.Quote(tag)
+			} else {
+				ft += " `" + tag + "`"
+			}
+		}
+		fields = append(fields, ft)
+	}
+	if promoted {
+		decls[name] = fmt.Sprintf("// %s has the fields of %s, embedding shadow types in place of the types with methods\ntype %s struct {\n%s\n}", name, t.Obj().Name(), name, strings.Join(fields, "\n"))
+	}
+	return name, nil
+}
+
+// promotes returns true if a struct embedding t would inherit protobuf3 methods from it, either t's own or those
+// t inherits from the types it embeds. seen holds the types already being checked.
+func (g *generator) promotes(t *types.Named, seen map[*types.Named]bool) bool {
+	if _, ok := g.named[t]; ok || isCustom(types.NewPointer(t)) || gosrc.IsGenerated(types.NewPointer(t)) {
+		return true
+	}
+	if seen[t] {
+		return false
+	}
+	if seen == nil {
+		seen = make(map[*types.Named]bool)
+	}
+	seen[t] = true
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return false
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		if f := st.Field(i); f.Embedded() {
+			et := f.Type()
+			if p, ok := et.(*types.Pointer); ok {
+				et = p.Elem()
+			}
+			if n, ok := et.(*types.Named); ok && g.promotes(n, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// messages returns the named struct types we generate methods for, sorted by name
+func (g *generator) messages() []*message {
+	var named []*message
+	for _, m := range g.named {
+		named = append(named, m)
+	}
+	sort.Slice(named, func(i, j int) bool { return named[i].typ.Obj().Name() < named[j].typ.Obj().Name() })
+	return named
+}
+
+func (g *generator) generateTest() ([]byte, error) {
+	var body bytes.Buffer
+	g.out = &body
+	g.imports = map[string]string{"testing": "testing"}
+	g.use("github.com/mistsys/protobuf3/protobuf3/codegen/crosscheck")
+
+	// the shadow types, which have the fields of the types but none of their methods, so they are encoded by reflection
+	decls := make(map[string]string)
+	shadows := make(map[*types.Named]string)
+	for _, m := range g.messages() {
+		name, err := g.shadow(m.typ, decls)
+		if err != nil {
+			return nil, err
+		}
+		shadows[m.typ] = name
+	}
+	names := make([]string, 0, len(decls))
+	for name := range decls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		g.p("")
+		g.p("%s", decls[name])
+	}
+
+	// test every type, since the shadow type of one type still uses the generated methods of the named types in its fields
+	for _, m := range g.messages() {
+		name := m.typ.Obj().Name()
+		g.p("")
+		g.p("// TestProtobuf3Generated_%s cross-checks the generated methods of %s against the reflective encoder and decoder", name, name)
+		g.p("func TestProtobuf3Generated_%s(t *testing.T) {", name)
+		g.p("crosscheck.Test(t, new(%s), new(%s))", name, shadows[m.typ])
+		g.p("}")
+	}
+
+	var out bytes.Buffer
+	g.out = &out
+	g.p("%s", Header)
+	g.p("")
+	g.p("package %s", g.pkg.Name)
+	g.imp()
+	out.Write(body.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return out.Bytes(), fmt.Errorf("codegen: generated code does not parse: %v", err)
+	}
+	return src, nil
+}
+
+// appendBody writes the body of an AppendProtobuf3 method
+func (g *generator) appendBody(fields []field) {
+	body := g.out
+	var buf bytes.Buffer
+	g.out = &buf
+	g.usesErr = false
+	for i := range fields {
+		f := &fields[i]
+		g.p("// %s", f.name)
+		if len(f.embeds) != 0 {
+			var nonNil []string
+			for _, e := range f.embeds {
+				nonNil = append(nonNil, "m."+e.path+" != nil")
+			}
+			g.p("if %s {", strings.Join(nonNil, " && "))
+			g.encode(f.c, f.id, f.expr())
+			g.p("}")
+			continue
+		}
+		g.encode(f.c, f.id, f.expr())
+	}
+	g.out = body
+	if g.usesErr {
+		g.p("var err error")
+	}
+	body.Write(buf.Bytes())
+	g.p("return b, nil")
+}
+
+// encode writes the code which appends field id of value x
+func (g *generator) encode(c *codec, id uint32, x string) {
+	tc := c.tagcode(id)
+	switch c.mode {
+	case modeNothing:
+		g.p("// a zero-length array encodes as nothing")
+
+	case modeValue:
+		g.encodeValue(c, tc, x, true)
+
+	case modeOptional:
+		g.p("if %s.Set {", x)
+		g.encodeValue(c, tc, x+".Value", false)
+		g.p("}")
+
+	case modePtr:
+		switch c.kind {
+		case kindGenerated, kindAppender, kindMarshaler, kindCodec, kindBinary, kindText:
+			g.p("if %s != nil {", x)
+			g.encodeCustom(c, tc, x, true)
+			g.p("}")
+		case kindDuration:
+			g.p("if %s != nil && *%s != 0 {", x, x)
+			g.encodeValue(c, tc, "(*"+x+")", false)
+			g.p("}")
+		default:
+			g.p("if %s != nil {", x)
+			g.encodeValue(c, tc, "(*"+x+")", false)
+			g.p("}")
+		}
+
+	case modePacked:
+		if c.array {
+			g.p("{")
+		} else {
+			g.p("if len(%s) != 0 {", x)
+		}
+		g.p("b = append(b, %s...)", tc)
+		g.p("b = append(b, 0)")
+		g.p("n := len(b)")
+		g.p("for _, x := range %s {", x)
+		g.encodeScalar(c, "x")
+		g.p("}")
+		g.p("b = protobuf3.FixupLength(b, n)")
+		g.p("}")
+
+	case modeRepeated:
+		if c.ptrElem {
+			g.p("for _, x := range %s {", x)
+			g.p("if x == nil {")
+			g.p("return b, protobuf3.ErrRepeatedHasNil")
+			g.p("}")
+			g.encodeValue(c, tc, "(*x)", false)
+		} else {
+			g.p("for i := range %s {", x)
+			g.encodeValue(c, tc, x+"[i]", false)
+		}
+		g.p("}")
+
+	case modeSet:
+		g.p("if len(%s) != 0 {", x)
+		g.p("elems := make(%s, 0, len(%s))", g.typeString(c.set.typ), x)
+		if isBool(c.elem) {
+			g.p("for k, v := range %s {", x)
+			g.p("if v {")
+			g.p("elems = append(elems, k)")
+			g.p("}")
+		} else {
+			g.p("for k := range %s {", x)
+			g.p("elems = append(elems, k)")
+		}
+		g.p("}")
+		g.encode(c.set, id, "elems")
+		g.p("}")
+
+	case modeMap:
+		g.p("for k, v := range %s {", x)
+		g.p("b = append(b, %s...)", tc)
+		g.p("b = append(b, 0)")
+		g.p("n := len(b)")
+		g.encode(c.key, 1, "k")
+		g.encode(c.val, 2, "v")
+		g.p("b = protobuf3.FixupLength(b, n)")
+		g.p("}")
+	}
+}
+
+// encodeScalar writes the code which appends the value x of a numeric or bool type
+func (g *generator) encodeScalar(c *codec, x string) {
+	enc, _, _ := c.valFuncs()
+	switch c.kind {
+	case kindBool:
+		g.p("if %s {", x)
+		g.p("b = %s(b, 1)", enc)
+		g.p("} else {")
+		g.p("b = %s(b, 0)", enc)
+		g.p("}")
+	default:
+		g.p("b = %s(b, %s)", enc, g.uint64(c, x))
+	}
+}
+
+// uint64 returns the expression which converts x to the uint64 which the reflective encoder encodes
+func (g *generator) uint64(c *codec, x string) string {
+	switch c.time {
+	case "unixnano":
+		return "uint64(protobuf3.UnixNanoTime(" + x + "))"
+	case "unixmilli":
+		return "uint64(protobuf3.UnixMilliTime(" + x + "))"
+	}
+	switch c.kind {
+	case kindFloat32:
+		g.use("math")
+		return "uint64(math.Float32bits(" + g.conv(c.elem, types.Float32, x) + "))"
+	case kindFloat64:
+		g.use("math")
+		return "math.Float64bits(" + g.conv(c.elem, types.Float64, x) + ")"
+	}
+	if types.Identical(c.elem, types.Typ[types.Uint64]) {
+		return x
+	}
+	return "uint64(" + x + ")"
+}
+
+// encodeValue writes the code which appends the value x of type T with tagcode tc. If elide is true then the zero value
+// is omitted
+func (g *generator) encodeValue(c *codec, tc, x string, elide bool) {
+	switch c.kind {
+	case kindBool:
+		enc, _, _ := c.valFuncs()
+		if elide {
+			g.p("if %s {", x)
+			g.p("b = append(b, %s...)", tc)
+			g.p("b = %s(b, 1)", enc)
+			g.p("}")
+			return
+		}
+		g.p("b = append(b, %s...)", tc)
+		g.encodeScalar(c, x)
+
+	case kindInt, kindFloat32, kindFloat64:
+		if elide && c.time != "" {
+			enc, _, _ := c.valFuncs()
+			g.p("if u := %s; u != 0 {", g.uint64(c, x))
+			g.p("b = append(b, %s...)", tc)
+			g.p("b = %s(b, u)", enc)
+			g.p("}")
+			return
+		}
+		if elide {
+			if c.kind == kindInt {
+				g.p("if %s != 0 {", x)
+			} else {
+				g.p("if %s != 0 {", g.uint64(c, x))
+			}
+		}
+		g.p("b = append(b, %s...)", tc)
+		g.encodeScalar(c, x)
+		if elide {
+			g.p("}")
+		}
+
+	case kindString:
+		if c.time != "" {
+			if elide {
+				g.p("if s := protobuf3.RFC3339Time(%s); len(s) != 0 {", x)
+				g.p("b = append(b, %s...)", tc)
+				g.p("b = protobuf3.AppendStringBytes(b, s)")
+				g.p("}")
+				return
+			}
+			g.p("b = append(b, %s...)", tc)
+			g.p("b = protobuf3.AppendStringBytes(b, protobuf3.RFC3339Time(%s))", x)
+			return
+		}
+		if elide {
+			g.p("if len(%s) != 0 {", x)
+		}
+		g.p("b = append(b, %s...)", tc)
+		g.p("b = protobuf3.AppendStringBytes(b, %s)", g.conv(c.elem, types.String, x))
+		if elide {
+			g.p("}")
+		}
+
+	case kindByteArray:
+		g.p("b = append(b, %s...)", tc)
+		g.p("b = protobuf3.AppendRawBytes(b, %s[:])", x)
+
+	case kindBytes:
+		t := c.elem
+		if c.mode == modeValue {
+			t = c.typ
+		}
+		if c.array {
+			g.p("b = append(b, %s...)", tc)
+			g.p("b = protobuf3.AppendRawBytes(b, %s[:])", x)
+			return
+		}
+		if elide {
+			g.p("if len(%s) != 0 {", x)
+		}
+		g.p("b = append(b, %s...)", tc)
+		if types.Identical(t, types.NewSlice(types.Typ[types.Byte])) {
+			g.p("b = protobuf3.AppendRawBytes(b, %s)", x)
+		} else {
+			g.p("b = protobuf3.AppendRawBytes(b, []byte(%s))", x)
+		}
+		if elide {
+			g.p("}")
+		}
+
+	case kindDuration, kindTime:
+		if elide && c.kind == kindDuration {
+			g.p("if %s != 0 {", x)
+		} else {
+			g.p("{")
+		}
+		g.p("b = append(b, %s...)", tc)
+		g.p("b = append(b, 0)")
+		g.p("n := len(b)")
+		if c.kind == kindDuration {
+			g.p("b = protobuf3.AppendDuration(b, %s)", x)
+		} else {
+			g.p("b = protobuf3.AppendTimestamp(b, %s)", x)
+		}
+		g.p("b = protobuf3.FixupLength(b, n)")
+		g.p("}")
+
+	case kindStruct:
+		g.usesErr = true
+		g.p("{")
+		if elide {
+			g.p("n1 := len(b)")
+		}
+		g.p("b = append(b, %s...)", tc)
+		g.p("b = append(b, 0)")
+		g.p("n := len(b)")
+		g.p("if b, err = protobuf3Append_%s(b, %s); err != nil {", c.anon.name, addr(x))
+		g.p("return b, err")
+		g.p("}")
+		if elide {
+			g.p("if len(b) == n {")
+			g.p("b = b[:n1]")
+			g.p("} else {")
+			g.p("b = protobuf3.FixupLength(b, n)")
+			g.p("}")
+		} else {
+			g.p("b = protobuf3.FixupLength(b, n)")
+		}
+		g.p("}")
+
+	case kindGenerated, kindAppender, kindMarshaler, kindCodec, kindBinary, kindText:
+		g.encodeCustom(c, tc, addr(x), elide)
+	}
+}
+
+// encodeCustom writes the code which appends *ptr, an Appender, a Marshaler, or a type encoded by a codec or by its encoding methods
+func (g *generator) encodeCustom(c *codec, tc, ptr string, elide bool) {
+	g.usesErr = true
+	if c.mapKey {
+		g.p("if b, err = protobuf3.AppendCodecKey(b, %s, %s, %s); err != nil {", tc, wireConst(c.wire), ptr)
+		g.p("return b, err")
+		g.p("}")
+		return
+	}
+	fn := "protobuf3.AppendAppender"
+	switch c.kind {
+	case kindMarshaler:
+		fn = "protobuf3.AppendMarshaler"
+	case kindCodec:
+		fn = "protobuf3.AppendCodec"
+	case kindBinary:
+		fn = "protobuf3.AppendBinary"
+	case kindText:
+		fn = "protobuf3.AppendText"
+	}
+	if c.kind == kindBinary || c.kind == kindText {
+		// these are always WireBytes
+		g.p("if b, err = %s(b, %s, %s, %t); err != nil {", fn, tc, ptr, !elide)
+	} else {
+		g.p("if b, err = %s(b, %s, %s, %s, %t); err != nil {", fn, tc, wireConst(c.wire), ptr, !elide)
+	}
+	g.p("return b, err")
+	g.p("}")
+}
+
+// unmarshalBody writes the body of an UnmarshalProtobuf3 method. tname names the type in errors
+func (g *generator) unmarshalBody(fields []field, tname string) {
+	body := g.out
+	var buf bytes.Buffer
+	g.out = &buf
+	g.vars = nil
+	for i := range fields {
+		f := &fields[i]
+		if f.c.mode == modeNothing {
+			continue // the reflective decoder skips it too
+		}
+		g.p("case %d: // %s", f.id, f.name)
+		for _, e := range f.embeds {
+			// like dec_embedded(), allocate the embedded struct when the first of its fields arrives
+			g.p("if m.%s == nil {", e.path)
+			g.p("m.%s = new(%s)", e.path, g.typeString(e.typ))
+			g.p("}")
+		}
+		g.decode(f.c, f.expr(), "b", tname, f.name, "i"+strconv.Itoa(int(f.id)))
+	}
+	g.out = body
+
+	for _, v := range g.vars {
+		g.p("var %s int // index of the next element of an array", v)
+	}
+	g.p("for !b.EOF() {")
+	g.p("tag, wt, err := b.DecodeTag()")
+	g.p("if err != nil {")
+	g.p("return err")
+	g.p("}")
+	g.p("switch tag {")
+	body.Write(buf.Bytes())
+	g.p("default:")
+	g.p("if err := b.SkipValue(wt); err != nil {")
+	g.p("return err")
+	g.p("}")
+	g.p("}")
+	g.p("}")
+	g.p("return nil")
+}
+
+func (g *generator) check() {
+	g.p("if err != nil {")
+	g.p("return err")
+	g.p("}")
+}
+
+// how decodeValue stores a decoded value
+type store int
+
+const (
+	storeValue  store = iota // x = v
+	storePtr                 // x = &v
+	storeAppend              // x = append(x, v)
+	storeIndex               // x[i] = v
+)
+
+// decode writes the code which decodes a field into x from buffer B. idx is the name of the array index
+// variable, should the field need one
+func (g *generator) decode(c *codec, x, B, tname, fname, idx string) {
+	if c.mode == modeSet {
+		g.p("var elems %s", g.typeString(c.set.typ))
+		g.decode(c.set, "elems", B, tname, fname, idx)
+		g.p("if %s == nil {", x)
+		g.p("%s = make(%s, len(elems))", x, g.typeString(c.typ))
+		g.p("}")
+		g.p("for _, k := range elems {")
+		if isBool(c.elem) {
+			g.p("%s[k] = true", x)
+		} else {
+			g.p("%s[k] = %s{}", x, g.typeString(c.elem))
+		}
+		g.p("}")
+		return
+	}
+
+	want := c.wireType()
+	g.p("if wt != %s {", want)
+	g.p("return protobuf3.WireTypeError(%q, %q, wt, %s)", tname, fname, want)
+	g.p("}")
+
+	switch c.mode {
+	case modeValue:
+		g.decodeValue(c, x, B, fname, storeValue, "")
+
+	case modePtr:
+		g.decodeValue(c, x, B, fname, storePtr, "")
+
+	case modeOptional:
+		g.decodeValue(c, x+".Value", B, fname, storeValue, "")
+		g.p("%s.Set = true", x)
+
+	case modePacked:
+		_, dec, cnt := c.valFuncs()
+		g.p("raw, err := %s.DecodeRawBytes()", B)
+		g.check()
+		g.p("p := %s.NestedBuffer(raw)", B)
+		if c.array {
+			// like the reflective decoder we assume the array is encoded in one block
+			g.p("i := 0")
+		} else {
+			g.p("if %s == nil {", x)
+			g.p("%s = make(%s, 0, p.%s(uint(len(raw))))", x, g.typeString(c.typ), cnt)
+			g.p("}")
+		}
+		g.p("for !p.EOF() {")
+		g.p("u, err := p.%s()", dec)
+		g.check()
+		if c.array {
+			g.p("if i < len(%s) {", x)
+			g.p("%s[i] = %s", x, g.fromUint64(c, "u"))
+			g.p("i++")
+			g.p("}")
+		} else {
+			g.p("%s = append(%s, %s)", x, x, g.fromUint64(c, "u"))
+		}
+		g.p("}")
+
+	case modeRepeated:
+		if c.array {
+			g.vars = append(g.vars, idx)
+			g.decodeValue(c, x, B, fname, storeIndex, idx)
+		} else {
+			g.decodeValue(c, x, B, fname, storeAppend, "")
+		}
+
+	case modeMap:
+		g.p("raw, err := %s.DecodeRawBytes()", B)
+		g.check()
+		g.p("if %s == nil {", x)
+		g.p("%s = make(%s)", x, g.typeString(c.typ))
+		g.p("}")
+		g.p("var k %s", g.typeString(c.key.typ))
+		g.p("var v %s", g.typeString(c.val.typ))
+		g.p("e := %s.NestedBuffer(raw)", B)
+		g.p("for !e.EOF() {")
+		g.p("tag, wt, err := e.DecodeTag()")
+		g.check()
+		g.p("switch tag {")
+		g.p("case 1:")
+		g.decode(c.key, "k", "e", tname, fname+".Key", "")
+		g.p("case 2:")
+		g.decode(c.val, "v", "e", tname, fname+".Value", "")
+		g.p("default:")
+		g.use("fmt")
+		g.p(`return fmt.Errorf("protobuf3: bad map data tag %%d", tag)`)
+		g.p("}")
+		g.p("}")
+		g.p("%s[k] = v", x)
+	}
+}
+
+// fromUint64 returns the expression which converts u, as decoded, to the element type of c
+func (g *generator) fromUint64(c *codec, u string) string {
+	switch c.time {
+	case "unixnano":
+		return "protobuf3.TimeFromUnixNano(int64(" + u + "))"
+	case "unixmilli":
+		return "protobuf3.TimeFromUnixMilli(int64(" + u + "))"
+	}
+	switch c.kind {
+	case kindBool:
+		return g.conv(c.elem, types.Bool, u+" != 0")
+	case kindFloat32:
+		g.use("math")
+		return g.conv(c.elem, types.Float32, "math.Float32frombits(uint32("+u+"))")
+	case kindFloat64:
+		g.use("math")
+		return g.conv(c.elem, types.Float64, "math.Float64frombits("+u+")")
+	}
+	return g.conv(c.elem, types.Uint64, u)
+}
+
+// decodeValue writes the code which decodes one value of type T from B and stores it in x. fname names the field in errors
+func (g *generator) decodeValue(c *codec, x, B, fname string, st store, idx string) {
+	et := c.elem
+	if c.kind == kindBytes && c.mode == modeValue {
+		et = c.typ
+	}
+
+	switch c.kind {
+	case kindBool, kindInt, kindFloat32, kindFloat64:
+		_, dec, _ := c.valFuncs()
+		g.p("u, err := %s.%s()", B, dec)
+		g.check()
+		g.storeValue(x, g.fromUint64(c, "u"), st, idx)
+
+	case kindString:
+		g.p("s, err := %s.DecodeStringBytes()", B)
+		g.check()
+		if c.time != "" {
+			g.p("y, err := protobuf3.TimeFromRFC3339(s)")
+			g.check()
+			g.storeValue(x, "y", st, idx)
+			return
+		}
+		g.storeValue(x, g.conv(et, types.String, "s"), st, idx)
+
+	case kindBytes:
+		g.p("raw, err := %s.DecodeRawBytes()", B)
+		g.check()
+		if c.array {
+			// like the reflective decoder we assume the array is encoded in one block
+			g.p("copy(%s[:], raw)", x)
+			return
+		}
+		g.p("y := make(%s, len(raw))", g.typeString(et))
+		g.p("copy(y, raw)")
+		g.storeValue(x, "y", st, idx)
+
+	case kindByteArray:
+		g.p("raw, err := %s.DecodeRawBytes()", B)
+		g.check()
+		if !c.resize {
+			// like copy_array_byte(), elements must be the length of the array unless the field has the resize option
+			n := et.Underlying().(*types.Array).Len()
+			g.p("if len(raw) != %d {", n)
+			g.use("fmt")
+			g.p(`return fmt.Errorf("protobuf3: %s element is %%d bytes long, not %d", len(raw))`, fname, n)
+			g.p("}")
+		}
+		if c.ptrElem {
+			g.p("y := new(%s)", g.typeString(et))
+		} else {
+			g.p("var y %s", g.typeString(et))
+		}
+		g.p("copy(y[:], raw)")
+		g.storeValue(x, "y", st, idx)
+
+	case kindDuration, kindTime:
+		g.p("raw, err := %s.DecodeRawBytes()", B)
+		g.check()
+		g.p("d := %s.NestedBuffer(raw)", B)
+		if c.kind == kindDuration {
+			g.p("y, err := d.DecodeDuration()")
+		} else {
+			g.p("y, err := d.DecodeTimestamp()")
+		}
+		g.check()
+		if c.kind == kindTime && st == storePtr {
+			// the reflective decoder overwrites an existing time.Time
+			g.p("if %s == nil {", x)
+			g.p("%s = new(%s)", x, g.typeString(et))
+			g.p("}")
+			g.p("*%s = y", x)
+			return
+		}
+		if c.ptrElem {
+			g.storeValue(x, "&y", st, idx)
+			return
+		}
+		g.storeValue(x, "y", st, idx)
+
+	case kindStruct, kindGenerated, kindAppender, kindMarshaler, kindCodec, kindBinary, kindText:
+		if c.kind == kindStruct {
+			g.p("raw, err := %s.DecodeRawBytes()", B)
+		} else {
+			g.p("raw, err := %s.DecodeRawValue(wt)", B)
+		}
+		g.check()
+		switch {
+		case st == storeValue:
+			g.unmarshalInto(c, B, addr(x))
+		case st == storePtr:
+			g.p("if %s == nil {", x)
+			g.p("%s = new(%s)", x, g.typeString(et))
+			g.p("}")
+			g.unmarshalInto(c, B, x)
+		case c.ptrElem:
+			g.p("y := new(%s)", g.typeString(et))
+			g.unmarshalInto(c, B, "y")
+			g.storeValue(x, "y", st, idx)
+		case st == storeAppend:
+			g.p("var y %s", g.typeString(et))
+			g.p("%s = append(%s, y)", x, x)
+			g.unmarshalInto(c, B, "&"+x+"[len("+x+")-1]")
+		case st == storeIndex:
+			g.p("if %s < len(%s) {", idx, x)
+			g.unmarshalInto(c, B, "&"+x+"["+idx+"]")
+			g.p("%s++", idx)
+			g.p("}")
+		}
+	}
+}
+
+// storeValue writes the code which stores the value v in x
+func (g *generator) storeValue(x, v string, st store, idx string) {
+	switch st {
+	case storeValue:
+		g.p("%s = %s", x, v)
+	case storePtr:
+		if v != "y" {
+			g.p("y := %s", v)
+		}
+		g.p("%s = &y", x)
+	case storeAppend:
+		g.p("%s = append(%s, %s)", x, x, v)
+	case storeIndex:
+		g.p("if %s < len(%s) {", idx, x)
+		g.p("%s[%s] = %s", x, idx, v)
+		g.p("%s++", idx)
+		g.p("}")
+	}
+}
+
+// unmarshalInto writes the code which unmarshals raw, which was decoded from B, into the message at ptr
+func (g *generator) unmarshalInto(c *codec, B, ptr string) {
+	switch {
+	case c.kind == kindStruct:
+		g.p("d := %s.NestedBuffer(raw)", B)
+		g.p("if err := protobuf3Unmarshal_%s(&d, %s); err != nil {", c.anon.name, ptr)
+	case c.kind == kindGenerated:
+		// decode with B's settings
+		if strings.HasPrefix(ptr, "&") {
+			ptr = ptr[1:]
+		}
+		g.p("d := %s.NestedBuffer(raw)", B)
+		g.p("if err := %s.DecodeProtobuf3(&d); err != nil {", ptr)
+	case c.mapKey:
+		g.p("if err := protobuf3.UnmarshalCodecKey(raw, %s); err != nil {", ptr)
+	case c.kind == kindCodec:
+		g.p("if err := protobuf3.UnmarshalCodec(raw, %s); err != nil {", ptr)
+	default:
+		if strings.HasPrefix(ptr, "&") {
+			// methods can be called on the addressable value
+			ptr = ptr[1:]
+		}
+		method := "UnmarshalProtobuf3"
+		switch c.kind {
+		case kindBinary:
+			method = "UnmarshalBinary"
+		case kindText:
+			method = "UnmarshalText"
+		}
+		g.p("if err := %s.%s(raw); err != nil {", ptr, method)
+	}
+	g.p("return err")
+	g.p("}")
+}
+
+// isCustom returns true if t implements protobuf3.Appender or protobuf3.Marshaler
+func isCustom(t types.Type) bool {
+	return gosrc.IsAppender(t) || gosrc.IsMarshaler(t)
+}
+
+func isStruct(t types.Type) bool {
+	_, ok := t.Underlying().(*types.Struct)
+	return ok
+}
+
+func isByte(t types.Type) bool {
+	b, ok := t.Underlying().(*types.Basic)
+	return ok && b.Kind() == types.Uint8
+}
+
+func isBool(t types.Type) bool {
+	b, ok := t.Underlying().(*types.Basic)
+	return ok && b.Kind() == types.Bool
+}
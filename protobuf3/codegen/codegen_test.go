@@ -0,0 +1,158 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package codegen_test
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mistsys/protobuf3/protobuf3"
+	"github.com/mistsys/protobuf3/protobuf3/codegen"
+	"github.com/mistsys/protobuf3/protobuf3/codegen/internal/example"
+	"github.com/mistsys/protobuf3/protobuf3/internal/gosrc"
+)
+
+// the generated example code is checked in, and serves as our golden output. regenerating it
+// (which loads the package including its already generated methods) must reproduce it exactly
+func TestGenerateExample(t *testing.T) {
+	pkgs, err := gosrc.Load("github.com/mistsys/protobuf3/protobuf3/codegen/internal/example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := pkgs[0]
+
+	for _, c := range []struct {
+		file string
+		gen  func(*gosrc.Package, codegen.Options, ...string) ([]byte, error)
+	}{
+		{"internal/example/example_protobuf3.go", codegen.Generate},
+		{"internal/example/example_protobuf3_test.go", codegen.GenerateTest},
+	} {
+		got, err := c.gen(pkg, codegen.Options{}, "Device", "Base") // as go:generate in example.go
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := os.ReadFile(c.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("%s is stale; run go generate in internal/example. got\n%s", c.file, got)
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	pkgs, err := gosrc.Load("github.com/mistsys/protobuf3/protobuf3/codegen/internal/example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"Nope":   "not found",
+		"Mode":   "not a named struct type",
+		"Stamps": "[]*time.Time cannot have option unixnano, which only time.Time, *time.Time and []time.Time can have",
+	} {
+		_, err := codegen.Generate(pkgs[0], codegen.Options{}, name)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Generate(%s) returned error %v, expected %q", name, err, want)
+		}
+	}
+}
+
+// generated types still describe themselves as messages, rather than as opaque custom types
+func TestGeneratedAsProtobuf(t *testing.T) {
+	var _ protobuf3.Generated = new(example.Device)
+
+	s, err := protobuf3.AsProtobufFull(reflect.TypeOf(example.Device{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"message Device {", "message Port {", "message Link {"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("missing %q in\n%s", want, s)
+		}
+	}
+}
+
+// Unmarshal decodes generated types, and the messages nested within them, with the Buffer's Limits
+func TestGeneratedLimits(t *testing.T) {
+	peer := strings.Repeat("x", 60)
+	data, err := protobuf3.Marshal(&example.Device{Links: []*example.Link{{Peer: peer, Via: &example.Link{Peer: peer}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, limits := range []protobuf3.DecodeLimits{
+		{MaxBytesLength: 50},
+		{MaxAllocation: 100}, // each string is within the limit, but not both
+	} {
+		b := protobuf3.NewBuffer(data)
+		b.Limits = limits
+		var le *protobuf3.LimitError
+		if err := b.Unmarshal(new(example.Device)); !errors.As(err, &le) {
+			t.Errorf("Unmarshal with %+v returned %v, expected a LimitError", limits, err)
+		}
+	}
+	if err := protobuf3.Unmarshal(data, new(example.Device)); err != nil {
+		t.Error(err)
+	}
+}
+
+type countingInterner map[string]int
+
+func (c countingInterner) Intern(b []byte) string {
+	c[string(b)]++
+	return string(b)
+}
+
+// Unmarshal passes the Buffer's Interner to generated types and the messages nested within them
+func TestGeneratedInterner(t *testing.T) {
+	data, err := protobuf3.Marshal(&example.Device{Name: "dev", Links: []*example.Link{{Peer: "a", Via: &example.Link{Peer: "b"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	interned := make(countingInterner)
+	b := protobuf3.NewBuffer(data)
+	b.Interner = interned
+	if err := b.Unmarshal(new(example.Device)); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"dev", "a", "b"} {
+		if interned[s] != 1 {
+			t.Errorf("%q was interned %d times", s, interned[s])
+		}
+	}
+}
@@ -0,0 +1,350 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package crosscheck compares the methods generated by package codegen with the reflective encoder and decoder.
+// The tests which protobuf3-gen -test generates use it.
+package crosscheck
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/mistsys/protobuf3/protobuf3"
+)
+
+// Iterations is the number of random values Test checks
+var Iterations = 200
+
+// Test checks Iterations pseudo-random values of the type gen points to. shadow must point to a type declared
+// as `type shadow T`, which has the same fields as T but none of its methods, and so is encoded by reflection.
+// When T embeds a struct with methods, which the shadow type would inherit, shadow must instead point to a struct
+// with T's fields in which the embedded structs are replaced by their shadow types.
+func Test(t testing.TB, gen protobuf3.Generated, shadow interface{}) {
+	t.Helper()
+	r := rand.New(rand.NewSource(1))
+	typ := reflect.TypeOf(gen).Elem()
+	for i := 0; i < Iterations; i++ {
+		v := reflect.New(typ)
+		Fill(v.Interface(), r)
+		if err := Check(v.Interface().(protobuf3.Generated), shadow); err != nil {
+			t.Fatalf("%s #%d: %v\nvalue: %+v", typ, i, err, v.Elem().Interface())
+		}
+	}
+}
+
+// Check encodes *gen with its generated AppendProtobuf3 method and with the reflective encoder, by way of
+// the type shadow points to, and returns an error if the encodings differ. Since maps are encoded in random order,
+// encodings which differ are accepted if they have the same length and decode to the same value. Then it decodes
+// the encoding with the generated UnmarshalProtobuf3 method and with the reflective decoder, and the deterministic
+// encoding (see protobuf3.MarshalDeterministic) with the generated method, and returns an error if the results
+// differ. Floats are the same only if they have the same bits, so that -0 and NaN are compared too.
+func Check(gen protobuf3.Generated, shadow interface{}) error {
+	gv := reflect.ValueOf(gen)
+	st := reflect.TypeOf(shadow)
+	if st.Kind() != reflect.Ptr || !sameLayout(gv.Type().Elem(), st.Elem()) {
+		return fmt.Errorf("crosscheck: %s and %s don't have the same fields", gv.Type(), st)
+	}
+
+	genBytes, err := gen.AppendProtobuf3(nil)
+	if err != nil {
+		return fmt.Errorf("AppendProtobuf3: %v", err)
+	}
+	sv := reflect.NewAt(st.Elem(), unsafe.Pointer(gv.Pointer()))
+	reflBytes, err := protobuf3.Marshal(sv.Interface())
+	if err != nil {
+		return fmt.Errorf("reflective Marshal: %v", err)
+	}
+	if !bytes.Equal(genBytes, reflBytes) {
+		a, b := reflect.New(st.Elem()), reflect.New(st.Elem())
+		if len(genBytes) != len(reflBytes) || protobuf3.Unmarshal(genBytes, a.Interface()) != nil ||
+			protobuf3.Unmarshal(reflBytes, b.Interface()) != nil || !equal(a, b) {
+			return fmt.Errorf("encodings differ:\ngenerated  %x\nreflective %x", genBytes, reflBytes)
+		}
+	}
+	detBytes, err := protobuf3.MarshalDeterministic(sv.Interface())
+	if err != nil {
+		return fmt.Errorf("reflective MarshalDeterministic: %v", err)
+	}
+
+	gd := reflect.New(gv.Type().Elem())
+	if err := gd.Interface().(protobuf3.Generated).UnmarshalProtobuf3(genBytes); err != nil {
+		return fmt.Errorf("UnmarshalProtobuf3: %v", err)
+	}
+	sd := reflect.New(st.Elem())
+	if err := protobuf3.Unmarshal(genBytes, sd.Interface()); err != nil {
+		return fmt.Errorf("reflective Unmarshal: %v", err)
+	}
+	if !equal(gd, reflect.NewAt(gd.Type().Elem(), unsafe.Pointer(sd.Pointer()))) {
+		return fmt.Errorf("decodings differ:\ngenerated  %+v\nreflective %+v", gd.Elem().Interface(), sd.Elem().Interface())
+	}
+	dd := reflect.New(gv.Type().Elem())
+	if err := dd.Interface().(protobuf3.Generated).UnmarshalProtobuf3(detBytes); err != nil {
+		return fmt.Errorf("UnmarshalProtobuf3 of the deterministic encoding: %v", err)
+	}
+	if !equal(gd, dd) {
+		return fmt.Errorf("decodings of the deterministic encoding differ:\nencoding   %+v\ndeterministic %+v", gd.Elem().Interface(), dd.Elem().Interface())
+	}
+	return nil
+}
+
+// equal is like reflect.DeepEqual, except that floats are equal only if they have the same bits. a and b have
+// the same type, or types with the same layout.
+func equal(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return math.Float64bits(a.Float()) == math.Float64bits(b.Float())
+	case reflect.Complex64, reflect.Complex128:
+		ca, cb := a.Complex(), b.Complex()
+		return math.Float64bits(real(ca)) == math.Float64bits(real(cb)) && math.Float64bits(imag(ca)) == math.Float64bits(imag(cb))
+	case reflect.Ptr, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		return equal(a.Elem(), b.Elem())
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if !equal(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice:
+		if a.IsNil() != b.IsNil() {
+			return false
+		}
+		fallthrough
+	case reflect.Array:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !equal(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if a.IsNil() != b.IsNil() || a.Len() != b.Len() {
+			return false
+		}
+		for it := a.MapRange(); it.Next(); {
+			// (the keys of protobuf maps aren't floats, so they can be looked up)
+			v := b.MapIndex(it.Key())
+			if !v.IsValid() || !equal(it.Value(), v) {
+				return false
+			}
+		}
+		return true
+	case reflect.Bool:
+		return a.Bool() == b.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() == b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() == b.Uint()
+	case reflect.String:
+		return a.String() == b.String()
+	}
+	// channels and funcs, which aren't encoded
+	return a.IsNil() == b.IsNil()
+}
+
+// sameLayout returns true if a value of type a is also a value of type b: if the types are the same, or are
+// structs, or pointers to structs, whose fields have the same names, offsets, tags and layouts
+func sameLayout(a, b reflect.Type) bool {
+	if a == b {
+		return true
+	}
+	if a.Kind() != b.Kind() {
+		return false
+	}
+	switch a.Kind() {
+	case reflect.Ptr:
+		return sameLayout(a.Elem(), b.Elem())
+	case reflect.Struct:
+		if a.NumField() != b.NumField() || a.Size() != b.Size() {
+			return false
+		}
+		for i := 0; i < a.NumField(); i++ {
+			fa, fb := a.Field(i), b.Field(i)
+			if fa.Offset != fb.Offset || fa.Tag != fb.Tag || fa.Anonymous != fb.Anonymous || (!fa.Anonymous && fa.Name != fb.Name) || !sameLayout(fa.Type, fb.Type) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// Fill fills the value v points to with pseudo-random values, including -0, NaN and infinite floats, and maps of
+// up to three entries. The net and net/netip address types get valid addresses. Types which implement protobuf3.Marshaler or protobuf3.Appender, but not protobuf3.Generated,
+// are left as their zero value, since only they know what values are valid.
+func Fill(v interface{}, r *rand.Rand) {
+	fill(reflect.ValueOf(v).Elem(), r, 0)
+}
+
+var (
+	generatedType = reflect.TypeOf((*protobuf3.Generated)(nil)).Elem()
+	appenderType  = reflect.TypeOf((*protobuf3.Appender)(nil)).Elem()
+	marshalerType = reflect.TypeOf((*protobuf3.Marshaler)(nil)).Elem()
+	timeType      = reflect.TypeOf(time.Time{})
+)
+
+// maxDepth limits the recursion into recursive types
+const maxDepth = 4
+
+func fill(v reflect.Value, r *rand.Rand, depth int) {
+	pt := reflect.PtrTo(v.Type())
+	if (pt.Implements(appenderType) || pt.Implements(marshalerType)) && !pt.Implements(generatedType) {
+		return
+	}
+	if depth > maxDepth || r.Intn(5) == 0 {
+		return // leave some values zero, since zero values are special cases of the encoding
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(int64(r.Uint64()) >> r.Intn(64))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		v.SetUint(r.Uint64() >> r.Intn(64))
+	case reflect.Float32, reflect.Float64:
+		switch r.Intn(8) {
+		case 0:
+			v.SetFloat(math.Copysign(0, -1))
+		case 1:
+			v.SetFloat(math.NaN())
+		case 2:
+			v.SetFloat(math.Inf(1 - 2*r.Intn(2)))
+		default:
+			v.SetFloat(r.NormFloat64() * 1000)
+		}
+	case reflect.String:
+		b := make([]byte, r.Intn(20))
+		for i := range b {
+			b[i] = byte('a' + r.Intn(26))
+		}
+		v.SetString(string(b))
+	case reflect.Ptr:
+		p := reflect.New(v.Type().Elem())
+		fill(p.Elem(), r, depth+1)
+		v.Set(p)
+	case reflect.Slice:
+		if fillNet(v, r) {
+			return
+		}
+		n := r.Intn(4)
+		s := reflect.MakeSlice(v.Type(), n, n)
+		for i := 0; i < n; i++ {
+			fill(s.Index(i), r, depth+1)
+			if s.Index(i).Kind() == reflect.Ptr && s.Index(i).IsNil() {
+				// nil elements can't be encoded
+				s.Index(i).Set(reflect.New(s.Type().Elem().Elem()))
+			}
+		}
+		v.Set(s)
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			fill(v.Index(i), r, depth+1)
+			if v.Index(i).Kind() == reflect.Ptr && v.Index(i).IsNil() {
+				v.Index(i).Set(reflect.New(v.Type().Elem().Elem()))
+			}
+		}
+	case reflect.Map:
+		m := reflect.MakeMap(v.Type())
+		for n := r.Intn(4); n > 0; n-- {
+			k := reflect.New(v.Type().Key()).Elem()
+			e := reflect.New(v.Type().Elem()).Elem()
+			fill(k, r, depth+1)
+			fill(e, r, depth+1)
+			m.SetMapIndex(k, e)
+		}
+		v.Set(m)
+	case reflect.Struct:
+		if v.Type() == timeType {
+			v.Set(reflect.ValueOf(time.Unix(r.Int63n(1<<34)-1<<33, r.Int63n(1e9))))
+			return
+		}
+		if fillNet(v, r) {
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Field(i)
+			if !f.CanSet() {
+				// unexported fields are encoded too
+				f = reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem()
+			}
+			fill(f, r, depth+1)
+		}
+	}
+}
+
+// fillNet fills v with a random address if it is one of the net or net/netip types protobuf3 encodes, whose
+// unexported fields or lengths can't be random
+func fillNet(v reflect.Value, r *rand.Rand) bool {
+	addr := func() netip.Addr {
+		var a [16]byte
+		r.Read(a[:])
+		if r.Intn(2) == 0 {
+			return netip.AddrFrom4([4]byte{a[0], a[1], a[2], a[3]})
+		}
+		return netip.AddrFrom16(a)
+	}
+	var x interface{}
+	switch v.Interface().(type) {
+	case netip.Addr:
+		x = addr()
+	case netip.Prefix:
+		a := addr()
+		x = netip.PrefixFrom(a, r.Intn(a.BitLen()+1))
+	case netip.AddrPort:
+		x = netip.AddrPortFrom(addr(), uint16(r.Uint32()))
+	case net.IP:
+		x = net.IP(addr().AsSlice())
+	case net.IPNet:
+		ip := addr().AsSlice()
+		x = net.IPNet{IP: ip, Mask: net.CIDRMask(r.Intn(len(ip)*8+1), len(ip)*8)}
+	default:
+		return false
+	}
+	v.Set(reflect.ValueOf(x))
+	return true
+}
@@ -0,0 +1,236 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package example declares types which exercise the generated code. example_protobuf3.go and
+// example_protobuf3_test.go are generated from them by protobuf3-gen.
+package example
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/mistsys/protobuf3/protobuf3"
+)
+
+//go:generate go run github.com/mistsys/protobuf3/protobuf3/cmd/protobuf3-gen -test -o example_protobuf3.go . Device Base
+
+// Device uses most of the kinds of fields protobuf3 can encode
+type Device struct {
+	ID       uint64            `protobuf:"varint,1"`
+	Name     string            `protobuf:"bytes,2"`
+	Enabled  bool              `protobuf:"varint,3"`
+	Temp     float32           `protobuf:"fixed32,4"`
+	Load     float64           `protobuf:"fixed64,5"`
+	Offset   int32             `protobuf:"zigzag32,6"`
+	Delta    int64             `protobuf:"zigzag64,7"`
+	Small    int8              `protobuf:"varint,8"`
+	MAC      [6]byte           `protobuf:"bytes,9"`
+	Blob     []byte            `protobuf:"bytes,10"`
+	Seen     time.Time         `protobuf:"bytes,11"`
+	Uptime   time.Duration     `protobuf:"bytes,12"`
+	Ports    []Port            `protobuf:"bytes,13"`
+	Uplink   *Port             `protobuf:"bytes,14"`
+	Links    []*Link           `protobuf:"bytes,15"`
+	Tags     []string          `protobuf:"bytes,16"`
+	Vlans    []uint16          `protobuf:"varint,17"`
+	Flags    [4]bool           `protobuf:"varint,18"`
+	Labels   map[string]string `protobuf:"bytes,19" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+	ByIndex  map[uint32]*Port  `protobuf:"bytes,20" protobuf_key:"varint,1" protobuf_val:"bytes,2"`
+	Location struct {
+		Lat float64 `protobuf:"fixed64,1"`
+		Lon float64 `protobuf:"fixed64,2"`
+	} `protobuf:"bytes,21"`
+	Boot     *time.Time                        `protobuf:"bytes,22"`
+	Timeout  *time.Duration                    `protobuf:"bytes,23"`
+	Level    *int32                            `protobuf:"varint,24"`
+	Note     *string                           `protobuf:"bytes,25"`
+	Chunks   [][]byte                          `protobuf:"bytes,26"`
+	Delays   []time.Duration                   `protobuf:"bytes,27"`
+	Samples  []float64                         `protobuf:"fixed64,28"`
+	Mode     Mode                              `protobuf:"varint,29"`
+	Aliases  [2]string                         `protobuf:"bytes,30"`
+	Addr     IP                                `protobuf:"bytes,31"`
+	Hops     [2]Port                           `protobuf:"bytes,32"`
+	Counters []int64                           `protobuf:"zigzag64,33"`
+	Flaky    *bool                             `protobuf:"varint,34"`
+	Backup   *IP                               `protobuf:"bytes,35"`
+	Ratios   [3]float32                        `protobuf:"fixed32,36"`
+	Spare    [0]int                            `protobuf:"varint,37"`
+	History  []time.Time                       `protobuf:"bytes,40"`
+	Resets   []*time.Time                      `protobuf:"bytes,41"`
+	LastSeen map[string]time.Time              `protobuf:"bytes,42" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+	Born     time.Time                         `protobuf:"fixed64,43,unixnano"`
+	Expiry   *time.Time                        `protobuf:"zigzag64,44,unixmilli"`
+	Epochs   []time.Time                       `protobuf:"varint,45,unixmilli"`
+	Changed  *time.Time                        `protobuf:"bytes,46,rfc3339"`
+	Stamps   []time.Time                       `protobuf:"bytes,47,rfc3339"`
+	Modified time.Time                         `protobuf:"bytes,48,rfc3339"`
+	OptTemp  protobuf3.Optional[float32]       `protobuf:"fixed32,52"`
+	OptName  protobuf3.Optional[string]        `protobuf:"bytes,53"`
+	OptBlob  protobuf3.Optional[[]byte]        `protobuf:"bytes,54"`
+	OptMode  protobuf3.Optional[Mode]          `protobuf:"varint,55"`
+	OptWait  protobuf3.Optional[time.Duration] `protobuf:"bytes,56"`
+	Mgmt     netip.Addr                        `protobuf:"bytes,57"`
+	Gateway  *netip.AddrPort                   `protobuf:"bytes,58"`
+	Subnets  []netip.Prefix                    `protobuf:"bytes,59"`
+	DNS      [2]net.IP                         `protobuf:"bytes,60"`
+	MAC2     net.HardwareAddr                  `protobuf:"bytes,61"`
+	Routes   map[netip.Prefix]netip.Addr       `protobuf:"bytes,62" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+	Net      net.IPNet                         `protobuf:"bytes,63"`
+	Stamp    time.Time                         `protobuf:"bytes,64,text"`
+	Version  *Version                          `protobuf:"bytes,65,text"`
+	Keys     [2]Key                            `protobuf:"bytes,66,binary"`
+	Peers    map[string]Version                `protobuf:"bytes,67" protobuf_key:"bytes,1" protobuf_val:"bytes,2,text"`
+	Matrix   [][]int32                         `protobuf:"zigzag32,68"`
+	Grid     [2][]Port                         `protobuf:"bytes,69"`
+	Words    [][2]string                       `protobuf:"bytes,70"`
+	Cube     [][][]float64                     `protobuf:"fixed64,71"`
+	Groups   map[string][]Port                 `protobuf:"bytes,72" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+	Counts   map[string]map[uint16]int64       `protobuf:"bytes,73" protobuf_key:"bytes,1" protobuf_val:"bytes,2" protobuf_val_key:"varint,1" protobuf_val_val:"zigzag64,2"`
+	Levels   map[uint32][3]float32             `protobuf:"bytes,74" protobuf_key:"varint,1" protobuf_val:"fixed32,2"`
+	Deep     map[int32]map[string][]string     `protobuf:"bytes,75" protobuf_key:"varint,1" protobuf_val:"bytes,2" protobuf_val_key:"bytes,1" protobuf_val_val:"bytes,2"`
+	Members  map[string]struct{}               `protobuf:"bytes,76"`
+	Allowed  map[uint16]bool                   `protobuf:"varint,77,set"`
+	Peered   map[Port]struct{}                 `protobuf:"bytes,78"`
+	Modes    map[Mode]struct{}                 `protobuf:"zigzag32,79"`
+	Hashes   [][16]byte                        `protobuf:"bytes,80"`
+	Digests  [2][4]byte                        `protobuf:"bytes,81"`
+	Nonces   []*[8]byte                        `protobuf:"bytes,82,resize"`
+	Chains   map[string][][4]byte              `protobuf:"bytes,83" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+	Instants [][]time.Time                     `protobuf:"varint,88,unixnano"`
+	Releases [2][]Version                      `protobuf:"bytes,89,text"`
+	Octets   []uint8                           `protobuf:"varint,90"`
+	Pad      [3]byte                           `protobuf:"varint,91"`
+	Base     `protobuf:"embedded"`
+	*Meta    `protobuf:"embedded"`
+
+	_ protobuf3.Reserved `protobuf:"38,39"`
+}
+
+// Base is embedded in Device
+type Base struct {
+	Created int64  `protobuf:"varint,50"`
+	owner   string `protobuf:"bytes,51"`
+}
+
+// Meta is embedded in Device by pointer
+type Meta struct {
+	Site   string `protobuf:"bytes,84"`
+	Serial uint32 `protobuf:"varint,85"`
+	*Owner `protobuf:"embedded"`
+
+	_ protobuf3.Reserved `protobuf:"87"`
+}
+
+// Owner is embedded in Meta by pointer
+type Owner struct {
+	Contact string `protobuf:"bytes,86"`
+}
+
+// Port is a port of a Device
+type Port struct {
+	Index uint32 `protobuf:"varint,1"`
+	Speed uint64 `protobuf:"fixed64,2"`
+	Up    bool   `protobuf:"varint,3"`
+}
+
+// Link connects a Device to a peer
+type Link struct {
+	Peer string `protobuf:"bytes,1"`
+	Port *Port  `protobuf:"bytes,2"`
+	Cost int    `protobuf:"zigzag64,3"`
+	Via  *Link  `protobuf:"bytes,4"`
+}
+
+// Mode is an enumeration
+type Mode int32
+
+// IP is an IPv4 address which marshals itself
+type IP [4]byte
+
+// MarshalProtobuf3 encodes the zero address as nothing
+func (ip *IP) MarshalProtobuf3() ([]byte, error) {
+	if *ip == (IP{}) {
+		return nil, nil
+	}
+	return ip[:], nil
+}
+
+// UnmarshalProtobuf3 decodes what MarshalProtobuf3 encoded
+func (ip *IP) UnmarshalProtobuf3(buf []byte) error {
+	copy(ip[:], buf)
+	return nil
+}
+
+// Version is encoded by its encoding.TextMarshaler methods
+type Version struct {
+	Major, Minor uint16
+}
+
+// MarshalText formats v as major.minor
+func (v *Version) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d.%d", v.Major, v.Minor)), nil
+}
+
+// UnmarshalText parses what MarshalText formatted
+func (v *Version) UnmarshalText(text []byte) error {
+	_, err := fmt.Sscanf(string(text), "%d.%d", &v.Major, &v.Minor)
+	return err
+}
+
+// Key is encoded by its encoding.BinaryMarshaler methods
+type Key [8]byte
+
+// MarshalBinary encodes the zero Key as nothing
+func (k Key) MarshalBinary() ([]byte, error) {
+	if k == (Key{}) {
+		return nil, nil
+	}
+	return k[:], nil
+}
+
+// UnmarshalBinary decodes what MarshalBinary encoded
+func (k *Key) UnmarshalBinary(data []byte) error {
+	if len(data) != 0 && len(data) != len(k) {
+		return fmt.Errorf("Key length %d", len(data))
+	}
+	copy(k[:], data)
+	return nil
+}
+
+// Stamps can't be generated: the time formats don't apply to []*time.Time
+type Stamps struct {
+	At []*time.Time `protobuf:"varint,1,unixnano"`
+}
@@ -0,0 +1,3243 @@
+// Code generated by protobuf3-gen. DO NOT EDIT.
+
+package example
+
+import (
+	"fmt"
+	"math"
+	"net/netip"
+	"time"
+
+	"github.com/mistsys/protobuf3/protobuf3"
+)
+
+var _ protobuf3.Generated = (*Base)(nil)
+
+// AppendProtobuf3 appends the protobuf encoding of m to b. It implements protobuf3.Appender.
+func (m *Base) AppendProtobuf3(b []byte) ([]byte, error) {
+	// Created
+	if m.Created != 0 {
+		b = append(b, "\x90\x03"...)
+		b = protobuf3.AppendVarint(b, uint64(m.Created))
+	}
+	// owner
+	if len(m.owner) != 0 {
+		b = append(b, "\x9a\x03"...)
+		b = protobuf3.AppendStringBytes(b, m.owner)
+	}
+	return b, nil
+}
+
+// UnmarshalProtobuf3 decodes buf into m, merging with the existing contents of m. It implements protobuf3.Appender.
+func (m *Base) UnmarshalProtobuf3(buf []byte) error {
+	b := protobuf3.MakeBuffer(buf)
+	return m.DecodeProtobuf3(&b)
+}
+
+// DecodeProtobuf3 decodes the rest of b into m, merging with the existing contents of m. It implements protobuf3.Generated.
+func (m *Base) DecodeProtobuf3(b *protobuf3.Buffer) error {
+	for !b.EOF() {
+		tag, wt, err := b.DecodeTag()
+		if err != nil {
+			return err
+		}
+		switch tag {
+		case 50: // Created
+			if wt != protobuf3.WireVarint {
+				return protobuf3.WireTypeError("example.Base", "Created", wt, protobuf3.WireVarint)
+			}
+			u, err := b.DecodeVarint()
+			if err != nil {
+				return err
+			}
+			m.Created = int64(u)
+		case 51: // owner
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Base", "owner", wt, protobuf3.WireBytes)
+			}
+			s, err := b.DecodeStringBytes()
+			if err != nil {
+				return err
+			}
+			m.owner = s
+		default:
+			if err := b.SkipValue(wt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Protobuf3Generated marks Base as implementing protobuf3.Generated
+func (*Base) Protobuf3Generated() {}
+
+var _ protobuf3.Generated = (*Device)(nil)
+
+// AppendProtobuf3 appends the protobuf encoding of m to b. It implements protobuf3.Appender.
+func (m *Device) AppendProtobuf3(b []byte) ([]byte, error) {
+	var err error
+	// ID
+	if m.ID != 0 {
+		b = append(b, "\x08"...)
+		b = protobuf3.AppendVarint(b, m.ID)
+	}
+	// Name
+	if len(m.Name) != 0 {
+		b = append(b, "\x12"...)
+		b = protobuf3.AppendStringBytes(b, m.Name)
+	}
+	// Enabled
+	if m.Enabled {
+		b = append(b, "\x18"...)
+		b = protobuf3.AppendVarint(b, 1)
+	}
+	// Temp
+	if uint64(math.Float32bits(m.Temp)) != 0 {
+		b = append(b, "\x25"...)
+		b = protobuf3.AppendFixed32(b, uint64(math.Float32bits(m.Temp)))
+	}
+	// Load
+	if math.Float64bits(m.Load) != 0 {
+		b = append(b, "\x29"...)
+		b = protobuf3.AppendFixed64(b, math.Float64bits(m.Load))
+	}
+	// Offset
+	if m.Offset != 0 {
+		b = append(b, "\x30"...)
+		b = protobuf3.AppendZigzag32(b, uint64(m.Offset))
+	}
+	// Delta
+	if m.Delta != 0 {
+		b = append(b, "\x38"...)
+		b = protobuf3.AppendZigzag64(b, uint64(m.Delta))
+	}
+	// Small
+	if m.Small != 0 {
+		b = append(b, "\x40"...)
+		b = protobuf3.AppendVarint(b, uint64(m.Small))
+	}
+	// MAC
+	b = append(b, "\x4a"...)
+	b = protobuf3.AppendRawBytes(b, m.MAC[:])
+	// Blob
+	if len(m.Blob) != 0 {
+		b = append(b, "\x52"...)
+		b = protobuf3.AppendRawBytes(b, m.Blob)
+	}
+	// Seen
+	{
+		b = append(b, "\x5a"...)
+		b = append(b, 0)
+		n := len(b)
+		b = protobuf3.AppendTimestamp(b, m.Seen)
+		b = protobuf3.FixupLength(b, n)
+	}
+	// Uptime
+	if m.Uptime != 0 {
+		b = append(b, "\x62"...)
+		b = append(b, 0)
+		n := len(b)
+		b = protobuf3.AppendDuration(b, m.Uptime)
+		b = protobuf3.FixupLength(b, n)
+	}
+	// Ports
+	for i := range m.Ports {
+		if b, err = protobuf3.AppendAppender(b, "\x6a", protobuf3.WireBytes, &m.Ports[i], true); err != nil {
+			return b, err
+		}
+	}
+	// Uplink
+	if m.Uplink != nil {
+		if b, err = protobuf3.AppendAppender(b, "\x72", protobuf3.WireBytes, m.Uplink, false); err != nil {
+			return b, err
+		}
+	}
+	// Links
+	for _, x := range m.Links {
+		if x == nil {
+			return b, protobuf3.ErrRepeatedHasNil
+		}
+		if b, err = protobuf3.AppendAppender(b, "\x7a", protobuf3.WireBytes, x, true); err != nil {
+			return b, err
+		}
+	}
+	// Tags
+	for i := range m.Tags {
+		b = append(b, "\x82\x01"...)
+		b = protobuf3.AppendStringBytes(b, m.Tags[i])
+	}
+	// Vlans
+	if len(m.Vlans) != 0 {
+		b = append(b, "\x8a\x01"...)
+		b = append(b, 0)
+		n := len(b)
+		for _, x := range m.Vlans {
+			b = protobuf3.AppendVarint(b, uint64(x))
+		}
+		b = protobuf3.FixupLength(b, n)
+	}
+	// Flags
+	{
+		b = append(b, "\x92\x01"...)
+		b = append(b, 0)
+		n := len(b)
+		for _, x := range m.Flags {
+			if x {
+				b = protobuf3.AppendVarint(b, 1)
+			} else {
+				b = protobuf3.AppendVarint(b, 0)
+			}
+		}
+		b = protobuf3.FixupLength(b, n)
+	}
+	// Labels
+	for k, v := range m.Labels {
+		b = append(b, "\x9a\x01"...)
+		b = append(b, 0)
+		n := len(b)
+		if len(k) != 0 {
+			b = append(b, "\x0a"...)
+			b = protobuf3.AppendStringBytes(b, k)
+		}
+		if len(v) != 0 {
+			b = append(b, "\x12"...)
+			b = protobuf3.AppendStringBytes(b, v)
+		}
+		b = protobuf3.FixupLength(b, n)
+	}
+	// ByIndex
+	for k, v := range m.ByIndex {
+		b = append(b, "\xa2\x01"...)
+		b = append(b, 0)
+		n := len(b)
+		if k != 0 {
+			b = append(b, "\x08"...)
+			b = protobuf3.AppendVarint(b, uint64(k))
+		}
+		if v != nil {
+			if b, err = protobuf3.AppendAppender(b, "\x12", protobuf3.WireBytes, v, false); err != nil {
+				return b, err
+			}
+		}
+		b = protobuf3.FixupLength(b, n)
+	}
+	// Location
+	{
+		n1 := len(b)
+		b = append(b, "\xaa\x01"...)
+		b = append(b, 0)
+		n := len(b)
+		if b, err = protobuf3Append_Device_Location(b, &m.Location); err != nil {
+			return b, err
+		}
+		if len(b) == n {
+			b = b[:n1]
+		} else {
+			b = protobuf3.FixupLength(b, n)
+		}
+	}
+	// Boot
+	if m.Boot != nil {
+		{
+			b = append(b, "\xb2\x01"...)
+			b = append(b, 0)
+			n := len(b)
+			b = protobuf3.AppendTimestamp(b, (*m.Boot))
+			b = protobuf3.FixupLength(b, n)
+		}
+	}
+	// Timeout
+	if m.Timeout != nil && *m.Timeout != 0 {
+		{
+			b = append(b, "\xba\x01"...)
+			b = append(b, 0)
+			n := len(b)
+			b = protobuf3.AppendDuration(b, (*m.Timeout))
+			b = protobuf3.FixupLength(b, n)
+		}
+	}
+	// Level
+	if m.Level != nil {
+		b = append(b, "\xc0\x01"...)
+		b = protobuf3.AppendVarint(b, uint64((*m.Level)))
+	}
+	// Note
+	if m.Note != nil {
+		b = append(b, "\xca\x01"...)
+		b = protobuf3.AppendStringBytes(b, (*m.Note))
+	}
+	// Chunks
+	for i := range m.Chunks {
+		b = append(b, "\xd2\x01"...)
+		b = protobuf3.AppendRawBytes(b, m.Chunks[i])
+	}
+	// Delays
+	for i := range m.Delays {
+		{
+			b = append(b, "\xda\x01"...)
+			b = append(b, 0)
+			n := len(b)
+			b = protobuf3.AppendDuration(b, m.Delays[i])
+			b = protobuf3.FixupLength(b, n)
+		}
+	}
+	// Samples
+	if len(m.Samples) != 0 {
+		b = append(b, "\xe2\x01"...)
+		b = append(b, 0)
+		n := len(b)
+		for _, x := range m.Samples {
+			b = protobuf3.AppendFixed64(b, math.Float64bits(x))
+		}
+		b = protobuf3.FixupLength(b, n)
+	}
+	// Mode
+	if m.Mode != 0 {
+		b = append(b, "\xe8\x01"...)
+		b = protobuf3.AppendVarint(b, uint64(m.Mode))
+	}
+	// Aliases
+	for i := range m.Aliases {
+		b = append(b, "\xf2\x01"...)
+		b = protobuf3.AppendStringBytes(b, m.Aliases[i])
+	}
+	// Addr
+	if b, err = protobuf3.AppendMarshaler(b, "\xfa\x01", protobuf3.WireBytes, &m.Addr, false); err != nil {
+		return b, err
+	}
+	// Hops
+	for i := range m.Hops {
+		if b, err = protobuf3.AppendAppender(b, "\x82\x02", protobuf3.WireBytes, &m.Hops[i], true); err != nil {
+			return b, err
+		}
+	}
+	// Counters
+	if len(m.Counters) != 0 {
+		b = append(b, "\x8a\x02"...)
+		b = append(b, 0)
+		n := len(b)
+		for _, x := range m.Counters {
+			b = protobuf3.AppendZigzag64(b, uint64(x))
+		}
+		b = protobuf3.FixupLength(b, n)
+	}
+	// Flaky
+	if m.Flaky != nil {
+		b = append(b, "\x90\x02"...)
+		if *m.Flaky {
+			b = protobuf3.AppendVarint(b, 1)
+		} else {
+			b = protobuf3.AppendVarint(b, 0)
+		}
+	}
+	// Backup
+	if m.Backup != nil {
+		if b, err = protobuf3.AppendMarshaler(b, "\x9a\x02", protobuf3.WireBytes, m.Backup, false); err != nil {
+			return b, err
+		}
+	}
+	// Ratios
+	{
+		b = append(b, "\xa2\x02"...)
+		b = append(b, 0)
+		n := len(b)
+		for _, x := range m.Ratios {
+			b = protobuf3.AppendFixed32(b, uint64(math.Float32bits(x)))
+		}
+		b = protobuf3.FixupLength(b, n)
+	}
+	// Spare
+	// a zero-length array encodes as nothing
+	// History
+	for i := range m.History {
+		{
+			b = append(b, "\xc2\x02"...)
+			b = append(b, 0)
+			n := len(b)
+			b = protobuf3.AppendTimestamp(b, m.History[i])
+			b = protobuf3.FixupLength(b, n)
+		}
+	}
+	// Resets
+	for _, x := range m.Resets {
+		if x == nil {
+			return b, protobuf3.ErrRepeatedHasNil
+		}
+		{
+			b = append(b, "\xca\x02"...)
+			b = append(b, 0)
+			n := len(b)
+			b = protobuf3.AppendTimestamp(b, (*x))
+			b = protobuf3.FixupLength(b, n)
+		}
+	}
+	// LastSeen
+	for k, v := range m.LastSeen {
+		b = append(b, "\xd2\x02"...)
+		b = append(b, 0)
+		n := len(b)
+		if len(k) != 0 {
+			b = append(b, "\x0a"...)
+			b = protobuf3.AppendStringBytes(b, k)
+		}
+		{
+			b = append(b, "\x12"...)
+			b = append(b, 0)
+			n := len(b)
+			b = protobuf3.AppendTimestamp(b, v)
+			b = protobuf3.FixupLength(b, n)
+		}
+		b = protobuf3.FixupLength(b, n)
+	}
+	// Born
+	if u := uint64(protobuf3.UnixNanoTime(m.Born)); u != 0 {
+		b = append(b, "\xd9\x02"...)
+		b = protobuf3.AppendFixed64(b, u)
+	}
+	// Expiry
+	if m.Expiry != nil {
+		b = append(b, "\xe0\x02"...)
+		b = protobuf3.AppendZigzag64(b, uint64(protobuf3.UnixMilliTime((*m.Expiry))))
+	}
+	// Epochs
+	if len(m.Epochs) != 0 {
+		b = append(b, "\xea\x02"...)
+		b = append(b, 0)
+		n := len(b)
+		for _, x := range m.Epochs {
+			b = protobuf3.AppendVarint(b, uint64(protobuf3.UnixMilliTime(x)))
+		}
+		b = protobuf3.FixupLength(b, n)
+	}
+	// Changed
+	if m.Changed != nil {
+		b = append(b, "\xf2\x02"...)
+		b = protobuf3.AppendStringBytes(b, protobuf3.RFC3339Time((*m.Changed)))
+	}
+	// Stamps
+	for i := range m.Stamps {
+		b = append(b, "\xfa\x02"...)
+		b = protobuf3.AppendStringBytes(b, protobuf3.RFC3339Time(m.Stamps[i]))
+	}
+	// Modified
+	if s := protobuf3.RFC3339Time(m.Modified); len(s) != 0 {
+		b = append(b, "\x82\x03"...)
+		b = protobuf3.AppendStringBytes(b, s)
+	}
+	// Created
+	if m.Base.Created != 0 {
+		b = append(b, "\x90\x03"...)
+		b = protobuf3.AppendVarint(b, uint64(m.Base.Created))
+	}
+	// owner
+	if len(m.Base.owner) != 0 {
+		b = append(b, "\x9a\x03"...)
+		b = protobuf3.AppendStringBytes(b, m.Base.owner)
+	}
+	// OptTemp
+	if m.OptTemp.Set {
+		b = append(b, "\xa5\x03"...)
+		b = protobuf3.AppendFixed32(b, uint64(math.Float32bits(m.OptTemp.Value)))
+	}
+	// OptName
+	if m.OptName.Set {
+		b = append(b, "\xaa\x03"...)
+		b = protobuf3.AppendStringBytes(b, m.OptName.Value)
+	}
+	// OptBlob
+	if m.OptBlob.Set {
+		b = append(b, "\xb2\x03"...)
+		b = protobuf3.AppendRawBytes(b, m.OptBlob.Value)
+	}
+	// OptMode
+	if m.OptMode.Set {
+		b = append(b, "\xb8\x03"...)
+		b = protobuf3.AppendVarint(b, uint64(m.OptMode.Value))
+	}
+	// OptWait
+	if m.OptWait.Set {
+		{
+			b = append(b, "\xc2\x03"...)
+			b = append(b, 0)
+			n := len(b)
+			b = protobuf3.AppendDuration(b, m.OptWait.Value)
+			b = protobuf3.FixupLength(b, n)
+		}
+	}
+	// Mgmt
+	if b, err = protobuf3.AppendCodec(b, "\xca\x03", protobuf3.WireBytes, &m.Mgmt, false); err != nil {
+		return b, err
+	}
+	// Gateway
+	if m.Gateway != nil {
+		if b, err = protobuf3.AppendCodec(b, "\xd2\x03", protobuf3.WireBytes, m.Gateway, false); err != nil {
+			return b, err
+		}
+	}
+	// Subnets
+	for i := range m.Subnets {
+		if b, err = protobuf3.AppendCodec(b, "\xda\x03", protobuf3.WireBytes, &m.Subnets[i], true); err != nil {
+			return b, err
+		}
+	}
+	// DNS
+	for i := range m.DNS {
+		if b, err = protobuf3.AppendCodec(b, "\xe2\x03", protobuf3.WireBytes, &m.DNS[i], true); err != nil {
+			return b, err
+		}
+	}
+	// MAC2
+	if b, err = protobuf3.AppendCodec(b, "\xea\x03", protobuf3.WireBytes, &m.MAC2, false); err != nil {
+		return b, err
+	}
+	// Routes
+	for k, v := range m.Routes {
+		b = append(b, "\xf2\x03"...)
+		b = append(b, 0)
+		n := len(b)
+		if b, err = protobuf3.AppendCodecKey(b, "\x0a", protobuf3.WireBytes, &k); err != nil {
+			return b, err
+		}
+		if b, err = protobuf3.AppendCodec(b, "\x12", protobuf3.WireBytes, &v, false); err != nil {
+			return b, err
+		}
+		b = protobuf3.FixupLength(b, n)
+	}
+	// Net
+	if b, err = protobuf3.AppendCodec(b, "\xfa\x03", protobuf3.WireBytes, &m.Net, false); err != nil {
+		return b, err
+	}
+	// Stamp
+	if b, err = protobuf3.AppendText(b, "\x82\x04", &m.Stamp, false); err != nil {
+		return b, err
+	}
+	// Version
+	if m.Version != nil {
+		if b, err = protobuf3.AppendText(b, "\x8a\x04", m.Version, false); err != nil {
+			return b, err
+		}
+	}
+	// Keys
+	for i := range m.Keys {
+		if b, err = protobuf3.AppendBinary(b, "\x92\x04", &m.Keys[i], true); err != nil {
+			return b, err
+		}
+	}
+	// Peers
+	for k, v := range m.Peers {
+		b = append(b, "\x9a\x04"...)
+		b = append(b, 0)
+		n := len(b)
+		if len(k) != 0 {
+			b = append(b, "\x0a"...)
+			b = protobuf3.AppendStringBytes(b, k)
+		}
+		if b, err = protobuf3.AppendText(b, "\x12", &v, false); err != nil {
+			return b, err
+		}
+		b = protobuf3.FixupLength(b, n)
+	}
+	// Matrix
+	for i := range m.Matrix {
+		{
+			b = append(b, "\xa2\x04"...)
+			b = append(b, 0)
+			n := len(b)
+			if b, err = protobuf3Append_Device_Matrix(b, &m.Matrix[i]); err != nil {
+				return b, err
+			}
+			b = protobuf3.FixupLength(b, n)
+		}
+	}
+	// Grid
+	for i := range m.Grid {
+		{
+			b = append(b, "\xaa\x04"...)
+			b = append(b, 0)
+			n := len(b)
+			if b, err = protobuf3Append_Device_Grid(b, &m.Grid[i]); err != nil {
+				return b, err
+			}
+			b = protobuf3.FixupLength(b, n)
+		}
+	}
+	// Words
+	for i := range m.Words {
+		{
+			b = append(b, "\xb2\x04"...)
+			b = append(b, 0)
+			n := len(b)
+			if b, err = protobuf3Append_Device_Words(b, &m.Words[i]); err != nil {
+				return b, err
+			}
+			b = protobuf3.FixupLength(b, n)
+		}
+	}
+	// Cube
+	for i := range m.Cube {
+		{
+			b = append(b, "\xba\x04"...)
+			b = append(b, 0)
+			n := len(b)
+			if b, err = protobuf3Append_Device_Cube(b, &m.Cube[i]); err != nil {
+				return b, err
+			}
+			b = protobuf3.FixupLength(b, n)
+		}
+	}
+	// Groups
+	for k, v := range m.Groups {
+		b = append(b, "\xc2\x04"...)
+		b = append(b, 0)
+		n := len(b)
+		if len(k) != 0 {
+			b = append(b, "\x0a"...)
+			b = protobuf3.AppendStringBytes(b, k)
+		}
+		{
+			n1 := len(b)
+			b = append(b, "\x12"...)
+			b = append(b, 0)
+			n := len(b)
+			if b, err = protobuf3Append_Device_Groups_Value(b, &v); err != nil {
+				return b, err
+			}
+			if len(b) == n {
+				b = b[:n1]
+			} else {
+				b = protobuf3.FixupLength(b, n)
+			}
+		}
+		b = protobuf3.FixupLength(b, n)
+	}
+	// Counts
+	for k, v := range m.Counts {
+		b = append(b, "\xca\x04"...)
+		b = append(b, 0)
+		n := len(b)
+		if len(k) != 0 {
+			b = append(b, "\x0a"...)
+			b = protobuf3.AppendStringBytes(b, k)
+		}
+		{
+			n1 := len(b)
+			b = append(b, "\x12"...)
+			b = append(b, 0)
+			n := len(b)
+			if b, err = protobuf3Append_Device_Counts_Value(b, &v); err != nil {
+				return b, err
+			}
+			if len(b) == n {
+				b = b[:n1]
+			} else {
+				b = protobuf3.FixupLength(b, n)
+			}
+		}
+		b = protobuf3.FixupLength(b, n)
+	}
+	// Levels
+	for k, v := range m.Levels {
+		b = append(b, "\xd2\x04"...)
+		b = append(b, 0)
+		n := len(b)
+		if k != 0 {
+			b = append(b, "\x08"...)
+			b = protobuf3.AppendVarint(b, uint64(k))
+		}
+		{
+			n1 := len(b)
+			b = append(b, "\x12"...)
+			b = append(b, 0)
+			n := len(b)
+			if b, err = protobuf3Append_Device_Levels_Value(b, &v); err != nil {
+				return b, err
+			}
+			if len(b) == n {
+				b = b[:n1]
+			} else {
+				b = protobuf3.FixupLength(b, n)
+			}
+		}
+		b = protobuf3.FixupLength(b, n)
+	}
+	// Deep
+	for k, v := range m.Deep {
+		b = append(b, "\xda\x04"...)
+		b = append(b, 0)
+		n := len(b)
+		if k != 0 {
+			b = append(b, "\x08"...)
+			b = protobuf3.AppendVarint(b, uint64(k))
+		}
+		{
+			n1 := len(b)
+			b = append(b, "\x12"...)
+			b = append(b, 0)
+			n := len(b)
+			if b, err = protobuf3Append_Device_Deep_Value(b, &v); err != nil {
+				return b, err
+			}
+			if len(b) == n {
+				b = b[:n1]
+			} else {
+				b = protobuf3.FixupLength(b, n)
+			}
+		}
+		b = protobuf3.FixupLength(b, n)
+	}
+	// Members
+	if len(m.Members) != 0 {
+		elems := make([]string, 0, len(m.Members))
+		for k := range m.Members {
+			elems = append(elems, k)
+		}
+		for i := range elems {
+			b = append(b, "\xe2\x04"...)
+			b = protobuf3.AppendStringBytes(b, elems[i])
+		}
+	}
+	// Allowed
+	if len(m.Allowed) != 0 {
+		elems := make([]uint16, 0, len(m.Allowed))
+		for k, v := range m.Allowed {
+			if v {
+				elems = append(elems, k)
+			}
+		}
+		if len(elems) != 0 {
+			b = append(b, "\xea\x04"...)
+			b = append(b, 0)
+			n := len(b)
+			for _, x := range elems {
+				b = protobuf3.AppendVarint(b, uint64(x))
+			}
+			b = protobuf3.FixupLength(b, n)
+		}
+	}
+	// Peered
+	if len(m.Peered) != 0 {
+		elems := make([]Port, 0, len(m.Peered))
+		for k := range m.Peered {
+			elems = append(elems, k)
+		}
+		for i := range elems {
+			if b, err = protobuf3.AppendAppender(b, "\xf2\x04", protobuf3.WireBytes, &elems[i], true); err != nil {
+				return b, err
+			}
+		}
+	}
+	// Modes
+	if len(m.Modes) != 0 {
+		elems := make([]Mode, 0, len(m.Modes))
+		for k := range m.Modes {
+			elems = append(elems, k)
+		}
+		if len(elems) != 0 {
+			b = append(b, "\xfa\x04"...)
+			b = append(b, 0)
+			n := len(b)
+			for _, x := range elems {
+				b = protobuf3.AppendZigzag32(b, uint64(x))
+			}
+			b = protobuf3.FixupLength(b, n)
+		}
+	}
+	// Hashes
+	for i := range m.Hashes {
+		b = append(b, "\x82\x05"...)
+		b = protobuf3.AppendRawBytes(b, m.Hashes[i][:])
+	}
+	// Digests
+	for i := range m.Digests {
+		b = append(b, "\x8a\x05"...)
+		b = protobuf3.AppendRawBytes(b, m.Digests[i][:])
+	}
+	// Nonces
+	for _, x := range m.Nonces {
+		if x == nil {
+			return b, protobuf3.ErrRepeatedHasNil
+		}
+		b = append(b, "\x92\x05"...)
+		b = protobuf3.AppendRawBytes(b, (*x)[:])
+	}
+	// Chains
+	for k, v := range m.Chains {
+		b = append(b, "\x9a\x05"...)
+		b = append(b, 0)
+		n := len(b)
+		if len(k) != 0 {
+			b = append(b, "\x0a"...)
+			b = protobuf3.AppendStringBytes(b, k)
+		}
+		{
+			n1 := len(b)
+			b = append(b, "\x12"...)
+			b = append(b, 0)
+			n := len(b)
+			if b, err = protobuf3Append_Device_Chains_Value(b, &v); err != nil {
+				return b, err
+			}
+			if len(b) == n {
+				b = b[:n1]
+			} else {
+				b = protobuf3.FixupLength(b, n)
+			}
+		}
+		b = protobuf3.FixupLength(b, n)
+	}
+	// Site
+	if m.Meta != nil {
+		if len(m.Meta.Site) != 0 {
+			b = append(b, "\xa2\x05"...)
+			b = protobuf3.AppendStringBytes(b, m.Meta.Site)
+		}
+	}
+	// Serial
+	if m.Meta != nil {
+		if m.Meta.Serial != 0 {
+			b = append(b, "\xa8\x05"...)
+			b = protobuf3.AppendVarint(b, uint64(m.Meta.Serial))
+		}
+	}
+	// Contact
+	if m.Meta != nil && m.Meta.Owner != nil {
+		if len(m.Meta.Owner.Contact) != 0 {
+			b = append(b, "\xb2\x05"...)
+			b = protobuf3.AppendStringBytes(b, m.Meta.Owner.Contact)
+		}
+	}
+	// Instants
+	for i := range m.Instants {
+		{
+			b = append(b, "\xc2\x05"...)
+			b = append(b, 0)
+			n := len(b)
+			if b, err = protobuf3Append_Device_Instants(b, &m.Instants[i]); err != nil {
+				return b, err
+			}
+			b = protobuf3.FixupLength(b, n)
+		}
+	}
+	// Releases
+	for i := range m.Releases {
+		{
+			b = append(b, "\xca\x05"...)
+			b = append(b, 0)
+			n := len(b)
+			if b, err = protobuf3Append_Device_Releases(b, &m.Releases[i]); err != nil {
+				return b, err
+			}
+			b = protobuf3.FixupLength(b, n)
+		}
+	}
+	// Octets
+	if len(m.Octets) != 0 {
+		b = append(b, "\xd2\x05"...)
+		b = protobuf3.AppendRawBytes(b, m.Octets)
+	}
+	// Pad
+	b = append(b, "\xda\x05"...)
+	b = protobuf3.AppendRawBytes(b, m.Pad[:])
+	return b, nil
+}
+
+// UnmarshalProtobuf3 decodes buf into m, merging with the existing contents of m. It implements protobuf3.Appender.
+func (m *Device) UnmarshalProtobuf3(buf []byte) error {
+	b := protobuf3.MakeBuffer(buf)
+	return m.DecodeProtobuf3(&b)
+}
+
+// DecodeProtobuf3 decodes the rest of b into m, merging with the existing contents of m. It implements protobuf3.Generated.
+func (m *Device) DecodeProtobuf3(b *protobuf3.Buffer) error {
+	var i30 int // index of the next element of an array
+	var i32 int // index of the next element of an array
+	var i60 int // index of the next element of an array
+	var i66 int // index of the next element of an array
+	var i69 int // index of the next element of an array
+	var i81 int // index of the next element of an array
+	var i89 int // index of the next element of an array
+	for !b.EOF() {
+		tag, wt, err := b.DecodeTag()
+		if err != nil {
+			return err
+		}
+		switch tag {
+		case 1: // ID
+			if wt != protobuf3.WireVarint {
+				return protobuf3.WireTypeError("example.Device", "ID", wt, protobuf3.WireVarint)
+			}
+			u, err := b.DecodeVarint()
+			if err != nil {
+				return err
+			}
+			m.ID = u
+		case 2: // Name
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Name", wt, protobuf3.WireBytes)
+			}
+			s, err := b.DecodeStringBytes()
+			if err != nil {
+				return err
+			}
+			m.Name = s
+		case 3: // Enabled
+			if wt != protobuf3.WireVarint {
+				return protobuf3.WireTypeError("example.Device", "Enabled", wt, protobuf3.WireVarint)
+			}
+			u, err := b.DecodeVarint()
+			if err != nil {
+				return err
+			}
+			m.Enabled = u != 0
+		case 4: // Temp
+			if wt != protobuf3.WireFixed32 {
+				return protobuf3.WireTypeError("example.Device", "Temp", wt, protobuf3.WireFixed32)
+			}
+			u, err := b.DecodeFixed32()
+			if err != nil {
+				return err
+			}
+			m.Temp = math.Float32frombits(uint32(u))
+		case 5: // Load
+			if wt != protobuf3.WireFixed64 {
+				return protobuf3.WireTypeError("example.Device", "Load", wt, protobuf3.WireFixed64)
+			}
+			u, err := b.DecodeFixed64()
+			if err != nil {
+				return err
+			}
+			m.Load = math.Float64frombits(u)
+		case 6: // Offset
+			if wt != protobuf3.WireVarint {
+				return protobuf3.WireTypeError("example.Device", "Offset", wt, protobuf3.WireVarint)
+			}
+			u, err := b.DecodeZigzag32()
+			if err != nil {
+				return err
+			}
+			m.Offset = int32(u)
+		case 7: // Delta
+			if wt != protobuf3.WireVarint {
+				return protobuf3.WireTypeError("example.Device", "Delta", wt, protobuf3.WireVarint)
+			}
+			u, err := b.DecodeZigzag64()
+			if err != nil {
+				return err
+			}
+			m.Delta = int64(u)
+		case 8: // Small
+			if wt != protobuf3.WireVarint {
+				return protobuf3.WireTypeError("example.Device", "Small", wt, protobuf3.WireVarint)
+			}
+			u, err := b.DecodeVarint()
+			if err != nil {
+				return err
+			}
+			m.Small = int8(u)
+		case 9: // MAC
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "MAC", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			copy(m.MAC[:], raw)
+		case 10: // Blob
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Blob", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			y := make([]byte, len(raw))
+			copy(y, raw)
+			m.Blob = y
+		case 11: // Seen
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Seen", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			d := b.NestedBuffer(raw)
+			y, err := d.DecodeTimestamp()
+			if err != nil {
+				return err
+			}
+			m.Seen = y
+		case 12: // Uptime
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Uptime", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			d := b.NestedBuffer(raw)
+			y, err := d.DecodeDuration()
+			if err != nil {
+				return err
+			}
+			m.Uptime = y
+		case 13: // Ports
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Ports", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawValue(wt)
+			if err != nil {
+				return err
+			}
+			var y Port
+			m.Ports = append(m.Ports, y)
+			d := b.NestedBuffer(raw)
+			if err := m.Ports[len(m.Ports)-1].DecodeProtobuf3(&d); err != nil {
+				return err
+			}
+		case 14: // Uplink
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Uplink", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawValue(wt)
+			if err != nil {
+				return err
+			}
+			if m.Uplink == nil {
+				m.Uplink = new(Port)
+			}
+			d := b.NestedBuffer(raw)
+			if err := m.Uplink.DecodeProtobuf3(&d); err != nil {
+				return err
+			}
+		case 15: // Links
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Links", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawValue(wt)
+			if err != nil {
+				return err
+			}
+			y := new(Link)
+			d := b.NestedBuffer(raw)
+			if err := y.DecodeProtobuf3(&d); err != nil {
+				return err
+			}
+			m.Links = append(m.Links, y)
+		case 16: // Tags
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Tags", wt, protobuf3.WireBytes)
+			}
+			s, err := b.DecodeStringBytes()
+			if err != nil {
+				return err
+			}
+			m.Tags = append(m.Tags, s)
+		case 17: // Vlans
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Vlans", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			p := b.NestedBuffer(raw)
+			if m.Vlans == nil {
+				m.Vlans = make([]uint16, 0, p.CountVarints(uint(len(raw))))
+			}
+			for !p.EOF() {
+				u, err := p.DecodeVarint()
+				if err != nil {
+					return err
+				}
+				m.Vlans = append(m.Vlans, uint16(u))
+			}
+		case 18: // Flags
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Flags", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			p := b.NestedBuffer(raw)
+			i := 0
+			for !p.EOF() {
+				u, err := p.DecodeVarint()
+				if err != nil {
+					return err
+				}
+				if i < len(m.Flags) {
+					m.Flags[i] = u != 0
+					i++
+				}
+			}
+		case 19: // Labels
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Labels", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			if m.Labels == nil {
+				m.Labels = make(map[string]string)
+			}
+			var k string
+			var v string
+			e := b.NestedBuffer(raw)
+			for !e.EOF() {
+				tag, wt, err := e.DecodeTag()
+				if err != nil {
+					return err
+				}
+				switch tag {
+				case 1:
+					if wt != protobuf3.WireBytes {
+						return protobuf3.WireTypeError("example.Device", "Labels.Key", wt, protobuf3.WireBytes)
+					}
+					s, err := e.DecodeStringBytes()
+					if err != nil {
+						return err
+					}
+					k = s
+				case 2:
+					if wt != protobuf3.WireBytes {
+						return protobuf3.WireTypeError("example.Device", "Labels.Value", wt, protobuf3.WireBytes)
+					}
+					s, err := e.DecodeStringBytes()
+					if err != nil {
+						return err
+					}
+					v = s
+				default:
+					return fmt.Errorf("protobuf3: bad map data tag %d", tag)
+				}
+			}
+			m.Labels[k] = v
+		case 20: // ByIndex
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "ByIndex", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			if m.ByIndex == nil {
+				m.ByIndex = make(map[uint32]*Port)
+			}
+			var k uint32
+			var v *Port
+			e := b.NestedBuffer(raw)
+			for !e.EOF() {
+				tag, wt, err := e.DecodeTag()
+				if err != nil {
+					return err
+				}
+				switch tag {
+				case 1:
+					if wt != protobuf3.WireVarint {
+						return protobuf3.WireTypeError("example.Device", "ByIndex.Key", wt, protobuf3.WireVarint)
+					}
+					u, err := e.DecodeVarint()
+					if err != nil {
+						return err
+					}
+					k = uint32(u)
+				case 2:
+					if wt != protobuf3.WireBytes {
+						return protobuf3.WireTypeError("example.Device", "ByIndex.Value", wt, protobuf3.WireBytes)
+					}
+					raw, err := e.DecodeRawValue(wt)
+					if err != nil {
+						return err
+					}
+					if v == nil {
+						v = new(Port)
+					}
+					d := e.NestedBuffer(raw)
+					if err := v.DecodeProtobuf3(&d); err != nil {
+						return err
+					}
+				default:
+					return fmt.Errorf("protobuf3: bad map data tag %d", tag)
+				}
+			}
+			m.ByIndex[k] = v
+		case 21: // Location
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Location", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			d := b.NestedBuffer(raw)
+			if err := protobuf3Unmarshal_Device_Location(&d, &m.Location); err != nil {
+				return err
+			}
+		case 22: // Boot
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Boot", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			d := b.NestedBuffer(raw)
+			y, err := d.DecodeTimestamp()
+			if err != nil {
+				return err
+			}
+			if m.Boot == nil {
+				m.Boot = new(time.Time)
+			}
+			*m.Boot = y
+		case 23: // Timeout
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Timeout", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			d := b.NestedBuffer(raw)
+			y, err := d.DecodeDuration()
+			if err != nil {
+				return err
+			}
+			m.Timeout = &y
+		case 24: // Level
+			if wt != protobuf3.WireVarint {
+				return protobuf3.WireTypeError("example.Device", "Level", wt, protobuf3.WireVarint)
+			}
+			u, err := b.DecodeVarint()
+			if err != nil {
+				return err
+			}
+			y := int32(u)
+			m.Level = &y
+		case 25: // Note
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Note", wt, protobuf3.WireBytes)
+			}
+			s, err := b.DecodeStringBytes()
+			if err != nil {
+				return err
+			}
+			y := s
+			m.Note = &y
+		case 26: // Chunks
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Chunks", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			y := make([]byte, len(raw))
+			copy(y, raw)
+			m.Chunks = append(m.Chunks, y)
+		case 27: // Delays
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Delays", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			d := b.NestedBuffer(raw)
+			y, err := d.DecodeDuration()
+			if err != nil {
+				return err
+			}
+			m.Delays = append(m.Delays, y)
+		case 28: // Samples
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Samples", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			p := b.NestedBuffer(raw)
+			if m.Samples == nil {
+				m.Samples = make([]float64, 0, p.CountFixed64s(uint(len(raw))))
+			}
+			for !p.EOF() {
+				u, err := p.DecodeFixed64()
+				if err != nil {
+					return err
+				}
+				m.Samples = append(m.Samples, math.Float64frombits(u))
+			}
+		case 29: // Mode
+			if wt != protobuf3.WireVarint {
+				return protobuf3.WireTypeError("example.Device", "Mode", wt, protobuf3.WireVarint)
+			}
+			u, err := b.DecodeVarint()
+			if err != nil {
+				return err
+			}
+			m.Mode = Mode(u)
+		case 30: // Aliases
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Aliases", wt, protobuf3.WireBytes)
+			}
+			s, err := b.DecodeStringBytes()
+			if err != nil {
+				return err
+			}
+			if i30 < len(m.Aliases) {
+				m.Aliases[i30] = s
+				i30++
+			}
+		case 31: // Addr
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Addr", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawValue(wt)
+			if err != nil {
+				return err
+			}
+			if err := m.Addr.UnmarshalProtobuf3(raw); err != nil {
+				return err
+			}
+		case 32: // Hops
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Hops", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawValue(wt)
+			if err != nil {
+				return err
+			}
+			if i32 < len(m.Hops) {
+				d := b.NestedBuffer(raw)
+				if err := m.Hops[i32].DecodeProtobuf3(&d); err != nil {
+					return err
+				}
+				i32++
+			}
+		case 33: // Counters
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Counters", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			p := b.NestedBuffer(raw)
+			if m.Counters == nil {
+				m.Counters = make([]int64, 0, p.CountVarints(uint(len(raw))))
+			}
+			for !p.EOF() {
+				u, err := p.DecodeZigzag64()
+				if err != nil {
+					return err
+				}
+				m.Counters = append(m.Counters, int64(u))
+			}
+		case 34: // Flaky
+			if wt != protobuf3.WireVarint {
+				return protobuf3.WireTypeError("example.Device", "Flaky", wt, protobuf3.WireVarint)
+			}
+			u, err := b.DecodeVarint()
+			if err != nil {
+				return err
+			}
+			y := u != 0
+			m.Flaky = &y
+		case 35: // Backup
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Backup", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawValue(wt)
+			if err != nil {
+				return err
+			}
+			if m.Backup == nil {
+				m.Backup = new(IP)
+			}
+			if err := m.Backup.UnmarshalProtobuf3(raw); err != nil {
+				return err
+			}
+		case 36: // Ratios
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Ratios", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			p := b.NestedBuffer(raw)
+			i := 0
+			for !p.EOF() {
+				u, err := p.DecodeFixed32()
+				if err != nil {
+					return err
+				}
+				if i < len(m.Ratios) {
+					m.Ratios[i] = math.Float32frombits(uint32(u))
+					i++
+				}
+			}
+		case 40: // History
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "History", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			d := b.NestedBuffer(raw)
+			y, err := d.DecodeTimestamp()
+			if err != nil {
+				return err
+			}
+			m.History = append(m.History, y)
+		case 41: // Resets
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Resets", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			d := b.NestedBuffer(raw)
+			y, err := d.DecodeTimestamp()
+			if err != nil {
+				return err
+			}
+			m.Resets = append(m.Resets, &y)
+		case 42: // LastSeen
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "LastSeen", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			if m.LastSeen == nil {
+				m.LastSeen = make(map[string]time.Time)
+			}
+			var k string
+			var v time.Time
+			e := b.NestedBuffer(raw)
+			for !e.EOF() {
+				tag, wt, err := e.DecodeTag()
+				if err != nil {
+					return err
+				}
+				switch tag {
+				case 1:
+					if wt != protobuf3.WireBytes {
+						return protobuf3.WireTypeError("example.Device", "LastSeen.Key", wt, protobuf3.WireBytes)
+					}
+					s, err := e.DecodeStringBytes()
+					if err != nil {
+						return err
+					}
+					k = s
+				case 2:
+					if wt != protobuf3.WireBytes {
+						return protobuf3.WireTypeError("example.Device", "LastSeen.Value", wt, protobuf3.WireBytes)
+					}
+					raw, err := e.DecodeRawBytes()
+					if err != nil {
+						return err
+					}
+					d := e.NestedBuffer(raw)
+					y, err := d.DecodeTimestamp()
+					if err != nil {
+						return err
+					}
+					v = y
+				default:
+					return fmt.Errorf("protobuf3: bad map data tag %d", tag)
+				}
+			}
+			m.LastSeen[k] = v
+		case 43: // Born
+			if wt != protobuf3.WireFixed64 {
+				return protobuf3.WireTypeError("example.Device", "Born", wt, protobuf3.WireFixed64)
+			}
+			u, err := b.DecodeFixed64()
+			if err != nil {
+				return err
+			}
+			m.Born = protobuf3.TimeFromUnixNano(int64(u))
+		case 44: // Expiry
+			if wt != protobuf3.WireVarint {
+				return protobuf3.WireTypeError("example.Device", "Expiry", wt, protobuf3.WireVarint)
+			}
+			u, err := b.DecodeZigzag64()
+			if err != nil {
+				return err
+			}
+			y := protobuf3.TimeFromUnixMilli(int64(u))
+			m.Expiry = &y
+		case 45: // Epochs
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Epochs", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			p := b.NestedBuffer(raw)
+			if m.Epochs == nil {
+				m.Epochs = make([]time.Time, 0, p.CountVarints(uint(len(raw))))
+			}
+			for !p.EOF() {
+				u, err := p.DecodeVarint()
+				if err != nil {
+					return err
+				}
+				m.Epochs = append(m.Epochs, protobuf3.TimeFromUnixMilli(int64(u)))
+			}
+		case 46: // Changed
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Changed", wt, protobuf3.WireBytes)
+			}
+			s, err := b.DecodeStringBytes()
+			if err != nil {
+				return err
+			}
+			y, err := protobuf3.TimeFromRFC3339(s)
+			if err != nil {
+				return err
+			}
+			m.Changed = &y
+		case 47: // Stamps
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Stamps", wt, protobuf3.WireBytes)
+			}
+			s, err := b.DecodeStringBytes()
+			if err != nil {
+				return err
+			}
+			y, err := protobuf3.TimeFromRFC3339(s)
+			if err != nil {
+				return err
+			}
+			m.Stamps = append(m.Stamps, y)
+		case 48: // Modified
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Modified", wt, protobuf3.WireBytes)
+			}
+			s, err := b.DecodeStringBytes()
+			if err != nil {
+				return err
+			}
+			y, err := protobuf3.TimeFromRFC3339(s)
+			if err != nil {
+				return err
+			}
+			m.Modified = y
+		case 50: // Created
+			if wt != protobuf3.WireVarint {
+				return protobuf3.WireTypeError("example.Device", "Created", wt, protobuf3.WireVarint)
+			}
+			u, err := b.DecodeVarint()
+			if err != nil {
+				return err
+			}
+			m.Base.Created = int64(u)
+		case 51: // owner
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "owner", wt, protobuf3.WireBytes)
+			}
+			s, err := b.DecodeStringBytes()
+			if err != nil {
+				return err
+			}
+			m.Base.owner = s
+		case 52: // OptTemp
+			if wt != protobuf3.WireFixed32 {
+				return protobuf3.WireTypeError("example.Device", "OptTemp", wt, protobuf3.WireFixed32)
+			}
+			u, err := b.DecodeFixed32()
+			if err != nil {
+				return err
+			}
+			m.OptTemp.Value = math.Float32frombits(uint32(u))
+			m.OptTemp.Set = true
+		case 53: // OptName
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "OptName", wt, protobuf3.WireBytes)
+			}
+			s, err := b.DecodeStringBytes()
+			if err != nil {
+				return err
+			}
+			m.OptName.Value = s
+			m.OptName.Set = true
+		case 54: // OptBlob
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "OptBlob", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			y := make([]byte, len(raw))
+			copy(y, raw)
+			m.OptBlob.Value = y
+			m.OptBlob.Set = true
+		case 55: // OptMode
+			if wt != protobuf3.WireVarint {
+				return protobuf3.WireTypeError("example.Device", "OptMode", wt, protobuf3.WireVarint)
+			}
+			u, err := b.DecodeVarint()
+			if err != nil {
+				return err
+			}
+			m.OptMode.Value = Mode(u)
+			m.OptMode.Set = true
+		case 56: // OptWait
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "OptWait", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			d := b.NestedBuffer(raw)
+			y, err := d.DecodeDuration()
+			if err != nil {
+				return err
+			}
+			m.OptWait.Value = y
+			m.OptWait.Set = true
+		case 57: // Mgmt
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Mgmt", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawValue(wt)
+			if err != nil {
+				return err
+			}
+			if err := protobuf3.UnmarshalCodec(raw, &m.Mgmt); err != nil {
+				return err
+			}
+		case 58: // Gateway
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Gateway", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawValue(wt)
+			if err != nil {
+				return err
+			}
+			if m.Gateway == nil {
+				m.Gateway = new(netip.AddrPort)
+			}
+			if err := protobuf3.UnmarshalCodec(raw, m.Gateway); err != nil {
+				return err
+			}
+		case 59: // Subnets
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Subnets", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawValue(wt)
+			if err != nil {
+				return err
+			}
+			var y netip.Prefix
+			m.Subnets = append(m.Subnets, y)
+			if err := protobuf3.UnmarshalCodec(raw, &m.Subnets[len(m.Subnets)-1]); err != nil {
+				return err
+			}
+		case 60: // DNS
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "DNS", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawValue(wt)
+			if err != nil {
+				return err
+			}
+			if i60 < len(m.DNS) {
+				if err := protobuf3.UnmarshalCodec(raw, &m.DNS[i60]); err != nil {
+					return err
+				}
+				i60++
+			}
+		case 61: // MAC2
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "MAC2", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawValue(wt)
+			if err != nil {
+				return err
+			}
+			if err := protobuf3.UnmarshalCodec(raw, &m.MAC2); err != nil {
+				return err
+			}
+		case 62: // Routes
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Routes", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			if m.Routes == nil {
+				m.Routes = make(map[netip.Prefix]netip.Addr)
+			}
+			var k netip.Prefix
+			var v netip.Addr
+			e := b.NestedBuffer(raw)
+			for !e.EOF() {
+				tag, wt, err := e.DecodeTag()
+				if err != nil {
+					return err
+				}
+				switch tag {
+				case 1:
+					if wt != protobuf3.WireBytes {
+						return protobuf3.WireTypeError("example.Device", "Routes.Key", wt, protobuf3.WireBytes)
+					}
+					raw, err := e.DecodeRawValue(wt)
+					if err != nil {
+						return err
+					}
+					if err := protobuf3.UnmarshalCodecKey(raw, &k); err != nil {
+						return err
+					}
+				case 2:
+					if wt != protobuf3.WireBytes {
+						return protobuf3.WireTypeError("example.Device", "Routes.Value", wt, protobuf3.WireBytes)
+					}
+					raw, err := e.DecodeRawValue(wt)
+					if err != nil {
+						return err
+					}
+					if err := protobuf3.UnmarshalCodec(raw, &v); err != nil {
+						return err
+					}
+				default:
+					return fmt.Errorf("protobuf3: bad map data tag %d", tag)
+				}
+			}
+			m.Routes[k] = v
+		case 63: // Net
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Net", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawValue(wt)
+			if err != nil {
+				return err
+			}
+			if err := protobuf3.UnmarshalCodec(raw, &m.Net); err != nil {
+				return err
+			}
+		case 64: // Stamp
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Stamp", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawValue(wt)
+			if err != nil {
+				return err
+			}
+			if err := m.Stamp.UnmarshalText(raw); err != nil {
+				return err
+			}
+		case 65: // Version
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Version", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawValue(wt)
+			if err != nil {
+				return err
+			}
+			if m.Version == nil {
+				m.Version = new(Version)
+			}
+			if err := m.Version.UnmarshalText(raw); err != nil {
+				return err
+			}
+		case 66: // Keys
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Keys", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawValue(wt)
+			if err != nil {
+				return err
+			}
+			if i66 < len(m.Keys) {
+				if err := m.Keys[i66].UnmarshalBinary(raw); err != nil {
+					return err
+				}
+				i66++
+			}
+		case 67: // Peers
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Peers", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			if m.Peers == nil {
+				m.Peers = make(map[string]Version)
+			}
+			var k string
+			var v Version
+			e := b.NestedBuffer(raw)
+			for !e.EOF() {
+				tag, wt, err := e.DecodeTag()
+				if err != nil {
+					return err
+				}
+				switch tag {
+				case 1:
+					if wt != protobuf3.WireBytes {
+						return protobuf3.WireTypeError("example.Device", "Peers.Key", wt, protobuf3.WireBytes)
+					}
+					s, err := e.DecodeStringBytes()
+					if err != nil {
+						return err
+					}
+					k = s
+				case 2:
+					if wt != protobuf3.WireBytes {
+						return protobuf3.WireTypeError("example.Device", "Peers.Value", wt, protobuf3.WireBytes)
+					}
+					raw, err := e.DecodeRawValue(wt)
+					if err != nil {
+						return err
+					}
+					if err := v.UnmarshalText(raw); err != nil {
+						return err
+					}
+				default:
+					return fmt.Errorf("protobuf3: bad map data tag %d", tag)
+				}
+			}
+			m.Peers[k] = v
+		case 68: // Matrix
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Matrix", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			var y []int32
+			m.Matrix = append(m.Matrix, y)
+			d := b.NestedBuffer(raw)
+			if err := protobuf3Unmarshal_Device_Matrix(&d, &m.Matrix[len(m.Matrix)-1]); err != nil {
+				return err
+			}
+		case 69: // Grid
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Grid", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			if i69 < len(m.Grid) {
+				d := b.NestedBuffer(raw)
+				if err := protobuf3Unmarshal_Device_Grid(&d, &m.Grid[i69]); err != nil {
+					return err
+				}
+				i69++
+			}
+		case 70: // Words
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Words", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			var y [2]string
+			m.Words = append(m.Words, y)
+			d := b.NestedBuffer(raw)
+			if err := protobuf3Unmarshal_Device_Words(&d, &m.Words[len(m.Words)-1]); err != nil {
+				return err
+			}
+		case 71: // Cube
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Cube", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			var y [][]float64
+			m.Cube = append(m.Cube, y)
+			d := b.NestedBuffer(raw)
+			if err := protobuf3Unmarshal_Device_Cube(&d, &m.Cube[len(m.Cube)-1]); err != nil {
+				return err
+			}
+		case 72: // Groups
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Groups", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			if m.Groups == nil {
+				m.Groups = make(map[string][]Port)
+			}
+			var k string
+			var v []Port
+			e := b.NestedBuffer(raw)
+			for !e.EOF() {
+				tag, wt, err := e.DecodeTag()
+				if err != nil {
+					return err
+				}
+				switch tag {
+				case 1:
+					if wt != protobuf3.WireBytes {
+						return protobuf3.WireTypeError("example.Device", "Groups.Key", wt, protobuf3.WireBytes)
+					}
+					s, err := e.DecodeStringBytes()
+					if err != nil {
+						return err
+					}
+					k = s
+				case 2:
+					if wt != protobuf3.WireBytes {
+						return protobuf3.WireTypeError("example.Device", "Groups.Value", wt, protobuf3.WireBytes)
+					}
+					raw, err := e.DecodeRawBytes()
+					if err != nil {
+						return err
+					}
+					d := e.NestedBuffer(raw)
+					if err := protobuf3Unmarshal_Device_Groups_Value(&d, &v); err != nil {
+						return err
+					}
+				default:
+					return fmt.Errorf("protobuf3: bad map data tag %d", tag)
+				}
+			}
+			m.Groups[k] = v
+		case 73: // Counts
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Counts", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			if m.Counts == nil {
+				m.Counts = make(map[string]map[uint16]int64)
+			}
+			var k string
+			var v map[uint16]int64
+			e := b.NestedBuffer(raw)
+			for !e.EOF() {
+				tag, wt, err := e.DecodeTag()
+				if err != nil {
+					return err
+				}
+				switch tag {
+				case 1:
+					if wt != protobuf3.WireBytes {
+						return protobuf3.WireTypeError("example.Device", "Counts.Key", wt, protobuf3.WireBytes)
+					}
+					s, err := e.DecodeStringBytes()
+					if err != nil {
+						return err
+					}
+					k = s
+				case 2:
+					if wt != protobuf3.WireBytes {
+						return protobuf3.WireTypeError("example.Device", "Counts.Value", wt, protobuf3.WireBytes)
+					}
+					raw, err := e.DecodeRawBytes()
+					if err != nil {
+						return err
+					}
+					d := e.NestedBuffer(raw)
+					if err := protobuf3Unmarshal_Device_Counts_Value(&d, &v); err != nil {
+						return err
+					}
+				default:
+					return fmt.Errorf("protobuf3: bad map data tag %d", tag)
+				}
+			}
+			m.Counts[k] = v
+		case 74: // Levels
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Levels", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			if m.Levels == nil {
+				m.Levels = make(map[uint32][3]float32)
+			}
+			var k uint32
+			var v [3]float32
+			e := b.NestedBuffer(raw)
+			for !e.EOF() {
+				tag, wt, err := e.DecodeTag()
+				if err != nil {
+					return err
+				}
+				switch tag {
+				case 1:
+					if wt != protobuf3.WireVarint {
+						return protobuf3.WireTypeError("example.Device", "Levels.Key", wt, protobuf3.WireVarint)
+					}
+					u, err := e.DecodeVarint()
+					if err != nil {
+						return err
+					}
+					k = uint32(u)
+				case 2:
+					if wt != protobuf3.WireBytes {
+						return protobuf3.WireTypeError("example.Device", "Levels.Value", wt, protobuf3.WireBytes)
+					}
+					raw, err := e.DecodeRawBytes()
+					if err != nil {
+						return err
+					}
+					d := e.NestedBuffer(raw)
+					if err := protobuf3Unmarshal_Device_Levels_Value(&d, &v); err != nil {
+						return err
+					}
+				default:
+					return fmt.Errorf("protobuf3: bad map data tag %d", tag)
+				}
+			}
+			m.Levels[k] = v
+		case 75: // Deep
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Deep", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			if m.Deep == nil {
+				m.Deep = make(map[int32]map[string][]string)
+			}
+			var k int32
+			var v map[string][]string
+			e := b.NestedBuffer(raw)
+			for !e.EOF() {
+				tag, wt, err := e.DecodeTag()
+				if err != nil {
+					return err
+				}
+				switch tag {
+				case 1:
+					if wt != protobuf3.WireVarint {
+						return protobuf3.WireTypeError("example.Device", "Deep.Key", wt, protobuf3.WireVarint)
+					}
+					u, err := e.DecodeVarint()
+					if err != nil {
+						return err
+					}
+					k = int32(u)
+				case 2:
+					if wt != protobuf3.WireBytes {
+						return protobuf3.WireTypeError("example.Device", "Deep.Value", wt, protobuf3.WireBytes)
+					}
+					raw, err := e.DecodeRawBytes()
+					if err != nil {
+						return err
+					}
+					d := e.NestedBuffer(raw)
+					if err := protobuf3Unmarshal_Device_Deep_Value(&d, &v); err != nil {
+						return err
+					}
+				default:
+					return fmt.Errorf("protobuf3: bad map data tag %d", tag)
+				}
+			}
+			m.Deep[k] = v
+		case 76: // Members
+			var elems []string
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Members", wt, protobuf3.WireBytes)
+			}
+			s, err := b.DecodeStringBytes()
+			if err != nil {
+				return err
+			}
+			elems = append(elems, s)
+			if m.Members == nil {
+				m.Members = make(map[string]struct{}, len(elems))
+			}
+			for _, k := range elems {
+				m.Members[k] = struct{}{}
+			}
+		case 77: // Allowed
+			var elems []uint16
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Allowed", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			p := b.NestedBuffer(raw)
+			if elems == nil {
+				elems = make([]uint16, 0, p.CountVarints(uint(len(raw))))
+			}
+			for !p.EOF() {
+				u, err := p.DecodeVarint()
+				if err != nil {
+					return err
+				}
+				elems = append(elems, uint16(u))
+			}
+			if m.Allowed == nil {
+				m.Allowed = make(map[uint16]bool, len(elems))
+			}
+			for _, k := range elems {
+				m.Allowed[k] = true
+			}
+		case 78: // Peered
+			var elems []Port
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Peered", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawValue(wt)
+			if err != nil {
+				return err
+			}
+			var y Port
+			elems = append(elems, y)
+			d := b.NestedBuffer(raw)
+			if err := elems[len(elems)-1].DecodeProtobuf3(&d); err != nil {
+				return err
+			}
+			if m.Peered == nil {
+				m.Peered = make(map[Port]struct{}, len(elems))
+			}
+			for _, k := range elems {
+				m.Peered[k] = struct{}{}
+			}
+		case 79: // Modes
+			var elems []Mode
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Modes", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			p := b.NestedBuffer(raw)
+			if elems == nil {
+				elems = make([]Mode, 0, p.CountVarints(uint(len(raw))))
+			}
+			for !p.EOF() {
+				u, err := p.DecodeZigzag32()
+				if err != nil {
+					return err
+				}
+				elems = append(elems, Mode(u))
+			}
+			if m.Modes == nil {
+				m.Modes = make(map[Mode]struct{}, len(elems))
+			}
+			for _, k := range elems {
+				m.Modes[k] = struct{}{}
+			}
+		case 80: // Hashes
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Hashes", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			if len(raw) != 16 {
+				return fmt.Errorf("protobuf3: Hashes element is %d bytes long, not 16", len(raw))
+			}
+			var y [16]byte
+			copy(y[:], raw)
+			m.Hashes = append(m.Hashes, y)
+		case 81: // Digests
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Digests", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			if len(raw) != 4 {
+				return fmt.Errorf("protobuf3: Digests element is %d bytes long, not 4", len(raw))
+			}
+			var y [4]byte
+			copy(y[:], raw)
+			if i81 < len(m.Digests) {
+				m.Digests[i81] = y
+				i81++
+			}
+		case 82: // Nonces
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Nonces", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			y := new([8]byte)
+			copy(y[:], raw)
+			m.Nonces = append(m.Nonces, y)
+		case 83: // Chains
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Chains", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			if m.Chains == nil {
+				m.Chains = make(map[string][][4]byte)
+			}
+			var k string
+			var v [][4]byte
+			e := b.NestedBuffer(raw)
+			for !e.EOF() {
+				tag, wt, err := e.DecodeTag()
+				if err != nil {
+					return err
+				}
+				switch tag {
+				case 1:
+					if wt != protobuf3.WireBytes {
+						return protobuf3.WireTypeError("example.Device", "Chains.Key", wt, protobuf3.WireBytes)
+					}
+					s, err := e.DecodeStringBytes()
+					if err != nil {
+						return err
+					}
+					k = s
+				case 2:
+					if wt != protobuf3.WireBytes {
+						return protobuf3.WireTypeError("example.Device", "Chains.Value", wt, protobuf3.WireBytes)
+					}
+					raw, err := e.DecodeRawBytes()
+					if err != nil {
+						return err
+					}
+					d := e.NestedBuffer(raw)
+					if err := protobuf3Unmarshal_Device_Chains_Value(&d, &v); err != nil {
+						return err
+					}
+				default:
+					return fmt.Errorf("protobuf3: bad map data tag %d", tag)
+				}
+			}
+			m.Chains[k] = v
+		case 84: // Site
+			if m.Meta == nil {
+				m.Meta = new(Meta)
+			}
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Site", wt, protobuf3.WireBytes)
+			}
+			s, err := b.DecodeStringBytes()
+			if err != nil {
+				return err
+			}
+			m.Meta.Site = s
+		case 85: // Serial
+			if m.Meta == nil {
+				m.Meta = new(Meta)
+			}
+			if wt != protobuf3.WireVarint {
+				return protobuf3.WireTypeError("example.Device", "Serial", wt, protobuf3.WireVarint)
+			}
+			u, err := b.DecodeVarint()
+			if err != nil {
+				return err
+			}
+			m.Meta.Serial = uint32(u)
+		case 86: // Contact
+			if m.Meta == nil {
+				m.Meta = new(Meta)
+			}
+			if m.Meta.Owner == nil {
+				m.Meta.Owner = new(Owner)
+			}
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Contact", wt, protobuf3.WireBytes)
+			}
+			s, err := b.DecodeStringBytes()
+			if err != nil {
+				return err
+			}
+			m.Meta.Owner.Contact = s
+		case 88: // Instants
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Instants", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			var y []time.Time
+			m.Instants = append(m.Instants, y)
+			d := b.NestedBuffer(raw)
+			if err := protobuf3Unmarshal_Device_Instants(&d, &m.Instants[len(m.Instants)-1]); err != nil {
+				return err
+			}
+		case 89: // Releases
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Releases", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			if i89 < len(m.Releases) {
+				d := b.NestedBuffer(raw)
+				if err := protobuf3Unmarshal_Device_Releases(&d, &m.Releases[i89]); err != nil {
+					return err
+				}
+				i89++
+			}
+		case 90: // Octets
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Octets", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			y := make([]uint8, len(raw))
+			copy(y, raw)
+			m.Octets = y
+		case 91: // Pad
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Device", "Pad", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			copy(m.Pad[:], raw)
+		default:
+			if err := b.SkipValue(wt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Protobuf3Generated marks Device as implementing protobuf3.Generated
+func (*Device) Protobuf3Generated() {}
+
+var _ protobuf3.Generated = (*Link)(nil)
+
+// AppendProtobuf3 appends the protobuf encoding of m to b. It implements protobuf3.Appender.
+func (m *Link) AppendProtobuf3(b []byte) ([]byte, error) {
+	var err error
+	// Peer
+	if len(m.Peer) != 0 {
+		b = append(b, "\x0a"...)
+		b = protobuf3.AppendStringBytes(b, m.Peer)
+	}
+	// Port
+	if m.Port != nil {
+		if b, err = protobuf3.AppendAppender(b, "\x12", protobuf3.WireBytes, m.Port, false); err != nil {
+			return b, err
+		}
+	}
+	// Cost
+	if m.Cost != 0 {
+		b = append(b, "\x18"...)
+		b = protobuf3.AppendZigzag64(b, uint64(m.Cost))
+	}
+	// Via
+	if m.Via != nil {
+		if b, err = protobuf3.AppendAppender(b, "\x22", protobuf3.WireBytes, m.Via, false); err != nil {
+			return b, err
+		}
+	}
+	return b, nil
+}
+
+// UnmarshalProtobuf3 decodes buf into m, merging with the existing contents of m. It implements protobuf3.Appender.
+func (m *Link) UnmarshalProtobuf3(buf []byte) error {
+	b := protobuf3.MakeBuffer(buf)
+	return m.DecodeProtobuf3(&b)
+}
+
+// DecodeProtobuf3 decodes the rest of b into m, merging with the existing contents of m. It implements protobuf3.Generated.
+func (m *Link) DecodeProtobuf3(b *protobuf3.Buffer) error {
+	for !b.EOF() {
+		tag, wt, err := b.DecodeTag()
+		if err != nil {
+			return err
+		}
+		switch tag {
+		case 1: // Peer
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Link", "Peer", wt, protobuf3.WireBytes)
+			}
+			s, err := b.DecodeStringBytes()
+			if err != nil {
+				return err
+			}
+			m.Peer = s
+		case 2: // Port
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Link", "Port", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawValue(wt)
+			if err != nil {
+				return err
+			}
+			if m.Port == nil {
+				m.Port = new(Port)
+			}
+			d := b.NestedBuffer(raw)
+			if err := m.Port.DecodeProtobuf3(&d); err != nil {
+				return err
+			}
+		case 3: // Cost
+			if wt != protobuf3.WireVarint {
+				return protobuf3.WireTypeError("example.Link", "Cost", wt, protobuf3.WireVarint)
+			}
+			u, err := b.DecodeZigzag64()
+			if err != nil {
+				return err
+			}
+			m.Cost = int(u)
+		case 4: // Via
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("example.Link", "Via", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawValue(wt)
+			if err != nil {
+				return err
+			}
+			if m.Via == nil {
+				m.Via = new(Link)
+			}
+			d := b.NestedBuffer(raw)
+			if err := m.Via.DecodeProtobuf3(&d); err != nil {
+				return err
+			}
+		default:
+			if err := b.SkipValue(wt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Protobuf3Generated marks Link as implementing protobuf3.Generated
+func (*Link) Protobuf3Generated() {}
+
+var _ protobuf3.Generated = (*Port)(nil)
+
+// AppendProtobuf3 appends the protobuf encoding of m to b. It implements protobuf3.Appender.
+func (m *Port) AppendProtobuf3(b []byte) ([]byte, error) {
+	// Index
+	if m.Index != 0 {
+		b = append(b, "\x08"...)
+		b = protobuf3.AppendVarint(b, uint64(m.Index))
+	}
+	// Speed
+	if m.Speed != 0 {
+		b = append(b, "\x11"...)
+		b = protobuf3.AppendFixed64(b, m.Speed)
+	}
+	// Up
+	if m.Up {
+		b = append(b, "\x18"...)
+		b = protobuf3.AppendVarint(b, 1)
+	}
+	return b, nil
+}
+
+// UnmarshalProtobuf3 decodes buf into m, merging with the existing contents of m. It implements protobuf3.Appender.
+func (m *Port) UnmarshalProtobuf3(buf []byte) error {
+	b := protobuf3.MakeBuffer(buf)
+	return m.DecodeProtobuf3(&b)
+}
+
+// DecodeProtobuf3 decodes the rest of b into m, merging with the existing contents of m. It implements protobuf3.Generated.
+func (m *Port) DecodeProtobuf3(b *protobuf3.Buffer) error {
+	for !b.EOF() {
+		tag, wt, err := b.DecodeTag()
+		if err != nil {
+			return err
+		}
+		switch tag {
+		case 1: // Index
+			if wt != protobuf3.WireVarint {
+				return protobuf3.WireTypeError("example.Port", "Index", wt, protobuf3.WireVarint)
+			}
+			u, err := b.DecodeVarint()
+			if err != nil {
+				return err
+			}
+			m.Index = uint32(u)
+		case 2: // Speed
+			if wt != protobuf3.WireFixed64 {
+				return protobuf3.WireTypeError("example.Port", "Speed", wt, protobuf3.WireFixed64)
+			}
+			u, err := b.DecodeFixed64()
+			if err != nil {
+				return err
+			}
+			m.Speed = u
+		case 3: // Up
+			if wt != protobuf3.WireVarint {
+				return protobuf3.WireTypeError("example.Port", "Up", wt, protobuf3.WireVarint)
+			}
+			u, err := b.DecodeVarint()
+			if err != nil {
+				return err
+			}
+			m.Up = u != 0
+		default:
+			if err := b.SkipValue(wt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Protobuf3Generated marks Port as implementing protobuf3.Generated
+func (*Port) Protobuf3Generated() {}
+
+// protobuf3Append_Device_Location appends the protobuf encoding of m, an anonymous struct, to b
+func protobuf3Append_Device_Location(b []byte, m *struct {
+	Lat float64 "protobuf:\"fixed64,1\""
+	Lon float64 "protobuf:\"fixed64,2\""
+}) ([]byte, error) {
+	// Lat
+	if math.Float64bits(m.Lat) != 0 {
+		b = append(b, "\x09"...)
+		b = protobuf3.AppendFixed64(b, math.Float64bits(m.Lat))
+	}
+	// Lon
+	if math.Float64bits(m.Lon) != 0 {
+		b = append(b, "\x11"...)
+		b = protobuf3.AppendFixed64(b, math.Float64bits(m.Lon))
+	}
+	return b, nil
+}
+
+// protobuf3Unmarshal_Device_Location decodes the rest of b into m, an anonymous struct
+func protobuf3Unmarshal_Device_Location(b *protobuf3.Buffer, m *struct {
+	Lat float64 "protobuf:\"fixed64,1\""
+	Lon float64 "protobuf:\"fixed64,2\""
+}) error {
+	for !b.EOF() {
+		tag, wt, err := b.DecodeTag()
+		if err != nil {
+			return err
+		}
+		switch tag {
+		case 1: // Lat
+			if wt != protobuf3.WireFixed64 {
+				return protobuf3.WireTypeError("Device_Location", "Lat", wt, protobuf3.WireFixed64)
+			}
+			u, err := b.DecodeFixed64()
+			if err != nil {
+				return err
+			}
+			m.Lat = math.Float64frombits(u)
+		case 2: // Lon
+			if wt != protobuf3.WireFixed64 {
+				return protobuf3.WireTypeError("Device_Location", "Lon", wt, protobuf3.WireFixed64)
+			}
+			u, err := b.DecodeFixed64()
+			if err != nil {
+				return err
+			}
+			m.Lon = math.Float64frombits(u)
+		default:
+			if err := b.SkipValue(wt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// protobuf3Append_Device_Matrix appends the protobuf encoding of m, a list or map encoded as a message, to b
+func protobuf3Append_Device_Matrix(b []byte, m *[]int32) ([]byte, error) {
+	// Items
+	if len((*m)) != 0 {
+		b = append(b, "\x0a"...)
+		b = append(b, 0)
+		n := len(b)
+		for _, x := range *m {
+			b = protobuf3.AppendZigzag32(b, uint64(x))
+		}
+		b = protobuf3.FixupLength(b, n)
+	}
+	return b, nil
+}
+
+// protobuf3Unmarshal_Device_Matrix decodes the rest of b into m, a list or map encoded as a message
+func protobuf3Unmarshal_Device_Matrix(b *protobuf3.Buffer, m *[]int32) error {
+	for !b.EOF() {
+		tag, wt, err := b.DecodeTag()
+		if err != nil {
+			return err
+		}
+		switch tag {
+		case 1: // Items
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("Device_Matrix", "Items", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			p := b.NestedBuffer(raw)
+			if (*m) == nil {
+				(*m) = make([]int32, 0, p.CountVarints(uint(len(raw))))
+			}
+			for !p.EOF() {
+				u, err := p.DecodeZigzag32()
+				if err != nil {
+					return err
+				}
+				(*m) = append((*m), int32(u))
+			}
+		default:
+			if err := b.SkipValue(wt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// protobuf3Append_Device_Grid appends the protobuf encoding of m, a list or map encoded as a message, to b
+func protobuf3Append_Device_Grid(b []byte, m *[]Port) ([]byte, error) {
+	var err error
+	// Items
+	for i := range *m {
+		if b, err = protobuf3.AppendAppender(b, "\x0a", protobuf3.WireBytes, &(*m)[i], true); err != nil {
+			return b, err
+		}
+	}
+	return b, nil
+}
+
+// protobuf3Unmarshal_Device_Grid decodes the rest of b into m, a list or map encoded as a message
+func protobuf3Unmarshal_Device_Grid(b *protobuf3.Buffer, m *[]Port) error {
+	for !b.EOF() {
+		tag, wt, err := b.DecodeTag()
+		if err != nil {
+			return err
+		}
+		switch tag {
+		case 1: // Items
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("Device_Grid", "Items", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawValue(wt)
+			if err != nil {
+				return err
+			}
+			var y Port
+			(*m) = append((*m), y)
+			d := b.NestedBuffer(raw)
+			if err := (*m)[len((*m))-1].DecodeProtobuf3(&d); err != nil {
+				return err
+			}
+		default:
+			if err := b.SkipValue(wt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// protobuf3Append_Device_Words appends the protobuf encoding of m, a list or map encoded as a message, to b
+func protobuf3Append_Device_Words(b []byte, m *[2]string) ([]byte, error) {
+	// Items
+	for i := range *m {
+		b = append(b, "\x0a"...)
+		b = protobuf3.AppendStringBytes(b, (*m)[i])
+	}
+	return b, nil
+}
+
+// protobuf3Unmarshal_Device_Words decodes the rest of b into m, a list or map encoded as a message
+func protobuf3Unmarshal_Device_Words(b *protobuf3.Buffer, m *[2]string) error {
+	var i1 int // index of the next element of an array
+	for !b.EOF() {
+		tag, wt, err := b.DecodeTag()
+		if err != nil {
+			return err
+		}
+		switch tag {
+		case 1: // Items
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("Device_Words", "Items", wt, protobuf3.WireBytes)
+			}
+			s, err := b.DecodeStringBytes()
+			if err != nil {
+				return err
+			}
+			if i1 < len((*m)) {
+				(*m)[i1] = s
+				i1++
+			}
+		default:
+			if err := b.SkipValue(wt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// protobuf3Append_Device_Cube appends the protobuf encoding of m, a list or map encoded as a message, to b
+func protobuf3Append_Device_Cube(b []byte, m *[][]float64) ([]byte, error) {
+	var err error
+	// Items
+	for i := range *m {
+		{
+			b = append(b, "\x0a"...)
+			b = append(b, 0)
+			n := len(b)
+			if b, err = protobuf3Append_Device_Cube_Items(b, &(*m)[i]); err != nil {
+				return b, err
+			}
+			b = protobuf3.FixupLength(b, n)
+		}
+	}
+	return b, nil
+}
+
+// protobuf3Unmarshal_Device_Cube decodes the rest of b into m, a list or map encoded as a message
+func protobuf3Unmarshal_Device_Cube(b *protobuf3.Buffer, m *[][]float64) error {
+	for !b.EOF() {
+		tag, wt, err := b.DecodeTag()
+		if err != nil {
+			return err
+		}
+		switch tag {
+		case 1: // Items
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("Device_Cube", "Items", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			var y []float64
+			(*m) = append((*m), y)
+			d := b.NestedBuffer(raw)
+			if err := protobuf3Unmarshal_Device_Cube_Items(&d, &(*m)[len((*m))-1]); err != nil {
+				return err
+			}
+		default:
+			if err := b.SkipValue(wt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// protobuf3Append_Device_Cube_Items appends the protobuf encoding of m, a list or map encoded as a message, to b
+func protobuf3Append_Device_Cube_Items(b []byte, m *[]float64) ([]byte, error) {
+	// Items
+	if len((*m)) != 0 {
+		b = append(b, "\x0a"...)
+		b = append(b, 0)
+		n := len(b)
+		for _, x := range *m {
+			b = protobuf3.AppendFixed64(b, math.Float64bits(x))
+		}
+		b = protobuf3.FixupLength(b, n)
+	}
+	return b, nil
+}
+
+// protobuf3Unmarshal_Device_Cube_Items decodes the rest of b into m, a list or map encoded as a message
+func protobuf3Unmarshal_Device_Cube_Items(b *protobuf3.Buffer, m *[]float64) error {
+	for !b.EOF() {
+		tag, wt, err := b.DecodeTag()
+		if err != nil {
+			return err
+		}
+		switch tag {
+		case 1: // Items
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("Device_Cube_Items", "Items", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			p := b.NestedBuffer(raw)
+			if (*m) == nil {
+				(*m) = make([]float64, 0, p.CountFixed64s(uint(len(raw))))
+			}
+			for !p.EOF() {
+				u, err := p.DecodeFixed64()
+				if err != nil {
+					return err
+				}
+				(*m) = append((*m), math.Float64frombits(u))
+			}
+		default:
+			if err := b.SkipValue(wt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// protobuf3Append_Device_Groups_Value appends the protobuf encoding of m, a list or map encoded as a message, to b
+func protobuf3Append_Device_Groups_Value(b []byte, m *[]Port) ([]byte, error) {
+	var err error
+	// Items
+	for i := range *m {
+		if b, err = protobuf3.AppendAppender(b, "\x0a", protobuf3.WireBytes, &(*m)[i], true); err != nil {
+			return b, err
+		}
+	}
+	return b, nil
+}
+
+// protobuf3Unmarshal_Device_Groups_Value decodes the rest of b into m, a list or map encoded as a message
+func protobuf3Unmarshal_Device_Groups_Value(b *protobuf3.Buffer, m *[]Port) error {
+	for !b.EOF() {
+		tag, wt, err := b.DecodeTag()
+		if err != nil {
+			return err
+		}
+		switch tag {
+		case 1: // Items
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("Device_Groups_Value", "Items", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawValue(wt)
+			if err != nil {
+				return err
+			}
+			var y Port
+			(*m) = append((*m), y)
+			d := b.NestedBuffer(raw)
+			if err := (*m)[len((*m))-1].DecodeProtobuf3(&d); err != nil {
+				return err
+			}
+		default:
+			if err := b.SkipValue(wt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// protobuf3Append_Device_Counts_Value appends the protobuf encoding of m, a list or map encoded as a message, to b
+func protobuf3Append_Device_Counts_Value(b []byte, m *map[uint16]int64) ([]byte, error) {
+	// Items
+	for k, v := range *m {
+		b = append(b, "\x0a"...)
+		b = append(b, 0)
+		n := len(b)
+		if k != 0 {
+			b = append(b, "\x08"...)
+			b = protobuf3.AppendVarint(b, uint64(k))
+		}
+		if v != 0 {
+			b = append(b, "\x10"...)
+			b = protobuf3.AppendZigzag64(b, uint64(v))
+		}
+		b = protobuf3.FixupLength(b, n)
+	}
+	return b, nil
+}
+
+// protobuf3Unmarshal_Device_Counts_Value decodes the rest of b into m, a list or map encoded as a message
+func protobuf3Unmarshal_Device_Counts_Value(b *protobuf3.Buffer, m *map[uint16]int64) error {
+	for !b.EOF() {
+		tag, wt, err := b.DecodeTag()
+		if err != nil {
+			return err
+		}
+		switch tag {
+		case 1: // Items
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("Device_Counts_Value", "Items", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			if (*m) == nil {
+				(*m) = make(map[uint16]int64)
+			}
+			var k uint16
+			var v int64
+			e := b.NestedBuffer(raw)
+			for !e.EOF() {
+				tag, wt, err := e.DecodeTag()
+				if err != nil {
+					return err
+				}
+				switch tag {
+				case 1:
+					if wt != protobuf3.WireVarint {
+						return protobuf3.WireTypeError("Device_Counts_Value", "Items.Key", wt, protobuf3.WireVarint)
+					}
+					u, err := e.DecodeVarint()
+					if err != nil {
+						return err
+					}
+					k = uint16(u)
+				case 2:
+					if wt != protobuf3.WireVarint {
+						return protobuf3.WireTypeError("Device_Counts_Value", "Items.Value", wt, protobuf3.WireVarint)
+					}
+					u, err := e.DecodeZigzag64()
+					if err != nil {
+						return err
+					}
+					v = int64(u)
+				default:
+					return fmt.Errorf("protobuf3: bad map data tag %d", tag)
+				}
+			}
+			(*m)[k] = v
+		default:
+			if err := b.SkipValue(wt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// protobuf3Append_Device_Levels_Value appends the protobuf encoding of m, a list or map encoded as a message, to b
+func protobuf3Append_Device_Levels_Value(b []byte, m *[3]float32) ([]byte, error) {
+	// Items
+	{
+		b = append(b, "\x0a"...)
+		b = append(b, 0)
+		n := len(b)
+		for _, x := range *m {
+			b = protobuf3.AppendFixed32(b, uint64(math.Float32bits(x)))
+		}
+		b = protobuf3.FixupLength(b, n)
+	}
+	return b, nil
+}
+
+// protobuf3Unmarshal_Device_Levels_Value decodes the rest of b into m, a list or map encoded as a message
+func protobuf3Unmarshal_Device_Levels_Value(b *protobuf3.Buffer, m *[3]float32) error {
+	for !b.EOF() {
+		tag, wt, err := b.DecodeTag()
+		if err != nil {
+			return err
+		}
+		switch tag {
+		case 1: // Items
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("Device_Levels_Value", "Items", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			p := b.NestedBuffer(raw)
+			i := 0
+			for !p.EOF() {
+				u, err := p.DecodeFixed32()
+				if err != nil {
+					return err
+				}
+				if i < len((*m)) {
+					(*m)[i] = math.Float32frombits(uint32(u))
+					i++
+				}
+			}
+		default:
+			if err := b.SkipValue(wt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// protobuf3Append_Device_Deep_Value appends the protobuf encoding of m, a list or map encoded as a message, to b
+func protobuf3Append_Device_Deep_Value(b []byte, m *map[string][]string) ([]byte, error) {
+	var err error
+	// Items
+	for k, v := range *m {
+		b = append(b, "\x0a"...)
+		b = append(b, 0)
+		n := len(b)
+		if len(k) != 0 {
+			b = append(b, "\x0a"...)
+			b = protobuf3.AppendStringBytes(b, k)
+		}
+		{
+			n1 := len(b)
+			b = append(b, "\x12"...)
+			b = append(b, 0)
+			n := len(b)
+			if b, err = protobuf3Append_Device_Deep_Value_Items_Value(b, &v); err != nil {
+				return b, err
+			}
+			if len(b) == n {
+				b = b[:n1]
+			} else {
+				b = protobuf3.FixupLength(b, n)
+			}
+		}
+		b = protobuf3.FixupLength(b, n)
+	}
+	return b, nil
+}
+
+// protobuf3Unmarshal_Device_Deep_Value decodes the rest of b into m, a list or map encoded as a message
+func protobuf3Unmarshal_Device_Deep_Value(b *protobuf3.Buffer, m *map[string][]string) error {
+	for !b.EOF() {
+		tag, wt, err := b.DecodeTag()
+		if err != nil {
+			return err
+		}
+		switch tag {
+		case 1: // Items
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("Device_Deep_Value", "Items", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			if (*m) == nil {
+				(*m) = make(map[string][]string)
+			}
+			var k string
+			var v []string
+			e := b.NestedBuffer(raw)
+			for !e.EOF() {
+				tag, wt, err := e.DecodeTag()
+				if err != nil {
+					return err
+				}
+				switch tag {
+				case 1:
+					if wt != protobuf3.WireBytes {
+						return protobuf3.WireTypeError("Device_Deep_Value", "Items.Key", wt, protobuf3.WireBytes)
+					}
+					s, err := e.DecodeStringBytes()
+					if err != nil {
+						return err
+					}
+					k = s
+				case 2:
+					if wt != protobuf3.WireBytes {
+						return protobuf3.WireTypeError("Device_Deep_Value", "Items.Value", wt, protobuf3.WireBytes)
+					}
+					raw, err := e.DecodeRawBytes()
+					if err != nil {
+						return err
+					}
+					d := e.NestedBuffer(raw)
+					if err := protobuf3Unmarshal_Device_Deep_Value_Items_Value(&d, &v); err != nil {
+						return err
+					}
+				default:
+					return fmt.Errorf("protobuf3: bad map data tag %d", tag)
+				}
+			}
+			(*m)[k] = v
+		default:
+			if err := b.SkipValue(wt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// protobuf3Append_Device_Deep_Value_Items_Value appends the protobuf encoding of m, a list or map encoded as a message, to b
+func protobuf3Append_Device_Deep_Value_Items_Value(b []byte, m *[]string) ([]byte, error) {
+	// Items
+	for i := range *m {
+		b = append(b, "\x0a"...)
+		b = protobuf3.AppendStringBytes(b, (*m)[i])
+	}
+	return b, nil
+}
+
+// protobuf3Unmarshal_Device_Deep_Value_Items_Value decodes the rest of b into m, a list or map encoded as a message
+func protobuf3Unmarshal_Device_Deep_Value_Items_Value(b *protobuf3.Buffer, m *[]string) error {
+	for !b.EOF() {
+		tag, wt, err := b.DecodeTag()
+		if err != nil {
+			return err
+		}
+		switch tag {
+		case 1: // Items
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("Device_Deep_Value_Items_Value", "Items", wt, protobuf3.WireBytes)
+			}
+			s, err := b.DecodeStringBytes()
+			if err != nil {
+				return err
+			}
+			(*m) = append((*m), s)
+		default:
+			if err := b.SkipValue(wt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// protobuf3Append_Device_Chains_Value appends the protobuf encoding of m, a list or map encoded as a message, to b
+func protobuf3Append_Device_Chains_Value(b []byte, m *[][4]byte) ([]byte, error) {
+	// Items
+	for i := range *m {
+		b = append(b, "\x0a"...)
+		b = protobuf3.AppendRawBytes(b, (*m)[i][:])
+	}
+	return b, nil
+}
+
+// protobuf3Unmarshal_Device_Chains_Value decodes the rest of b into m, a list or map encoded as a message
+func protobuf3Unmarshal_Device_Chains_Value(b *protobuf3.Buffer, m *[][4]byte) error {
+	for !b.EOF() {
+		tag, wt, err := b.DecodeTag()
+		if err != nil {
+			return err
+		}
+		switch tag {
+		case 1: // Items
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("Device_Chains_Value", "Items", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			if len(raw) != 4 {
+				return fmt.Errorf("protobuf3: Items element is %d bytes long, not 4", len(raw))
+			}
+			var y [4]byte
+			copy(y[:], raw)
+			(*m) = append((*m), y)
+		default:
+			if err := b.SkipValue(wt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// protobuf3Append_Device_Instants appends the protobuf encoding of m, a list or map encoded as a message, to b
+func protobuf3Append_Device_Instants(b []byte, m *[]time.Time) ([]byte, error) {
+	// Items
+	if len((*m)) != 0 {
+		b = append(b, "\x0a"...)
+		b = append(b, 0)
+		n := len(b)
+		for _, x := range *m {
+			b = protobuf3.AppendVarint(b, uint64(protobuf3.UnixNanoTime(x)))
+		}
+		b = protobuf3.FixupLength(b, n)
+	}
+	return b, nil
+}
+
+// protobuf3Unmarshal_Device_Instants decodes the rest of b into m, a list or map encoded as a message
+func protobuf3Unmarshal_Device_Instants(b *protobuf3.Buffer, m *[]time.Time) error {
+	for !b.EOF() {
+		tag, wt, err := b.DecodeTag()
+		if err != nil {
+			return err
+		}
+		switch tag {
+		case 1: // Items
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("Device_Instants", "Items", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawBytes()
+			if err != nil {
+				return err
+			}
+			p := b.NestedBuffer(raw)
+			if (*m) == nil {
+				(*m) = make([]time.Time, 0, p.CountVarints(uint(len(raw))))
+			}
+			for !p.EOF() {
+				u, err := p.DecodeVarint()
+				if err != nil {
+					return err
+				}
+				(*m) = append((*m), protobuf3.TimeFromUnixNano(int64(u)))
+			}
+		default:
+			if err := b.SkipValue(wt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// protobuf3Append_Device_Releases appends the protobuf encoding of m, a list or map encoded as a message, to b
+func protobuf3Append_Device_Releases(b []byte, m *[]Version) ([]byte, error) {
+	var err error
+	// Items
+	for i := range *m {
+		if b, err = protobuf3.AppendText(b, "\x0a", &(*m)[i], true); err != nil {
+			return b, err
+		}
+	}
+	return b, nil
+}
+
+// protobuf3Unmarshal_Device_Releases decodes the rest of b into m, a list or map encoded as a message
+func protobuf3Unmarshal_Device_Releases(b *protobuf3.Buffer, m *[]Version) error {
+	for !b.EOF() {
+		tag, wt, err := b.DecodeTag()
+		if err != nil {
+			return err
+		}
+		switch tag {
+		case 1: // Items
+			if wt != protobuf3.WireBytes {
+				return protobuf3.WireTypeError("Device_Releases", "Items", wt, protobuf3.WireBytes)
+			}
+			raw, err := b.DecodeRawValue(wt)
+			if err != nil {
+				return err
+			}
+			var y Version
+			(*m) = append((*m), y)
+			if err := (*m)[len((*m))-1].UnmarshalText(raw); err != nil {
+				return err
+			}
+		default:
+			if err := b.SkipValue(wt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+// Code generated by protobuf3-gen. DO NOT EDIT.
+
+package example
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/mistsys/protobuf3/protobuf3"
+	"github.com/mistsys/protobuf3/protobuf3/codegen/crosscheck"
+)
+
+type reflectBase Base // same fields, without the methods
+
+// reflectDevice has the fields of Device, embedding shadow types in place of the types with methods
+type reflectDevice struct {
+	ID       uint64            `protobuf:"varint,1"`
+	Name     string            `protobuf:"bytes,2"`
+	Enabled  bool              `protobuf:"varint,3"`
+	Temp     float32           `protobuf:"fixed32,4"`
+	Load     float64           `protobuf:"fixed64,5"`
+	Offset   int32             `protobuf:"zigzag32,6"`
+	Delta    int64             `protobuf:"zigzag64,7"`
+	Small    int8              `protobuf:"varint,8"`
+	MAC      [6]byte           `protobuf:"bytes,9"`
+	Blob     []byte            `protobuf:"bytes,10"`
+	Seen     time.Time         `protobuf:"bytes,11"`
+	Uptime   time.Duration     `protobuf:"bytes,12"`
+	Ports    []Port            `protobuf:"bytes,13"`
+	Uplink   *Port             `protobuf:"bytes,14"`
+	Links    []*Link           `protobuf:"bytes,15"`
+	Tags     []string          `protobuf:"bytes,16"`
+	Vlans    []uint16          `protobuf:"varint,17"`
+	Flags    [4]bool           `protobuf:"varint,18"`
+	Labels   map[string]string `protobuf:"bytes,19" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+	ByIndex  map[uint32]*Port  `protobuf:"bytes,20" protobuf_key:"varint,1" protobuf_val:"bytes,2"`
+	Location struct {
+		Lat float64 "protobuf:\"fixed64,1\""
+		Lon float64 "protobuf:\"fixed64,2\""
+	} `protobuf:"bytes,21"`
+	Boot        *time.Time                        `protobuf:"bytes,22"`
+	Timeout     *time.Duration                    `protobuf:"bytes,23"`
+	Level       *int32                            `protobuf:"varint,24"`
+	Note        *string                           `protobuf:"bytes,25"`
+	Chunks      [][]byte                          `protobuf:"bytes,26"`
+	Delays      []time.Duration                   `protobuf:"bytes,27"`
+	Samples     []float64                         `protobuf:"fixed64,28"`
+	Mode        Mode                              `protobuf:"varint,29"`
+	Aliases     [2]string                         `protobuf:"bytes,30"`
+	Addr        IP                                `protobuf:"bytes,31"`
+	Hops        [2]Port                           `protobuf:"bytes,32"`
+	Counters    []int64                           `protobuf:"zigzag64,33"`
+	Flaky       *bool                             `protobuf:"varint,34"`
+	Backup      *IP                               `protobuf:"bytes,35"`
+	Ratios      [3]float32                        `protobuf:"fixed32,36"`
+	Spare       [0]int                            `protobuf:"varint,37"`
+	History     []time.Time                       `protobuf:"bytes,40"`
+	Resets      []*time.Time                      `protobuf:"bytes,41"`
+	LastSeen    map[string]time.Time              `protobuf:"bytes,42" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+	Born        time.Time                         `protobuf:"fixed64,43,unixnano"`
+	Expiry      *time.Time                        `protobuf:"zigzag64,44,unixmilli"`
+	Epochs      []time.Time                       `protobuf:"varint,45,unixmilli"`
+	Changed     *time.Time                        `protobuf:"bytes,46,rfc3339"`
+	Stamps      []time.Time                       `protobuf:"bytes,47,rfc3339"`
+	Modified    time.Time                         `protobuf:"bytes,48,rfc3339"`
+	OptTemp     protobuf3.Optional[float32]       `protobuf:"fixed32,52"`
+	OptName     protobuf3.Optional[string]        `protobuf:"bytes,53"`
+	OptBlob     protobuf3.Optional[[]byte]        `protobuf:"bytes,54"`
+	OptMode     protobuf3.Optional[Mode]          `protobuf:"varint,55"`
+	OptWait     protobuf3.Optional[time.Duration] `protobuf:"bytes,56"`
+	Mgmt        netip.Addr                        `protobuf:"bytes,57"`
+	Gateway     *netip.AddrPort                   `protobuf:"bytes,58"`
+	Subnets     []netip.Prefix                    `protobuf:"bytes,59"`
+	DNS         [2]net.IP                         `protobuf:"bytes,60"`
+	MAC2        net.HardwareAddr                  `protobuf:"bytes,61"`
+	Routes      map[netip.Prefix]netip.Addr       `protobuf:"bytes,62" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+	Net         net.IPNet                         `protobuf:"bytes,63"`
+	Stamp       time.Time                         `protobuf:"bytes,64,text"`
+	Version     *Version                          `protobuf:"bytes,65,text"`
+	Keys        [2]Key                            `protobuf:"bytes,66,binary"`
+	Peers       map[string]Version                `protobuf:"bytes,67" protobuf_key:"bytes,1" protobuf_val:"bytes,2,text"`
+	Matrix      [][]int32                         `protobuf:"zigzag32,68"`
+	Grid        [2][]Port                         `protobuf:"bytes,69"`
+	Words       [][2]string                       `protobuf:"bytes,70"`
+	Cube        [][][]float64                     `protobuf:"fixed64,71"`
+	Groups      map[string][]Port                 `protobuf:"bytes,72" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+	Counts      map[string]map[uint16]int64       `protobuf:"bytes,73" protobuf_key:"bytes,1" protobuf_val:"bytes,2" protobuf_val_key:"varint,1" protobuf_val_val:"zigzag64,2"`
+	Levels      map[uint32][3]float32             `protobuf:"bytes,74" protobuf_key:"varint,1" protobuf_val:"fixed32,2"`
+	Deep        map[int32]map[string][]string     `protobuf:"bytes,75" protobuf_key:"varint,1" protobuf_val:"bytes,2" protobuf_val_key:"bytes,1" protobuf_val_val:"bytes,2"`
+	Members     map[string]struct{}               `protobuf:"bytes,76"`
+	Allowed     map[uint16]bool                   `protobuf:"varint,77,set"`
+	Peered      map[Port]struct{}                 `protobuf:"bytes,78"`
+	Modes       map[Mode]struct{}                 `protobuf:"zigzag32,79"`
+	Hashes      [][16]byte                        `protobuf:"bytes,80"`
+	Digests     [2][4]byte                        `protobuf:"bytes,81"`
+	Nonces      []*[8]byte                        `protobuf:"bytes,82,resize"`
+	Chains      map[string][][4]byte              `protobuf:"bytes,83" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+	Instants    [][]time.Time                     `protobuf:"varint,88,unixnano"`
+	Releases    [2][]Version                      `protobuf:"bytes,89,text"`
+	Octets      []uint8                           `protobuf:"varint,90"`
+	Pad         [3]byte                           `protobuf:"varint,91"`
+	reflectBase `protobuf:"embedded"`
+	*Meta       `protobuf:"embedded"`
+	_           protobuf3.Reserved `protobuf:"38,39"`
+}
+
+type reflectLink Link // same fields, without the methods
+
+type reflectPort Port // same fields, without the methods
+
+// TestProtobuf3Generated_Base cross-checks the generated methods of Base against the reflective encoder and decoder
+func TestProtobuf3Generated_Base(t *testing.T) {
+	crosscheck.Test(t, new(Base), new(reflectBase))
+}
+
+// TestProtobuf3Generated_Device cross-checks the generated methods of Device against the reflective encoder and decoder
+func TestProtobuf3Generated_Device(t *testing.T) {
+	crosscheck.Test(t, new(Device), new(reflectDevice))
+}
+
+// TestProtobuf3Generated_Link cross-checks the generated methods of Link against the reflective encoder and decoder
+func TestProtobuf3Generated_Link(t *testing.T) {
+	crosscheck.Test(t, new(Link), new(reflectLink))
+}
+
+// TestProtobuf3Generated_Port cross-checks the generated methods of Port against the reflective encoder and decoder
+func TestProtobuf3Generated_Port(t *testing.T) {
+	crosscheck.Test(t, new(Port), new(reflectPort))
+}
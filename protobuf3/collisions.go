@@ -0,0 +1,192 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package protobuf3
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Go types are named within their packages, but AsProtobufFull defines all the messages and enums in a single .proto
+// package. When types from different Go packages have the same name (two packages both declaring a Config, say),
+// AsProtobufFull renames them, leaving the type in the package of the first type it was passed (or in none of them)
+// with its name, and qualifying the rest with the names of their packages, as named by the Generator's PackageName, so
+// wifi.Config becomes WifiConfig. References to the renamed types, and the names of wrapper messages of lists and maps
+// of the renamed types, are renamed to match. Collisions which that doesn't resolve, and wrappers named the same as a
+// message or enum, are reported as errors.
+
+// definesByName returns true if AsProtobufFull generates the definition of named type t, and so names it messageName(t)
+func definesByName(t reflect.Type) bool {
+	ptr_t := reflect.PtrTo(t)
+	switch {
+	case t == time_Time_type, t == time_Duration_type, lookupCodec(t) != nil:
+		return false
+	case (isAppender(ptr_t) || isMarshaler(ptr_t)) && !isGenerated(ptr_t):
+		return false
+	case isAsProtobuf3er(ptr_t), isAsV1Protobuf3er(ptr_t):
+		return false
+	}
+	return lookupEnum(t) != nil || t.Kind() == reflect.Struct
+}
+
+// renameCollisions renames those of the types which collide with types from other packages. pkgpath is the package
+// whose types keep their names.
+func (n *namer) renameCollisions(pkgpath string, types []reflect.Type) error {
+	byName := make(map[string][]reflect.Type)
+	for _, t := range types {
+		if wrapperName(t) == "" && definesByName(t) {
+			name := n.messageName(t)
+			byName[name] = append(byName[name], t)
+		}
+	}
+
+	for name, ts := range byName {
+		if len(ts) < 2 {
+			continue
+		}
+		for _, t := range ts {
+			if t.PkgPath() != pkgpath {
+				if n.renames == nil {
+					n.renames = make(map[reflect.Type]string)
+				}
+				// qualify the name with the name of the package
+				n.renames[t] = identifier(n.packageName(t.PkgPath())) + name
+			}
+		}
+	}
+	if n.renames == nil {
+		return nil
+	}
+
+	// check the new names are unique. the wrappers of the same name have the same definition, and are defined once
+	names := make(map[string]reflect.Type)
+	var collisions []string
+	for _, t := range types {
+		if wrapperName(t) != "" || !definesByName(t) {
+			continue
+		}
+		name := n.messageName(t)
+		if t2, ok := names[name]; ok {
+			a, b := t2.String(), t.String()
+			if a > b {
+				a, b = b, a
+			}
+			collisions = append(collisions, fmt.Sprintf("%s and %s are both named %s", a, b, name))
+		}
+		names[name] = t
+	}
+	if len(collisions) != 0 {
+		sort.Strings(collisions)
+		return fmt.Errorf("protobuf3: colliding message names: %s", strings.Join(collisions, "; "))
+	}
+	return nil
+}
+
+// checkWrapperNames returns an error if any of the wrappers of lists and maps among the types are named the same as a
+// message or enum which the types define. The wrappers are named after their items, so a user's StringList, say,
+// collides with the wrapper of a [][]string.
+func (n *namer) checkWrapperNames(types []reflect.Type) error {
+	names := make(map[string]reflect.Type)
+	for _, t := range types {
+		if wrapperName(t) == "" && definesByName(t) {
+			names[n.messageName(t)] = t
+		}
+	}
+	collisions := make(map[string]bool) // (wrappers of the same list type with different tags are the same collision)
+	for _, t := range types {
+		if wrapperName(t) == "" {
+			continue
+		}
+		name := n.messageName(t)
+		if t2, ok := names[name]; ok {
+			collisions[fmt.Sprintf("%s and the wrapper of %s are both named %s", t2, t.Field(0).Type, name)] = true
+		}
+	}
+	if len(collisions) != 0 {
+		lines := make([]string, 0, len(collisions))
+		for c := range collisions {
+			lines = append(lines, c)
+		}
+		sort.Strings(lines)
+		return fmt.Errorf("protobuf3: colliding message names: %s", strings.Join(lines, "; "))
+	}
+	return nil
+}
+
+// protobufType returns p.asProtobuf, with the names of the types named as n names them
+func (p *Properties) protobufType(n *namer) string {
+	if n.cached() {
+		return p.asProtobuf
+	}
+	if p.mkeyprop != nil && p.mvalprop != nil {
+		return fmt.Sprintf("map<%s, %s>", p.mkeyprop.protobufType(n), p.mvalprop.protobufType(n))
+	}
+	if p.stype == nil {
+		return p.asProtobuf
+	}
+
+	name := strings.TrimPrefix(p.asProtobuf, "repeated ")
+	prefix := p.asProtobuf[:len(p.asProtobuf)-len(name)]
+	switch {
+	case wrapperName(p.stype) != "":
+		if name == wrapperName(p.stype) {
+			return prefix + n.messageName(p.stype)
+		}
+	case p.stype.Name() == "":
+		if p.sprop == nil {
+			break
+		}
+		// an anonymous struct's definition is inline, like stypeAsProtobuf() does it, and its fields need naming too
+		inline := func(name string, n *namer) string {
+			str := p.sprop.asProtobuf(p.stype, name, n) + "\n" + name
+			return prefix + strings.Replace(str, "\n", "\n  ", -1)
+		}
+		if inline(MakeTypeName(p.stype, p.Name), nil) == p.asProtobuf {
+			return inline(n.typeName(p.stype, p.Name), n)
+		}
+	case p.enum != nil:
+		if name == p.stype.Name() {
+			return prefix + n.messageName(p.stype)
+		}
+	case definesByName(p.stype):
+		// replace the name, as long as the field refers to the type by name
+		// (and not, for instance, as a string because of a "text" option)
+		if name == MakeTypeName(p.stype, p.Name) {
+			return prefix + n.typeName(p.stype, p.Name)
+		}
+	}
+	return p.asProtobuf
+}
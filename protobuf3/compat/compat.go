@@ -0,0 +1,296 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+/*
+Package compat checks that changes to the protobuf3 tags of Go types don't break
+compatibility with messages encoded by earlier versions of the same types.
+
+The tags in a Go struct are edited by hand, and mistakes such as reusing the id of a deleted
+field, or changing "varint" to "zigzag64", are easy to make and hard to spot in review. The
+checks in this package compare the .proto definition of the current types (as generated by
+protobuf3.AsProtobufFull()) against a previously saved snapshot of the same definitions and
+report every change which alters the meaning of data on the wire.
+*/
+package compat
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/mistsys/protobuf3/protobuf3"
+)
+
+// ChangeKind enumerates the kinds of wire incompatible changes
+type ChangeKind int
+
+const (
+	TagReused            ChangeKind = iota + 1 // a tag id is now used by a different field of an incompatible type
+	WireTypeChanged                            // the wiretype of a field changed
+	IntEncodingChanged                         // the wiretype is the same, but the integer encoding changed (varint <-> zigzag, or integer <-> float)
+	ScalarMessageChanged                       // a field changed from a scalar to a message, or from a message to a scalar
+	MessageTypeChanged                         // a field changed from one message type to another
+	LabelChanged                               // a field changed between repeated and singular
+	MapChanged                                 // the key or value type of a map changed, or a field changed to or from a map
+	FieldRemoved                               // a field was removed without its tag id being reserved
+	ReservedReused                             // a reserved tag id is used by a field
+	ReservationDropped                         // a reserved tag id is no longer reserved
+)
+
+var changeKindNames = []string{
+	TagReused:            "tag reused",
+	WireTypeChanged:      "wiretype changed",
+	IntEncodingChanged:   "integer encoding changed",
+	ScalarMessageChanged: "scalar/message changed",
+	MessageTypeChanged:   "message type changed",
+	LabelChanged:         "repeated/singular changed",
+	MapChanged:           "map changed",
+	FieldRemoved:         "field removed without being reserved",
+	ReservedReused:       "reserved tag reused",
+	ReservationDropped:   "reservation dropped",
+}
+
+func (k ChangeKind) String() string {
+	if k > 0 && int(k) < len(changeKindNames) {
+		return changeKindNames[k]
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+// Change describes one wire incompatible difference between the old and new definition of a message
+type Change struct {
+	Kind    ChangeKind
+	Message string // scoped name of the message
+	Field   string // name of the field (the old name, unless the field is new)
+	Tag     uint32
+	Old     string // description of the old definition ("" if there was none)
+	New     string // description of the new definition ("" if there is none)
+}
+
+func (c Change) String() string {
+	s := fmt.Sprintf("%s.%s = %d: %s", c.Message, c.Field, c.Tag, c.Kind)
+	if c.Old != "" || c.New != "" {
+		s += fmt.Sprintf(" (was %q, now %q)", c.Old, c.New)
+	}
+	return s
+}
+
+// CheckType compares the protobuf definition of t, and all the types it depends on (plus any additional types
+// in more), against snapshot, which is the .proto source generated from an earlier version of the same types
+// by protobuf3.AsProtobufFull(). It returns the list of wire incompatible changes.
+func CheckType(t reflect.Type, snapshot string, more ...reflect.Type) ([]Change, error) {
+	cur, err := protobuf3.AsProtobufFull(t, more...)
+	if err != nil {
+		return nil, err
+	}
+	return CheckProto(snapshot, cur)
+}
+
+// CheckProto compares two versions of the .proto source of some messages and returns the list of wire incompatible changes.
+func CheckProto(old, new string) ([]Change, error) {
+	of, err := ParseProto("old", old)
+	if err != nil {
+		return nil, err
+	}
+	nf, err := ParseProto("new", new)
+	if err != nil {
+		return nil, err
+	}
+	return Compare(of, nf), nil
+}
+
+// Compare returns the wire incompatible changes between messages defined in both old and new.
+// Messages which are present in only one of old or new are not compared; if they are used by
+// other messages the change will be reported at the field which uses them.
+func Compare(old, new *File) []Change {
+	var changes []Change
+
+	names := make([]string, 0, len(old.Messages))
+	for name := range old.Messages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		om := old.Messages[name]
+		nm, ok := new.Messages[name]
+		if !ok {
+			continue
+		}
+		changes = append(changes, compareMessages(old, om, new, nm, make(map[[2]string]bool))...)
+	}
+
+	return changes
+}
+
+// compare the two versions of a message. Renaming a field or a message doesn't change the encoding,
+// so a different name is only reported when the type changed as well. assumed holds the pairs of
+// differently named messages which are being compared, so that recursive types terminate.
+func compareMessages(old *File, om *Message, new *File, nm *Message, assumed map[[2]string]bool) []Change {
+	var changes []Change
+	add := func(kind ChangeKind, f *Field, tag uint32, o, n string) {
+		name := ""
+		if f != nil {
+			name = f.Name
+		}
+		changes = append(changes, Change{Kind: kind, Message: om.Name, Field: name, Tag: tag, Old: o, New: n})
+	}
+
+	for _, of := range om.Fields {
+		nf := nm.field(of.Tag)
+		if nf == nil {
+			if !nm.isReserved(of.Tag) {
+				add(FieldRemoved, of, of.Tag, of.Type, "")
+			}
+			continue
+		}
+
+		changed := func(kind ChangeKind, o, n string) {
+			if of.Name != nf.Name {
+				// a different name together with an incompatible type is most likely a new field reusing the id
+				add(TagReused, of, of.Tag, of.Name+" "+o, nf.Name+" "+n)
+			} else {
+				add(kind, of, of.Tag, o, n)
+			}
+		}
+
+		if (of.KeyType != "") != (nf.KeyType != "") {
+			changed(MapChanged, of.Type, nf.Type)
+			continue
+		}
+		if of.KeyType != "" {
+			// both are maps. compare the keys and values
+			old_key := old.classify(om.Name, of.KeyType)
+			new_key := new.classify(nm.Name, nf.KeyType)
+			old_val := old.classify(om.Name, of.ValueType)
+			new_val := new.classify(nm.Name, nf.ValueType)
+			if _, diff := compareTypes(old, old_key, new, new_key, assumed); diff {
+				changed(MapChanged, of.Type, nf.Type)
+			} else if _, diff := compareTypes(old, old_val, new, new_val, assumed); diff {
+				changed(MapChanged, of.Type, nf.Type)
+			}
+			continue
+		}
+
+		if (of.Label == "repeated") != (nf.Label == "repeated") {
+			changed(LabelChanged, of.labeledType(), nf.labeledType())
+			continue
+		}
+
+		if kind, diff := compareTypes(old, old.classify(om.Name, of.Type), new, new.classify(nm.Name, nf.Type), assumed); diff {
+			changed(kind, of.Type, nf.Type)
+		}
+	}
+
+	// look for fields which use ids which were reserved, and for ids which are no longer reserved
+	for _, r := range om.Reserved {
+		if f := nm.field(r); f != nil {
+			add(ReservedReused, f, r, "reserved", f.Type)
+		} else if !nm.isReserved(r) {
+			add(ReservationDropped, nil, r, "reserved", "")
+		}
+	}
+
+	return changes
+}
+
+// typeInfo is what we know about a type which matters to its encoding
+type typeInfo struct {
+	name      string // the scoped name of a message or enum, or the scalar type
+	isMessage bool
+	wire      protobuf3.WireType
+	encoding  string // how the value is encoded within the wiretype
+}
+
+// scalars maps the protobuf scalar types to their encodings
+var scalars = map[string]typeInfo{
+	"int32":    {wire: protobuf3.WireVarint, encoding: "varint"},
+	"int64":    {wire: protobuf3.WireVarint, encoding: "varint"},
+	"uint32":   {wire: protobuf3.WireVarint, encoding: "varint"},
+	"uint64":   {wire: protobuf3.WireVarint, encoding: "varint"},
+	"bool":     {wire: protobuf3.WireVarint, encoding: "varint"},
+	"sint32":   {wire: protobuf3.WireVarint, encoding: "zigzag"},
+	"sint64":   {wire: protobuf3.WireVarint, encoding: "zigzag"},
+	"fixed32":  {wire: protobuf3.WireFixed32, encoding: "integer"},
+	"sfixed32": {wire: protobuf3.WireFixed32, encoding: "integer"},
+	"float":    {wire: protobuf3.WireFixed32, encoding: "float"},
+	"fixed64":  {wire: protobuf3.WireFixed64, encoding: "integer"},
+	"sfixed64": {wire: protobuf3.WireFixed64, encoding: "integer"},
+	"double":   {wire: protobuf3.WireFixed64, encoding: "float"},
+	"string":   {wire: protobuf3.WireBytes, encoding: "bytes"},
+	"bytes":    {wire: protobuf3.WireBytes, encoding: "bytes"},
+}
+
+// classify the type typ used in message scope
+func (f *File) classify(scope, typ string) typeInfo {
+	if ti, ok := scalars[typ]; ok {
+		ti.name = typ
+		return ti
+	}
+	name := f.resolve(scope, typ)
+	if _, ok := f.Enums[name]; ok {
+		return typeInfo{name: name, wire: protobuf3.WireVarint, encoding: "varint"}
+	}
+	// anything else is a message, whether we know its definition or not (it could be imported, like google.protobuf.Timestamp)
+	return typeInfo{name: name, isMessage: true, wire: protobuf3.WireBytes, encoding: "message"}
+}
+
+// compareTypes compares the old and new type of a field. A message which was renamed (for instance
+// by AsProtobufFull to resolve a name collision) is compatible as long as its fields are.
+func compareTypes(old *File, o typeInfo, new *File, n typeInfo, assumed map[[2]string]bool) (ChangeKind, bool) {
+	switch {
+	case o.isMessage != n.isMessage:
+		return ScalarMessageChanged, true
+	case o.isMessage:
+		if o.name != n.name {
+			pair := [2]string{o.name, n.name}
+			if assumed[pair] {
+				return 0, false
+			}
+			om, nm := old.Messages[o.name], new.Messages[n.name]
+			if om == nil || nm == nil {
+				return MessageTypeChanged, true
+			}
+			assumed[pair] = true
+			if len(compareMessages(old, om, new, nm, assumed)) != 0 {
+				delete(assumed, pair)
+				return MessageTypeChanged, true
+			}
+		}
+	case o.wire != n.wire:
+		return WireTypeChanged, true
+	case o.encoding != n.encoding:
+		return IntEncodingChanged, true
+	}
+	return 0, false
+}
@@ -0,0 +1,202 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package compat_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mistsys/protobuf3/protobuf3"
+	"github.com/mistsys/protobuf3/protobuf3/compat"
+)
+
+// the .proto of an earlier version of type Device, as it was generated by AsProtobufFull()
+const deviceSnapshot = `// protobuf definitions generated by protobuf3.AsProtobufFull(github.com/mistsys/protobuf3/protobuf3/compat_test.Device)
+
+syntax = "proto3";
+
+package compat_test;
+
+import "google/protobuf/timestamp.proto";
+
+message Device {
+  string name = 1;
+  int64 uptime = 2;
+  uint32 flags = 3;
+  float load = 4;
+  Location location = 5;
+  repeated sint32 temps = 6;
+  map<string, int32> counters = 7;
+  string serial = 8;
+  bytes mac = 9;
+  uint32 model = 10;
+  google.protobuf.Timestamp seen = 11;
+  Port port = 12;
+  reserved 13;
+  string owner = 14;
+  Location home = 15;
+}
+
+message Location {
+  /* note the comment */ double lat = 1;
+  double lon = 2;
+}
+
+message Port {
+  message Link {
+    uint32 speed = 1;
+  }
+  Link link = 1;
+}
+`
+
+// the current version of Device, with several breaking changes
+type Device struct {
+	Name     string           `protobuf:"bytes,1"`
+	Uptime   int64            `protobuf:"zigzag64,2"` // was varint
+	Flags    uint32           `protobuf:"fixed32,3"`  // was varint
+	Load     uint32           `protobuf:"fixed32,4"`  // was a float
+	Location string           `protobuf:"bytes,5"`    // was a message
+	Temps    int32            `protobuf:"zigzag32,6"` // was repeated
+	Counters map[string]int64 `protobuf:"bytes,7" protobuf_key:"bytes,1" protobuf_val:"zigzag64,2"`
+	// Serial (8) has been removed without being reserved
+	MacAddr []byte             `protobuf:"bytes,9"` // renamed, which is compatible
+	_       protobuf3.Reserved `protobuf:"10"`      // Model was deleted and correctly reserved
+	Seen    time.Time          `protobuf:"bytes,11"`
+	Port    Port               `protobuf:"bytes,12"`
+	Reused  bool               `protobuf:"varint,13"` // 13 was reserved
+	OwnerID uint64             `protobuf:"varint,14"` // 14 was the owner's name
+	Home    Place              `protobuf:"bytes,15"`  // Location was renamed Place, which is compatible
+}
+
+type Place Location
+
+type Location struct {
+	Lat float64 `protobuf:"fixed64,1"`
+	Lon float64 `protobuf:"fixed64,2"`
+}
+
+type Port struct {
+	Link struct {
+		Speed uint64 `protobuf:"varint,1"` // uint32 -> uint64 is compatible
+	} `protobuf:"bytes,1"`
+}
+
+func TestCheckType(t *testing.T) {
+	changes, err := compat.CheckType(reflect.TypeOf(Device{}), deviceSnapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]compat.ChangeKind{
+		"uptime":   compat.IntEncodingChanged,
+		"flags":    compat.WireTypeChanged,
+		"load":     compat.IntEncodingChanged,
+		"location": compat.ScalarMessageChanged,
+		"temps":    compat.LabelChanged,
+		"counters": compat.MapChanged,
+		"serial":   compat.FieldRemoved,
+		"owner":    compat.TagReused,
+		"reused":   compat.ReservedReused,
+	}
+	for _, c := range changes {
+		t.Log(c)
+		if c.Message != "Device" {
+			t.Errorf("unexpected change %v", c)
+			continue
+		}
+		if kind, ok := expected[c.Field]; !ok || kind != c.Kind {
+			t.Errorf("unexpected change %v", c)
+			continue
+		}
+		delete(expected, c.Field)
+	}
+	for f, kind := range expected {
+		t.Errorf("missing %s change of field %s", kind, f)
+	}
+}
+
+func TestCheckIdentical(t *testing.T) {
+	cur, err := protobuf3.AsProtobufFull(reflect.TypeOf(Device{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := compat.CheckType(reflect.TypeOf(Device{}), cur)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("identical types reported changes %v", changes)
+	}
+}
+
+func TestCheckProto(t *testing.T) {
+	const old = `syntax = "proto3";
+enum Color {
+  RED = 0;
+  BLUE = 1;
+}
+message M {
+  Color c = 1;
+  repeated int32 x = 2 [packed=true];
+  oneof o {
+    string s = 3;
+    int32 i = 4;
+  }
+  reserved 5 to 7, "gone";
+}`
+	const new = `syntax = "proto3";
+message M {
+  int32 c = 1;
+  repeated int32 x = 2;
+  string s = 3;
+  bytes i = 4;
+  reserved 5, 7;
+}`
+	changes, err := compat.CheckProto(old, new)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// enum -> int32 is compatible, as is removing oneof, but int32 -> bytes and dropping reserved 6 are not
+	if len(changes) != 2 ||
+		changes[0].Kind != compat.WireTypeChanged || changes[0].Tag != 4 ||
+		changes[1].Kind != compat.ReservationDropped || changes[1].Tag != 6 {
+		t.Errorf("unexpected changes %v", changes)
+	}
+
+	if _, err := compat.CheckProto("# Error: broken", new); err == nil {
+		t.Error("CheckProto should have failed on an AsProtobuf() error")
+	}
+}
@@ -0,0 +1,586 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package compat
+
+/*
+ * A parser for the subset of the .proto language which AsProtobufFull() emits
+ * (plus enough of the rest that hand edited or protoc-compatible snapshot files parse too).
+ */
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// File is a parsed .proto file
+type File struct {
+	Package  string
+	Imports  []string
+	Messages map[string]*Message // all messages, including nested ones, keyed by their scoped name ("Outer.Inner")
+	Enums    map[string]*Enum    // all enums, keyed by their scoped name
+}
+
+// Message is a parsed protobuf message definition
+type Message struct {
+	Name     string   // scoped name of the message ("Outer.Inner" for nested messages)
+	Fields   []*Field // in the order they were defined
+	Reserved []uint32 // the reserved tag ids, with any ranges expanded
+}
+
+// Field is a parsed field of a message. Fields of a oneof are flattened into the enclosing message.
+type Field struct {
+	Name      string
+	Tag       uint32
+	Label     string // "", "optional" or "repeated"
+	Type      string // the type as written in the .proto ("map<K, V>" in the case of maps)
+	KeyType   string // set for maps only
+	ValueType string // set for maps only
+}
+
+// Enum is a parsed enum definition
+type Enum struct {
+	Name   string // scoped name of the enum
+	Values map[string]int32
+}
+
+// field returns the field with id tag, or nil
+func (m *Message) field(tag uint32) *Field {
+	for _, f := range m.Fields {
+		if f.Tag == tag {
+			return f
+		}
+	}
+	return nil
+}
+
+// labeledType returns the type of f, prefixed by its label if it has one
+func (f *Field) labeledType() string {
+	if f.Label != "" {
+		return f.Label + " " + f.Type
+	}
+	return f.Type
+}
+
+// isReserved returns true if tag is reserved in message m
+func (m *Message) isReserved(tag uint32) bool {
+	for _, r := range m.Reserved {
+		if r == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseProto parses the .proto source src. name is used in error messages.
+func ParseProto(name, src string) (*File, error) {
+	p := &parser{
+		name: name,
+		src:  src,
+		line: 1,
+		file: &File{
+			Messages: make(map[string]*Message),
+			Enums:    make(map[string]*Enum),
+		},
+	}
+	if err := p.parseFile(); err != nil {
+		return nil, err
+	}
+	return p.file, nil
+}
+
+// parser is a simple recursive descent parser for .proto files
+type parser struct {
+	name string
+	src  string
+	pos  int // offset of the next unread byte of src
+	line int // line number of pos, for error messages
+	file *File
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%s:%d: %s", p.name, p.line, fmt.Sprintf(format, args...))
+}
+
+// skip whitespace and comments
+func (p *parser) skipSpace() error {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == '\n':
+			p.line++
+			p.pos++
+		case c == ' ' || c == '\t' || c == '\r':
+			p.pos++
+		case strings.HasPrefix(p.src[p.pos:], "//"):
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		case strings.HasPrefix(p.src[p.pos:], "/*"):
+			end := strings.Index(p.src[p.pos+2:], "*/")
+			if end < 0 {
+				return p.errorf("unterminated comment")
+			}
+			p.line += strings.Count(p.src[p.pos:p.pos+2+end], "\n")
+			p.pos += 2 + end + 2
+		case c == '#':
+			// AsProtobuf() emits "# Error: ..." lines in order to break the protobuf compiler. we break too.
+			end := strings.IndexByte(p.src[p.pos:], '\n')
+			if end < 0 {
+				end = len(p.src) - p.pos
+			}
+			return p.errorf("%s", p.src[p.pos:p.pos+end])
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// next returns the next token, or "" at the end of the input
+func (p *parser) next() (string, error) {
+	if err := p.skipSpace(); err != nil {
+		return "", err
+	}
+	if p.pos == len(p.src) {
+		return "", nil
+	}
+	start := p.pos
+	c := p.src[p.pos]
+	switch {
+	case c == '"' || c == '\'':
+		p.pos++
+		for p.pos < len(p.src) && p.src[p.pos] != c {
+			if p.src[p.pos] == '\\' {
+				p.pos++
+			}
+			p.pos++
+		}
+		if p.pos >= len(p.src) {
+			return "", p.errorf("unterminated string")
+		}
+		p.pos++
+	case isIdentRune(rune(c)) || c == '.' || c == '-' || c == '+':
+		p.pos++
+		for p.pos < len(p.src) && (isIdentRune(rune(p.src[p.pos])) || p.src[p.pos] == '.') {
+			p.pos++
+		}
+	default:
+		p.pos++
+	}
+	return p.src[start:p.pos], nil
+}
+
+// peek returns the next token without consuming it
+func (p *parser) peek() (string, error) {
+	pos, line := p.pos, p.line
+	tok, err := p.next()
+	p.pos, p.line = pos, line
+	return tok, err
+}
+
+// expect consumes the next token, which must be want
+func (p *parser) expect(want string) error {
+	tok, err := p.next()
+	if err != nil {
+		return err
+	}
+	if tok != want {
+		return p.errorf("expected %q, found %q", want, tok)
+	}
+	return nil
+}
+
+// skipStatement skips up to and including the next ';' at the current nesting level
+func (p *parser) skipStatement() error {
+	depth := 0
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case "":
+			return p.errorf("unexpected end of file")
+		case "{", "[", "(":
+			depth++
+		case "}", "]", ")":
+			depth--
+		case ";":
+			if depth <= 0 {
+				return nil
+			}
+		}
+	}
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func (p *parser) parseFile() error {
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case "":
+			return nil
+		case ";":
+			// empty statement
+		case "syntax":
+			if err := p.expect("="); err != nil {
+				return err
+			}
+			s, err := p.next()
+			if err != nil {
+				return err
+			}
+			if s != `"proto3"` && s != `'proto3'` {
+				return p.errorf("unsupported syntax %s", s)
+			}
+			if err := p.expect(";"); err != nil {
+				return err
+			}
+		case "package":
+			p.file.Package, err = p.next()
+			if err != nil {
+				return err
+			}
+			if err := p.expect(";"); err != nil {
+				return err
+			}
+		case "import":
+			s, err := p.next()
+			if err != nil {
+				return err
+			}
+			if s == "public" || s == "weak" {
+				s, err = p.next()
+				if err != nil {
+					return err
+				}
+			}
+			imp, err := strconv.Unquote(s)
+			if err != nil {
+				return p.errorf("bad import %s", s)
+			}
+			p.file.Imports = append(p.file.Imports, imp)
+			if err := p.expect(";"); err != nil {
+				return err
+			}
+		case "option":
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+		case "message":
+			if err := p.parseMessage(""); err != nil {
+				return err
+			}
+		case "enum":
+			if err := p.parseEnum(""); err != nil {
+				return err
+			}
+		default:
+			return p.errorf("unexpected %q", tok)
+		}
+	}
+}
+
+// parseMessage parses a message definition. "message" has already been consumed.
+func (p *parser) parseMessage(scope string) error {
+	name, err := p.next()
+	if err != nil {
+		return err
+	}
+	if scope != "" {
+		name = scope + "." + name
+	}
+	if _, ok := p.file.Messages[name]; ok {
+		return p.errorf("duplicate message %s", name)
+	}
+	m := &Message{Name: name}
+	p.file.Messages[name] = m
+
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	return p.parseMessageBody(m)
+}
+
+// parseMessageBody parses the fields (and nested types) of a message, up to and including the closing '}'
+func (p *parser) parseMessageBody(m *Message) error {
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case "":
+			return p.errorf("unexpected end of file in message %s", m.Name)
+		case "}":
+			return nil
+		case ";":
+			// empty statement
+		case "option":
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+		case "message":
+			if err := p.parseMessage(m.Name); err != nil {
+				return err
+			}
+		case "enum":
+			if err := p.parseEnum(m.Name); err != nil {
+				return err
+			}
+		case "reserved":
+			if err := p.parseReserved(m); err != nil {
+				return err
+			}
+		case "oneof":
+			// the fields of a oneof are encoded just like the fields of the enclosing message
+			if _, err := p.next(); err != nil {
+				return err
+			}
+			if err := p.expect("{"); err != nil {
+				return err
+			}
+			if err := p.parseMessageBody(m); err != nil {
+				return err
+			}
+		case "extensions", "extend", "group":
+			return p.errorf("%s are not supported in proto3", tok)
+		default:
+			if err := p.parseField(m, tok); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// parseField parses a field of message m, the first token of which has already been consumed
+func (p *parser) parseField(m *Message, tok string) error {
+	f := new(Field)
+	if tok == "optional" || tok == "repeated" {
+		f.Label = tok
+		var err error
+		tok, err = p.next()
+		if err != nil {
+			return err
+		}
+	}
+
+	if tok == "map" {
+		if err := p.expect("<"); err != nil {
+			return err
+		}
+		var err error
+		f.KeyType, err = p.next()
+		if err != nil {
+			return err
+		}
+		if err := p.expect(","); err != nil {
+			return err
+		}
+		f.ValueType, err = p.next()
+		if err != nil {
+			return err
+		}
+		if err := p.expect(">"); err != nil {
+			return err
+		}
+		f.Type = fmt.Sprintf("map<%s, %s>", f.KeyType, f.ValueType)
+	} else {
+		f.Type = tok
+	}
+
+	var err error
+	f.Name, err = p.next()
+	if err != nil {
+		return err
+	}
+	if err := p.expect("="); err != nil {
+		return err
+	}
+	s, err := p.next()
+	if err != nil {
+		return err
+	}
+	tag, err := strconv.ParseUint(s, 0, 29)
+	if err != nil || tag == 0 {
+		return p.errorf("bad tag id %q for field %s.%s", s, m.Name, f.Name)
+	}
+	f.Tag = uint32(tag)
+
+	// skip any field options ([packed=true] and the like) and the closing ';'
+	if err := p.skipStatement(); err != nil {
+		return err
+	}
+
+	m.Fields = append(m.Fields, f)
+	return nil
+}
+
+// parseReserved parses a list of reserved ids or names. "reserved" has already been consumed.
+func (p *parser) parseReserved(m *Message) error {
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		switch {
+		case tok == ";":
+			return nil
+		case tok == ",":
+			// separator
+		case tok != "" && (tok[0] == '"' || tok[0] == '\''):
+			// reserved field names don't matter on the wire
+		default:
+			lo, err := strconv.ParseUint(tok, 0, 29)
+			if err != nil {
+				return p.errorf("bad reserved id %q in message %s", tok, m.Name)
+			}
+			hi := lo
+			if next, _ := p.peek(); next == "to" {
+				p.next()
+				tok, err = p.next()
+				if err != nil {
+					return err
+				}
+				if tok == "max" {
+					hi = 1<<29 - 1
+				} else if hi, err = strconv.ParseUint(tok, 0, 29); err != nil || hi < lo {
+					return p.errorf("bad reserved range %d to %q in message %s", lo, tok, m.Name)
+				}
+			}
+			if hi-lo > 1<<16 {
+				// don't expand enormous ranges. they only occur as "N to max", and the tail end is never used anyway
+				hi = lo + 1<<16
+			}
+			for r := lo; r <= hi; r++ {
+				m.Reserved = append(m.Reserved, uint32(r))
+			}
+		}
+	}
+}
+
+// parseEnum parses an enum definition. "enum" has already been consumed.
+func (p *parser) parseEnum(scope string) error {
+	name, err := p.next()
+	if err != nil {
+		return err
+	}
+	if scope != "" {
+		name = scope + "." + name
+	}
+	e := &Enum{Name: name, Values: make(map[string]int32)}
+	p.file.Enums[name] = e
+
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case "":
+			return p.errorf("unexpected end of file in enum %s", name)
+		case "}":
+			return nil
+		case ";":
+		case "option", "reserved":
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+		default:
+			if err := p.expect("="); err != nil {
+				return err
+			}
+			s, err := p.next()
+			if err != nil {
+				return err
+			}
+			v, err := strconv.ParseInt(s, 0, 32)
+			if err != nil {
+				return p.errorf("bad value %q for enum %s.%s", s, name, tok)
+			}
+			e.Values[tok] = int32(v)
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// resolve returns the scoped name of the message or enum named typ, as seen from within message scope.
+// the protobuf scoping rules are followed: the innermost scope is searched first.
+func (f *File) resolve(scope, typ string) string {
+	if strings.HasPrefix(typ, ".") {
+		// fully qualified name
+		typ = typ[1:]
+		if f.Package != "" {
+			typ = strings.TrimPrefix(typ, f.Package+".")
+		}
+		return typ
+	}
+	for {
+		name := typ
+		if scope != "" {
+			name = scope + "." + typ
+		}
+		if _, ok := f.Messages[name]; ok {
+			return name
+		}
+		if _, ok := f.Enums[name]; ok {
+			return name
+		}
+		if scope == "" {
+			break
+		}
+		if i := strings.LastIndexByte(scope, '.'); i >= 0 {
+			scope = scope[:i]
+		} else {
+			scope = ""
+		}
+	}
+	if f.Package != "" {
+		typ = strings.TrimPrefix(typ, f.Package+".")
+	}
+	return typ
+}
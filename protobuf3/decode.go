@@ -43,7 +43,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"os"
 	"reflect"
 	"time"
 	"unsafe"
@@ -370,11 +369,24 @@ func (p *Buffer) DecodeRawBytes() ([]byte, error) {
 
 // DecodeStringBytes reads an encoded string from the Buffer.
 // This is the format used for the proto3 string type.
+// If the Buffer has an Interner the string comes from it. Otherwise if the Buffer is Immutable
+// the string points into the Buffer's bytes.
 func (p *Buffer) DecodeStringBytes() (string, error) {
 	buf, err := p.DecodeRawBytes()
 	if err != nil {
 		return "", err
 	}
+	// an Immutable Buffer's strings point into its bytes, but an Interner copies the strings it hasn't seen before
+	if err := p.addBytes(len(buf), p.Immutable && p.Interner == nil); err != nil {
+		return "", err
+	}
+	if p.Interner != nil {
+		return p.Interner.Intern(buf), nil
+	}
+	if p.Immutable && len(buf) != 0 {
+		// the caller promised the bytes won't change, so the string can share them
+		return unsafe.String(&buf[0], len(buf)), nil
+	}
 	return string(buf), nil
 }
 
@@ -477,13 +489,22 @@ func Unmarshal(bytes []byte, pb Message) error {
 // Unmarshal parses the protocol buffer representation in the
 // Buffer and places the decoded result in pb.  If the struct
 // underlying pb does not match the data in the buffer, the results can be
-// unpredictable.
+// unpredictable. pb can also point to a slice, an array, a map or a scalar
+// which Marshal encoded as the single field of a message.
 func (p *Buffer) Unmarshal(pb Message) error {
 	if pb == nil { // we need a non-nil interface or this won't work
 		return ErrNil // NOTE this could almost qualify for a panic(), because the calling code is clearly quite confused
 	}
 
+	if max := p.Limits.MaxMessageSize; max != 0 && len(p.buf)-int(p.index) > max {
+		return &LimitError{"MaxMessageSize", max}
+	}
+	p.allocated = 0
+
 	// If the object can unmarshal itself, let it.
+	if g, ok := pb.(Generated); ok {
+		return g.DecodeProtobuf3(p)
+	}
 	if m, ok := pb.(unmarshaler); ok {
 		err := m.UnmarshalProtobuf3(p.buf[p.index:])
 		p.index = ulen(p.buf)
@@ -496,19 +517,32 @@ func (p *Buffer) Unmarshal(pb Message) error {
 		return ErrNotPointerToStruct
 	}
 	t = t.Elem()
+	st := t
 	if t.Kind() != reflect.Struct {
-		return ErrNotPointerToStruct
+		// or a pointer to a value we wrapped in a message when we marshaled it
+		if !isTopLevelWrapped(t) {
+			return ErrNotPointerToStruct
+		}
+		var err error
+		st, err = topLevelType(t, p.TopLevelTag)
+		if err != nil {
+			return err
+		}
 	}
 
-	// the caller already checked that pb is a pointer-to-struct type
+	// the caller already checked that pb is a pointer-to-struct type (or a pointer to something with the layout of one)
 	base := unsafe.Pointer(reflect.ValueOf(pb).Pointer())
 
-	prop, err := GetProperties(t)
+	prop, err := GetProperties(st)
 	if err != nil {
 		return err
 	}
 
-	return p.unmarshal_struct(t, prop, base)
+	err = p.unmarshal_struct(st, prop, base)
+	if err != nil {
+		err = topLevelError(err, t, st)
+	}
+	return err
 }
 
 // unmarshal_struct does the work of unmarshaling a structure.
@@ -518,15 +552,26 @@ func (o *Buffer) unmarshal_struct(st reflect.Type, prop *StructProperties, base
 	var ptag = -1     // -1, or the previous tag (matched or not, depending on whether p is nil or not)
 	var p *Properties // nil, or the p where p.Tag == ptag
 
+	if prop == time_Time_sprop {
+		// time.Time isn't a struct we can decode field by field. It is a google.protobuf.Timestamp, and
+		// the callers decoding slices, arrays and pointers of structs have already positioned us on its body
+		ts, err := o.DecodeTimestamp()
+		if err == nil {
+			*(*time.Time)(base) = ts
+		}
+		return err
+	}
+
 	o.recursion_depth++
 	if o.recursion_depth > o.MaxRecursionDepth {
 		return fmt.Errorf("reached MaxRecursionDepth %d while unmarshaling %s", o.MaxRecursionDepth, st.Name())
 	}
 
+	var start uint
+	var wire WireType
+	var tag int
 	for err == nil && o.index < ulen(o.buf) {
-		start := o.index
-		var wire WireType
-		var tag int
+		start = o.index
 		// most tags are one byte varints, so make that a special case and don't call DecodeVarint() and avoid error checks too
 		b := uint64(o.buf[start])
 		if b < 0x80 {
@@ -542,12 +587,15 @@ func (o *Buffer) unmarshal_struct(st reflect.Type, prop *StructProperties, base
 			var u uint64
 			u, err = o.DecodeVarint()
 			if err != nil {
+				p, tag, wire = nil, 0, 0 // the error isn't in the previous field
 				break
 			}
 			wire = WireType(u & 0x7)
 			tag = int(u >> 3)
 			if tag <= 0 {
-				return fmt.Errorf("protobuf3: %s: illegal tag %d (wiretype %v) at index %d of %d", st, tag, wire, start, len(o.buf))
+				p = nil
+				err = fmt.Errorf("protobuf3: illegal tag %d (wiretype %v) at index %d of %d", tag, wire, start, len(o.buf))
+				break
 			}
 		}
 
@@ -576,16 +624,33 @@ func (o *Buffer) unmarshal_struct(st reflect.Type, prop *StructProperties, base
 		}
 
 		if p.dec == nil {
-			fmt.Fprintf(os.Stderr, "protobuf3: no protobuf decoder for %s.%s\n", st, p.Name)
+			logError(fmt.Errorf("protobuf3: no protobuf decoder for %s.%s", st, p.Name))
 			continue
 		}
 		if wire != p.WireType {
-			err = fmt.Errorf("protobuf3: bad wiretype for field %s.%s: got wiretype %v, wanted %v", st, p.Name, wire, p.WireType)
+			err = fmt.Errorf("bad wiretype, wanted %v", p.WireType)
 			break
 		}
 		err = p.dec(o, p, base)
 	}
 	o.recursion_depth--
+	if err != nil {
+		err = fieldError(st, p, tag, wire, start, err)
+	}
+	return err
+}
+
+// unmarshal_nested unmarshals raw, the body of a field which was just decoded from o.buf, into the p.stype at ptr.
+// i is the index of the element when the field is repeated, or -1
+func (o *Buffer) unmarshal_nested(raw []byte, p *Properties, ptr unsafe.Pointer, i int) error {
+	// swizzle around and reuse the buffer. less gc
+	obuf, oi := o.buf, o.index
+	o.buf, o.index = raw, 0
+	err := o.unmarshal_struct(p.stype, p.sprop, ptr)
+	o.buf, o.index = obuf, oi
+	if err != nil {
+		err = nestedError(err, i, int(oi)-len(raw))
+	}
 	return err
 }
 
@@ -792,6 +857,9 @@ func (o *Buffer) dec_slice_byte(p *Properties, base unsafe.Pointer) error {
 		return err
 	}
 
+	if err := o.addBytes(len(raw), o.Immutable); err != nil {
+		return err
+	}
 	if !o.Immutable {
 		copied := make([]byte, len(raw))
 		copy(copied, raw)
@@ -840,8 +908,14 @@ func (o *Buffer) dec_slice_packed_bool(p *Properties, base unsafe.Pointer) error
 	}
 
 	y := *v
-	if y == nil {
-		y = make([]bool, 0, p.valCnt(o, fin))
+	if y == nil || o.limited() {
+		cnt := p.valCnt(o, fin)
+		if err := o.addRepeated(len(y)+cnt, cnt, unsafe.Sizeof(y[0])); err != nil {
+			return err
+		}
+		if y == nil {
+			y = make([]bool, 0, cnt)
+		}
 	}
 
 	for o.index < fin {
@@ -909,8 +983,14 @@ func (o *Buffer) dec_slice_packed_int8(p *Properties, base unsafe.Pointer) error
 	}
 
 	y := *v
-	if y == nil {
-		y = make([]int8, 0, p.valCnt(o, fin))
+	if y == nil || o.limited() {
+		cnt := p.valCnt(o, fin)
+		if err := o.addRepeated(len(y)+cnt, cnt, unsafe.Sizeof(y[0])); err != nil {
+			return err
+		}
+		if y == nil {
+			y = make([]int8, 0, cnt)
+		}
 	}
 
 	for o.index < fin {
@@ -979,8 +1059,14 @@ func (o *Buffer) dec_slice_packed_int16(p *Properties, base unsafe.Pointer) erro
 	}
 
 	y := *v
-	if y == nil {
-		y = make([]uint16, 0, p.valCnt(o, fin))
+	if y == nil || o.limited() {
+		cnt := p.valCnt(o, fin)
+		if err := o.addRepeated(len(y)+cnt, cnt, unsafe.Sizeof(y[0])); err != nil {
+			return err
+		}
+		if y == nil {
+			y = make([]uint16, 0, cnt)
+		}
 	}
 
 	for o.index < fin {
@@ -1049,8 +1135,14 @@ func (o *Buffer) dec_slice_packed_int32(p *Properties, base unsafe.Pointer) erro
 	}
 
 	y := *v
-	if y == nil {
-		y = make([]uint32, 0, p.valCnt(o, fin))
+	if y == nil || o.limited() {
+		cnt := p.valCnt(o, fin)
+		if err := o.addRepeated(len(y)+cnt, cnt, unsafe.Sizeof(y[0])); err != nil {
+			return err
+		}
+		if y == nil {
+			y = make([]uint32, 0, cnt)
+		}
 	}
 
 	for o.index < fin {
@@ -1119,8 +1211,14 @@ func (o *Buffer) dec_slice_packed_int(p *Properties, base unsafe.Pointer) error
 	}
 
 	y := *v
-	if y == nil {
-		y = make([]uint, 0, p.valCnt(o, fin))
+	if y == nil || o.limited() {
+		cnt := p.valCnt(o, fin)
+		if err := o.addRepeated(len(y)+cnt, cnt, unsafe.Sizeof(y[0])); err != nil {
+			return err
+		}
+		if y == nil {
+			y = make([]uint, 0, cnt)
+		}
 	}
 
 	for o.index < fin {
@@ -1189,8 +1287,14 @@ func (o *Buffer) dec_slice_packed_int64(p *Properties, base unsafe.Pointer) erro
 	}
 
 	y := *v
-	if y == nil {
-		y = make([]uint64, 0, p.valCnt(o, fin))
+	if y == nil || o.limited() {
+		cnt := p.valCnt(o, fin)
+		if err := o.addRepeated(len(y)+cnt, cnt, unsafe.Sizeof(y[0])); err != nil {
+			return err
+		}
+		if y == nil {
+			y = make([]uint64, 0, cnt)
+		}
 	}
 
 	for o.index < fin {
@@ -1251,8 +1355,10 @@ func (o *Buffer) dec_slice_string(p *Properties, base unsafe.Pointer) error {
 
 	if y == nil {
 		// preallocate what is probably the right sized slice immediately (if it isn't then we'll append later)
-		n, _ := o.count_ahead(p.Tag, p.WireType)
-		y = make([]string, 0, 1+n)
+		y = make([]string, 0, o.countAhead(p, unsafe.Sizeof(y[0])))
+	}
+	if err := o.addRepeated(len(y)+1, 1, unsafe.Sizeof(y[0])); err != nil {
+		return err
 	}
 
 	*v = append(y, s)
@@ -1288,6 +1394,9 @@ func (o *Buffer) dec_slice_slice_byte(p *Properties, base unsafe.Pointer) error
 		return err
 	}
 
+	if err := o.addBytes(len(raw), o.Immutable); err != nil {
+		return err
+	}
 	if !o.Immutable {
 		copied := make([]byte, len(raw))
 		copy(copied, raw)
@@ -1299,14 +1408,100 @@ func (o *Buffer) dec_slice_slice_byte(p *Properties, base unsafe.Pointer) error
 
 	if s == nil {
 		// preallocate what is probably the right sized slice immediately (if it isn't then we'll append later)
-		n, _ := o.count_ahead(p.Tag, p.WireType)
-		s = make([][]byte, 0, 1+n)
+		s = make([][]byte, 0, o.countAhead(p, unsafe.Sizeof(s[0])))
+	}
+	if err := o.addRepeated(len(s)+1, 1, unsafe.Sizeof(s[0])); err != nil {
+		return err
 	}
 
 	*v = append(s, raw)
 	return nil
 }
 
+// Decode a slice of arrays of bytes ([][n]byte).
+func (o *Buffer) dec_slice_array_byte(p *Properties, base unsafe.Pointer) error {
+	raw, err := o.DecodeRawBytes()
+	if err != nil {
+		return err
+	}
+
+	// build a reflect.Value of the slice
+	ptr := unsafe.Pointer(uintptr(base) + p.offset)
+	slice := reflect.NewAt(reflect.SliceOf(p.stype), ptr).Elem()
+
+	if slice.IsNil() {
+		// preallocate what is probably the right sized slice immediately (if it isn't then we'll append later)
+		slice.Set(reflect.MakeSlice(slice.Type(), 0, o.countAhead(p, p.stype.Size())))
+	}
+	if err := o.addRepeated(slice.Len()+1, 1, p.stype.Size()); err != nil {
+		return err
+	}
+
+	// extend the slice with a new zero value, and copy into it
+	slice.Set(reflect.Append(slice, reflect.Zero(p.stype)))
+	return p.copy_array_byte(unsafe.Pointer(slice.Index(slice.Len()-1).UnsafeAddr()), raw)
+}
+
+// Decode an array of arrays of bytes ([m][n]byte).
+func (o *Buffer) dec_array_array_byte(p *Properties, base unsafe.Pointer) error {
+	raw, err := o.DecodeRawBytes()
+	if err != nil {
+		return err
+	}
+
+	ptr := unsafe.Pointer(uintptr(base) + p.offset) // address of 1st element of the array
+	i := o.array_indexes[ptr]
+	if i < p.length {
+		err = p.copy_array_byte(unsafe.Pointer(uintptr(ptr)+uintptr(i*p.elemLength)), raw)
+		i++
+		o.saveIndex(ptr, i)
+	}
+
+	return err
+}
+
+// Decode a slice of pointers to arrays of bytes ([]*[n]byte).
+func (o *Buffer) dec_slice_ptr_array_byte(p *Properties, base unsafe.Pointer) error {
+	raw, err := o.DecodeRawBytes()
+	if err != nil {
+		return err
+	}
+
+	pslice := (*[]unsafe.Pointer)(unsafe.Pointer(uintptr(base) + p.offset))
+	s := *pslice
+	if err := o.addRepeated(len(s)+1, 1, unsafe.Sizeof(s[0])+p.stype.Size()); err != nil {
+		return err
+	}
+
+	pa := unsafe.Pointer(reflect.New(p.stype).Pointer())
+	if err := p.copy_array_byte(pa, raw); err != nil {
+		return err
+	}
+
+	if s == nil {
+		// preallocate what is probably the right sized slice immediately (if it isn't then we'll append later)
+		s = make([]unsafe.Pointer, 0, o.countAhead(p, unsafe.Sizeof(s[0])))
+	}
+
+	*pslice = append(s, pa)
+	return nil
+}
+
+// copy_array_byte copies raw, one element of a repeated [n]byte field, into the array at ptr.
+// Unlike a lone [n]byte field, the length of each element must be n, unless the field has the "resize"
+// option, in which case longer elements are truncated and shorter ones are padded with zeros.
+func (p *Properties) copy_array_byte(ptr unsafe.Pointer, raw []byte) error {
+	n := p.elemLength
+	if ulen(raw) != n && !p.resize {
+		return fmt.Errorf("protobuf3: %s element is %d bytes long, not %d", p.Name, len(raw), n)
+	}
+	s := unsafe.Slice((*byte)(ptr), n)
+	for i := copy(s, raw); i < len(s); i++ {
+		s[i] = 0
+	}
+	return nil
+}
+
 // Decode a map field.
 func (o *Buffer) dec_new_map(p *Properties, base unsafe.Pointer) error {
 	o.recursion_depth++
@@ -1367,6 +1562,9 @@ func (o *Buffer) dec_new_map(p *Properties, base unsafe.Pointer) error {
 		valelem = reflect.Zero(p.mtype.Elem())
 	}
 
+	if err := o.addMapEntry(v, keyelem, p.mtype.Key().Size()+p.mtype.Elem().Size()); err != nil {
+		return err
+	}
 	v.SetMapIndex(keyelem, valelem)
 	o.recursion_depth--
 	return nil
@@ -1381,14 +1579,7 @@ func (o *Buffer) dec_struct_message(p *Properties, base unsafe.Pointer) error {
 
 	ptr := unsafe.Pointer(uintptr(base) + p.offset)
 
-	// swizzle around and reuse the buffer. less gc
-	obuf, oi := o.buf, o.index
-	o.buf, o.index = raw, 0
-
-	err = o.unmarshal_struct(p.stype, p.sprop, ptr)
-
-	o.buf, o.index = obuf, oi
-	return err
+	return o.unmarshal_nested(raw, p, ptr, -1)
 }
 
 // Decode a pointer to an embedded message.
@@ -1402,19 +1593,15 @@ func (o *Buffer) dec_ptr_struct_message(p *Properties, base unsafe.Pointer) erro
 	ptr := *pptr
 	var val reflect.Value
 	if ptr == nil {
+		if err := o.allocate(p.stype.Size()); err != nil {
+			return err
+		}
 		val = reflect.New(p.stype)
 		ptr = unsafe.Pointer(val.Pointer()) // Is this gc safe? it seems not to be to me, but I don't have a better solution, and it's what google's code does
 		*pptr = ptr
 	} // else the value is already allocated and we merge into it
 
-	// swizzle around and reuse the buffer. less gc
-	obuf, oi := o.buf, o.index
-	o.buf, o.index = raw, 0
-
-	err = o.unmarshal_struct(p.stype, p.sprop, ptr)
-
-	o.buf, o.index = obuf, oi
-	return err
+	return o.unmarshal_nested(raw, p, ptr, -1)
 }
 
 // Decode into a slice of messages ([]struct)
@@ -1431,8 +1618,10 @@ func (o *Buffer) dec_slice_struct_message(p *Properties, base unsafe.Pointer) er
 
 	if slice.IsNil() {
 		// preallocate what is probably the right sized slice immediately (if it isn't then we'll append later)
-		n, _ := o.count_ahead(p.Tag, p.WireType)
-		slice.Set(reflect.MakeSlice(slice.Type(), 0, 1+n))
+		slice.Set(reflect.MakeSlice(slice.Type(), 0, o.countAhead(p, p.stype.Size())))
+	}
+	if err := o.addRepeated(slice.Len()+1, 1, p.stype.Size()); err != nil {
+		return err
 	}
 
 	n := slice.Len()
@@ -1446,18 +1635,13 @@ func (o *Buffer) dec_slice_struct_message(p *Properties, base unsafe.Pointer) er
 	// and unmarshal into it
 	val := slice.Index(n)
 	if p.isAppender || p.isMarshaler {
-		return val.Addr().Interface().(unmarshaler).UnmarshalProtobuf3(raw)
+		return o.decodeMessage(val.Addr().Interface().(unmarshaler), raw)
 	}
 
 	pval := unsafe.Pointer(val.UnsafeAddr())
 
 	// unmarshal into pval
-	obuf, oi := o.buf, o.index
-	o.buf, o.index = raw, 0
-	err = o.unmarshal_struct(p.stype, p.sprop, pval)
-	o.buf, o.index = obuf, oi
-
-	return err
+	return o.unmarshal_nested(raw, p, pval, n)
 }
 
 // Decode into an array of messages ([N]struct)
@@ -1476,13 +1660,10 @@ func (o *Buffer) dec_array_struct_message(p *Properties, base unsafe.Pointer) er
 		ptr_elem := unsafe.Pointer(uintptr(ptr) + uintptr(i)*p.stype.Size())
 
 		if p.isAppender || p.isMarshaler {
-			err = reflect.NewAt(p.stype, ptr_elem).Interface().(unmarshaler).UnmarshalProtobuf3(raw)
+			err = o.decodeMessage(reflect.NewAt(p.stype, ptr_elem).Interface().(unmarshaler), raw)
 		} else {
 			// unmarshal into pval
-			obuf, oi := o.buf, o.index
-			o.buf, o.index = raw, 0
-			err = o.unmarshal_struct(p.stype, p.sprop, ptr_elem)
-			o.buf, o.index = obuf, oi
+			err = o.unmarshal_nested(raw, p, ptr_elem, int(i))
 		}
 
 		i++
@@ -1499,31 +1680,30 @@ func (o *Buffer) dec_slice_ptr_struct_message(p *Properties, base unsafe.Pointer
 		return err
 	}
 
+	// we'll append a pointer to a new struct to the slice []*struct, once it is unmarshaled
+	pslice := (*[]unsafe.Pointer)(unsafe.Pointer(uintptr(base) + p.offset))
+	s := *pslice
+	if err := o.addRepeated(len(s)+1, 1, unsafe.Sizeof(s[0])+p.stype.Size()); err != nil {
+		return err
+	}
+
 	// construct a new *struct
 	v := reflect.New(p.stype)
 	pv := unsafe.Pointer(v.Pointer())
 
 	// unmarshal into the new struct
 	if p.isAppender || p.isMarshaler {
-		err = v.Interface().(unmarshaler).UnmarshalProtobuf3(raw)
+		err = o.decodeMessage(v.Interface().(unmarshaler), raw)
 	} else {
-		obuf, oi := o.buf, o.index
-		o.buf, o.index = raw, 0
-		err = o.unmarshal_struct(p.stype, p.sprop, pv)
-		o.buf, o.index = obuf, oi
+		err = o.unmarshal_nested(raw, p, pv, len(s))
 	}
 	if err != nil {
 		return err
 	}
 
-	// append pv to the slice []*struct
-	pslice := (*[]unsafe.Pointer)(unsafe.Pointer(uintptr(base) + p.offset))
-	s := *pslice
-
 	if s == nil {
 		// preallocate what is probably the right sized slice immediately (if it isn't then we'll append later)
-		n, _ := o.count_ahead(p.Tag, p.WireType)
-		s = make([]unsafe.Pointer, 0, 1+n)
+		s = make([]unsafe.Pointer, 0, o.countAhead(p, unsafe.Sizeof(s[0])))
 	}
 
 	*pslice = append(s, pv)
@@ -1539,26 +1719,26 @@ func (o *Buffer) dec_array_ptr_struct_message(p *Properties, base unsafe.Pointer
 	}
 
 	// construct a new *struct
+	if err := o.allocate(p.stype.Size()); err != nil {
+		return err
+	}
 	v := reflect.New(p.stype)
 	pv := unsafe.Pointer(v.Pointer())
 
+	// address of the start of the array
+	ptr := unsafe.Pointer(uintptr(base) + p.offset)
+	n := p.length
+	i := o.array_indexes[ptr]
+
 	// unmarshal into the new struct
 	if p.isAppender || p.isMarshaler {
-		err = v.Interface().(unmarshaler).UnmarshalProtobuf3(raw)
+		err = o.decodeMessage(v.Interface().(unmarshaler), raw)
 	} else {
-		obuf, oi := o.buf, o.index
-		o.buf, o.index = raw, 0
-		err = o.unmarshal_struct(p.stype, p.sprop, pv)
-		o.buf, o.index = obuf, oi
+		err = o.unmarshal_nested(raw, p, pv, int(i))
 	}
 	if err != nil {
 		return err
 	}
-
-	// address of the start of the array
-	ptr := unsafe.Pointer(uintptr(base) + p.offset)
-	n := p.length
-	i := o.array_indexes[ptr]
 	if i < n {
 		// address of pointer i
 		*(*unsafe.Pointer)(unsafe.Pointer(uintptr(ptr) + uintptr(i)*unsafe.Sizeof(unsafe.Pointer(nil)))) = pv
@@ -1578,7 +1758,7 @@ func (o *Buffer) dec_unmarshaler(p *Properties, base unsafe.Pointer) error {
 
 	ptr := unsafe.Pointer(uintptr(base) + p.offset)
 	iv := reflect.NewAt(p.stype, ptr).Interface()
-	err = iv.(unmarshaler).UnmarshalProtobuf3(raw)
+	err = o.decodeMessage(iv.(unmarshaler), raw)
 	return err
 }
 
@@ -1592,13 +1772,16 @@ func (o *Buffer) dec_ptr_unmarshaler(p *Properties, base unsafe.Pointer) error {
 	pptr := (*unsafe.Pointer)(unsafe.Pointer(uintptr(base) + p.offset))
 	var val reflect.Value
 	if *pptr == nil {
+		if err := o.allocate(p.stype.Size()); err != nil {
+			return err
+		}
 		val = reflect.New(p.stype)
 		*pptr = unsafe.Pointer(val.Pointer()) // Is this gc safe? it seems not to be to me, but I don't have a better solution, and it's what google's code does
 	} else {
 		// else the value is already allocated and we merge into it
 		val = reflect.NewAt(p.stype, *pptr)
 	}
-	err = val.Interface().(unmarshaler).UnmarshalProtobuf3(raw)
+	err = o.decodeMessage(val.Interface().(unmarshaler), raw)
 	return err
 }
 
@@ -1616,8 +1799,10 @@ func (o *Buffer) dec_slice_unmarshaler(p *Properties, base unsafe.Pointer) error
 
 	if slice.IsNil() {
 		// preallocate what is probably the right sized slice immediately (if it isn't then we'll append later)
-		n, _ := o.count_ahead(p.Tag, p.WireType)
-		slice.Set(reflect.MakeSlice(slice.Type(), 0, 1+n))
+		slice.Set(reflect.MakeSlice(slice.Type(), 0, o.countAhead(p, p.stype.Size())))
+	}
+	if err := o.addRepeated(slice.Len()+1, 1, p.stype.Size()); err != nil {
+		return err
 	}
 
 	n := slice.Len()
@@ -1630,7 +1815,7 @@ func (o *Buffer) dec_slice_unmarshaler(p *Properties, base unsafe.Pointer) error
 
 	// and unmarshal into it
 	val := slice.Index(n)
-	err = val.Addr().Interface().(unmarshaler).UnmarshalProtobuf3(raw)
+	err = o.decodeMessage(val.Addr().Interface().(unmarshaler), raw)
 	return err
 }
 
@@ -1647,7 +1832,7 @@ func (o *Buffer) dec_array_unmarshaler(p *Properties, base unsafe.Pointer) error
 	if i < n {
 		// address of element i
 		ptr_elem := unsafe.Pointer(uintptr(ptr) + uintptr(i)*p.stype.Size())
-		err = reflect.NewAt(p.stype, ptr_elem).Interface().(unmarshaler).UnmarshalProtobuf3(raw)
+		err = o.decodeMessage(reflect.NewAt(p.stype, ptr_elem).Interface().(unmarshaler), raw)
 		i++
 		o.saveIndex(ptr, i)
 	}
@@ -1776,29 +1961,38 @@ func (o *Buffer) dec_Duration(p *Properties) (time.Duration, error) {
 
 	// restrict ourselves to p.index:end
 	oo := newBuffer(o.buf[o.index:end:end])
+	d, err := oo.DecodeDuration()
+	oo.release()
+	if err != nil {
+		return 0, err
+	}
+
+	o.index = end
 
+	return d, nil
+}
+
+// DecodeDuration decodes a google.protobuf.Duration message body as a time.Duration
+func (o *Buffer) DecodeDuration() (time.Duration, error) {
 	var secs, nanos uint64
-	for oo.index < ulen(oo.buf) {
-		tag, err := oo.DecodeVarint()
+	for o.index < ulen(o.buf) {
+		tag, err := o.DecodeVarint()
 		if err != nil {
 			return 0, err
 		}
 		switch tag {
 		case 1<<3 | uint64(WireVarint): // seconds
-			secs, err = oo.DecodeVarint()
+			secs, err = o.DecodeVarint()
 		case 2<<3 | uint64(WireVarint): // nanoseconds
-			nanos, err = oo.DecodeVarint()
+			nanos, err = o.DecodeVarint()
 		default:
 			// do the protobuf thing and ignore unknown tags
-			err = oo.skip(nil, WireType(tag)&7)
+			err = o.skip(nil, WireType(tag)&7)
 		}
 		if err != nil {
 			return 0, err
 		}
 	}
-	oo.release()
-
-	o.index = end
 
 	d := time.Duration(secs)*time.Second + time.Duration(nanos)*time.Nanosecond
 
@@ -1828,8 +2022,10 @@ func (o *Buffer) dec_slice_time_Duration(p *Properties, base unsafe.Pointer) err
 
 	if s == nil {
 		// preallocate what is probably the right sized slice immediately (if it isn't then we'll append later)
-		n, _ := o.count_ahead(p.Tag, p.WireType)
-		s = make([]time.Duration, 0, 1+n)
+		s = make([]time.Duration, 0, o.countAhead(p, unsafe.Sizeof(s[0])))
+	}
+	if err := o.addRepeated(len(s)+1, 1, unsafe.Sizeof(s[0])); err != nil {
+		return err
 	}
 
 	*v = append(s, d)
@@ -0,0 +1,86 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package protobuf3
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// A struct embedded by pointer with the `protobuf:"embedded"` tag has its fields merged into the embedding struct,
+// just like a struct embedded by value. Since the embedded fields aren't at a fixed offset from the embedding
+// struct, each one is wrapped in an embeddedField which follows the pointer. Encoding skips the fields when the
+// pointer is nil, and decoding allocates the embedded struct when the first of its fields arrives. A struct
+// embedded by pointer inside another one is wrapped twice, and so on.
+
+// a field of a struct embedded by pointer
+type embeddedField struct {
+	field *Properties  // the properties of the field, with an offset relative to the embedded struct
+	stype reflect.Type // the type of the embedded struct
+}
+
+// embedPtr wraps p, a field of struct type st, which is embedded by a pointer at offset in the embedding struct
+func (p *Properties) embedPtr(st reflect.Type, offset uintptr) {
+	v := *p // same tag, same everything
+	p.embed = &embeddedField{
+		field: &v,
+		stype: st,
+	}
+	p.offset = offset
+	p.enc = (*Buffer).enc_embedded
+	p.dec = (*Buffer).dec_embedded
+}
+
+// Encode a field of a struct embedded by pointer, unless the pointer is nil
+func (o *Buffer) enc_embedded(p *Properties, base unsafe.Pointer) {
+	ptr := *(*unsafe.Pointer)(unsafe.Pointer(uintptr(base) + p.offset))
+	if ptr == nil {
+		return
+	}
+	v := p.embed.field
+	v.enc(o, v, ptr)
+}
+
+// Decode a field of a struct embedded by pointer, allocating the struct if the pointer is nil
+func (o *Buffer) dec_embedded(p *Properties, base unsafe.Pointer) error {
+	pptr := (*unsafe.Pointer)(unsafe.Pointer(uintptr(base) + p.offset))
+	if *pptr == nil {
+		if err := o.allocate(p.embed.stype.Size()); err != nil {
+			return err
+		}
+		*pptr = unsafe.Pointer(reflect.New(p.embed.stype).Pointer())
+	}
+	v := p.embed.field
+	return v.dec(o, v, *pptr)
+}
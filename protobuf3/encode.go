@@ -48,14 +48,14 @@ import (
 )
 
 var (
-	// errRepeatedHasNil is the error returned if Marshal is called with
+	// ErrRepeatedHasNil is the error returned if Marshal is called with
 	// a struct with a repeated field containing a nil element.
-	errRepeatedHasNil = errors.New("protobuf3: repeated field has nil element")
+	ErrRepeatedHasNil = errors.New("protobuf3: repeated field has nil element")
 
 	// ErrNil is the error returned if Marshal is called with nil.
 	ErrNil = errors.New("protobuf3: [Un]Marshal called with nil")
 
-	ErrNotPointerToStruct = errors.New("protobuf3: Unmarshal called with argument which is not a pointer to a struct")
+	ErrNotPointerToStruct = errors.New("protobuf3: Unmarshal called with argument which is not a pointer to a struct, slice, array, map or scalar")
 )
 
 // The fundamental encoders that put bytes on the wire.
@@ -233,12 +233,25 @@ func Marshal(pb Message) ([]byte, error) {
 	return bytes, nil
 }
 
+// MarshalDeterministic is like Marshal, except that the entries of every map field, and not only the members of sets,
+// are encoded in the order of their keys, so that equal values always encode to the same bytes. See Buffer.Deterministic.
+func MarshalDeterministic(pb Message) ([]byte, error) {
+	buf := newBuffer(nil)
+	buf.Deterministic = true
+	err := buf.Marshal(pb)
+	bytes := buf.release()
+	if err != nil {
+		return nil, err
+	}
+	return bytes, nil
+}
+
 // Marshal takes the protocol buffer
 // and encodes it into the wire format, writing the result to the
-// Buffer.
+// Buffer. pb is normally a pointer to a struct, but it can also be a slice, an array, a map or a
+// scalar (or a pointer to one), which is encoded as the single field of a message. See Buffer.TopLevelTag.
 func (o *Buffer) Marshal(pb Message) error {
 	// Can it marshal itself?
-	// (note: we don't ask Appenders to marshal themselves b/c there's a problem handling WireBytes and removing the prepended length)
 	if m, ok := pb.(Marshaler); ok {
 		data, err := m.MarshalProtobuf3()
 		if err != nil {
@@ -248,6 +261,19 @@ func (o *Buffer) Marshal(pb Message) error {
 		o.buf = append(o.buf, data...)
 		return o.err
 	}
+	// Appenders don't append their own tag or length, so at the top level, where there is neither, they append the entire message
+	if a, ok := pb.(Appender); ok {
+		n := len(o.buf)
+		b, err := a.AppendProtobuf3(o.buf)
+		if err != nil {
+			o.noteError(err)
+		} else if len(b) < n {
+			o.noteError(fmt.Errorf("protobuf3: buggy %T.AppendProtobuf3 implementation returned []byte len %d", pb, len(b)))
+		} else {
+			o.buf = b
+		}
+		return o.err
+	}
 
 	// unpack the interface and sanity check
 	if pb == nil {
@@ -256,6 +282,9 @@ func (o *Buffer) Marshal(pb Message) error {
 	v := reflect.ValueOf(pb)
 	t := v.Type()
 	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		if isTopLevelWrapped(t) || (t.Kind() == reflect.Ptr && isTopLevelWrapped(t.Elem())) {
+			return o.marshalTopLevel(v)
+		}
 		return fmt.Errorf("protobuf3: can't Marshal(%s): not a *struct type", t)
 	}
 	base := unsafe.Pointer(v.Pointer())
@@ -558,21 +587,7 @@ func (o *Buffer) encode_appender(p *Properties, ptr unsafe.Pointer, must_encode
 
 	if p.WireType == WireBytes {
 		// fixup the length
-		n := uint64(len(o.buf) - n2)
-		if n < 128 {
-			// it fits in the placeholder byte we reserved (including length 0)
-			o.buf[n2-1] = byte(n)
-		} else {
-			// move the appended data forward to make room for the byte length
-			s := SizeVarint(n)
-			o.buf = append(o.buf[:n2-1+s], o.buf[n2:]...)
-			// temporarily rewind to where the length goes
-			n3 := len(o.buf)
-			o.buf = o.buf[:n2-1]
-			o.EncodeVarint(n)
-			// then restore to the whole buffer
-			o.buf = o.buf[:n3]
-		}
+		o.buf = FixupLength(o.buf, n2)
 	}
 
 	return nil
@@ -981,6 +996,44 @@ func (o *Buffer) enc_slice_slice_byte(p *Properties, base unsafe.Pointer) {
 	}
 }
 
+// Encode a slice of arrays of bytes ([][n]byte).
+func (o *Buffer) enc_slice_array_byte(p *Properties, base unsafe.Pointer) {
+	s := *(*[]byte)(unsafe.Pointer(uintptr(base) + p.offset)) // note this is really a [][n]byte
+	n := ulen(s)                                              // note this is the # of elements, not the # of bytes
+	if n == 0 {
+		return
+	}
+	enc_array_bytes(o, p, unsafe.Pointer(&s[0]), n)
+}
+
+// Encode an array of arrays of bytes ([m][n]byte).
+func (o *Buffer) enc_array_array_byte(p *Properties, base unsafe.Pointer) {
+	enc_array_bytes(o, p, unsafe.Pointer(uintptr(base)+p.offset), p.length)
+}
+
+// utility function to encode a series of 'n' arrays of bytes in a line in memory (from a slice or from an array)
+func enc_array_bytes(o *Buffer, p *Properties, base unsafe.Pointer, n uint) {
+	sz := p.elemLength
+	s := unsafe.Slice((*byte)(base), n*sz)
+	for i := uint(0); i < n; i++ {
+		o.buf = append(o.buf, p.tagcode...)
+		o.EncodeRawBytes(s[i*sz : (i+1)*sz])
+	}
+}
+
+// Encode a slice of pointers to arrays of bytes ([]*[n]byte).
+func (o *Buffer) enc_slice_ptr_array_byte(p *Properties, base unsafe.Pointer) {
+	s := *(*[]unsafe.Pointer)(unsafe.Pointer(uintptr(base) + p.offset))
+	for _, ap := range s {
+		if ap == nil {
+			o.noteError(ErrRepeatedHasNil)
+			return
+		}
+		o.buf = append(o.buf, p.tagcode...)
+		o.EncodeRawBytes(unsafe.Slice((*byte)(ap), p.elemLength))
+	}
+}
+
 // Encode a slice of strings ([]string).
 func (o *Buffer) enc_slice_string(p *Properties, base unsafe.Pointer) {
 	ss := *(*[]string)(unsafe.Pointer(uintptr(base) + p.offset))
@@ -1009,7 +1062,7 @@ func (o *Buffer) enc_slice_ptr_struct_message(p *Properties, base unsafe.Pointer
 	if p.isAppender {
 		for _, structp := range s {
 			if structp == nil {
-				o.noteError(errRepeatedHasNil)
+				o.noteError(ErrRepeatedHasNil)
 				return
 			}
 
@@ -1023,7 +1076,7 @@ func (o *Buffer) enc_slice_ptr_struct_message(p *Properties, base unsafe.Pointer
 	if p.isMarshaler {
 		for _, structp := range s {
 			if structp == nil {
-				o.noteError(errRepeatedHasNil)
+				o.noteError(ErrRepeatedHasNil)
 				return
 			}
 
@@ -1045,7 +1098,7 @@ func (o *Buffer) enc_slice_ptr_struct_message(p *Properties, base unsafe.Pointer
 
 	for _, structp := range s {
 		if structp == nil {
-			o.noteError(errRepeatedHasNil)
+			o.noteError(ErrRepeatedHasNil)
 			return
 		}
 
@@ -1064,7 +1117,7 @@ func (o *Buffer) enc_array_ptr_struct_message(p *Properties, base unsafe.Pointer
 	if p.isAppender {
 		for _, structp := range s {
 			if structp == nil {
-				o.noteError(errRepeatedHasNil)
+				o.noteError(ErrRepeatedHasNil)
 				return
 			}
 
@@ -1078,7 +1131,7 @@ func (o *Buffer) enc_array_ptr_struct_message(p *Properties, base unsafe.Pointer
 	if p.isMarshaler {
 		for _, structp := range s {
 			if structp == nil {
-				o.noteError(errRepeatedHasNil)
+				o.noteError(ErrRepeatedHasNil)
 				return
 			}
 
@@ -1100,7 +1153,7 @@ func (o *Buffer) enc_array_ptr_struct_message(p *Properties, base unsafe.Pointer
 
 	for _, structp := range s {
 		if structp == nil {
-			o.noteError(errRepeatedHasNil)
+			o.noteError(ErrRepeatedHasNil)
 			return
 		}
 
@@ -1261,8 +1314,12 @@ func (o *Buffer) enc_new_map(p *Properties, base unsafe.Pointer) {
 		p.mvalprop.enc(o, p.mvalprop, valbase)
 	}
 
-	// Don't sort map keys. It is not required by the spec, and C++ doesn't do it.
-	for _, key := range v.MapKeys() {
+	// Don't sort map keys, unless asked to. It is not required by the spec, and C++ doesn't do it.
+	keys := v.MapKeys()
+	if o.Deterministic {
+		sortKeys(keys)
+	}
+	for _, key := range keys {
 		val := v.MapIndex(key)
 
 		keycopy.Set(key)
@@ -1378,6 +1435,22 @@ func (o *Buffer) enc_time_Duration(p *Properties, base unsafe.Pointer) {
 
 // helper function to encode a time.Duration value
 func (o *Buffer) enc_Duration(p *Properties, d time.Duration) {
+	// go time.Duration is not a struct, but protobuf Duration is a message,
+	// so we have to prepend the tag and length (we expect time.Duration to be sent as bytes,
+	// as a protobuf message always is)
+	o.buf = append(o.buf, p.tagcode...)
+	// the byte length cannot take more than 1 byte to encode as a varint because
+	// the greatest length of a protobuf Duration is two negative varint encoded uint64s,
+	// and their ID bytes, or 22 bytes.
+	o.buf = append(o.buf, 0) // placeholder for the length
+	body_start := len(o.buf)
+	o.EncodeDuration(d)
+	// go back and fill in the byte length
+	o.buf[body_start-1] = uint8(len(o.buf) - body_start)
+}
+
+// EncodeDuration marshals a time.Duration as the body of a google.protobuf.Duration, which is a pair of varints (secs,nanos) tagged 1 and 2, each omitted when 0
+func (o *WriteBuffer) EncodeDuration(d time.Duration) {
 	// protobuf Duration uses its own encoding, different from time.Duration
 	// we have to convert. protobuf Duration uses signed seconds and nanoseconds,
 	// where seconds and nanoseconds must have the same sign or be == 0.
@@ -1389,15 +1462,6 @@ func (o *Buffer) enc_Duration(p *Properties, d time.Duration) {
 	secs := nanos / 1000_000_000 // note secs ends up with the same sign as nanos, or is 0
 	nanos -= secs * 1000_000_000 // note this preserves the sign of nanos (or sets it to 0)
 
-	// furthermore go time.Duration is not a struct, but protobuf Duration is a message,
-	// so we have to prepend the tag and length (we expect time.Duration to be sent as bytes,
-	// as a protobuf message always is)
-	o.buf = append(o.buf, p.tagcode...)
-	// the byte length cannot take more than 1 byte to encode as a varint because
-	// the greatest length of a protobuf Duration is two negative varint encoded uint64s,
-	// and their ID bytes, or 22 bytes.
-	o.buf = append(o.buf, 0) // placeholder for the length
-	body_start := len(o.buf)
 	if secs != 0 {
 		o.buf = append(o.buf, 1<<3|byte(WireVarint))
 		o.EncodeVarint(uint64(secs)) // NOTE WELL the duration.proto uses protobuf type 'int64' for seconds, not 'sint64'. So Varint is correct
@@ -1406,8 +1470,6 @@ func (o *Buffer) enc_Duration(p *Properties, d time.Duration) {
 		o.buf = append(o.buf, 2<<3|byte(WireVarint))
 		o.EncodeVarint(uint64(nanos))
 	}
-	// go back and fill in the byte length
-	o.buf[body_start-1] = uint8(len(o.buf) - body_start)
 }
 
 // custom encoder for *time.Duration, ... protobuf Duration message
@@ -0,0 +1,311 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package protobuf3
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// an enumeration type registered by RegisterEnum
+type enumInfo struct {
+	typ    reflect.Type
+	names  map[string]int64 // name -> value
+	values map[int64]string // value -> name. when several names alias one value, the first name in sorted order
+}
+
+// an enum field's decoder, wrapped by dec_enum
+type enumField struct {
+	*enumInfo
+	ftype  reflect.Type // the type of the field (the enum type, or a pointer, slice or array of it)
+	scalar string       // the protobuf integer type the field would have had if it were not an enum
+	dec    decoder
+}
+
+var (
+	enumsMu sync.RWMutex
+	enums   = make(map[reflect.Type]*enumInfo)
+)
+
+// RegisterEnum registers integer type t as an enumeration whose named values are given by values.
+// Fields of type t (and pointers, slices and arrays of t) then have protobuf type t.Name() in the output
+// of AsProtobuf[Full](), AsProtobufFull() emits the `enum` definition, EnumString() and friends use the
+// names, and a Buffer with StrictEnums set rejects values which are not in values.
+//
+// Fields of enum types must be tagged `protobuf:"varint,..."`, since that is how protobuf enums are encoded.
+// The protobuf definition requires one of the values to be 0. Protobuf enum values share their package's
+// namespace, so AsProtobufFull() fails if two enums it emits have values of the same name; prefixing each
+// name with its enum's name, as protoc users do, avoids that.
+//
+// RegisterEnum must be called before the properties of any struct using t are computed, so call it from init().
+// It panics if t is not an integer type, if a value does not fit in t, or if t is already registered.
+func RegisterEnum(t reflect.Type, values map[string]int32) {
+	zero := reflect.New(t).Elem()
+	e := &enumInfo{
+		typ:    t,
+		names:  make(map[string]int64, len(values)),
+		values: make(map[int64]string, len(values)),
+	}
+	for name, v := range values {
+		x := int64(v)
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if zero.OverflowInt(x) {
+				panic(fmt.Sprintf("protobuf3: RegisterEnum(%s): value %s = %d overflows %s", t, name, x, t))
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if x < 0 || zero.OverflowUint(uint64(x)) {
+				panic(fmt.Sprintf("protobuf3: RegisterEnum(%s): value %s = %d overflows %s", t, name, x, t))
+			}
+		default:
+			panic(fmt.Sprintf("protobuf3: RegisterEnum(%s): type is not an integer type", t))
+		}
+		if name == "" {
+			panic(fmt.Sprintf("protobuf3: RegisterEnum(%s): value %d has no name", t, x))
+		}
+		e.names[name] = x
+		if n, ok := e.values[x]; !ok || name < n {
+			e.values[x] = name
+		}
+	}
+
+	enumsMu.Lock()
+	defer enumsMu.Unlock()
+	if _, ok := enums[t]; ok {
+		panic(fmt.Sprintf("protobuf3: RegisterEnum(%s): type is already registered", t))
+	}
+	enums[t] = e
+}
+
+// returns the registered enumeration of type t, or nil
+func lookupEnum(t reflect.Type) *enumInfo {
+	enumsMu.RLock()
+	e := enums[t]
+	enumsMu.RUnlock()
+	return e
+}
+
+// returns the registered enumeration used by a field of type t, or nil
+func fieldEnum(t reflect.Type) *enumInfo {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		t = t.Elem()
+	}
+	return lookupEnum(t)
+}
+
+// returns the integer value of v, which must have an integer kind
+func enumValue(v reflect.Value) int64 {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return int64(v.Uint())
+	}
+	return v.Int()
+}
+
+// asProtobuf returns the protobuf definition of the enumeration, which is named name
+func (e *enumInfo) asProtobuf(name string) (string, error) {
+	if _, ok := e.values[0]; !ok {
+		return "", fmt.Errorf("protobuf3: enum %s has no value 0, which protobuf v3 requires", name)
+	}
+
+	type value struct {
+		name string
+		x    int64
+	}
+	values := make([]value, 0, len(e.names))
+	for n, x := range e.names {
+		values = append(values, value{n, x})
+	}
+	// protobuf v3 requires the zero value be first. the rest we list in numerical order
+	sort.Slice(values, func(i, j int) bool {
+		vi, vj := values[i], values[j]
+		if (vi.x == 0) != (vj.x == 0) {
+			return vi.x == 0
+		}
+		if vi.x != vj.x {
+			return vi.x < vj.x
+		}
+		return vi.name < vj.name
+	})
+
+	lines := []string{fmt.Sprintf("enum %s {", name)}
+	if len(e.values) != len(e.names) {
+		// some values have more than one name
+		lines = append(lines, "  option allow_alias = true;")
+	}
+	for _, v := range values {
+		lines = append(lines, fmt.Sprintf("  %s = %d;", v.name, v.x))
+	}
+	lines = append(lines, "}")
+	return strings.Join(lines, "\n"), nil
+}
+
+// checkClashes returns an error if any of the names of the values of the enumeration, which is named name, are already
+// in defined, which maps the names of the values of the enumerations defined so far to their enumeration. Otherwise it
+// adds the names to defined.
+func (e *enumInfo) checkClashes(name string, defined map[string]string) error {
+	names := make([]string, 0, len(e.names))
+	for n := range e.names {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		if other, ok := defined[n]; ok {
+			return fmt.Errorf("protobuf3: value %s of enum %s is also a value of enum %s, and protobuf enum values share their package's namespace", n, name, other)
+		}
+	}
+	for _, n := range names {
+		defined[n] = name
+	}
+	return nil
+}
+
+// check that the values in v (the field, or the map key or value, being decoded) starting at
+// index n (for slices) are defined
+func (e *enumField) check(p *Properties, v reflect.Value, n int) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	case reflect.Slice, reflect.Array:
+		for i := n; i < v.Len(); i++ {
+			if err := e.checkValue(p, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return e.checkValue(p, v)
+}
+
+func (e *enumField) checkValue(p *Properties, v reflect.Value) error {
+	x := enumValue(v)
+	if _, ok := e.values[x]; !ok {
+		return fmt.Errorf("protobuf3: %s: %d is not a defined value of enum %s", p.Name, x, e.typ.Name())
+	}
+	return nil
+}
+
+// Decode a field of a registered enum type, and when StrictEnums is set, check the decoded values are defined
+func (o *Buffer) dec_enum(p *Properties, base unsafe.Pointer) error {
+	e := p.enum
+	if !o.StrictEnums {
+		return e.dec(o, p, base)
+	}
+	v := reflect.NewAt(e.ftype, unsafe.Pointer(uintptr(base)+p.offset)).Elem()
+	n := 0
+	if v.Kind() == reflect.Slice {
+		n = v.Len() // only check the elements we decode now
+	}
+	if err := e.dec(o, p, base); err != nil {
+		return err
+	}
+	return e.check(p, v, n)
+}
+
+// EnumName returns the name of v, whose type must have been registered with RegisterEnum.
+// It returns false if v's type is not registered, or if v is not one of the registered values.
+func EnumName(v interface{}) (string, bool) {
+	rv := reflect.ValueOf(v)
+	e := lookupEnum(rv.Type())
+	if e == nil {
+		return "", false
+	}
+	name, ok := e.values[enumValue(rv)]
+	return name, ok
+}
+
+// EnumString returns the name of v, or, if v has no name, its decimal value. It is handy
+// for implementing the String() method of an enum type.
+func EnumString(v interface{}) string {
+	if name, ok := EnumName(v); ok {
+		return name
+	}
+	return strconv.FormatInt(enumValue(reflect.ValueOf(v)), 10)
+}
+
+// MarshalEnumText returns the name of v, or its decimal value if v has no name. It is an error if v's type is
+// not registered. It is handy for implementing encoding.TextMarshaler, so that the encoding/json and other
+// text encoders use the names of the values.
+func MarshalEnumText(v interface{}) ([]byte, error) {
+	if lookupEnum(reflect.TypeOf(v)) == nil {
+		return nil, fmt.Errorf("protobuf3: %T is not a registered enum", v)
+	}
+	return []byte(EnumString(v)), nil
+}
+
+// UnmarshalEnumText sets the enum pointed to by v from its name, or from its decimal value. It is the inverse of
+// MarshalEnumText, and is handy for implementing encoding.TextUnmarshaler.
+func UnmarshalEnumText(v interface{}, text []byte) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("protobuf3: UnmarshalEnumText needs a non-nil pointer, not %T", v)
+	}
+	rv = rv.Elem()
+	e := lookupEnum(rv.Type())
+	if e == nil {
+		return fmt.Errorf("protobuf3: %s is not a registered enum", rv.Type())
+	}
+
+	s := string(text)
+	x, ok := e.names[s]
+	if !ok {
+		var err error
+		x, err = strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("protobuf3: %q is not a value of enum %s", s, e.typ.Name())
+		}
+	}
+	switch rv.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if x < 0 || rv.OverflowUint(uint64(x)) {
+			return fmt.Errorf("protobuf3: %s overflows enum %s", s, e.typ.Name())
+		}
+		rv.SetUint(uint64(x))
+	default:
+		if rv.OverflowInt(x) {
+			return fmt.Errorf("protobuf3: %s overflows enum %s", s, e.typ.Name())
+		}
+		rv.SetInt(x)
+	}
+	return nil
+}
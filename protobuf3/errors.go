@@ -0,0 +1,168 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package protobuf3
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// A DecodeError describes a failure to decode a field of a message. Use errors.As to retrieve it from the errors
+// returned by Unmarshal and Buffer.Unmarshal. (Types which unmarshal themselves, including those with generated
+// methods, return their own errors.)
+type DecodeError struct {
+	Type     reflect.Type // the Go type of the struct holding the field (which, in a nested message, isn't the top-level type)
+	Path     string       // the path to the field from the top-level type, like Outer.Items[3].Name
+	Tag      int          // the field's id
+	WireType WireType     // the field's wiretype, as encoded
+	Offset   int          // the offset in the buffer of the field's tag
+	Err      error        // the cause
+
+	relative bool // true while Path and Offset are relative to a nested message
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("protobuf3: can't decode %s (id %d, wiretype %v) at offset %d: %s", e.Path, e.Tag, e.WireType, e.Offset, cause(e.Err))
+}
+
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+// A TagError describes a bad protobuf struct tag, or a field whose tag doesn't suit its type. Use errors.As to retrieve
+// it from the errors returned while preparing a type to be encoded or decoded.
+type TagError struct {
+	Type  reflect.Type      // the struct type
+	Field string            // the name of the field
+	Tag   reflect.StructTag // the field's tags
+	Err   error             // the cause
+}
+
+func (e *TagError) Error() string {
+	tname := e.Type.Name()
+	if tname == "" {
+		tname = e.Type.String()
+	}
+	return fmt.Sprintf("protobuf3: error preparing field %q of type %q: %s", e.Field, tname, cause(e.Err))
+}
+
+// cause returns the message of err, the cause of one of our errors, without the "protobuf3: " prefix which
+// our error's message already has
+func cause(err error) string {
+	return strings.TrimPrefix(err.Error(), "protobuf3: ")
+}
+
+func (e *TagError) Unwrap() error { return e.Err }
+
+// newTagError returns a *TagError for field name, tagged tag, of struct type t. It logs the error unless err
+// is already a *TagError of a nested type, which was logged when it was created.
+func newTagError(t reflect.Type, name string, tag reflect.StructTag, err error) error {
+	te := &TagError{
+		Type:  t,
+		Field: name,
+		Tag:   tag,
+		Err:   err,
+	}
+	var nested *TagError
+	if !errors.As(err, &nested) {
+		logError(te)
+	}
+	return te
+}
+
+// ErrorLogger, if not nil, is called with the errors this package finds in types as it prepares to encode and decode
+// them, and with the fields it can't decode and skips. The errors it finds in types are also returned, so most
+// programs leave ErrorLogger nil. It must be safe to call from any goroutine. Try to set it early in main().
+var ErrorLogger func(error)
+
+// logError passes err to the ErrorLogger, if any
+func logError(err error) {
+	if ErrorLogger != nil {
+		ErrorLogger(err)
+	}
+}
+
+// fieldError returns err, which occurred while decoding field p (or an unknown field, if p is nil) of struct type st
+// whose tag began at offset start in o.buf, as a relative *DecodeError
+func fieldError(st reflect.Type, p *Properties, tag int, wire WireType, start uint, err error) error {
+	var name string
+	if p != nil {
+		name = "." + p.Name
+	}
+	if de, ok := err.(*DecodeError); ok && de.relative {
+		// err is from a nested message. it already describes the field; we add to its path
+		de.Path = name + de.Path
+		return de
+	}
+	return &DecodeError{
+		Type:     st,
+		Path:     name,
+		Tag:      tag,
+		WireType: wire,
+		Offset:   int(start),
+		Err:      err,
+		relative: true,
+	}
+}
+
+// nestedError adjusts err, which occurred while decoding the element i (or -1 if the field isn't repeated) of a
+// field whose body began at offset start in o.buf
+func nestedError(err error, i int, start int) error {
+	if de, ok := err.(*DecodeError); ok && de.relative {
+		de.Offset += start
+		if i >= 0 {
+			de.Path = "[" + strconv.Itoa(i) + "]" + de.Path
+		}
+	}
+	return err
+}
+
+// topLevelError completes err, which occurred while decoding a value of type t as a struct of type st
+func topLevelError(err error, t, st reflect.Type) error {
+	if de, ok := err.(*DecodeError); ok && de.relative {
+		name := t.Name()
+		if name == "" {
+			name = t.String()
+		}
+		path := de.Path
+		if st != t {
+			// t was the field of the message synthesized by topLevelType()
+			path = strings.TrimPrefix(path, ".Value")
+		}
+		de.Path = name + path
+		de.relative = false
+	}
+	return err
+}
@@ -34,9 +34,14 @@
 package protobuf3_test
 
 import (
+	"bytes"
+	"errors"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
+	"runtime"
 	"testing"
 
 	"github.com/mistsys/protobuf3/protobuf3"
@@ -216,3 +221,163 @@ func scan_bytes(pb []byte) {
 		}
 	}
 }
+
+// LimitsMsg has fields of all the shapes which a small message can make Unmarshal allocate a lot of memory for
+type LimitsMsg struct {
+	Ints    []int64             `protobuf:"varint,1"`
+	Strings []string            `protobuf:"bytes,2"`
+	Bytes   [][]byte            `protobuf:"bytes,3"`
+	Bigs    []LimitsBig         `protobuf:"bytes,4"`
+	PtrBigs []*LimitsBig        `protobuf:"bytes,5"`
+	Map     map[int]LimitsBig   `protobuf:"bytes,6" protobuf_key:"varint,1" protobuf_val:"bytes,2"`
+	Set     map[int32]struct{}  `protobuf:"varint,7"`
+	Big     *LimitsBig          `protobuf:"bytes,8"`
+	String  string              `protobuf:"bytes,9"`
+	Nested  []LimitsMsg         `protobuf:"bytes,10"`
+	Fixed   []float64           `protobuf:"fixed64,11"`
+	Strs    map[string][]string `protobuf:"bytes,12" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+	IP      net.IP              `protobuf:"bytes,13"`
+	MAC     *net.HardwareAddr   `protobuf:"bytes,14"`
+	IPs     []net.IP            `protobuf:"bytes,15"`
+	MACs    [2]net.HardwareAddr `protobuf:"bytes,16"`
+	URL     *url.URL            `protobuf:"bytes,17,binary"`
+}
+
+// LimitsBig is much larger decoded than encoded
+type LimitsBig struct {
+	A [512]int64 `protobuf:"varint,1"`
+}
+
+var limits = protobuf3.DecodeLimits{
+	MaxMessageSize: 1 << 16,
+	MaxAllocation:  1 << 20,
+	MaxRepeated:    1000,
+	MaxMapEntries:  100,
+	MaxBytesLength: 1000,
+}
+
+// unmarshalLimited unmarshals pb into a LimitsMsg with the limits, and returns the error and the bytes allocated
+func unmarshalLimited(pb []byte) (*LimitsMsg, error, uint64) {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	var m LimitsMsg
+	buf := protobuf3.NewBuffer(pb)
+	buf.Limits = limits
+	err := buf.Unmarshal(&m)
+	runtime.ReadMemStats(&after)
+	return &m, err, after.TotalAlloc - before.TotalAlloc
+}
+
+// checkLimits verifies that m, as decoded by unmarshalLimited, is within the limits
+func checkLimits(t *testing.T, m *LimitsMsg, alloc uint64) {
+	// the allocation is estimated, and doesn't include the spare capacity of slices and maps, so allow some slack
+	if alloc > 4*uint64(limits.MaxAllocation)+1<<16 {
+		t.Errorf("Unmarshal allocated %d bytes", alloc)
+	}
+	if len(m.Ints) > limits.MaxRepeated || len(m.Strings) > limits.MaxRepeated || len(m.Bytes) > limits.MaxRepeated ||
+		len(m.Bigs) > limits.MaxRepeated || len(m.PtrBigs) > limits.MaxRepeated || len(m.Nested) > limits.MaxRepeated || len(m.Fixed) > limits.MaxRepeated {
+		t.Errorf("Unmarshal decoded too many repeated elements")
+	}
+	if len(m.Map) > limits.MaxMapEntries || len(m.Set) > limits.MaxMapEntries || len(m.Strs) > limits.MaxMapEntries {
+		t.Errorf("Unmarshal decoded too many map entries")
+	}
+	for _, s := range append([]string{m.String}, m.Strings...) {
+		if len(s) > limits.MaxBytesLength {
+			t.Errorf("Unmarshal decoded a %d byte string", len(s))
+		}
+	}
+	for _, b := range m.Bytes {
+		if len(b) > limits.MaxBytesLength {
+			t.Errorf("Unmarshal decoded a %d byte []byte", len(b))
+		}
+	}
+	for _, ip := range append([]net.IP{m.IP}, m.IPs...) {
+		if len(ip) > limits.MaxBytesLength {
+			t.Errorf("Unmarshal decoded a %d byte net.IP", len(ip))
+		}
+	}
+	if m.MAC != nil && len(*m.MAC) > limits.MaxBytesLength || len(m.MACs[0]) > limits.MaxBytesLength || len(m.MACs[1]) > limits.MaxBytesLength {
+		t.Errorf("Unmarshal decoded a too long net.HardwareAddr")
+	}
+	if m.URL != nil && len(m.URL.Path) > limits.MaxBytesLength {
+		t.Errorf("Unmarshal decoded a %d byte url.URL", len(m.URL.Path))
+	}
+	for i := range m.Nested {
+		checkLimits(t, &m.Nested[i], 0)
+	}
+}
+
+// bombs are small messages which decode to a lot of memory, and the limit each exceeds
+var bombs = []struct {
+	name  string
+	pb    []byte
+	limit string
+}{
+	// 1500 zero length LimitsBig, each 4 KiB
+	{"Bigs", bytes.Repeat([]byte{4<<3 | 2, 0}, 1500), "MaxAllocation"},
+	{"PtrBigs", bytes.Repeat([]byte{5<<3 | 2, 0}, 1500), "MaxAllocation"},
+	{"Nested Bigs", append([]byte{10<<3 | 2, 0x80, 0x20}, bytes.Repeat([]byte{4<<3 | 2, 0}, 2048)...), "MaxAllocation"},
+	// 2000 packed int64s in 2000 bytes
+	{"Ints", append([]byte{1<<3 | 2, 0xd0, 0x0f}, make([]byte, 2000)...), "MaxRepeated"},
+	// 2000 empty strings
+	{"Strings", bytes.Repeat([]byte{2<<3 | 2, 0}, 2000), "MaxRepeated"},
+	// map entries with different keys
+	{"Map", func() []byte {
+		var pb []byte
+		for i := 0; i < 200; i++ {
+			pb = append(pb, 6<<3|2, 2, 1<<3, byte(i&0x7f))
+		}
+		return pb
+	}(), "MaxMapEntries"},
+	{"Set", func() []byte {
+		pb := []byte{7<<3 | 2, 0x90, 0x03}
+		for i := 0; i < 200; i++ {
+			pb = append(pb, 0x80|byte(i&0x7f), byte(i>>7))
+		}
+		return pb
+	}(), "MaxMapEntries"},
+	{"String", append([]byte{9<<3 | 2, 0xe9, 0x07}, make([]byte, 1001)...), "MaxBytesLength"},
+	// 1001 byte values decoded by codecs
+	{"IP", append([]byte{13<<3 | 2, 0xe9, 0x07}, make([]byte, 1001)...), "MaxBytesLength"},
+	{"MAC", append([]byte{14<<3 | 2, 0xe9, 0x07}, make([]byte, 1001)...), "MaxBytesLength"},
+	{"IPs", append([]byte{15<<3 | 2, 0xe9, 0x07}, make([]byte, 1001)...), "MaxBytesLength"},
+	{"MACs", append([]byte{0x80 | 16<<3&0x7f | 2, 16 >> 4, 0xe9, 0x07}, make([]byte, 1001)...), "MaxBytesLength"},
+	{"URL", append([]byte{0x80 | 17<<3&0x7f | 2, 17 >> 4, 0xe9, 0x07}, bytes.Repeat([]byte{'a'}, 1001)...), "MaxBytesLength"},
+	{"Size", make([]byte, 1<<16+1), "MaxMessageSize"},
+}
+
+func TestDecodeLimits(t *testing.T) {
+	for _, b := range bombs {
+		m, err, alloc := unmarshalLimited(b.pb)
+		var le *protobuf3.LimitError
+		if !errors.As(err, &le) || le.Limit != b.limit {
+			t.Errorf("Unmarshal(%s) returned %v, not a LimitError of %s", b.name, err, b.limit)
+		}
+		checkLimits(t, m, alloc)
+	}
+
+	// and without limits the same messages decode
+	for _, b := range bombs[:len(bombs)-1] {
+		var m LimitsMsg
+		if err := protobuf3.Unmarshal(b.pb, &m); err != nil {
+			t.Errorf("Unmarshal(%s) without limits returned %v", b.name, err)
+		}
+	}
+}
+
+func FuzzUnmarshalLimits(f *testing.F) {
+	f.Add([]byte{})
+	add_all_pb_files(f)
+	for _, b := range bombs {
+		f.Add(b.pb)
+	}
+
+	f.Fuzz(func(t *testing.T, pb []byte) {
+		m, err, alloc := unmarshalLimited(pb)
+		if err != nil {
+			// the limits must hold even for messages which fail to decode
+			m.Nested = nil // (except that a failed element of Nested might be partially decoded, and isn't checked)
+		}
+		checkLimits(t, m, alloc)
+	})
+}
@@ -0,0 +1,313 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package protobuf3
+
+import (
+	"encoding"
+	"fmt"
+	"time"
+)
+
+// Generated is implemented by types whose AppendProtobuf3 and UnmarshalProtobuf3 methods
+// were generated by protobuf3-gen from the type's protobuf struct tags. Unlike a hand written
+// Appender, a Generated type encodes exactly as the reflective encoder would have encoded it,
+// so Marshal calls AppendProtobuf3 directly, and AsProtobufFull defines the type from its
+// struct tags rather than treating it as an opaque custom type.
+type Generated interface {
+	Appender
+	// DecodeProtobuf3 decodes the rest of b, as UnmarshalProtobuf3 would, but with b's Immutable, Limits and Interner.
+	// Unmarshal calls it rather than UnmarshalProtobuf3.
+	DecodeProtobuf3(b *Buffer) error
+	Protobuf3Generated() // marker method. it is never called
+}
+
+// The functions and methods below are the primitives used by the generated code. They are
+// exported so that the generated code can live in the package of the type it encodes, but
+// they can just as well be used by hand written Appenders.
+
+// AppendVarint appends x to b as a varint, returning the extended slice.
+func AppendVarint(b []byte, x uint64) []byte {
+	w := WriteBuffer{buf: b}
+	w.EncodeVarint(x)
+	return w.buf
+}
+
+// AppendFixed32 appends the low 32 bits of x to b as a fixed32
+func AppendFixed32(b []byte, x uint64) []byte {
+	return append(b, uint8(x), uint8(x>>8), uint8(x>>16), uint8(x>>24))
+}
+
+// AppendFixed64 appends x to b as a fixed64
+func AppendFixed64(b []byte, x uint64) []byte {
+	return append(b, uint8(x), uint8(x>>8), uint8(x>>16), uint8(x>>24), uint8(x>>32), uint8(x>>40), uint8(x>>48), uint8(x>>56))
+}
+
+// AppendZigzag32 appends the low 32 bits of x to b as a zigzag encoded varint
+func AppendZigzag32(b []byte, x uint64) []byte {
+	w := WriteBuffer{buf: b}
+	w.EncodeZigzag32(x)
+	return w.buf
+}
+
+// AppendZigzag64 appends x to b as a zigzag encoded varint
+func AppendZigzag64(b []byte, x uint64) []byte {
+	w := WriteBuffer{buf: b}
+	w.EncodeZigzag64(x)
+	return w.buf
+}
+
+// AppendRawBytes appends the length of x, followed by x, to b
+func AppendRawBytes(b []byte, x []byte) []byte {
+	w := WriteBuffer{buf: b}
+	w.EncodeRawBytes(x)
+	return w.buf
+}
+
+// AppendStringBytes appends the length of s, followed by s, to b
+func AppendStringBytes(b []byte, s string) []byte {
+	w := WriteBuffer{buf: b}
+	w.EncodeStringBytes(s)
+	return w.buf
+}
+
+// AppendTimestamp appends the body of a google.protobuf.Timestamp message. The caller must supply
+// the tag and length.
+func AppendTimestamp(b []byte, t time.Time) []byte {
+	w := WriteBuffer{buf: b}
+	w.EncodeTimestamp(t)
+	return w.buf
+}
+
+// AppendDuration appends the body of a google.protobuf.Duration message. The caller must supply
+// the tag and length.
+func AppendDuration(b []byte, d time.Duration) []byte {
+	w := WriteBuffer{buf: b}
+	w.EncodeDuration(d)
+	return w.buf
+}
+
+// FixupLength completes a length-prefixed value. Before appending the value the caller appended
+// a single placeholder byte at b[start-1], and then appended the value starting at b[start].
+// FixupLength writes the varint length of the value in the placeholder, moving the value forward
+// if the length needs more than one byte, and returns the resulting slice. This is how the
+// reflective encoder lays out nested messages, and the result is byte-for-byte the same.
+func FixupLength(b []byte, start int) []byte {
+	n := uint64(len(b) - start)
+	if n < 128 {
+		// it fits in the placeholder byte (including length 0)
+		b[start-1] = byte(n)
+		return b
+	}
+	// move the value forward to make room for the longer length
+	s := SizeVarint(n)
+	b = append(b[:start-1+s], b[start:]...)
+	w := WriteBuffer{buf: b[:start-1]}
+	w.EncodeVarint(n)
+	return b
+}
+
+// AppendAppender appends the tagcode and the encoding of a, including the length when wt is WireBytes.
+// If a appends nothing and must_encode is false then nothing is appended at all, which is how
+// the reflective encoder treats an Appender field (as opposed to an element of a slice or array).
+func AppendAppender(b []byte, tagcode string, wt WireType, a Appender, must_encode bool) ([]byte, error) {
+	n1 := len(b)
+	b = append(b, tagcode...)
+	if wt == WireBytes {
+		b = append(b, 0) // placeholder for the length
+	}
+	n2 := len(b)
+
+	b2, err := a.AppendProtobuf3(b)
+	if err != nil {
+		return b[:n1], err
+	}
+	if len(b2) < n2 {
+		return b[:n1], fmt.Errorf("protobuf3: buggy (%T).AppendProtobuf3 implementation returned []byte len %d", a, len(b2))
+	}
+	b = b2
+
+	if !must_encode && len(b) == n2 {
+		// a is the zero value; remove the tagcode and length placeholder
+		return b[:n1], nil
+	}
+	if wt == WireBytes {
+		b = FixupLength(b, n2)
+	}
+	return b, nil
+}
+
+// AppendMarshaler appends the tagcode and the output of m.MarshalProtobuf3(), including the length
+// when wt is WireBytes. If m marshals to nil and must_encode is false then nothing is appended.
+func AppendMarshaler(b []byte, tagcode string, wt WireType, m Marshaler, must_encode bool) ([]byte, error) {
+	data, err := m.MarshalProtobuf3()
+	if err != nil {
+		return b, err
+	}
+	if data == nil && !must_encode {
+		return b, nil
+	}
+	b = append(b, tagcode...)
+	if wt == WireBytes {
+		b = AppendVarint(b, uint64(len(data)))
+	}
+	return append(b, data...), nil
+}
+
+// AppendBinary appends the tagcode, the length and the output of m.MarshalBinary(), the way the reflective
+// encoder encodes a field with the binary option. If m marshals to nothing and must_encode is false then
+// nothing is appended.
+func AppendBinary(b []byte, tagcode string, m encoding.BinaryMarshaler, must_encode bool) ([]byte, error) {
+	data, err := m.MarshalBinary()
+	if err != nil {
+		return b, err
+	}
+	return appendEncoded(b, tagcode, data, must_encode), nil
+}
+
+// AppendText is AppendBinary for a field with the text option, encoded by m.MarshalText()
+func AppendText(b []byte, tagcode string, m encoding.TextMarshaler, must_encode bool) ([]byte, error) {
+	text, err := m.MarshalText()
+	if err != nil {
+		return b, err
+	}
+	return appendEncoded(b, tagcode, text, must_encode), nil
+}
+
+// appends the tagcode and the length-prefixed data, unless data is empty and must_encode is false
+func appendEncoded(b []byte, tagcode string, data []byte, must_encode bool) []byte {
+	if len(data) == 0 && !must_encode {
+		return b
+	}
+	b = append(b, tagcode...)
+	return AppendRawBytes(b, data)
+}
+
+// AppendCodec appends the tagcode and the encoding of *v by the codec registered for its type, in the
+// manner of AppendAppender. protobuf3-gen uses it for the net/netip and net types.
+func AppendCodec(b []byte, tagcode string, wt WireType, v interface{}, must_encode bool) ([]byte, error) {
+	c, err := codecOf(v, false)
+	if err != nil {
+		return b, err
+	}
+	return appendCodec(b, tagcode, wt, c, v, must_encode)
+}
+
+// AppendCodecKey is AppendCodec for a map key, which some codecs encode differently
+func AppendCodecKey(b []byte, tagcode string, wt WireType, v interface{}) ([]byte, error) {
+	c, err := codecOf(v, true)
+	if err != nil {
+		return b, err
+	}
+	return appendCodec(b, tagcode, wt, c, v, false)
+}
+
+// UnmarshalCodec decodes raw into *v with the codec registered for its type
+func UnmarshalCodec(raw []byte, v interface{}) error {
+	c, err := codecOf(v, false)
+	if err != nil {
+		return err
+	}
+	return c.Unmarshal(raw, v)
+}
+
+// UnmarshalCodecKey is UnmarshalCodec for a map key
+func UnmarshalCodecKey(raw []byte, v interface{}) error {
+	c, err := codecOf(v, true)
+	if err != nil {
+		return err
+	}
+	return c.Unmarshal(raw, v)
+}
+
+// MakeBuffer returns a Buffer, ready to decode e. It is NewBuffer for callers
+// who want a Buffer on their stack rather than on the heap.
+func MakeBuffer(e []byte) Buffer {
+	return Buffer{WriteBuffer: MakeWriteBuffer(e), MaxRecursionDepth: MaxRecursionDepth, Limits: DefaultDecodeLimits, Interner: DefaultInterner}
+}
+
+// NestedBuffer returns a Buffer, ready to decode e, a message nested within the message p is decoding.
+// It has p's Immutable, Limits and Interner, and its allocations count against p's Limits.MaxAllocation.
+func (p *Buffer) NestedBuffer(e []byte) Buffer {
+	return Buffer{WriteBuffer: MakeWriteBuffer(e), Immutable: p.Immutable, MaxRecursionDepth: p.MaxRecursionDepth, Limits: p.Limits, Interner: p.Interner, parent: p}
+}
+
+// decodeMessage decodes raw, a nested message, into u with o's settings if u's methods were generated
+func (o *Buffer) decodeMessage(u unmarshaler, raw []byte) error {
+	if g, ok := u.(Generated); ok {
+		n := o.NestedBuffer(raw)
+		return g.DecodeProtobuf3(&n)
+	}
+	return u.UnmarshalProtobuf3(raw)
+}
+
+// DecodeTag decodes the next field's tag and wiretype.
+func (p *Buffer) DecodeTag() (tag int, wt WireType, err error) {
+	var b uint8
+	if p.index < ulen(p.buf) {
+		b = p.buf[p.index]
+	}
+	if b != 0 && b < 0x80 {
+		// the common case of a 1-byte tag
+		p.index++
+		return int(b >> 3), WireType(b & 7), nil
+	}
+	start := p.index
+	u, err := p.DecodeVarint()
+	if err != nil {
+		return 0, 0, err
+	}
+	tag = int(u >> 3)
+	wt = WireType(u & 7)
+	if tag <= 0 || uint64(tag) != u>>3 {
+		return 0, 0, fmt.Errorf("protobuf3: illegal tag %d (wiretype %v) at index %d of %d", u>>3, wt, start, len(p.buf))
+	}
+	return tag, wt, nil
+}
+
+// SkipValue skips over the value of a field which has wiretype wt.
+func (p *Buffer) SkipValue(wt WireType) error {
+	return p.skip(nil, wt)
+}
+
+// DecodeRawValue returns the encoded value of a field which has wiretype wt. For WireBytes that is
+// the bytes without the length. This is what an Marshaler's UnmarshalProtobuf3 method is passed.
+// The returned slice points to shared memory. Treat as read-only.
+func (p *Buffer) DecodeRawValue(wt WireType) ([]byte, error) {
+	return p.get(nil, wt)
+}
+
+// WireTypeError returns the error the decoder returns when a field of type t has the wrong wiretype.
+func WireTypeError(t, field string, got, want WireType) error {
+	return fmt.Errorf("protobuf3: bad wiretype for field %s.%s: got wiretype %v, wanted %v", t, field, got, want)
+}
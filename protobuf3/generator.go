@@ -0,0 +1,416 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package protobuf3
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// A Generator generates .proto definitions of Go types, like AsProtobuf and AsProtobufFull do, but with its own
+// naming functions, headers and options rather than those of the package's global variables. Generators are
+// independent of each other, so libraries using different conventions can share a program.
+//
+// The zero Generator produces the same output as AsProtobufFull(). Its nil naming functions fall back to the
+// corresponding global variables (MakeFieldName, MakeTypeName, MakeGenericTypeName and MakePackageName).
+// XXXHack remains a global variable, since it affects how types are marshaled as well as how they are described.
+type Generator struct {
+	FieldName       func(f string, t reflect.Type) string // names the fields, like MakeFieldName
+	TypeName        func(t reflect.Type, f string) string // names the messages of the fields' types, like MakeTypeName
+	GenericTypeName func(t reflect.Type) string           // names the messages of instantiations of generic types, like MakeGenericTypeName
+	PackageName     func(pkgpath string) string           // names the protobuf package, like MakePackageName
+
+	Headers     []string          // lines inserted after the package line, like the extra_package_headers argument of AsProtobufFull2()
+	FileOptions map[string]string // string valued file options, such as "go_package" or "java_package", inserted after the package line in order of their names
+	NoComments  bool              // omit the comment saying what generated the output, and the TODO comments about types lacking definitions
+}
+
+// AsProtobuf returns type t expressed in protobuf v3 format, suitable for feeding back into the protobuf compiler
+func (g *Generator) AsProtobuf(t reflect.Type) (string, error) {
+	// dig down through any pointer types
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	prop, err := GetProperties(t)
+	if err != nil {
+		return "# Error: " + err.Error(), err // cause an error in the protobuf compiler if the input is used
+	}
+	n := &namer{Generator: g}
+	return prop.asProtobuf(t, n.messageName(t), n), nil
+}
+
+// AsProtobufFull returns type t expressed in protobuf v3 format, including all the types t and the types in more
+// depend on, and their imports
+func (g *Generator) AsProtobufFull(t reflect.Type, more ...reflect.Type) (string, error) {
+	// dig down through any pointer types on the first type, since we'll use that one to determine the package
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	todo := make(map[reflect.Type]struct{})
+	discovered := make(map[reflect.Type]struct{})
+
+	pkgpath := t.PkgPath()
+
+	var headers []string
+	if !g.NoComments {
+		headers = append(headers, fmt.Sprintf("// protobuf definitions generated by protobuf3.AsProtobufFull(%s.%s)", pkgpath, g.messageName(t)), "")
+	}
+	headers = append(headers, `syntax = "proto3";`, "")
+	imported := make(map[string]struct{}) // the set of all imported files
+	var body []string
+
+	if pkgpath != "" {
+		headers = append(headers, fmt.Sprintf("package %s;", g.packageName(pkgpath)))
+	} // else the type is synthesized and lacks a path; humans need to deal with the output (after all they caused this)
+
+	headers = append(headers, g.fileOptions()...)
+	headers = append(headers, g.Headers...)
+
+	// place all the arguments in the todo table to start things off
+	todo[t] = struct{}{}
+	for _, t := range more {
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		todo[t] = struct{}{}
+	}
+
+	// and lather/rinse/repeat until we've discovered all the types
+	var first_err error
+	for len(todo) != 0 {
+		for t := range todo {
+			// move t from todo to discovered
+			delete(todo, t)
+			discovered[t] = struct{}{}
+
+			// add to todo any new, non-anonymous types used by struct t's fields
+			p, err := GetProperties(t)
+			if err != nil {
+				if first_err == nil {
+					first_err = err
+				}
+				body = append(body, "# Error: "+err.Error()) // cause an error in the protobuf compiler
+				continue
+			}
+			var props []*Properties
+			for i := range p.props {
+				props = append(props, &p.props[i])
+				if mv := p.props[i].mvalprop; mv != nil {
+					props = append(props, mv) // map values can be messages too
+				}
+			}
+			for _, pp := range props {
+				tt := pp.Subtype()
+				if tt != nil {
+					if _, ok := discovered[tt]; !ok {
+						// it's a new type of field
+						switch {
+						case lookupCodec(tt) != nil:
+							// the codec supplies the definition, if any
+							discovered[tt] = struct{}{}
+						case pp.marshalFormat != noMarshalFormat && wrapperName(tt) == "":
+							// the field is plain bytes or a string, and there is nothing to define (unless it's a list of lists, whose wrapper needs defining)
+						case (pp.isAppender || pp.isMarshaler) && !isGenerated(reflect.PtrTo(tt)):
+							// we can't recurse further into a custom type
+							discovered[tt] = struct{}{}
+						case isAsProtobuf3er(reflect.PtrTo(tt)) || isAsV1Protobuf3er(reflect.PtrTo(tt)):
+							// this type has a custom protobuf definition. it presumably encodes its own types
+							discovered[tt] = struct{}{}
+						case lookupEnum(tt) != nil:
+							// the enum gets defined from its registered values
+							discovered[tt] = struct{}{}
+						case tt.Kind() == reflect.Struct:
+							switch tt {
+							case time_Time_type:
+								// the timestamp type get defined by an import of timestamp.proto
+								discovered[tt] = struct{}{}
+							default:
+								// put this new type in the todo table if it isn't already there
+								// (the duplicate insert when it is already present is a no-op)
+								todo[tt] = struct{}{}
+							}
+						case tt == time_Duration_type:
+							// the duration type get defined by an import of duration.proto
+							discovered[tt] = struct{}{}
+						}
+					}
+				}
+			}
+
+			// and we must break since todo has possibly been altered
+			break
+		}
+	}
+
+	// now that the types we need have all been discovered, sort their names and generate the .proto source
+	// the reason we do this in 2 passes is so that the output is consistent from run to run, and diff'able
+	// across runs with incremental differences.
+
+	ordered := make(Types, 0, len(discovered))
+	for t := range discovered {
+		if t.Name() != "" || wrapperName(t) != "" { // skip anonymous types, except the wrappers
+			ordered = append(ordered, t)
+		}
+	}
+
+	// types from different packages can have the same name, and must be renamed
+	n, err := g.newNamer(pkgpath, ordered)
+	if err != nil && first_err == nil {
+		first_err = err
+	}
+	sort.Slice(ordered, func(i, j int) bool { return n.messageName(ordered[i]) < n.messageName(ordered[j]) })
+
+	defined := make(map[string]bool)       // the wrappers already defined
+	enum_values := make(map[string]string) // enum value name -> the enum which defines it. like C++, protobuf puts enum values in the scope of their enum's parent
+	for _, t := range ordered {
+		if wrapperName(t) != "" {
+			// different list types can share a wrapper message (a [][]int32 and a [][4]int32, for instance)
+			if name := n.messageName(t); !defined[name] {
+				defined[name] = true
+				sprop, _ := GetProperties(t) // can't fail, since the wrapper was created from it
+				body = append(body, "", sprop.asProtobuf(t, name, n))
+			}
+			continue
+		}
+
+		// generate type t's protobuf definition
+		ptr_t := reflect.PtrTo(t)
+
+		var definition string
+		var imports []string
+		var external bool
+		switch {
+		case t == time_Time_type:
+			// the timestamp type gets defined by an import
+			imports = []string{"google/protobuf/timestamp.proto"}
+			external = true
+
+		case t == time_Duration_type:
+			// the duration type gets defined by an import
+			imports = []string{"google/protobuf/duration.proto"}
+			external = true
+
+		case lookupCodec(t) != nil:
+			c := lookupCodec(t)
+			definition, imports = c.Definition, c.Imports
+			if definition == "" {
+				// the codec's type name was sufficient
+				external = true
+			}
+
+		case (isAppender(ptr_t) || isMarshaler(ptr_t)) && !isGenerated(ptr_t):
+			// we can't define a custom type automatically. see if it can tell us, and otherwise remind the human to do it.
+			switch {
+			case isAsProtobuf3er(ptr_t):
+				_, definition, imports = reflect.NewAt(t, nil).Interface().(AsProtobuf3er).AsProtobuf3()
+			case isAsV1Protobuf3er(ptr_t):
+				_, definition = reflect.NewAt(t, nil).Interface().(AsV1Protobuf3er).AsProtobuf3()
+			default:
+				if !g.NoComments {
+					headers = append(headers, fmt.Sprintf("// TODO supply the definition of message %s", n.messageName(t)))
+				}
+			}
+			if definition == "" {
+				// the type doesn't need any additional definition (its name was sufficient)
+				external = true
+			}
+
+		case isAsProtobuf3er(ptr_t):
+			_, definition, imports = reflect.NewAt(t, nil).Interface().(AsProtobuf3er).AsProtobuf3()
+
+		case isAsV1Protobuf3er(ptr_t):
+			_, definition = reflect.NewAt(t, nil).Interface().(AsV1Protobuf3er).AsProtobuf3()
+
+		case lookupEnum(t) != nil:
+			var err error
+			definition, err = lookupEnum(t).asProtobuf(n.messageName(t))
+			if err == nil {
+				err = lookupEnum(t).checkClashes(n.messageName(t), enum_values)
+			}
+			if err != nil {
+				if first_err == nil {
+					first_err = err
+				}
+				definition = "# Error: " + err.Error() // cause an error in the protobuf compiler
+			}
+		}
+
+		for _, imp := range imports {
+			imported[imp] = struct{}{}
+		}
+		if !external {
+			if definition == "" {
+				sprop, err := GetProperties(t)
+				if err != nil {
+					if first_err == nil {
+						first_err = err
+					}
+					definition = "# Error: " + err.Error() // cause an error in the protobuf compiler
+				} else {
+					definition = sprop.asProtobuf(t, n.messageName(t), n)
+				}
+			}
+			if definition != "" {
+				body = append(body, "") // put a blank line between each message definition
+				body = append(body, definition)
+			}
+		} // else the type doesn't need any additional definition (its name and imports are sufficient)
+	}
+
+	// generate the import header lines. to make the output reproducible, sort them
+	// (if someone the order of import headers becomes important we'll have to do something fancier, but for now they are well written and independant)
+	if len(imported) != 0 {
+		import_headers := make([]string, 0, len(imported))
+		for imp := range imported {
+			import_headers = append(import_headers, fmt.Sprintf("import %q;", imp))
+		}
+		sort.Strings(import_headers)
+		headers = append(headers, "")
+		headers = append(headers, import_headers...)
+	}
+
+	return strings.Join(append(headers, body...), "\n"), first_err
+}
+
+// returns the name of the protobuf package of the go package pkgpath
+func (g *Generator) packageName(pkgpath string) string {
+	if g.PackageName != nil {
+		return g.PackageName(pkgpath)
+	}
+	return MakePackageName(pkgpath)
+}
+
+// returns the name of the message of named type t
+func (g *Generator) messageName(t reflect.Type) string {
+	if g.GenericTypeName != nil && isGeneric(t) {
+		return g.GenericTypeName(t)
+	}
+	return messageName(t)
+}
+
+// returns the option lines of g.FileOptions, in order
+func (g *Generator) fileOptions() []string {
+	lines := make([]string, 0, len(g.FileOptions))
+	for name, value := range g.FileOptions {
+		lines = append(lines, fmt.Sprintf("option %s = %q;", name, value))
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// a namer names the messages, enums and fields of one .proto, as configured by its Generator. The names cached in the
+// properties were made with the global naming functions, so unless those are in use, the names are made afresh.
+type namer struct {
+	*Generator
+	renames map[reflect.Type]string // the types renamed because of collisions, and the wrappers whose items' types were renamed
+}
+
+// newNamer returns the namer of the types which AsProtobufFull will define, renaming those which collide.
+// pkgpath is the package whose types keep their names.
+func (g *Generator) newNamer(pkgpath string, types []reflect.Type) (*namer, error) {
+	n := &namer{Generator: g}
+	err := n.renameCollisions(pkgpath, types)
+	if !n.cached() {
+		// the wrappers are named after their items, whose names may have changed. a wrapper of wrappers is
+		// renamed once the inner wrapper has been, so repeat until nothing changes
+		for changed := true; changed; {
+			changed = false
+			for _, t := range types {
+				if wrapperName(t) == "" {
+					continue
+				}
+				sprop, _ := GetProperties(t) // can't fail, since the wrapper was created from it
+				name, err := wrapperMessageName(t.Field(0).Type, &sprop.props[0], n)
+				if err != nil {
+					return nil, err
+				}
+				if name != n.messageName(t) {
+					if n.renames == nil {
+						n.renames = make(map[reflect.Type]string)
+					}
+					n.renames[t] = name
+					changed = true
+				}
+			}
+		}
+	}
+	if werr := n.checkWrapperNames(types); err == nil {
+		err = werr
+	}
+	return n, err
+}
+
+// cached returns true if the names cached in the properties are the names n would make
+func (n *namer) cached() bool {
+	return n == nil || (n.FieldName == nil && n.TypeName == nil && n.GenericTypeName == nil && len(n.renames) == 0)
+}
+
+// messageName returns the name of the message (or enum) of named type t, or of wrapper type t
+func (n *namer) messageName(t reflect.Type) string {
+	if name, ok := n.renames[t]; ok {
+		return name
+	}
+	if name := wrapperName(t); name != "" {
+		return name
+	}
+	if lookupEnum(t) != nil {
+		return t.Name()
+	}
+	return n.typeName(t, "")
+}
+
+// typeName returns the name of the message of t, the type of a field named f
+func (n *namer) typeName(t reflect.Type, f string) string {
+	if _, ok := n.renames[t]; ok || wrapperName(t) != "" || lookupEnum(t) != nil {
+		return n.messageName(t)
+	}
+	switch {
+	case n.TypeName != nil:
+		return n.TypeName(t, f)
+	case n.GenericTypeName != nil && isGeneric(t):
+		return n.GenericTypeName(t)
+	}
+	return MakeTypeName(t, f)
+}
+
+// fieldName returns the name of field f of struct type t
+func (n *namer) fieldName(f string, t reflect.Type) string {
+	if n != nil && n.FieldName != nil {
+		return n.FieldName(f, t)
+	}
+	return MakeFieldName(f, t)
+}
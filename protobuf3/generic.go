@@ -0,0 +1,174 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package protobuf3
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// reflect names an instantiation of a generic type after the type and its type arguments, qualified by their
+// package paths, as in "Page[github.com/mistsys/example.Device]". That isn't a valid protobuf identifier, so the
+// protobuf messages of such types are named by MakeGenericTypeName instead.
+
+// MakeGenericTypeName is a pointer to a function which returns what should be the name of the protobuf message of t,
+// which is an instantiation of a generic type. You can replace this with your own function before calling
+// AsProtobuf[Full]() to control the names yourself. The names it returns must be valid protobuf identifiers.
+var MakeGenericTypeName func(t reflect.Type) string = MakeOfGenericTypeName
+
+// MakeOfGenericTypeName names the instantiation of a generic type after the type and its type arguments, joined by
+// "Of" and "And", so Page[Device] is named PageOfDevice and Pair[string,*Device] is named PairOfStringAndPtrDevice.
+// Type arguments which are not named types are spelled out: []T is SliceOfT, [N]T is ArrayNOfT and map[K]V is
+// MapOfKToV. Package paths are dropped, like they are from the names of non-generic types.
+func MakeOfGenericTypeName(t reflect.Type) string {
+	g := genericNamer{s: t.Name()}
+	return g.typ()
+}
+
+// isGeneric returns true if named type t is an instantiation of a generic type
+func isGeneric(t reflect.Type) bool {
+	return strings.IndexByte(t.Name(), '[') >= 0
+}
+
+// messageName returns the name of the protobuf message of named type t
+func messageName(t reflect.Type) string {
+	if isGeneric(t) {
+		return MakeGenericTypeName(t)
+	}
+	return t.Name()
+}
+
+// genericNamer parses the Go syntax of a type, as formatted by reflect, and names it
+type genericNamer struct {
+	s string
+	i int // the offset of the next byte of s to parse
+}
+
+// typ names the type at g.i, and advances g.i past it
+func (g *genericNamer) typ() string {
+	s := g.s[g.i:]
+	switch {
+	case strings.HasPrefix(s, "*"):
+		g.i++
+		return "Ptr" + g.typ()
+	case strings.HasPrefix(s, "[]"):
+		g.i += 2
+		return "SliceOf" + g.typ()
+	case strings.HasPrefix(s, "["):
+		n := strings.IndexByte(s, ']')
+		g.i += n + 1
+		return "Array" + s[1:n] + "Of" + g.typ()
+	case strings.HasPrefix(s, "map["):
+		g.i += 4
+		k := g.typ()
+		g.i++ // skip the ']'
+		return "MapOf" + k + "To" + g.typ()
+	case strings.HasPrefix(s, "chan ") || strings.HasPrefix(s, "chan<- ") || strings.HasPrefix(s, "<-chan "):
+		g.skip()
+		return "Chan"
+	case strings.HasPrefix(s, "func("):
+		g.skip()
+		return "Func"
+	case strings.HasPrefix(s, "struct {"):
+		g.skip()
+		return "Struct"
+	case strings.HasPrefix(s, "interface {"):
+		g.skip()
+		return "Interface"
+	}
+
+	// a named or predeclared type, possibly qualified by its package path, and possibly generic itself
+	end := strings.IndexAny(s, "[],")
+	if end < 0 {
+		end = len(s)
+	}
+	name := s[:end]
+	name = identifier(name[strings.LastIndexByte(name, '.')+1:])
+	g.i += end
+	if g.i < len(g.s) && g.s[g.i] == '[' {
+		sep := "Of"
+		for g.i < len(g.s) && g.s[g.i] != ']' {
+			g.i++ // skip the '[' or ','
+			name += sep + g.typ()
+			sep = "And"
+		}
+		g.i++ // skip the ']'
+	}
+	return name
+}
+
+// skip advances g.i past the type at g.i, which is one we don't bother to parse
+func (g *genericNamer) skip() {
+	depth := 0
+	for ; g.i < len(g.s); g.i++ {
+		switch g.s[g.i] {
+		case '(', '[', '{':
+			depth++
+		case ')', '}':
+			depth--
+		case ']':
+			if depth == 0 {
+				return
+			}
+			depth--
+		case ',':
+			if depth == 0 {
+				return
+			}
+		case '"':
+			// skip over a quoted struct tag, which can contain anything
+			for g.i++; g.i < len(g.s) && g.s[g.i] != '"'; g.i++ {
+				if g.s[g.i] == '\\' {
+					g.i++
+				}
+			}
+		}
+	}
+}
+
+// identifier uppercases the first letter of Go identifier n, and drops any letters which are not valid in a protobuf identifier
+func identifier(n string) string {
+	buf := make([]byte, 0, len(n))
+	for _, r := range n {
+		if r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_') {
+			if len(buf) == 0 {
+				r = unicode.ToUpper(r)
+			}
+			buf = append(buf, byte(r))
+		}
+	}
+	return string(buf)
+}
@@ -0,0 +1,90 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package protobuf3
+
+import (
+	"sync"
+)
+
+// An Interner returns strings equal to the bytes it is passed, preferably strings it has returned before, so that
+// the many equal strings decoded from many messages share storage. Intern must not retain b, and it must be safe
+// to call from any goroutine.
+type Interner interface {
+	Intern(b []byte) string
+}
+
+// DefaultInterner is the default value of Buffer.Interner, and the Interner of Unmarshal. It is nil, so strings
+// aren't interned unless you ask. It is not safe to change DefaultInterner while calling this package's functions.
+// Try to set it early in main().
+var DefaultInterner Interner
+
+// NewInterner returns an Interner which remembers up to max strings no longer than max_len bytes. When it
+// has remembered max strings it forgets them all and starts over, so the strings which are still common are soon
+// remembered again, and the memory it uses stays bounded.
+func NewInterner(max, max_len int) Interner {
+	return &interner{
+		strs:    make(map[string]string),
+		max:     max,
+		max_len: max_len,
+	}
+}
+
+type interner struct {
+	mu      sync.RWMutex
+	strs    map[string]string
+	max     int
+	max_len int
+}
+
+func (in *interner) Intern(b []byte) string {
+	if len(b) > in.max_len {
+		return string(b)
+	}
+
+	in.mu.RLock()
+	s, ok := in.strs[string(b)] // note the compiler doesn't allocate for string(b) in a map index
+	in.mu.RUnlock()
+	if ok {
+		return s
+	}
+
+	s = string(b)
+	in.mu.Lock()
+	if len(in.strs) >= in.max {
+		in.strs = make(map[string]string, in.max)
+	}
+	in.strs[s] = s
+	in.mu.Unlock()
+	return s
+}
@@ -0,0 +1,421 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package gosrc
+
+import (
+	"fmt"
+	"go/types"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ProtobufPath is the import path of the protobuf3 package
+const ProtobufPath = "github.com/mistsys/protobuf3/protobuf3"
+
+// Tag is a parsed `protobuf:"..."` struct tag
+type Tag struct {
+	Wire    string   // "varint", "fixed32", "fixed64", "zigzag32", "zigzag64" or "bytes"
+	ID      uint32   // the protobuf field id
+	Options []string // any remaining comma separated options
+}
+
+// wiretypes which a tag can specify
+var wires = map[string]bool{
+	"varint":   true,
+	"fixed32":  true,
+	"fixed64":  true,
+	"zigzag32": true,
+	"zigzag64": true,
+	"bytes":    true,
+}
+
+// ParseTag parses a protobuf tag the same way protobuf3.Properties.Parse does. It returns skip=true for `protobuf:"-"`.
+func ParseTag(s string) (tag Tag, skip bool, err error) {
+	fields := strings.Split(s, ",")
+	if len(fields) < 2 {
+		if fields[0] == "-" {
+			return tag, true, nil
+		}
+		return tag, false, fmt.Errorf("tag has too few fields: %q", s)
+	}
+	if !wires[fields[0]] {
+		return tag, false, fmt.Errorf("tag has unknown wire type: %q", s)
+	}
+	tag.Wire = fields[0]
+	id, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return tag, false, fmt.Errorf("tag id invalid: %s: %v", s, err)
+	}
+	if id <= 0 {
+		return tag, false, fmt.Errorf("tag id out of range: %s", s)
+	}
+	tag.ID = uint32(id)
+	tag.Options = fields[2:]
+	return tag, false, nil
+}
+
+// HasOption returns true if the tag has the named option
+func (tag Tag) HasOption(opt string) bool {
+	for _, o := range tag.Options {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// TimeFormat returns the tag's option selecting an alternate encoding of a time.Time ("unixnano", "unixmilli" or
+// "rfc3339"), or "" if it has none. Like protobuf3.Properties.Parse, it is an error to have more than one.
+func (tag Tag) TimeFormat() (string, error) {
+	var tf string
+	for _, o := range tag.Options {
+		switch o {
+		case "unixnano", "unixmilli", "rfc3339":
+			if tf != "" {
+				return "", fmt.Errorf("tag has more than one time format: %s and %s", tf, o)
+			}
+			tf = o
+		}
+	}
+	return tf, nil
+}
+
+// MarshalFormat returns the tag's option selecting encoding through the encoding package's interfaces ("binary"
+// or "text"), or "" if it has none. Like protobuf3.Properties.Parse, it is an error to have more than one, or to
+// have one as well as a time format.
+func (tag Tag) MarshalFormat() (string, error) {
+	var mf string
+	for _, o := range tag.Options {
+		switch o {
+		case "binary", "text":
+			if mf != "" {
+				return "", fmt.Errorf("tag has more than one marshal format: %s and %s", mf, o)
+			}
+			mf = o
+		}
+	}
+	if tf, _ := tag.TimeFormat(); tf != "" && mf != "" {
+		return "", fmt.Errorf("tag has both time format %s and marshal format %s", tf, mf)
+	}
+	return mf, nil
+}
+
+// ParseReserved parses the tag of a protobuf3.Reserved field
+func ParseReserved(s string) ([]uint32, error) {
+	var ids []uint32
+	for _, f := range strings.Split(s, ",") {
+		id, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reserved tag id %q: %v", f, err)
+		}
+		if id <= 0 {
+			return nil, fmt.Errorf("reserved tag id %q out of range", f)
+		}
+		ids = append(ids, uint32(id))
+	}
+	return ids, nil
+}
+
+// IsNamed returns true if t is the named type pkgpath.name
+func IsNamed(t types.Type, pkgpath, name string) bool {
+	n, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := n.Obj()
+	return obj.Name() == name && obj.Pkg() != nil && obj.Pkg().Path() == pkgpath
+}
+
+// IsReserved returns true if t is protobuf3.Reserved
+func IsReserved(t types.Type) bool {
+	return IsNamed(t, ProtobufPath, "Reserved")
+}
+
+// IsTime returns true if t is time.Time
+func IsTime(t types.Type) bool {
+	return IsNamed(t, "time", "Time")
+}
+
+// IsDuration returns true if t is time.Duration
+func IsDuration(t types.Type) bool {
+	return IsNamed(t, "time", "Duration")
+}
+
+// IsNet returns true if t is one of the net/netip and net types which protobuf3 encodes as bytes
+func IsNet(t types.Type) bool {
+	return IsNetKey(t) || IsNamed(t, "net", "IP") || IsNamed(t, "net", "IPNet") || IsNamed(t, "net", "HardwareAddr")
+}
+
+// IsNetKey returns true if t is one of the net/netip types which protobuf3 encodes as a string when it is a map key
+func IsNetKey(t types.Type) bool {
+	return IsNamed(t, "net/netip", "Addr") || IsNamed(t, "net/netip", "Prefix") || IsNamed(t, "net/netip", "AddrPort")
+}
+
+// NetElem returns the net type of a field of type t, which can also be a pointer, slice or array of the net type,
+// and whether the field is repeated
+func NetElem(t types.Type) (elem types.Type, repeated, ok bool) {
+	if IsNet(t) {
+		return t, false, true
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Pointer:
+		return u.Elem(), false, IsNet(u.Elem())
+	case *types.Slice:
+		return u.Elem(), true, IsNet(u.Elem())
+	case *types.Array:
+		return u.Elem(), true, IsNet(u.Elem())
+	}
+	return nil, false, false
+}
+
+// IsList returns true if t is a slice or array other than []byte or [N]byte. Like protobuf3's isList(), a slice
+// or array of lists encodes each list as a wrapper message.
+func IsList(t types.Type) bool {
+	var elem types.Type
+	switch u := t.Underlying().(type) {
+	case *types.Slice:
+		elem = u.Elem()
+	case *types.Array:
+		elem = u.Elem()
+	default:
+		return false
+	}
+	b, ok := elem.Underlying().(*types.Basic)
+	return !ok || b.Kind() != types.Uint8
+}
+
+// OptionListElem returns the list type of t if t is a slice or non-empty array of lists whose time format or marshal
+// format option applies to the items of the lists, mirroring protobuf3's setOptionListEncAndDec().
+func OptionListElem(t types.Type) (types.Type, bool) {
+	var elem types.Type
+	switch u := t.Underlying().(type) {
+	case *types.Slice:
+		elem = u.Elem()
+	case *types.Array:
+		if u.Len() == 0 {
+			return nil, false
+		}
+		elem = u.Elem()
+	default:
+		return nil, false
+	}
+	if !IsList(elem) || IsAppender(types.NewPointer(elem)) || IsMarshaler(types.NewPointer(elem)) {
+		return nil, false
+	}
+	return elem, true
+}
+
+// IsByteArray returns true if t is a [N]byte. Slices and arrays of them are encoded as repeated bytes.
+func IsByteArray(t types.Type) bool {
+	a, ok := t.Underlying().(*types.Array)
+	if !ok {
+		return false
+	}
+	b, ok := a.Elem().Underlying().(*types.Basic)
+	return ok && b.Kind() == types.Uint8
+}
+
+// IsMapWrapped returns true if t, the value type of a map, is a list or a map. Like protobuf3's isMapWrapped(),
+// such values are encoded as wrapper messages.
+func IsMapWrapped(t types.Type) bool {
+	if _, ok := t.Underlying().(*types.Map); ok {
+		return true
+	}
+	return IsList(t)
+}
+
+// MapValueTag returns the protobuf_key and protobuf_val tags of the inner map of a map of maps with value type val
+// and struct tag stag. Like protobuf3's getMapValueWrapperLocked(), they are the protobuf_val_key and
+// protobuf_val_val tags of stag, and so on for maps nested deeper.
+func MapValueTag(stag reflect.StructTag, val types.Type) reflect.StructTag {
+	var tags []string
+	for prefix := ""; ; prefix += "val_" {
+		m, ok := val.Underlying().(*types.Map)
+		if !ok {
+			break
+		}
+		for _, kv := range []string{"key", "val"} {
+			if v, ok := stag.Lookup("protobuf_val_" + prefix + kv); ok {
+				tags = append(tags, fmt.Sprintf("protobuf_%s%s:%q", prefix, kv, v))
+			}
+		}
+		val = m.Elem()
+	}
+	return reflect.StructTag(strings.Join(tags, " "))
+}
+
+// SetElem returns K if t is a map[K]struct{} or a map[K]bool, the map types which can be sets
+func SetElem(t types.Type) (types.Type, bool) {
+	m, ok := t.Underlying().(*types.Map)
+	if !ok {
+		return nil, false
+	}
+	switch v := m.Elem().Underlying().(type) {
+	case *types.Basic:
+		return m.Key(), v.Kind() == types.Bool
+	case *types.Struct:
+		return m.Key(), v.NumFields() == 0
+	}
+	return nil, false
+}
+
+// IsSet returns true if a field of type t with tag pt and struct tag stag is a set, either because the tag has the
+// "set" option or because t is a map[K]struct{} without a protobuf_key tag. Like protobuf3's setEncAndDec(), types
+// which marshal themselves are not sets.
+func IsSet(t types.Type, pt Tag, stag reflect.StructTag) bool {
+	if ptr := types.NewPointer(t); IsAppender(ptr) || IsMarshaler(ptr) {
+		return false
+	}
+	if pt.HasOption("set") {
+		return true
+	}
+	m, ok := t.Underlying().(*types.Map)
+	if !ok {
+		return false
+	}
+	v, ok := m.Elem().Underlying().(*types.Struct)
+	return ok && v.NumFields() == 0 && stag.Get("protobuf_key") == ""
+}
+
+// OptionalElem returns T if t is protobuf3.Optional[T]
+func OptionalElem(t types.Type) (types.Type, bool) {
+	n, ok := t.(*types.Named)
+	if !ok || !IsNamed(n.Origin(), ProtobufPath, "Optional") || n.TypeArgs().Len() != 1 {
+		return nil, false
+	}
+	return n.TypeArgs().At(0), true
+}
+
+// IsOptionalElem returns true if t is a type protobuf3.Optional[t] supports: a bool, integer, float or string type, or []byte
+func IsOptionalElem(t types.Type) bool {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		return u.Info()&(types.IsBoolean|types.IsInteger|types.IsFloat|types.IsString) != 0 && u.Info()&types.IsUntyped == 0
+	case *types.Slice:
+		b, ok := u.Elem().Underlying().(*types.Basic)
+		return ok && b.Kind() == types.Uint8
+	}
+	return false
+}
+
+// IsAppender returns true if t implements protobuf3.Appender. Like the runtime, the tools
+// usually pass a pointer type, since that is how the methods are normally declared.
+func IsAppender(t types.Type) bool {
+	return hasMethod(t, "AppendProtobuf3") && hasMethod(t, "UnmarshalProtobuf3")
+}
+
+// IsMarshaler returns true if t implements protobuf3.Marshaler
+func IsMarshaler(t types.Type) bool {
+	return hasMethod(t, "MarshalProtobuf3") && hasMethod(t, "UnmarshalProtobuf3")
+}
+
+// IsEncoding returns true if t implements encoding.BinaryMarshaler and encoding.BinaryUnmarshaler when mf is
+// "binary", or encoding.TextMarshaler and encoding.TextUnmarshaler when it is "text"
+func IsEncoding(t types.Type, mf string) bool {
+	if mf == "text" {
+		return hasMethod(t, "MarshalText") && hasMethod(t, "UnmarshalText")
+	}
+	return hasMethod(t, "MarshalBinary") && hasMethod(t, "UnmarshalBinary")
+}
+
+// EncodingElem returns the type which a field of type t with marshal format mf encodes through its methods,
+// which is t itself or the element of a pointer, slice or array t, and whether the field is repeated
+func EncodingElem(t types.Type, mf string) (elem types.Type, repeated, ok bool) {
+	if IsEncoding(types.NewPointer(t), mf) {
+		return t, false, true
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Pointer:
+		return u.Elem(), false, IsEncoding(t, mf)
+	case *types.Slice:
+		return u.Elem(), true, IsEncoding(types.NewPointer(u.Elem()), mf)
+	case *types.Array:
+		return u.Elem(), true, IsEncoding(types.NewPointer(u.Elem()), mf)
+	}
+	return nil, false, false
+}
+
+// IsGenerated returns true if t implements protobuf3.Generated, which is to say its
+// Appender methods were generated by protobuf3-gen from its struct tags
+func IsGenerated(t types.Type) bool {
+	return IsAppender(t) && hasMethod(t, "DecodeProtobuf3") && hasMethod(t, "Protobuf3Generated")
+}
+
+// returns true if t's method set includes the named method
+func hasMethod(t types.Type, name string) bool {
+	ms := types.NewMethodSet(t)
+	for i := 0; i < ms.Len(); i++ {
+		if ms.At(i).Obj().Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// EmbeddedStruct returns the struct type of a `protobuf:"embedded"` field of type t, which is a struct or, like
+// protobuf3's embedded.go, a pointer to a struct
+func EmbeddedStruct(t types.Type) (*types.Struct, bool) {
+	if p, ok := t.Underlying().(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	s, ok := t.Underlying().(*types.Struct)
+	return s, ok
+}
+
+// StructIDs returns the tag ids used by the fields of s, including those merged in from `protobuf:"embedded"`
+// structs, and the ids reserved by s and its embedded structs. Fields with invalid tags are ignored.
+func StructIDs(s *types.Struct) (used, reserved []uint32) {
+	for i := 0; i < s.NumFields(); i++ {
+		f := s.Field(i)
+		tag := reflect.StructTag(s.Tag(i)).Get("protobuf")
+		if tag == "embedded" && f.Anonymous() {
+			if embedded, ok := EmbeddedStruct(f.Type()); ok {
+				u, r := StructIDs(embedded)
+				used = append(used, u...)
+				reserved = append(reserved, r...)
+			}
+			continue
+		}
+		if IsReserved(f.Type()) {
+			r, _ := ParseReserved(tag)
+			reserved = append(reserved, r...)
+			continue
+		}
+		if t, skip, err := ParseTag(tag); err == nil && !skip {
+			used = append(used, t.ID)
+		}
+	}
+	return used, reserved
+}
@@ -46,6 +46,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"reflect"
 	"strings"
 	"sync"
 	"unsafe"
@@ -53,7 +54,8 @@ import (
 
 // Message is implemented by generated protocol buffer messages.
 type Message interface {
-	// empty interface. As long as the fields are decorated with protobuf tags or the type implements Marshaler it's fine with us.
+	// empty interface. As long as the fields are decorated with protobuf tags, or the type implements Marshaler or Appender,
+	// or it is a slice, array, map or scalar which can be wrapped in a message, it's fine with us.
 }
 
 // Buffer is a byte slice buffer for marshaling and unmarshaling
@@ -61,16 +63,23 @@ type Message interface {
 // reduce memory usage.  It is not necessary to use a Buffer;
 // the global functions Marshal and Unmarshal create a
 // temporary Buffer and are fine for most applications.
-// However if you are decoding objects with large []bytes, creating
+// However if you are decoding objects with large []bytes or many strings, creating
 // your own Buffer and setting Immutable=true will result in the
-// decode []bytes references directly into the []byte passed to NewBuffer()
+// decoded []bytes and strings referencing directly into the []byte passed to NewBuffer()
 // rather than being expensive copies.
 type Buffer struct {
 	WriteBuffer
 	err               error                   // nil, or the first error which happened during operation
 	index             uint                    // read position in .buf[]
-	Immutable         bool                    // true if we the caller promises the contents of buf[] are immutable, and thus we can retain references to it for types which decode into []byte
+	Immutable         bool                    // true if we the caller promises the contents of buf[] are immutable, and thus we can retain references to it for types which decode into []byte or string
 	MaxRecursionDepth int                     // maximum recursion_depth before declaring the input to be malicious
+	StrictEnums       bool                    // true if values of registered enum types which are not among the registered values are an error
+	Deterministic     bool                    // true if the entries of all maps, not only sets, are encoded in the order of their keys. Types which marshal themselves, including those with generated methods, are not affected
+	TopLevelTag       reflect.StructTag       // the struct tag of the field holding a top-level slice, array, map or scalar. "" means id 1 and the type's usual wiretype
+	Limits            DecodeLimits            // the limits on what Unmarshal decodes, to defend against malicious input. Initially DefaultDecodeLimits
+	Interner          Interner                // if not nil, decoded strings come from the Interner, so equal strings share storage. Initially DefaultInterner
+	allocated         uintptr                 // bytes allocated by the current unmarshal, as counted against Limits.MaxAllocation
+	parent            *Buffer                 // the Buffer whose NestedBuffer() we are, and which counts our allocations, or nil
 	recursion_depth   int                     // current recursion depth of unmarshaling
 	array_indexes     map[unsafe.Pointer]uint // map of base address of array -> index of next unfilled slot (or nil if never used)
 }
@@ -91,7 +100,7 @@ type WriteBuffer struct {
 
 // NewBuffer allocates a new Buffer reading from (or writing to, but in that case a WriteBuffer is lighter weight) the argument slice.
 func NewBuffer(e []byte) *Buffer {
-	return &Buffer{WriteBuffer: MakeWriteBuffer(e), MaxRecursionDepth: MaxRecursionDepth}
+	return &Buffer{WriteBuffer: MakeWriteBuffer(e), MaxRecursionDepth: MaxRecursionDepth, Limits: DefaultDecodeLimits, Interner: DefaultInterner}
 }
 
 // MakeWriteBuffer returns a buffer initialize to write to the argument slice
@@ -105,6 +114,7 @@ func (p *Buffer) Reset() {
 	p.index = 0 // for reading
 	p.err = nil
 	p.recursion_depth = 0 // needed if we errored during the previous unmarshal
+	p.allocated = 0
 	p.array_indexes = nil
 }
 
@@ -122,6 +132,8 @@ func newBuffer(e []byte) *Buffer {
 	p := buffer_pool.Get().(*Buffer)
 	p.buf = e
 	p.MaxRecursionDepth = MaxRecursionDepth
+	p.Limits = DefaultDecodeLimits
+	p.Interner = DefaultInterner
 	return p
 }
 
@@ -131,8 +143,12 @@ func (p *Buffer) release() []byte {
 	p.buf = nil
 	p.index = 0
 	p.Immutable = false
+	p.Deterministic = false
+	p.TopLevelTag = ""
+	p.Interner = nil
 	p.err = nil
 	p.recursion_depth = 0
+	p.allocated = 0
 	p.array_indexes = nil
 	buffer_pool.Put(p)
 	return bytes
@@ -153,6 +169,10 @@ func (p *WriteBuffer) Bytes() []byte { return p.buf }
 func (p *Buffer) Rewind() {
 	p.index = 0
 	p.recursion_depth = 0
+	// forget the arrays we filled. the next unmarshal might be into a new value at the same address
+	for ptr := range p.array_indexes {
+		delete(p.array_indexes, ptr)
+	}
 }
 
 // uint(len([]byte)), b/c using uint for indexing saves us some bounds checks for negative indexes
@@ -0,0 +1,137 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package protobuf3
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// DecodeLimits bounds the memory Unmarshal allocates to decode a message, so that a small malicious message can't
+// make it allocate a lot more memory than the message's length. A field which is zero imposes no limit.
+// Types with hand written UnmarshalProtobuf3 methods decode without limits. Those with methods generated by
+// protobuf3-gen get the Buffer's Limits, but only MaxMessageSize and the limits on their strings apply.
+type DecodeLimits struct {
+	MaxMessageSize int // the length of the encoded message
+	MaxAllocation  int // the bytes allocated for decoded values, in total. This is an estimate; it counts the elements, entries, strings, []bytes, codec values and pointed-to structs decoded, not the spare capacity of slices or the overhead of maps
+	MaxRepeated    int // the elements of each repeated field
+	MaxMapEntries  int // the entries of each map or set
+	MaxBytesLength int // the length of each string and []byte, and of each encoded value decoded by a codec (net.IP, the netip types, fields with the binary or text option, ...)
+}
+
+// DefaultDecodeLimits is the default value of Buffer.Limits, and the limits of Unmarshal.
+// It is not safe to change DefaultDecodeLimits while calling this package's functions. Try to set it early in main().
+var DefaultDecodeLimits DecodeLimits
+
+// A LimitError is the cause of the DecodeError returned when a message exceeds one of the Buffer's DecodeLimits.
+type LimitError struct {
+	Limit string // the name of the DecodeLimits field
+	Max   int    // its value
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("protobuf3: exceeded DecodeLimits.%s of %d", e.Limit, e.Max)
+}
+
+// limited returns true if o has any DecodeLimits
+func (o *Buffer) limited() bool {
+	return o.Limits != DecodeLimits{}
+}
+
+// allocate counts n bytes allocated for decoded values against the MaxAllocation limit
+func (o *Buffer) allocate(n uintptr) error {
+	if o.parent != nil {
+		return o.parent.allocate(n)
+	}
+	if max := o.Limits.MaxAllocation; max != 0 {
+		o.allocated += n
+		if o.allocated > uintptr(max) {
+			return &LimitError{"MaxAllocation", max}
+		}
+	}
+	return nil
+}
+
+// addRepeated checks that a repeated field can hold n elements, having added added elements of size size
+func (o *Buffer) addRepeated(n, added int, size uintptr) error {
+	if max := o.Limits.MaxRepeated; max != 0 && n > max {
+		return &LimitError{"MaxRepeated", max}
+	}
+	return o.allocate(uintptr(added) * size)
+}
+
+// addMapEntry checks that map m can hold key, and counts the allocation of an entry of size size
+func (o *Buffer) addMapEntry(m, key reflect.Value, size uintptr) error {
+	if max := o.Limits.MaxMapEntries; max != 0 && m.Len() >= max && !m.MapIndex(key).IsValid() {
+		return &LimitError{"MaxMapEntries", max}
+	}
+	return o.allocate(size)
+}
+
+// addBytes checks that a string, []byte or value decoded by a codec can be n bytes long, and counts their allocation
+// unless they are aliased (they point into the buffer)
+func (o *Buffer) addBytes(n int, aliased bool) error {
+	if max := o.Limits.MaxBytesLength; max != 0 && n > max {
+		return &LimitError{"MaxBytesLength", max}
+	}
+	if aliased {
+		return nil
+	}
+	return o.allocate(uintptr(n))
+}
+
+// countAhead returns the capacity to preallocate for the elements of size size of repeated field p. The capacity is
+// that of the elements which follow in the buffer, but no more than the limits allow, since a malicious message can
+// claim many elements which it doesn't deliver.
+func (o *Buffer) countAhead(p *Properties, size uintptr) int {
+	n, _ := o.count_ahead(p.Tag, p.WireType)
+	return o.preallocate(1+n, size)
+}
+
+// preallocate returns n, the number of elements of size size to preallocate, reduced to what the limits allow
+func (o *Buffer) preallocate(n int, size uintptr) int {
+	if max := o.Limits.MaxRepeated; max != 0 && n > max {
+		n = max
+	}
+	if max := o.Limits.MaxAllocation; max != 0 && size != 0 {
+		room := 0
+		if o.allocated < uintptr(max) {
+			room = int((uintptr(max) - o.allocated) / size)
+		}
+		if n > room {
+			n = room
+		}
+	}
+	return n
+}
@@ -0,0 +1,127 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package protobuf3
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+)
+
+// marshalFormat selects the encoding of a field through the standard library's encoding interfaces,
+// by an option in its protobuf tag. It lets types from other packages (url.URL, big.Float, ...) which
+// implement those interfaces, but not Appender or Marshaler, be encoded without a registered Codec.
+type marshalFormat uint8
+
+const (
+	noMarshalFormat marshalFormat = iota // the usual encoding of the field's type
+	binaryFormat                         // `protobuf:"bytes,...,binary"`: the output of MarshalBinary(), as bytes
+	textFormat                           // `protobuf:"bytes,...,text"`: the output of MarshalText(), as a string
+)
+
+// the tag options which select each marshalFormat
+var marshalFormats = map[string]marshalFormat{
+	"binary": binaryFormat,
+	"text":   textFormat,
+}
+
+func (f marshalFormat) String() string {
+	for opt, ff := range marshalFormats {
+		if ff == f {
+			return opt
+		}
+	}
+	return ""
+}
+
+var (
+	binaryMarshalerType   = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+	textMarshalerType     = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType   = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// the codecs of the marshalFormats. Like a Marshaler, a value which marshals to nothing is omitted.
+var (
+	binaryCodec = &Codec{
+		Wire: WireBytes,
+		Name: "bytes",
+		Append: func(b []byte, v interface{}) ([]byte, error) {
+			data, err := v.(encoding.BinaryMarshaler).MarshalBinary()
+			return append(b, data...), err
+		},
+		Unmarshal: func(data []byte, v interface{}) error {
+			return v.(encoding.BinaryUnmarshaler).UnmarshalBinary(data)
+		},
+	}
+	textCodec = &Codec{
+		Wire: WireBytes,
+		Name: "string",
+		Append: func(b []byte, v interface{}) ([]byte, error) {
+			text, err := v.(encoding.TextMarshaler).MarshalText()
+			return append(b, text...), err
+		},
+		Unmarshal: func(data []byte, v interface{}) error {
+			return v.(encoding.TextUnmarshaler).UnmarshalText(data)
+		},
+	}
+)
+
+// setMarshalEncAndDec is the part of setEncAndDec which handles fields with a marshalFormat option. The field's
+// type, or the element type of a pointer, slice or array, must implement both interfaces of the format.
+func (p *Properties) setMarshalEncAndDec(t1 reflect.Type, wire *WireType, name string) error {
+	c, mt, ut := binaryCodec, binaryMarshalerType, binaryUnmarshalerType
+	if p.marshalFormat == textFormat {
+		c, mt, ut = textCodec, textMarshalerType, textUnmarshalerType
+	}
+	implements := func(t reflect.Type) bool {
+		pt := reflect.PtrTo(t)
+		return pt.Implements(mt) && pt.Implements(ut)
+	}
+
+	elem := t1
+	if !implements(elem) {
+		switch t1.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array:
+			elem = t1.Elem()
+		}
+		if !implements(elem) {
+			if ok, err := p.setOptionListEncAndDec(t1, wire); ok {
+				return err
+			}
+			return fmt.Errorf("protobuf3: %q %s with option %s does not implement %s and %s", name, t1, p.marshalFormat, mt, ut)
+		}
+	}
+	return p.setCodecEncAndDec(t1, elem, c, *wire, name)
+}
@@ -0,0 +1,276 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package protobuf3
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+	"reflect"
+)
+
+// The types of package net/netip and package net which carry addresses are encoded as protobuf `bytes`,
+// using codecs registered at init time (which a call to RegisterCodec for the same type replaces):
+//
+//	netip.Addr        the 4 or 16 byte address. The zone of an IPv6 address is not encoded.
+//	netip.Prefix      the 4 or 16 byte address followed by one byte holding the prefix length
+//	netip.AddrPort    the 4 or 16 byte address followed by the 2 byte big-endian port
+//	net.IP            the 4 or 16 byte address, as it is. (net.ParseIP returns IPv4 addresses in 16 bytes; use To4() to encode 4)
+//	net.IPNet         the 4 or 16 byte address followed by a mask of the same length
+//	net.HardwareAddr  the bytes of the address
+//
+// net.IP and net.HardwareAddr are encoded, and decoded, just as they were when they were treated as []byte.
+// The zero (or nil) value encodes as nothing. Fields must be tagged `protobuf:"bytes,..."`, and can also be
+// pointers, slices and arrays of these types. Since protobuf map keys cannot be bytes, the comparable netip
+// types are encoded as `string`s in their text form (for example "10.1.2.3", "10.0.0.0/8" and "[::1]:80") when
+// they are map keys, and that text form retains IPv6 zones.
+
+func init() {
+	registerBuiltinCodec(reflect.TypeOf(netip.Addr{}), Codec{
+		Wire:      WireBytes,
+		Append:    appendNetipAddr,
+		Unmarshal: unmarshalNetipAddr,
+		Name:      "bytes",
+		MapKey:    textKeyCodec(),
+	})
+	registerBuiltinCodec(reflect.TypeOf(netip.Prefix{}), Codec{
+		Wire:      WireBytes,
+		Append:    appendNetipPrefix,
+		Unmarshal: unmarshalNetipPrefix,
+		Name:      "bytes",
+		MapKey:    textKeyCodec(),
+	})
+	registerBuiltinCodec(reflect.TypeOf(netip.AddrPort{}), Codec{
+		Wire:      WireBytes,
+		Append:    appendNetipAddrPort,
+		Unmarshal: unmarshalNetipAddrPort,
+		Name:      "bytes",
+		MapKey:    textKeyCodec(),
+	})
+	registerBuiltinCodec(reflect.TypeOf(net.IP{}), Codec{
+		Wire:      WireBytes,
+		Append:    appendNetIP,
+		Unmarshal: unmarshalNetIP,
+		Name:      "bytes",
+	})
+	registerBuiltinCodec(reflect.TypeOf(net.IPNet{}), Codec{
+		Wire:      WireBytes,
+		Append:    appendNetIPNet,
+		Unmarshal: unmarshalNetIPNet,
+		Name:      "bytes",
+	})
+	registerBuiltinCodec(reflect.TypeOf(net.HardwareAddr{}), Codec{
+		Wire:      WireBytes,
+		Append:    appendNetHardwareAddr,
+		Unmarshal: unmarshalNetHardwareAddr,
+		Name:      "bytes",
+	})
+}
+
+// the map key codec of the netip types, which all implement encoding.TextMarshaler and TextUnmarshaler
+func textKeyCodec() *Codec {
+	return &Codec{
+		Wire: WireBytes,
+		Append: func(b []byte, v interface{}) ([]byte, error) {
+			text, err := v.(interface{ MarshalText() ([]byte, error) }).MarshalText()
+			return append(b, text...), err
+		},
+		Unmarshal: func(data []byte, v interface{}) error {
+			return v.(interface{ UnmarshalText([]byte) error }).UnmarshalText(data)
+		},
+		Name: "string",
+	}
+}
+
+// append the 4 or 16 bytes of a
+func appendAddr(b []byte, a netip.Addr) []byte {
+	if a.Is4() {
+		x := a.As4()
+		return append(b, x[:]...)
+	}
+	x := a.As16()
+	return append(b, x[:]...)
+}
+
+// decode the 4 or 16 bytes of an address
+func decodeAddr(data []byte, what string) (netip.Addr, error) {
+	switch len(data) {
+	case 4:
+		return netip.AddrFrom4(*(*[4]byte)(data)), nil
+	case 16:
+		return netip.AddrFrom16(*(*[16]byte)(data)), nil
+	}
+	return netip.Addr{}, fmt.Errorf("protobuf3: %d bytes of address in %s", len(data), what)
+}
+
+func appendNetipAddr(b []byte, v interface{}) ([]byte, error) {
+	a := *v.(*netip.Addr)
+	if !a.IsValid() {
+		return b, nil
+	}
+	return appendAddr(b, a), nil
+}
+
+func unmarshalNetipAddr(data []byte, v interface{}) error {
+	a := v.(*netip.Addr)
+	if len(data) == 0 {
+		*a = netip.Addr{}
+		return nil
+	}
+	x, err := decodeAddr(data, "netip.Addr")
+	if err != nil {
+		return err
+	}
+	*a = x
+	return nil
+}
+
+func appendNetipPrefix(b []byte, v interface{}) ([]byte, error) {
+	p := *v.(*netip.Prefix)
+	if !p.IsValid() {
+		return b, nil
+	}
+	b = appendAddr(b, p.Addr())
+	return append(b, byte(p.Bits())), nil
+}
+
+func unmarshalNetipPrefix(data []byte, v interface{}) error {
+	p := v.(*netip.Prefix)
+	if len(data) == 0 {
+		*p = netip.Prefix{}
+		return nil
+	}
+	n := len(data) - 1
+	a, err := decodeAddr(data[:n], "netip.Prefix")
+	if err != nil {
+		return err
+	}
+	x := netip.PrefixFrom(a, int(data[n]))
+	if !x.IsValid() {
+		return fmt.Errorf("protobuf3: netip.Prefix length %d is out of range", data[n])
+	}
+	*p = x
+	return nil
+}
+
+func appendNetipAddrPort(b []byte, v interface{}) ([]byte, error) {
+	ap := *v.(*netip.AddrPort)
+	if !ap.IsValid() {
+		return b, nil
+	}
+	b = appendAddr(b, ap.Addr())
+	port := ap.Port()
+	return append(b, byte(port>>8), byte(port)), nil
+}
+
+func unmarshalNetipAddrPort(data []byte, v interface{}) error {
+	ap := v.(*netip.AddrPort)
+	if len(data) == 0 {
+		*ap = netip.AddrPort{}
+		return nil
+	}
+	if len(data) < 2 {
+		return fmt.Errorf("protobuf3: %d bytes of netip.AddrPort", len(data))
+	}
+	n := len(data) - 2
+	a, err := decodeAddr(data[:n], "netip.AddrPort")
+	if err != nil {
+		return err
+	}
+	*ap = netip.AddrPortFrom(a, binary.BigEndian.Uint16(data[n:]))
+	return nil
+}
+
+func appendNetIP(b []byte, v interface{}) ([]byte, error) {
+	return append(b, *v.(*net.IP)...), nil
+}
+
+func unmarshalNetIP(data []byte, v interface{}) error {
+	// like dec_slice_byte(), accept any length, since a net.IP used to be decoded as a []byte
+	ip := v.(*net.IP)
+	if len(data) == 0 {
+		*ip = nil
+		return nil
+	}
+	*ip = append(net.IP(nil), data...)
+	return nil
+}
+
+func appendNetIPNet(b []byte, v interface{}) ([]byte, error) {
+	n := v.(*net.IPNet)
+	if len(n.IP) == 0 {
+		return b, nil
+	}
+	ip := n.IP
+	if ip4 := ip.To4(); ip4 != nil && len(n.Mask) == net.IPv4len {
+		ip = ip4
+	}
+	if len(ip) != len(n.Mask) {
+		return b, fmt.Errorf("protobuf3: net.IPNet %v has a %d byte address and a %d byte mask", n, len(ip), len(n.Mask))
+	}
+	b = append(b, ip...)
+	return append(b, n.Mask...), nil
+}
+
+func unmarshalNetIPNet(data []byte, v interface{}) error {
+	n := v.(*net.IPNet)
+	switch len(data) {
+	case 0:
+		*n = net.IPNet{}
+		return nil
+	case 2 * net.IPv4len, 2 * net.IPv6len:
+		h := len(data) / 2
+		*n = net.IPNet{
+			IP:   append(net.IP(nil), data[:h]...),
+			Mask: append(net.IPMask(nil), data[h:]...),
+		}
+		return nil
+	}
+	return fmt.Errorf("protobuf3: %d bytes of address and mask in net.IPNet", len(data))
+}
+
+func appendNetHardwareAddr(b []byte, v interface{}) ([]byte, error) {
+	return append(b, *v.(*net.HardwareAddr)...), nil
+}
+
+func unmarshalNetHardwareAddr(data []byte, v interface{}) error {
+	hw := v.(*net.HardwareAddr)
+	if len(data) == 0 {
+		*hw = nil
+		return nil
+	}
+	*hw = append(net.HardwareAddr(nil), data...)
+	return nil
+}
@@ -0,0 +1,148 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package protobuf3
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+// Optional is a value of scalar, string or []byte type T which can be distinguished from absence without the cost of
+// a pointer. A field of type Optional[T] is encoded like a field of type T, except that when Set is true the value is
+// encoded even if it is zero, and Set records whether the field was present in the decoded message. The field is
+// declared `optional` in the output of AsProtobuf[Full]().
+type Optional[T any] struct {
+	Value T
+	Set   bool
+}
+
+// OptionalOf returns an Optional set to v
+func OptionalOf[T any](v T) Optional[T] {
+	return Optional[T]{Value: v, Set: true}
+}
+
+// Get returns the value and whether it is set
+func (o Optional[T]) Get() (T, bool) {
+	return o.Value, o.Set
+}
+
+// Clear marks the value absent, and zeros it
+func (o *Optional[T]) Clear() {
+	*o = Optional[T]{}
+}
+
+// marker method, so we can recognize every instantiation of Optional
+func (*Optional[T]) protobuf3Optional() {}
+
+type optionalMarker interface {
+	protobuf3Optional()
+}
+
+var optionalMarkerType = reflect.TypeOf((*optionalMarker)(nil)).Elem()
+
+// isOptionalType returns true if t is an Optional[T]
+func isOptionalType(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && reflect.PtrTo(t).Implements(optionalMarkerType)
+}
+
+// an Optional[T] field
+type optionalField struct {
+	value *Properties // the properties of the Value field, with an offset relative to the Optional
+	set   uintptr     // the offset of the Set field
+}
+
+// setOptionalEncAndDec is the part of setEncAndDec which handles Optional[T] fields
+func (p *Properties) setOptionalEncAndDec(t1 reflect.Type, wire *WireType, f *reflect.StructField, name string, int_encoder IntEncoder) error {
+	vf, _ := t1.FieldByName("Value")
+	sf, _ := t1.FieldByName("Set")
+
+	switch k := vf.Type.Kind(); {
+	case k == reflect.Bool,
+		k >= reflect.Int && k <= reflect.Uint64,
+		k == reflect.Float32, k == reflect.Float64,
+		k == reflect.String,
+		k == reflect.Slice && vf.Type.Elem().Kind() == reflect.Uint8:
+	default:
+		return fmt.Errorf("protobuf3: %q %s: Optional supports only scalar, string and []byte types", name, t1)
+	}
+
+	v := *p // same tag, same everything
+	v.offset = vf.Offset
+	if err := v.setEncAndDec(vf.Type, f, name, int_encoder); err != nil {
+		return err
+	}
+	p.opt = &optionalField{
+		value: &v,
+		set:   sf.Offset,
+	}
+	p.enc = (*Buffer).enc_optional
+	p.dec = (*Buffer).dec_optional
+	p.asProtobuf = v.asProtobuf
+	p.stype = v.stype // in case it is a registered enum
+	p.isOptional = true
+	*wire = v.WireType
+	return nil
+}
+
+// Encode an Optional[T], even if it is zero, as long as it is Set
+func (o *Buffer) enc_optional(p *Properties, base unsafe.Pointer) {
+	ptr := unsafe.Pointer(uintptr(base) + p.offset)
+	if !*(*bool)(unsafe.Pointer(uintptr(ptr) + p.opt.set)) {
+		return
+	}
+	v := p.opt.value
+	n := len(o.buf)
+	v.enc(o, v, ptr)
+	if len(o.buf) == n {
+		// the value was zero, which v.enc omits. encode the zero value ourselves
+		o.buf = append(o.buf, v.tagcode...)
+		if v.valEnc != nil {
+			v.valEnc(o, 0)
+		} else {
+			o.EncodeVarint(0) // an empty string or []byte
+		}
+	}
+}
+
+// Decode an Optional[T], and mark it Set
+func (o *Buffer) dec_optional(p *Properties, base unsafe.Pointer) error {
+	ptr := unsafe.Pointer(uintptr(base) + p.offset)
+	v := p.opt.value
+	if err := v.dec(o, v, ptr); err != nil {
+		return err
+	}
+	*(*bool)(unsafe.Pointer(uintptr(ptr) + p.opt.set)) = true
+	return nil
+}
@@ -40,8 +40,8 @@ package protobuf3
  */
 
 import (
+	"errors"
 	"fmt"
-	"os"
 	"reflect"
 	"sort"
 	"strconv"
@@ -65,7 +65,7 @@ var XXXHack = false
 var MakeFieldName func(f string, t reflect.Type) string = MakeLowercaseFieldName
 
 // MakeTypeName is a pointer to a function which returns what should be the name of the protobuf message of type t, which is the type
-// of a field named f.
+// of a field named f. (f is "" when naming the definition of the message.)
 var MakeTypeName func(t reflect.Type, f string) string = MakeUppercaseTypeName
 
 // MakePackageName is a pointer to a function which returns what should be the name of the protobuf package given the go package path.
@@ -141,12 +141,13 @@ func (sp *StructProperties) Less(i, j int) bool {
 func (sp *StructProperties) Swap(i, j int) { sp.props[i], sp.props[j] = sp.props[j], sp.props[i] }
 
 // returns the properties into protobuf v3 format, suitable for feeding back into the protobuf compiler.
-func (sp *StructProperties) asProtobuf(t reflect.Type, tname string) string {
+// The fields and their types are named by n.
+func (sp *StructProperties) asProtobuf(t reflect.Type, tname string, n *namer) string {
 	lines := []string{fmt.Sprintf("message %s {", tname)}
 	for i := range sp.props {
 		pp := &sp.props[i]
 		if pp.Wire != "-" {
-			lines = append(lines, fmt.Sprintf("  %s%s %s = %d;", pp.optional(), pp.asProtobuf, pp.protobufFieldName(t), pp.Tag))
+			lines = append(lines, fmt.Sprintf("  %s%s %s = %d;", pp.optional(), pp.protobufType(n), pp.protobufFieldName(t, n), pp.Tag))
 		}
 	}
 	if len(sp.reserved) != 0 {
@@ -187,8 +188,8 @@ func (sp *StructProperties) parseReserved(tag string) error {
 	return nil
 }
 
-// return the name of this field in protobuf
-func (p *Properties) protobufFieldName(struct_type reflect.Type) string {
+// return the name of this field in protobuf, as named by n
+func (p *Properties) protobufFieldName(struct_type reflect.Type, n *namer) string {
 	// the "name=" tag overrides any computed field name. That lets us automate any manual fixup of names we might need.
 	for _, t := range strings.Split(p.Wire, ",") {
 		if strings.HasPrefix(t, "name=") {
@@ -196,7 +197,7 @@ func (p *Properties) protobufFieldName(struct_type reflect.Type) string {
 		}
 	}
 
-	return MakeFieldName(p.Name, struct_type)
+	return n.fieldName(p.Name, struct_type)
 }
 
 // return the protobuf "optional" field value (with a whitespace suffix for convenience)
@@ -248,15 +249,8 @@ func MakeLowercaseFieldName(f string, t reflect.Type) string {
 
 // returns the type expressed in protobuf v3 format, suitable for feeding back into the protobuf compiler.
 func AsProtobuf(t reflect.Type) (string, error) {
-	// dig down through any pointer types
-	for t.Kind() == reflect.Ptr {
-		t = t.Elem()
-	}
-	prop, err := GetProperties(t)
-	if err != nil {
-		return "# Error: " + err.Error(), err // cause an error in the protobuf compiler if the input is used
-	}
-	return prop.asProtobuf(t, t.Name()), nil
+	var g Generator
+	return g.AsProtobuf(t)
 }
 
 // given the full path of the package of the 1st type passed to AsProtobufFull(), return
@@ -278,186 +272,23 @@ func AsProtobufFull(t reflect.Type, more ...reflect.Type) (string, error) {
 // returns the type expressed in protobuf v3 format, including all dependent types and imports
 // extra_headers allow the caller to specify headers they want inserted after the `package` line.
 func AsProtobufFull2(t reflect.Type, extra_package_headers []string, more ...reflect.Type) (string, error) {
-	// dig down through any pointer types on the first type, since we'll use that one to determine the package
-	for t.Kind() == reflect.Ptr {
-		t = t.Elem()
-	}
-
-	todo := make(map[reflect.Type]struct{})
-	discovered := make(map[reflect.Type]struct{})
-
-	pkgpath := t.PkgPath()
-
-	headers := []string{
-		fmt.Sprintf("// protobuf definitions generated by protobuf3.AsProtobufFull(%s.%s)", pkgpath, t.Name()),
-		"",
-		`syntax = "proto3";`,
-		"",
-	}
-	imported := make(map[string]struct{}) // the set of all imported files
-	var body []string
-
-	if pkgpath != "" {
-		headers = append(headers, fmt.Sprintf("package %s;", MakePackageName(pkgpath)))
-	} // else the type is synthesized and lacks a path; humans need to deal with the output (after all they caused this)
-
-	headers = append(headers, extra_package_headers...)
-
-	// place all the arguments in the todo table to start things off
-	todo[t] = struct{}{}
-	for _, t := range more {
-		if t.Kind() == reflect.Ptr {
-			t = t.Elem()
-		}
-		todo[t] = struct{}{}
-	}
-
-	// and lather/rinse/repeat until we've discovered all the types
-	var first_err error
-	for len(todo) != 0 {
-		for t := range todo {
-			// move t from todo to discovered
-			delete(todo, t)
-			discovered[t] = struct{}{}
-
-			// add to todo any new, non-anonymous types used by struct t's fields
-			p, err := GetProperties(t)
-			if err != nil {
-				if first_err == nil {
-					first_err = err
-				}
-				body = append(body, "# Error: "+err.Error()) // cause an error in the protobuf compiler
-				continue
-			}
-			for i := range p.props {
-				pp := &p.props[i]
-				tt := pp.Subtype()
-				if tt != nil {
-					if _, ok := discovered[tt]; !ok {
-						// it's a new type of field
-						switch {
-						case pp.isAppender || pp.isMarshaler:
-							// we can't recurse further into a custom type
-							discovered[tt] = struct{}{}
-						case isAsProtobuf3er(reflect.PtrTo(tt)) || isAsV1Protobuf3er(reflect.PtrTo(tt)):
-							// this type has a custom protobuf definition. it presumably encodes its own types
-							discovered[tt] = struct{}{}
-						case tt.Kind() == reflect.Struct:
-							switch tt {
-							case time_Time_type:
-								// the timestamp type get defined by an import of timestamp.proto
-								discovered[tt] = struct{}{}
-							default:
-								// put this new type in the todo table if it isn't already there
-								// (the duplicate insert when it is already present is a no-op)
-								todo[tt] = struct{}{}
-							}
-						case tt == time_Duration_type:
-							// the duration type get defined by an import of duration.proto
-							discovered[tt] = struct{}{}
-						}
-					}
-				}
-			}
-
-			// and we must break since todo has possibly been altered
-			break
-		}
-	}
-
-	// now that the types we need have all been discovered, sort their names and generate the .proto source
-	// the reason we do this in 2 passes is so that the output is consistent from run to run, and diff'able
-	// across runs with incremental differences.
-
-	ordered := make(Types, 0, len(discovered))
-	for t := range discovered {
-		if t.Name() != "" { // skip anonymous types
-			ordered = append(ordered, t)
-		}
-	}
-	sort.Sort(ordered)
-
-	for _, t := range ordered {
-		// generate type t's protobuf definition
-		ptr_t := reflect.PtrTo(t)
-
-		var definition string
-		var imports []string
-		var external bool
-		switch {
-		case t == time_Time_type:
-			// the timestamp type gets defined by an import
-			imports = []string{"google/protobuf/timestamp.proto"}
-			external = true
-
-		case t == time_Duration_type:
-			// the duration type gets defined by an import
-			imports = []string{"google/protobuf/duration.proto"}
-			external = true
-
-		case isAppender(ptr_t) || isMarshaler(ptr_t):
-			// we can't define a custom type automatically. see if it can tell us, and otherwise remind the human to do it.
-			switch {
-			case isAsProtobuf3er(ptr_t):
-				_, definition, imports = reflect.NewAt(t, nil).Interface().(AsProtobuf3er).AsProtobuf3()
-			case isAsV1Protobuf3er(ptr_t):
-				_, definition = reflect.NewAt(t, nil).Interface().(AsV1Protobuf3er).AsProtobuf3()
-			default:
-				headers = append(headers, fmt.Sprintf("// TODO supply the definition of message %s", t.Name()))
-			}
-			if definition == "" {
-				// the type doesn't need any additional definition (its name was sufficient)
-				external = true
-			}
-
-		case isAsProtobuf3er(ptr_t):
-			_, definition, imports = reflect.NewAt(t, nil).Interface().(AsProtobuf3er).AsProtobuf3()
-
-		case isAsV1Protobuf3er(ptr_t):
-			_, definition = reflect.NewAt(t, nil).Interface().(AsV1Protobuf3er).AsProtobuf3()
-		}
-
-		for _, imp := range imports {
-			imported[imp] = struct{}{}
-		}
-		if !external {
-			if definition == "" {
-				var err error
-				definition, err = AsProtobuf(t)
-				if err != nil {
-					if first_err == nil {
-						first_err = err
-					}
-					// and definition already contains the error
-				}
-			}
-			if definition != "" {
-				body = append(body, "") // put a blank line between each message definition
-				body = append(body, definition)
-			}
-		} // else the type doesn't need any additional definition (its name and imports are sufficient)
-	}
-
-	// generate the import header lines. to make the output reproducible, sort them
-	// (if someone the order of import headers becomes important we'll have to do something fancier, but for now they are well written and independant)
-	if len(imported) != 0 {
-		import_headers := make([]string, 0, len(imported))
-		for imp := range imported {
-			import_headers = append(import_headers, fmt.Sprintf("import %q;", imp))
-		}
-		sort.Strings(import_headers)
-		headers = append(headers, "")
-		headers = append(headers, import_headers...)
-	}
-
-	return strings.Join(append(headers, body...), "\n"), first_err
+	g := Generator{Headers: extra_package_headers}
+	return g.AsProtobufFull(t, more...)
 }
 
 type Types []reflect.Type
 
 func (ts Types) Len() int           { return len(ts) }
 func (ts Types) Swap(i, j int)      { ts[i], ts[j] = ts[j], ts[i] }
-func (ts Types) Less(i, j int) bool { return ts[i].Name() < ts[j].Name() } // sort types by their names
+func (ts Types) Less(i, j int) bool { return typeName(ts[i]) < typeName(ts[j]) } // sort types by their names
+
+// returns the message name of t, or of the message of a wrapper type
+func typeName(t reflect.Type) string {
+	if name := wrapperName(t); name != "" {
+		return name
+	}
+	return messageName(t)
+}
 
 // Properties represents the protocol-specific behavior of a single struct field.
 type Properties struct {
@@ -467,21 +298,32 @@ type Properties struct {
 	Tag        uint32
 	WireType   WireType // the wiretype we expect to find in the messages. This is the wiretype from the protobuf: tag except in the case of repeated data, which is always packed in protobuf v3 and uses WireBytes
 
-	enc         encoder
-	valEnc      valueEncoder      // set for bool and numeric types only
-	offset      uintptr           // byte offset of this field within the struct
-	tagcode     string            // encoding of EncodeVarint((Tag<<3)|WireType), stored in a string for efficiency
-	stype       reflect.Type      // set for struct types and time.Duration only
-	sprop       *StructProperties // set for struct types only
-	isMarshaler bool              // true if the type implements Marshaler and marshals/unmarshals itself
-	isAppender  bool              // true if the type implements Appender and helps marshal itself into a *Buffer
-	isOptional  bool              // true if the "optional" attribute was specified in the protobuf: tag. This code (for the obvious reason that it doesn't generate the structs we unmarshal into) largely ignores "optional", but it is copied into the generated .proto, and protoc or some other protobuf code generator will obey it
+	enc           encoder
+	valEnc        valueEncoder      // set for bool and numeric types only
+	offset        uintptr           // byte offset of this field within the struct
+	tagcode       string            // encoding of EncodeVarint((Tag<<3)|WireType), stored in a string for efficiency
+	stype         reflect.Type      // set for struct types and time.Duration only
+	sprop         *StructProperties // set for struct types only
+	isMarshaler   bool              // true if the type implements Marshaler and marshals/unmarshals itself
+	isAppender    bool              // true if the type implements Appender and helps marshal itself into a *Buffer
+	timeFormat    timeFormat        // the encoding of a time.Time, selected by a "unixnano", "unixmilli" or "rfc3339" option in the protobuf: tag
+	marshalFormat marshalFormat     // the encoding through the encoding package's interfaces, selected by a "binary" or "text" option in the protobuf: tag
+	setOption     bool              // true if the "set" option was specified in the protobuf: tag
+	resize        bool              // true if the "resize" option was specified in the protobuf: tag
+	isOptional    bool              // true if the "optional" attribute was specified in the protobuf: tag. This code (for the obvious reason that it doesn't generate the structs we unmarshal into) largely ignores "optional", but it is copied into the generated .proto, and protoc or some other protobuf code generator will obey it
 
 	mtype    reflect.Type // set for map types only
 	mkeyprop *Properties  // set for map types only
 	mvalprop *Properties  // set for map types only
 
-	length uint // set for array types only
+	length     uint // set for array types only
+	elemLength uint // set for slices and arrays of byte arrays only
+
+	enum  *enumField     // set for fields of registered enum types only
+	opt   *optionalField // set for Optional[T] fields only
+	set   *setField      // set for set fields only
+	embed *embeddedField // set for fields of structs embedded by pointer only
+	codec *Codec         // set for fields of registered codec types only
 
 	dec    decoder
 	valDec valueDecoder // set for bool and numeric types only
@@ -585,8 +427,25 @@ func (p *Properties) Parse(s string) (IntEncoder, bool, error) {
 			p.isOptional = true
 			// and we don't care about any other fields
 			// (if you don't mark slices/arrays/maps with ",rep" that's your own problem; this encoder always repeats those types)
+		case "unixnano", "unixmilli", "rfc3339":
+			if p.timeFormat != timestampFormat {
+				return 0, false, fmt.Errorf("protobuf3: tag of %q has more than one time format: %q", p.Name, s)
+			}
+			p.timeFormat = timeFormats[field]
+		case "set":
+			p.setOption = true
+		case "resize":
+			p.resize = true
+		case "binary", "text":
+			if p.marshalFormat != noMarshalFormat {
+				return 0, false, fmt.Errorf("protobuf3: tag of %q has more than one marshal format: %q", p.Name, s)
+			}
+			p.marshalFormat = marshalFormats[field]
 		}
 	}
+	if p.timeFormat != timestampFormat && p.marshalFormat != noMarshalFormat {
+		return 0, false, fmt.Errorf("protobuf3: tag of %q has both a time format and a marshal format: %q", p.Name, s)
+	}
 
 	return enc, false, nil
 }
@@ -621,7 +480,25 @@ func (p *Properties) setEncAndDec(t1 reflect.Type, f *reflect.StructField, name
 
 	// can t1 marshal itself?
 	ptr_t1 := reflect.PtrTo(t1)
-	if isAppender(ptr_t1) {
+	if p.timeFormat != timestampFormat {
+		// a time.Time with an alternate encoding
+		if err := p.setTimeEncAndDec(t1, &wire, name, int64_encoder_txt); err != nil {
+			return err
+		}
+	} else if p.marshalFormat != noMarshalFormat {
+		// a type encoded through the encoding interfaces
+		if err := p.setMarshalEncAndDec(t1, &wire, name); err != nil {
+			return err
+		}
+	} else if elem, c := fieldCodec(t1); c != nil {
+		if err := p.setCodecEncAndDec(t1, elem, c, wire, name); err != nil {
+			return err
+		}
+	} else if isOptionalType(t1) {
+		if err := p.setOptionalEncAndDec(t1, &wire, f, name, int_encoder); err != nil {
+			return err
+		}
+	} else if isAppender(ptr_t1) {
 		p.isAppender = true
 		p.stype = t1
 		p.enc = (*Buffer).enc_appender
@@ -633,6 +510,10 @@ func (p *Properties) setEncAndDec(t1 reflect.Type, f *reflect.StructField, name
 		p.enc = (*Buffer).enc_marshaler
 		p.dec = (*Buffer).dec_unmarshaler
 		p.asProtobuf = p.stypeAsProtobuf()
+	} else if p.isSet(t1, f) {
+		if err := p.setSetEncAndDec(t1, &wire, f, name, int_encoder); err != nil {
+			return err
+		}
 	} else {
 		switch t1.Kind() {
 		default:
@@ -1095,17 +976,50 @@ func (p *Properties) setEncAndDec(t1 reflect.Type, f *reflect.StructField, name
 					if wire != WireBytes {
 						return fmt.Errorf("protobuf3: %q %s cannot have wiretype %s", name, t1, wire)
 					}
+
+				case reflect.Array:
+					if t3.Elem().Kind() != reflect.Uint8 {
+						return fmt.Errorf("protobuf3: no ptr encoder for %s -> %s -> %s", t1.Name(), t2.Name(), t3.Name())
+					}
+					p.stype = t3
+					p.elemLength = uint(t3.Len())
+					p.enc = (*Buffer).enc_slice_ptr_array_byte
+					p.dec = (*Buffer).dec_slice_ptr_array_byte
+					p.asProtobuf = "repeated bytes"
+					if wire != WireBytes {
+						return fmt.Errorf("protobuf3: %q %s cannot have wiretype %s", name, t1, wire)
+					}
 				}
 			case reflect.Slice:
 				switch t2.Elem().Kind() {
 				default:
-					return fmt.Errorf("protobuf3: no slice elem encoder for %s -> %s -> %s", t1.Name(), t2.Name(), t2.Elem().Name())
+					// a slice of lists
+					if err := p.setListEncAndDec(t2, &wire, false); err != nil {
+						return err
+					}
 
 				case reflect.Uint8:
 					p.enc = (*Buffer).enc_slice_slice_byte
 					p.dec = (*Buffer).dec_slice_slice_byte
 					p.asProtobuf = "repeated bytes"
 				}
+			case reflect.Array:
+				if t2.Elem().Kind() == reflect.Uint8 {
+					// a slice of [N]byte, each of which is a bytes
+					p.stype = t2
+					p.elemLength = uint(t2.Len())
+					p.enc = (*Buffer).enc_slice_array_byte
+					p.dec = (*Buffer).dec_slice_array_byte
+					p.asProtobuf = "repeated bytes"
+					if wire != WireBytes {
+						return fmt.Errorf("protobuf3: %q %s cannot have wiretype %s", name, t1, wire)
+					}
+					break
+				}
+				// a slice of lists
+				if err := p.setListEncAndDec(t2, &wire, false); err != nil {
+					return err
+				}
 			}
 
 		case reflect.Array:
@@ -1179,6 +1093,7 @@ func (p *Properties) setEncAndDec(t1 reflect.Type, f *reflect.StructField, name
 				// arrays of uint8 have a special type in protobuf: "bytes"
 				p.enc = (*Buffer).enc_array_byte
 				p.dec = (*Buffer).dec_array_byte
+				wire = WireBytes // packed=true... even for integers
 				p.asProtobuf = "bytes"
 			case reflect.Int16:
 				p.enc = (*Buffer).enc_array_packed_int16
@@ -1292,6 +1207,26 @@ func (p *Properties) setEncAndDec(t1 reflect.Type, f *reflect.StructField, name
 						return fmt.Errorf("protobuf3: %q %s cannot have wiretype %s", name, t1, wire)
 					}
 				}
+			case reflect.Slice, reflect.Array:
+				if t2.Kind() == reflect.Array && t2.Elem().Kind() == reflect.Uint8 {
+					// an array of [N]byte, each of which is a bytes
+					p.stype = t2
+					p.elemLength = uint(t2.Len())
+					p.enc = (*Buffer).enc_array_array_byte
+					p.dec = (*Buffer).dec_array_array_byte
+					p.asProtobuf = "repeated bytes"
+					if wire != WireBytes {
+						return fmt.Errorf("protobuf3: %q %s cannot have wiretype %s", name, t1, wire)
+					}
+					break
+				}
+				if !isList(t2) {
+					return fmt.Errorf("protobuf3: no array encoder for %s = %s", t1.Name(), t2.Name())
+				}
+				// an array of lists
+				if err := p.setListEncAndDec(t2, &wire, true); err != nil {
+					return err
+				}
 			}
 
 		case reflect.Map:
@@ -1300,7 +1235,6 @@ func (p *Properties) setEncAndDec(t1 reflect.Type, f *reflect.StructField, name
 
 			if p.WireType != WireBytes {
 				err := fmt.Errorf("protobuf3: %s.%s wiretype is not \"bytes\"", t1.String(), name)
-				fmt.Fprintln(os.Stderr, err) // print the error too
 				return err
 			}
 
@@ -1309,7 +1243,6 @@ func (p *Properties) setEncAndDec(t1 reflect.Type, f *reflect.StructField, name
 			key_tag := f.Tag.Get("protobuf_key")
 			if key_tag == "" {
 				err := fmt.Errorf("protobuf3: %s.%s lacks a protobuf_key tag", t1.String(), name)
-				fmt.Fprintln(os.Stderr, err) // print the error too
 				return err
 			}
 			skip, err := p.mkeyprop.init(p.mtype.Key(), "Key", key_tag, nil)
@@ -1318,42 +1251,84 @@ func (p *Properties) setEncAndDec(t1 reflect.Type, f *reflect.StructField, name
 			}
 			if skip {
 				err := fmt.Errorf("protobuf3: %s.%s protobuf_key tag cannot be \"-\"", t1.String(), name)
-				fmt.Fprintln(os.Stderr, err) // print the error too
 				return err
 			}
 			if p.mkeyprop.Tag != 1 {
 				// treat non-traditional map tags as an error since they won't be compatible with other protobuf marshalers
 				err := fmt.Errorf("protobuf3: %s.%s protobuf_key tag (%s) doesn't use id 1", key_tag, t1.String(), name)
-				fmt.Fprintln(os.Stderr, err) // print the error too
 				return err
 			}
 
+			if p.mkeyprop.enum != nil {
+				// protobuf map keys cannot be enums, so describe the key as the integer it is
+				p.mkeyprop.asProtobuf = p.mkeyprop.enum.scalar
+			}
+			if p.mkeyprop.marshalFormat != noMarshalFormat {
+				return fmt.Errorf("protobuf3: %s.%s protobuf_key tag cannot have option %s", t1.String(), name, p.mkeyprop.marshalFormat)
+			}
+			if c := p.mkeyprop.codec; c != nil && c.MapKey != nil && p.mkeyprop.stype == p.mtype.Key() {
+				// the codec has an encoding protobuf allows for map keys
+				p.mkeyprop.codec = c.MapKey
+				p.mkeyprop.asProtobuf = c.MapKey.Name
+			}
+
 			p.mvalprop = &Properties{}
 			val_tag := f.Tag.Get("protobuf_val")
 			if val_tag == "" {
 				err := fmt.Errorf("protobuf3: %s.%s lacks a protobuf_val tag", t1.String(), name)
-				fmt.Fprintln(os.Stderr, err) // print the error too
 				return err
 			}
-			skip, err = p.mvalprop.init(p.mtype.Elem(), "Value", val_tag, nil)
+			var w *wrapper
+			if vt := p.mtype.Elem(); isMapWrapped(vt) {
+				// the value is encoded as a wrapper message. see getMapValueWrapperLocked()
+				w, val_tag, err = getMapValueWrapperLocked(vt, f.Tag, val_tag)
+				if err != nil {
+					return fmt.Errorf("protobuf3: %s.%s: %v", t1.String(), name, err)
+				}
+				skip, err = p.mvalprop.init(w.typ, "Value", val_tag, nil)
+			} else {
+				skip, err = p.mvalprop.init(vt, "Value", val_tag, nil)
+			}
 			if err != nil {
 				return fmt.Errorf("protobuf3: while parsing the proto_val tag (%s) of %s.%s: %v", val_tag, t1.String(), name, err)
 			}
 			if skip {
 				err := fmt.Errorf("protobuf3: %s.%s protobuf_val tag cannot be \"-\"", t1.String(), name)
-				fmt.Fprintln(os.Stderr, err) // print the error too
 				return err
 			}
 			if p.mvalprop.Tag != 2 {
 				// treat non-traditional map tags as an error since they won't be compatible with other protobuf marshalers
 				err := fmt.Errorf("protobuf3: %s.%s protobuf_val tag (%s) doesn't use id 2", val_tag, t1.String(), name)
-				fmt.Fprintln(os.Stderr, err) // print the error too
 				return err
 			}
 
+			if w != nil {
+				p.mvalprop.asProtobuf = w.name
+			}
+
 			p.asProtobuf = fmt.Sprintf("map<%s, %s>", p.mkeyprop.asProtobuf, p.mvalprop.asProtobuf)
 		}
 
+		// registered enum types (and pointers, slices and arrays of them) are named by the enum type
+		if e := fieldEnum(t1); e != nil {
+			if int_encoder != VarintEncoder {
+				return fmt.Errorf("protobuf3: %q enum %s must have wiretype varint", name, t1)
+			}
+			p.enum = &enumField{
+				enumInfo: e,
+				ftype:    t1,
+				scalar:   p.asProtobuf,
+				dec:      p.dec,
+			}
+			p.dec = (*Buffer).dec_enum
+			p.stype = e.typ
+			if strings.HasPrefix(p.asProtobuf, "repeated ") {
+				p.asProtobuf = "repeated " + e.typ.Name()
+			} else {
+				p.asProtobuf = e.typ.Name()
+			}
+		}
+
 		// if the type overrides the protobuf definition, use that instead
 		var name, definition string
 		if isAsProtobuf3er(ptr_t1) {
@@ -1417,7 +1392,7 @@ func (p *Properties) stypeAsProtobuf() string {
 		//     Inner' inner = 1;
 		//   }
 		// where the section in `' is the string we need to generate.
-		lines := []string{p.sprop.asProtobuf(p.stype, name)}
+		lines := []string{p.sprop.asProtobuf(p.stype, name, nil)}
 		lines = append(lines, name)
 		str := strings.Join(lines, "\n")
 		// indent str two spaces to the right. we have to do this as a search step rather than as part of Join()
@@ -1436,11 +1411,11 @@ func (p *Properties) stypeAsProtobuf() string {
 // it is almost certainly uppercased too. So there isn't much to do except pick whichever is appropriate.
 func MakeUppercaseTypeName(t reflect.Type, f string) string {
 	// if the Go type is named, a good start is to use the name of the go type
-	// (even if it is in a different package than the enclosing type? that can cause collisions.
-	//  for now the humans can sort those out after protoc errors on the duplicate records)
-	n := t.Name()
-	if n != "" {
-		return n
+	// (even if it is in a different package than the enclosing type? that can cause collisions,
+	//  which AsProtobufFull2 resolves by renaming the types from the other packages)
+	// (and the instantiations of generic types are named by MakeGenericTypeName, since their Go names aren't valid protobuf)
+	if t.Name() != "" {
+		return messageName(t)
 	}
 
 	// the struct has no typename. It is an anonymous type in Go. The equivalent in Protobuf is
@@ -1458,6 +1433,7 @@ var (
 	appenderType         = reflect.TypeOf((*Appender)(nil)).Elem()
 	asprotobuffer3Type   = reflect.TypeOf((*AsProtobuf3er)(nil)).Elem()
 	asv1protobuffer3Type = reflect.TypeOf((*AsV1Protobuf3er)(nil)).Elem()
+	generatedType        = reflect.TypeOf((*Generated)(nil)).Elem()
 )
 
 // isMarshaler reports whether type t implements Marshaler.
@@ -1469,6 +1445,11 @@ func isAppender(t reflect.Type) bool {
 	return t.Implements(appenderType)
 }
 
+// isGenerated reports whether type t's Appender methods were generated by protobuf3-gen
+func isGenerated(t reflect.Type) bool {
+	return t.Implements(generatedType)
+}
+
 func isAsProtobuf3er(t reflect.Type) bool {
 	return t.Implements(asprotobuffer3Type)
 }
@@ -1488,7 +1469,6 @@ func (p *Properties) init(typ reflect.Type, name, tag string, f *reflect.StructF
 			return true, nil
 		}
 		err := fmt.Errorf("protobuf3: %s (%s) lacks a protobuf tag. Tag it, or mark it with `protobuf:\"-\"` if it isn't intended to be marshaled to/from protobuf", name, typ.String())
-		fmt.Fprintln(os.Stderr, err) // print the error too
 		return true, err
 	}
 
@@ -1596,10 +1576,18 @@ func getPropertiesLocked(t reflect.Type) (*StructProperties, error) {
 
 		if tag == "embedded" && f.Anonymous {
 			// field f is embedded in type t and has the special `protobuf:"embedded"` tag. Get f's fields and then merge them into t's
-			fprop, err := getPropertiesLocked(f.Type)
+			et := f.Type
+			if et.Kind() == reflect.Ptr {
+				et = et.Elem() // f is embedded by pointer. see embedded.go
+			}
+			if et.Kind() != reflect.Struct {
+				err := newTagError(t, name, f.Tag, errors.New("the embedded field is not a struct or a pointer to a struct"))
+				delete(propertiesMap, t)
+				return nil, err
+			}
+			fprop, err := getPropertiesLocked(et)
 			if err != nil {
-				err := fmt.Errorf("protobuf3: error preparing field %q of type %q: %v", name, t.Name(), err)
-				fmt.Fprintln(os.Stderr, err) // print the error too
+				err := newTagError(t, name, f.Tag, err)
 				delete(propertiesMap, t)
 				return nil, err
 			}
@@ -1607,7 +1595,11 @@ func getPropertiesLocked(t reflect.Type) (*StructProperties, error) {
 			// merge fprop's fields into prop
 			for ii, p := range fprop.props {
 				// fixup the field property as we copy them
-				p.offset += f.Offset
+				if et != f.Type {
+					p.embedPtr(et, f.Offset)
+				} else {
+					p.offset += f.Offset
+				}
 
 				prop.props = append(prop.props, p)
 
@@ -1620,14 +1612,17 @@ func getPropertiesLocked(t reflect.Type) (*StructProperties, error) {
 				}
 			}
 
+			// and the ids reserved by f are reserved in t too
+			prop.reserved = append(prop.reserved, fprop.reserved...)
+
 			continue
 		}
 
 		if f.Type == reservedType {
 			err := prop.parseReserved(tag)
 			if err != nil {
-				err := fmt.Errorf("protobuf3: error parsing protobuf3.Reserved field %q of type %q: %v", name, t.Name(), err)
-				fmt.Fprintln(os.Stderr, err) // print the error too
+				err := newTagError(t, name, f.Tag, fmt.Errorf("error parsing protobuf3.Reserved field: %v", err))
+				delete(propertiesMap, t)
 				return nil, err
 			}
 			continue
@@ -1638,8 +1633,7 @@ func getPropertiesLocked(t reflect.Type) (*StructProperties, error) {
 
 		skip, err := p.init(f.Type, name, tag, &f)
 		if err != nil {
-			err := fmt.Errorf("protobuf3: error preparing field %q of type %q: %v", name, t.Name(), err)
-			fmt.Fprintln(os.Stderr, err) // print the error too
+			err := newTagError(t, name, f.Tag, err)
 			delete(propertiesMap, t)
 			return nil, err
 		}
@@ -1658,12 +1652,7 @@ func getPropertiesLocked(t reflect.Type) (*StructProperties, error) {
 		}
 
 		if p.enc == nil || p.dec == nil {
-			tname := t.Name()
-			if tname == "" {
-				tname = "<anonymous struct>"
-			}
-			err := fmt.Errorf("protobuf3: error no encoder or decoder for field %q.%q of type %q", tname, name, f.Type.Name())
-			fmt.Fprintln(os.Stderr, err) // print the error too
+			err := newTagError(t, name, f.Tag, fmt.Errorf("no encoder or decoder for type %q", f.Type.String()))
 			delete(propertiesMap, t)
 			return nil, err
 		}
@@ -1701,12 +1690,16 @@ func getPropertiesLocked(t reflect.Type) (*StructProperties, error) {
 	for i := range prop.props {
 		p := &prop.props[i]
 		if prev_tag == p.Tag {
-			err = fmt.Errorf("protobuf3: error duplicate tag id %d assigned to %s.%s", p.Tag, t.String(), p.Name)
+			err = fmt.Errorf("duplicate tag id %d assigned to %s.%s", p.Tag, t.String(), p.Name)
 		} else if _, ok := reserved[p.Tag]; ok {
-			err = fmt.Errorf("protobuf3: error reserved tag id %d assigned to %s.%s", p.Tag, t.String(), p.Name)
+			err = fmt.Errorf("reserved tag id %d assigned to %s.%s", p.Tag, t.String(), p.Name)
 		}
 		if err != nil {
-			fmt.Fprintln(os.Stderr, err) // print the error too
+			var tag reflect.StructTag
+			if f, ok := t.FieldByName(p.Name); ok {
+				tag = f.Tag
+			}
+			err = newTagError(t, p.Name, tag, err)
 			delete(propertiesMap, t)
 			return nil, err
 		}
@@ -0,0 +1,1335 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+/*
+Package protogen generates .proto definitions of Go types from their source code, without running the program.
+
+protobuf3.AsProtobufFull() needs a reflect.Type, and so needs a program which links in the types. Generate works
+from types loaded and type checked by go/types instead, and produces the same output as AsProtobufFull2() would for
+the same types, using the default naming functions (MakeLowercaseFieldName, MakeUppercaseTypeName,
+MakeOfGenericTypeName and MakeSamePackageName). In addition, unless Options.NoComments is set, the Go doc comments
+of types and fields, and the line comments of fields, are carried into the .proto as comments.
+
+Types implementing AsProtobuf3er are handled by evaluating their AsProtobuf3() method, which is only possible when the
+method's source is loaded and its body is a single return statement of constants. The wiretypes in the tags are not
+validated beyond what is needed to generate the .proto; use package tagcheck for that.
+
+See cmd/protobuf3-proto for a command line wrapper.
+*/
+package protogen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mistsys/protobuf3/protobuf3"
+	"github.com/mistsys/protobuf3/protobuf3/internal/gosrc"
+)
+
+// Options modify the output of Generate
+type Options struct {
+	ExtraHeaders []string          // lines inserted after the package line, like the extra_package_headers argument of AsProtobufFull2()
+	FileOptions  map[string]string // same as protobuf3.Generator.FileOptions
+	NoComments   bool              // omit Go comments. The output is then identical to that of AsProtobufFull2()
+	XXXHack      bool              // same as protobuf3.XXXHack
+}
+
+// Generate returns the .proto definitions of type t and of the types t2 and all the types they depend on. The packages
+// in which the types are declared, and which were loaded with a single call to gosrc.Load, should be passed in pkgs,
+// so their comments and AsProtobuf3() methods can be found.
+func Generate(pkgs []*gosrc.Package, opts Options, t types.Type, more ...types.Type) (string, error) {
+	g := newGenerator(pkgs, opts)
+	return g.generate(t, more)
+}
+
+// Lookup finds the named type in one of the packages
+func Lookup(pkgs []*gosrc.Package, name string) (*types.Named, error) {
+	for _, pkg := range pkgs {
+		if obj, ok := pkg.Types.Scope().Lookup(name).(*types.TypeName); ok {
+			if n, ok := obj.Type().(*types.Named); ok {
+				return n, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("protogen: type %s not found", name)
+}
+
+// Structs returns the named struct types declared at package scope in pkg which have at least one protobuf tag, in order of their names.
+// Generic types are omitted, since only their instantiations have definitions.
+func Structs(pkg *gosrc.Package) []*types.Named {
+	var structs []*types.Named
+	scope := pkg.Types.Scope()
+	for _, name := range scope.Names() {
+		obj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || obj.IsAlias() {
+			continue
+		}
+		n, ok := obj.Type().(*types.Named)
+		if !ok || n.TypeParams().Len() != 0 {
+			continue
+		}
+		if s, ok := n.Underlying().(*types.Struct); ok {
+			for i := 0; i < s.NumFields(); i++ {
+				if _, ok := reflect.StructTag(s.Tag(i)).Lookup("protobuf"); ok {
+					structs = append(structs, n)
+					break
+				}
+			}
+		}
+	}
+	return structs
+}
+
+// the properties of one field, the equivalent of the subset of protobuf3.Properties which AsProtobuf needs
+type prop struct {
+	name       string // Go name of the field
+	wire       string // the protobuf tag
+	tag        uint32
+	optional   bool
+	asProtobuf string
+	stype      types.Type // set for struct types, custom types and time.Duration
+	custom     bool       // the type implements protobuf3.Appender or protobuf3.Marshaler
+	mkey, mval *prop      // the key and value of a map. mval.stype is discovered like AsProtobufFull2 discovers mvalprop's
+	doc        *ast.CommentGroup
+	comment    *ast.CommentGroup
+}
+
+// the properties of a struct, the equivalent of protobuf3.StructProperties
+type structProps struct {
+	props    []prop
+	reserved []uint32
+	wrapped  []types.Type // the list or map types of a wrapper message
+}
+
+// the source of a method, for evaluating AsProtobuf3()
+type funcSrc struct {
+	decl *ast.FuncDecl
+	info *types.Info
+}
+
+type generator struct {
+	opts     Options
+	fset     *token.FileSet
+	docs     map[string]*ast.CommentGroup // doc comments of types and fields, by objKey()
+	comments map[string]*ast.CommentGroup // line comments of fields
+	funcs    map[string]funcSrc           // method declarations
+	sprops   map[*types.Named]*structProps
+	wrappers map[string]*structProps // the wrapper messages of lists of lists and of map values, by name
+	renames  map[*types.Named]string // the new names of types whose names collided with those of other packages
+}
+
+func newGenerator(pkgs []*gosrc.Package, opts Options) *generator {
+	g := &generator{
+		opts:     opts,
+		docs:     make(map[string]*ast.CommentGroup),
+		comments: make(map[string]*ast.CommentGroup),
+		funcs:    make(map[string]funcSrc),
+		sprops:   make(map[*types.Named]*structProps),
+		wrappers: make(map[string]*structProps),
+	}
+	for _, pkg := range pkgs {
+		g.fset = pkg.Fset
+		for _, f := range pkg.Files {
+			g.index(pkg, f)
+		}
+	}
+	return g
+}
+
+// objKey returns a key which identifies a declaration by its position, which is the same whether the object
+// was type checked from source or imported from export data
+func (g *generator) objKey(obj types.Object) string {
+	if g.fset == nil || !obj.Pos().IsValid() {
+		return ""
+	}
+	pos := g.fset.Position(obj.Pos())
+	return fmt.Sprintf("%s:%d:%s", pos.Filename, pos.Line, obj.Name())
+}
+
+// index the comments and methods declared in f
+func (g *generator) index(pkg *gosrc.Package, f *ast.File) {
+	key := func(id *ast.Ident) string {
+		pos := pkg.Fset.Position(id.Pos())
+		return fmt.Sprintf("%s:%d:%s", pos.Filename, pos.Line, id.Name)
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.GenDecl:
+			if n.Tok == token.TYPE && len(n.Specs) == 1 && n.Doc != nil {
+				// a lone type declaration's doc comment is usually attached to the GenDecl rather than the TypeSpec
+				g.docs[key(n.Specs[0].(*ast.TypeSpec).Name)] = n.Doc
+			}
+		case *ast.TypeSpec:
+			if n.Doc != nil {
+				g.docs[key(n.Name)] = n.Doc
+			}
+		case *ast.Field:
+			var ids []*ast.Ident
+			ids = append(ids, n.Names...)
+			if len(ids) == 0 {
+				// embedded field; its name is that of its type
+				typ := n.Type
+				if star, ok := typ.(*ast.StarExpr); ok {
+					typ = star.X
+				}
+				switch typ := typ.(type) {
+				case *ast.Ident:
+					ids = append(ids, typ)
+				case *ast.SelectorExpr:
+					ids = append(ids, typ.Sel)
+				}
+			}
+			for _, id := range ids {
+				if n.Doc != nil {
+					g.docs[key(id)] = n.Doc
+				}
+				if n.Comment != nil {
+					g.comments[key(id)] = n.Comment
+				}
+			}
+		case *ast.FuncDecl:
+			if n.Recv != nil {
+				if obj := pkg.Info.Defs[n.Name]; obj != nil {
+					g.funcs[g.funcKey(obj.(*types.Func))] = funcSrc{n, pkg.Info}
+				}
+			}
+		}
+		return true
+	})
+}
+
+// key of a method, by the name of the receiver's type and of the method
+func (g *generator) funcKey(fn *types.Func) string {
+	recv := fn.Type().(*types.Signature).Recv().Type()
+	if p, ok := recv.(*types.Pointer); ok {
+		recv = p.Elem()
+	}
+	if n, ok := recv.(*types.Named); ok {
+		obj := n.Obj()
+		if obj.Pkg() != nil {
+			return obj.Pkg().Path() + "." + obj.Name() + "." + fn.Name()
+		}
+	}
+	return ""
+}
+
+func (g *generator) generate(t types.Type, more []types.Type) (string, error) {
+	// dig down through any pointer types on the first type, since we'll use that one to determine the package
+	t = deref(t)
+	named, ok := t.(*types.Named)
+	if !ok {
+		return "", fmt.Errorf("protogen: %s is not a named type", t)
+	}
+
+	todo := make(map[*types.Named]struct{})
+	discovered := make(map[*types.Named]struct{})
+
+	pkgpath := ""
+	if named.Obj().Pkg() != nil {
+		pkgpath = named.Obj().Pkg().Path()
+	}
+
+	headers := []string{
+		fmt.Sprintf("// protobuf definitions generated by protobuf3.AsProtobufFull(%s.%s)", pkgpath, messageName(named)),
+		"",
+		`syntax = "proto3";`,
+		"",
+	}
+	imported := make(map[string]struct{})
+	var body []string
+
+	if pkgpath != "" {
+		headers = append(headers, fmt.Sprintf("package %s;", protobuf3.MakeSamePackageName(pkgpath)))
+	}
+	options := make([]string, 0, len(g.opts.FileOptions))
+	for name, value := range g.opts.FileOptions {
+		options = append(options, fmt.Sprintf("option %s = %q;", name, value))
+	}
+	sort.Strings(options)
+	headers = append(headers, options...)
+	headers = append(headers, g.opts.ExtraHeaders...)
+
+	todo[named] = struct{}{}
+	for _, t := range more {
+		n, ok := deref(t).(*types.Named)
+		if !ok {
+			return "", fmt.Errorf("protogen: %s is not a named type", t)
+		}
+		todo[n] = struct{}{}
+	}
+
+	// discover all the types, the same way AsProtobufFull2 does
+	var first_err error
+	for len(todo) != 0 {
+		for t := range todo {
+			delete(todo, t)
+			discovered[t] = struct{}{}
+
+			sp, err := g.namedProps(t)
+			if err != nil {
+				if first_err == nil {
+					first_err = err
+				}
+				body = append(body, "# Error: "+err.Error())
+				break
+			}
+			var props []*prop
+			for i := range sp.props {
+				for pp := &sp.props[i]; pp != nil; pp = pp.mval {
+					props = append(props, pp)
+				}
+			}
+			for _, pp := range props {
+				tt, ok := pp.stype.(*types.Named)
+				if !ok {
+					continue // anonymous types are defined inline
+				}
+				if _, ok := discovered[tt]; ok {
+					continue
+				}
+				switch {
+				case pp.custom && !gosrc.IsGenerated(types.NewPointer(tt)):
+					discovered[tt] = struct{}{}
+				case g.isAsProtobuf3er(types.NewPointer(tt)):
+					discovered[tt] = struct{}{}
+				case isStruct(tt):
+					if gosrc.IsTime(tt) {
+						discovered[tt] = struct{}{}
+					} else {
+						todo[tt] = struct{}{}
+					}
+				case gosrc.IsDuration(tt):
+					discovered[tt] = struct{}{}
+				}
+			}
+			break
+		}
+	}
+
+	ordered := make([]*types.Named, 0, len(discovered))
+	for t := range discovered {
+		ordered = append(ordered, t)
+	}
+	sort.Slice(ordered, func(i, j int) bool { return messageName(ordered[i]) < messageName(ordered[j]) })
+
+	// types from different packages can have the same name, and must be renamed. since the names are baked into
+	// the properties, compute those again with the new names
+	renames, err := g.renameCollisions(pkgpath, ordered)
+	if err != nil && first_err == nil {
+		first_err = err
+	}
+	if renames != nil {
+		computed := g.sprops
+		g.renames = renames
+		g.sprops = make(map[*types.Named]*structProps)
+		g.wrappers = make(map[string]*structProps)
+		for t := range computed {
+			g.namedProps(t) // any error was already reported
+		}
+		sort.Slice(ordered, func(i, j int) bool { return g.messageName(ordered[i]) < g.messageName(ordered[j]) })
+	}
+	if err := g.checkWrapperNames(ordered); err != nil && first_err == nil {
+		first_err = err
+	}
+
+	// the definitions, which like AsProtobufFull2 we sort by name, with the list wrappers among the named types
+	type def struct {
+		name, definition string
+	}
+	var defs []def
+	for name, sp := range g.wrappers {
+		defs = append(defs, def{name, g.asProtobuf(sp, name)})
+	}
+
+	for _, t := range ordered {
+		ptr_t := types.NewPointer(t)
+
+		var definition string
+		var imports []string
+		var external bool
+		var err error
+		switch {
+		case gosrc.IsTime(t):
+			imports = []string{"google/protobuf/timestamp.proto"}
+			external = true
+
+		case gosrc.IsDuration(t):
+			imports = []string{"google/protobuf/duration.proto"}
+			external = true
+
+		case isCustom(ptr_t) && !gosrc.IsGenerated(ptr_t):
+			if g.isAsProtobuf3er(ptr_t) {
+				_, definition, imports, err = g.asProtobuf3(ptr_t)
+			} else {
+				headers = append(headers, fmt.Sprintf("// TODO supply the definition of message %s", g.messageName(t)))
+			}
+			if definition == "" {
+				external = true
+			}
+
+		case g.isAsProtobuf3er(ptr_t):
+			_, definition, imports, err = g.asProtobuf3(ptr_t)
+		}
+		if err != nil && first_err == nil {
+			first_err = err
+		}
+
+		for _, imp := range imports {
+			imported[imp] = struct{}{}
+		}
+		if !external {
+			if definition == "" {
+				sp, err := g.namedProps(t)
+				if err != nil {
+					if first_err == nil {
+						first_err = err
+					}
+					definition = "# Error: " + err.Error()
+				} else {
+					definition = g.typeDoc(t) + g.asProtobuf(sp, g.messageName(t))
+				}
+			}
+			defs = append(defs, def{g.messageName(t), definition})
+		}
+	}
+	sort.SliceStable(defs, func(i, j int) bool { return defs[i].name < defs[j].name })
+	for _, d := range defs {
+		body = append(body, "", d.definition)
+	}
+
+	if len(imported) != 0 {
+		import_headers := make([]string, 0, len(imported))
+		for imp := range imported {
+			import_headers = append(import_headers, fmt.Sprintf("import %q;", imp))
+		}
+		sort.Strings(import_headers)
+		headers = append(headers, "")
+		headers = append(headers, import_headers...)
+	}
+
+	return strings.Join(append(headers, body...), "\n"), first_err
+}
+
+// returns the message definition, like StructProperties.asProtobuf()
+func (g *generator) asProtobuf(sp *structProps, tname string) string {
+	lines := []string{fmt.Sprintf("message %s {", tname)}
+	for i := range sp.props {
+		pp := &sp.props[i]
+		lines = append(lines, g.comment(pp.doc, "  ")...)
+		line := fmt.Sprintf("  %s%s %s = %d;", pp.optionalPrefix(), pp.asProtobuf, pp.fieldName(), pp.tag)
+		if c := g.comment(pp.comment, ""); len(c) == 1 {
+			line += " " + c[0]
+		} else {
+			lines = append(lines, g.comment(pp.comment, "  ")...)
+		}
+		lines = append(lines, line)
+	}
+	if len(sp.reserved) != 0 {
+		var b strings.Builder
+		b.WriteString("  reserved ")
+		sep := ""
+		for _, r := range sp.reserved {
+			fmt.Fprintf(&b, "%s%d", sep, r)
+			sep = ", "
+		}
+		b.WriteByte(';')
+		lines = append(lines, b.String())
+	}
+	lines = append(lines, "}")
+	return strings.Join(lines, "\n")
+}
+
+// format a comment group as .proto comment lines with the given indent
+func (g *generator) comment(cg *ast.CommentGroup, indent string) []string {
+	if g.opts.NoComments || cg == nil {
+		return nil
+	}
+	text := strings.TrimRight(cg.Text(), "\n")
+	if text == "" {
+		return nil
+	}
+	var lines []string
+	for _, l := range strings.Split(text, "\n") {
+		if l == "" {
+			lines = append(lines, indent+"//")
+		} else {
+			lines = append(lines, indent+"// "+l)
+		}
+	}
+	return lines
+}
+
+// the doc comment of a named type, formatted to preceed its message definition
+func (g *generator) typeDoc(t *types.Named) string {
+	lines := g.comment(g.docs[g.objKey(t.Obj())], "")
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func (p *prop) optionalPrefix() string {
+	if p.optional {
+		return "optional "
+	}
+	return ""
+}
+
+// the name of the field in protobuf, like Properties.protobufFieldName()
+func (p *prop) fieldName() string {
+	for _, t := range strings.Split(p.wire, ",") {
+		if strings.HasPrefix(t, "name=") {
+			return t[5:]
+		}
+	}
+	return protobuf3.MakeLowercaseFieldName(p.name, nil)
+}
+
+// makeTypeName is the equivalent of MakeUppercaseTypeName, with the renamed types renamed
+func (g *generator) makeTypeName(t types.Type, f string) string {
+	if n, ok := t.(*types.Named); ok {
+		return g.messageName(n)
+	}
+	return f
+}
+
+// messageName returns the name of the message of t, or its new name if it was renamed
+func (g *generator) messageName(t *types.Named) string {
+	if n, ok := g.renames[t]; ok {
+		return n
+	}
+	return messageName(t)
+}
+
+// renameCollisions is the equivalent of protobuf3's renameCollisions(). The wrappers are renamed when the
+// properties are computed again.
+func (g *generator) renameCollisions(pkgpath string, ordered []*types.Named) (map[*types.Named]string, error) {
+	var defined []*types.Named
+	byName := make(map[string][]*types.Named)
+	for _, t := range ordered {
+		if !g.definesByName(t) {
+			continue
+		}
+		defined = append(defined, t)
+		byName[messageName(t)] = append(byName[messageName(t)], t)
+	}
+
+	var renames map[*types.Named]string
+	for _, ts := range byName {
+		if len(ts) < 2 {
+			continue
+		}
+		for _, t := range ts {
+			if path := t.Obj().Pkg().Path(); path != pkgpath {
+				if renames == nil {
+					renames = make(map[*types.Named]string)
+				}
+				renames[t] = identifier(protobuf3.MakeSamePackageName(path)) + messageName(t)
+			}
+		}
+	}
+	if renames == nil {
+		return nil, nil
+	}
+
+	// check the new names are unique
+	qualifier := func(pkg *types.Package) string { return pkg.Name() } // format the types like reflect does
+	names := make(map[string]*types.Named)
+	var collisions []string
+	for _, t := range defined {
+		n, ok := renames[t]
+		if !ok {
+			n = messageName(t)
+		}
+		if t2, ok := names[n]; ok {
+			a, b := types.TypeString(t2, qualifier), types.TypeString(t, qualifier)
+			if a > b {
+				a, b = b, a
+			}
+			collisions = append(collisions, fmt.Sprintf("%s and %s are both named %s", a, b, n))
+		}
+		names[n] = t
+	}
+	if len(collisions) != 0 {
+		sort.Strings(collisions)
+		return renames, fmt.Errorf("protobuf3: colliding message names: %s", strings.Join(collisions, "; "))
+	}
+	return renames, nil
+}
+
+// definesByName is the equivalent of protobuf3's definesByName()
+func (g *generator) definesByName(t *types.Named) bool {
+	ptr_t := types.NewPointer(t)
+	return !gosrc.IsTime(t) && !gosrc.IsDuration(t) && !(isCustom(ptr_t) && !gosrc.IsGenerated(ptr_t)) && !g.isAsProtobuf3er(ptr_t)
+}
+
+// checkWrapperNames is the equivalent of protobuf3's checkWrapperNames()
+func (g *generator) checkWrapperNames(ordered []*types.Named) error {
+	qualifier := func(pkg *types.Package) string { return pkg.Name() } // format the types like reflect does
+	names := make(map[string]*types.Named)
+	for _, t := range ordered {
+		if g.definesByName(t) {
+			names[g.messageName(t)] = t
+		}
+	}
+	collisions := make(map[string]bool)
+	for name, sp := range g.wrappers {
+		if t, ok := names[name]; ok {
+			for _, w := range sp.wrapped {
+				collisions[fmt.Sprintf("%s and the wrapper of %s are both named %s", types.TypeString(t, qualifier), types.TypeString(w, qualifier), name)] = true
+			}
+		}
+	}
+	if len(collisions) != 0 {
+		lines := make([]string, 0, len(collisions))
+		for c := range collisions {
+			lines = append(lines, c)
+		}
+		sort.Strings(lines)
+		return fmt.Errorf("protobuf3: colliding message names: %s", strings.Join(lines, "; "))
+	}
+	return nil
+}
+
+// messageName is the equivalent of protobuf3's messageName()
+func messageName(t *types.Named) string {
+	if t.TypeArgs().Len() != 0 {
+		return makeGenericTypeName(t)
+	}
+	return t.Obj().Name()
+}
+
+// makeGenericTypeName is the equivalent of MakeOfGenericTypeName
+func makeGenericTypeName(t types.Type) string {
+	switch t := t.(type) {
+	case *types.Named:
+		name := identifier(t.Obj().Name())
+		sep := "Of"
+		for i := 0; i < t.TypeArgs().Len(); i++ {
+			name += sep + makeGenericTypeName(t.TypeArgs().At(i))
+			sep = "And"
+		}
+		return name
+	case *types.Basic:
+		return identifier(types.Typ[t.Kind()].Name()) // reflect names byte and rune uint8 and int32
+	case *types.Pointer:
+		return "Ptr" + makeGenericTypeName(t.Elem())
+	case *types.Slice:
+		return "SliceOf" + makeGenericTypeName(t.Elem())
+	case *types.Array:
+		return fmt.Sprintf("Array%dOf%s", t.Len(), makeGenericTypeName(t.Elem()))
+	case *types.Map:
+		return "MapOf" + makeGenericTypeName(t.Key()) + "To" + makeGenericTypeName(t.Elem())
+	case *types.Chan:
+		return "Chan"
+	case *types.Signature:
+		return "Func"
+	case *types.Struct:
+		return "Struct"
+	case *types.Interface:
+		return "Interface"
+	}
+	if u := t.Underlying(); u != t {
+		return makeGenericTypeName(u) // an alias
+	}
+	return ""
+}
+
+// identifier is the equivalent of protobuf3's identifier()
+func identifier(n string) string {
+	buf := make([]byte, 0, len(n))
+	for _, r := range n {
+		if r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_') {
+			if len(buf) == 0 {
+				r = unicode.ToUpper(r)
+			}
+			buf = append(buf, byte(r))
+		}
+	}
+	return string(buf)
+}
+
+// namedProps returns the (cached) properties of named struct type t
+func (g *generator) namedProps(t *types.Named) (*structProps, error) {
+	if sp, ok := g.sprops[t]; ok {
+		return sp, nil
+	}
+	s, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return nil, fmt.Errorf("protogen: %s is not a struct", t)
+	}
+	sp, err := g.structProps(s, t.String())
+	if err != nil {
+		return nil, err
+	}
+	g.sprops[t] = sp
+	return sp, nil
+}
+
+// structProps builds the properties of the fields of s, the way getPropertiesLocked does
+func (g *generator) structProps(s *types.Struct, tname string) (*structProps, error) {
+	sp := new(structProps)
+	for i := 0; i < s.NumFields(); i++ {
+		f := s.Field(i)
+		name := f.Name()
+		stag := reflect.StructTag(s.Tag(i))
+		tag := stag.Get("protobuf")
+
+		if tag == "embedded" && f.Anonymous() {
+			es, ok := gosrc.EmbeddedStruct(f.Type())
+			if !ok {
+				return nil, fmt.Errorf("protogen: embedded field %s of %s is not a struct or a pointer to a struct", name, tname)
+			}
+			esp, err := g.structProps(es, f.Type().String())
+			if err != nil {
+				return nil, fmt.Errorf("protogen: error preparing field %q of type %q: %v", name, tname, err)
+			}
+			sp.props = append(sp.props, esp.props...)
+			sp.reserved = append(sp.reserved, esp.reserved...)
+			continue
+		}
+
+		if gosrc.IsReserved(f.Type()) {
+			ids, err := gosrc.ParseReserved(tag)
+			if err != nil {
+				return nil, fmt.Errorf("protogen: error parsing protobuf3.Reserved field %q of type %q: %v", name, tname, err)
+			}
+			sp.reserved = append(sp.reserved, ids...)
+			continue
+		}
+
+		if tag == "" {
+			if g.opts.XXXHack && strings.HasPrefix(name, "XXX_") {
+				continue
+			}
+			return nil, fmt.Errorf("protogen: %s.%s (%s) lacks a protobuf tag", tname, name, f.Type())
+		}
+		pt, skip, err := gosrc.ParseTag(tag)
+		if err != nil {
+			return nil, fmt.Errorf("protogen: error preparing field %q of type %q: %v", name, tname, err)
+		}
+		if skip {
+			continue
+		}
+
+		p := prop{
+			name:     name,
+			wire:     tag,
+			tag:      pt.ID,
+			optional: pt.HasOption("optional"),
+			doc:      g.docs[g.objKey(f)],
+			comment:  g.comments[g.objKey(f)],
+		}
+		if err := g.setTagType(&p, f.Type(), pt, stag); err != nil {
+			return nil, fmt.Errorf("protogen: error preparing field %q of type %q: %v", name, tname, err)
+		}
+		sp.props = append(sp.props, p)
+	}
+
+	// sort and de-dup the reserved ids
+	if len(sp.reserved) != 0 {
+		sort.Slice(sp.reserved, func(i, j int) bool { return sp.reserved[i] < sp.reserved[j] })
+		j := 0
+		for i, r := range sp.reserved {
+			if i == 0 || r != sp.reserved[j-1] {
+				sp.reserved[j] = r
+				j++
+			}
+		}
+		sp.reserved = sp.reserved[:j]
+	}
+
+	sort.SliceStable(sp.props, func(i, j int) bool { return sp.props[i].tag < sp.props[j].tag })
+	for i := range sp.props {
+		p := &sp.props[i]
+		if i > 0 && sp.props[i-1].tag == p.tag {
+			return nil, fmt.Errorf("protogen: error duplicate tag id %d assigned to %s.%s", p.tag, tname, p.name)
+		}
+		if j := sort.Search(len(sp.reserved), func(j int) bool { return sp.reserved[j] >= p.tag }); j < len(sp.reserved) && sp.reserved[j] == p.tag {
+			return nil, fmt.Errorf("protogen: error reserved tag id %d assigned to %s.%s", p.tag, tname, p.name)
+		}
+	}
+
+	return sp, nil
+}
+
+// the text of the protobuf integer types for each Go integer size, given the wiretype. Like in setEncAndDec(), a
+// combination which makes no sense is "".
+func intTexts(wire string) (int32_txt, uint32_txt, int64_txt, uint64_txt string) {
+	switch wire {
+	case "varint":
+		return "int32", "uint32", "int64", "uint64"
+	case "fixed32":
+		return "sfixed32", "fixed32", "", ""
+	case "fixed64":
+		return "", "", "sfixed64", "fixed64"
+	case "zigzag32":
+		return "sint32", "", "", ""
+	case "zigzag64":
+		return "", "", "sint64", ""
+	}
+	return "", "", "", ""
+}
+
+// setTagType sets p.asProtobuf and p.stype for a field of type t with tag pt, including any time or marshal format option
+func (g *generator) setTagType(p *prop, t types.Type, pt gosrc.Tag, stag reflect.StructTag) error {
+	tf, err := pt.TimeFormat()
+	if err != nil {
+		return err
+	}
+	if tf != "" {
+		if err := setTimeType(p, t, pt.Wire, tf); err != nil {
+			if t2, ok := gosrc.OptionListElem(t); ok {
+				// like setOptionListEncAndDec(), the option applies to the items of the lists
+				return g.setListType(p, t2, pt)
+			}
+			return err
+		}
+		return nil
+	}
+	mf, err := pt.MarshalFormat()
+	if err != nil {
+		return err
+	}
+	if mf != "" {
+		if err := setEncodingType(p, t, pt.Wire, mf); err != nil {
+			if t2, ok := gosrc.OptionListElem(t); ok {
+				return g.setListType(p, t2, pt)
+			}
+			return err
+		}
+		return nil
+	}
+	if gosrc.IsSet(t, pt, stag) {
+		// mirrors setSetEncAndDec(), which describes a set like a []K
+		elem, ok := gosrc.SetElem(t)
+		if !ok {
+			return fmt.Errorf("%s: the set option requires a map[K]struct{} or map[K]bool", t)
+		}
+		return g.setType(p, types.NewSlice(elem), pt.Wire, "")
+	}
+	return g.setType(p, t, pt.Wire, stag)
+}
+
+// setType sets p.asProtobuf and p.stype for a field of type t, mirroring setEncAndDec()
+func (g *generator) setType(p *prop, t types.Type, wire string, stag reflect.StructTag) error {
+	int32_txt, uint32_txt, int64_txt, uint64_txt := intTexts(wire)
+
+	// the protobuf type of the scalar type t, or ""
+	scalar := func(t types.Type) string {
+		b, ok := t.Underlying().(*types.Basic)
+		if !ok {
+			return ""
+		}
+		switch b.Kind() {
+		case types.Bool:
+			return "bool"
+		case types.Int, types.Int8, types.Int16, types.Int32:
+			return int32_txt
+		case types.Uint, types.Uint8, types.Uint16, types.Uint32:
+			return uint32_txt
+		case types.Int64:
+			if wire == "bytes" && gosrc.IsDuration(t) {
+				p.stype = t
+				return "google.protobuf.Duration"
+			}
+			return int64_txt
+		case types.Uint64:
+			return uint64_txt
+		case types.Float32:
+			return "float"
+		case types.Float64:
+			return "double"
+		case types.String:
+			return "string"
+		}
+		return ""
+	}
+	isScalar := func(t types.Type) bool {
+		b, ok := t.Underlying().(*types.Basic)
+		return ok && b.Info()&(types.IsBoolean|types.IsInteger|types.IsFloat|types.IsString) != 0 && b.Kind() != types.Uintptr
+	}
+	kind := func(t types.Type) types.BasicKind {
+		if b, ok := t.Underlying().(*types.Basic); ok {
+			return b.Kind()
+		}
+		return types.Invalid
+	}
+
+	if elem, ok := gosrc.OptionalElem(t); ok {
+		// like setOptionalEncAndDec(), an Optional[T] is a T which is always optional
+		if !gosrc.IsOptionalElem(elem) {
+			return fmt.Errorf("%s: Optional supports only scalar, string and []byte types", t)
+		}
+		p.optional = true
+		return g.setType(p, elem, wire, stag)
+	}
+	if _, repeated, ok := gosrc.NetElem(t); ok {
+		// like the codecs registered in net.go
+		if wire != "bytes" {
+			return fmt.Errorf("%s cannot have wiretype %s", t, wire)
+		}
+		p.asProtobuf = "bytes"
+		if repeated {
+			p.asProtobuf = "repeated bytes"
+		}
+		return nil
+	}
+
+	ptr_t := types.NewPointer(t)
+	if isCustom(ptr_t) {
+		p.custom = true
+		p.stype = t
+		return g.setStype(p, t, "")
+	}
+
+	switch u := t.Underlying().(type) {
+	default:
+		return fmt.Errorf("no encoder/decoder for type %s", t)
+
+	case *types.Basic:
+		if !isScalar(t) {
+			return fmt.Errorf("no encoder/decoder for type %s", t)
+		}
+		p.asProtobuf = scalar(t)
+
+	case *types.Struct:
+		if err := g.setStype(p, t, ""); err != nil {
+			return err
+		}
+
+	case *types.Pointer:
+		t2 := u.Elem()
+		if isCustom(t) {
+			p.custom = true
+			return g.setStype(p, t2, "")
+		}
+		if g.isAsProtobuf3er(t) {
+			p.stype = t2
+		}
+		switch {
+		case isScalar(t2):
+			p.asProtobuf = scalar(t2)
+		case isStruct(t2):
+			if err := g.setStype(p, t2, ""); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("no encoder function for %s", t)
+		}
+
+	case *types.Slice:
+		t2 := u.Elem()
+		if isCustom(types.NewPointer(t2)) {
+			p.custom = true
+			return g.setStype(p, t2, "repeated ")
+		}
+		if gosrc.IsByteArray(t2) {
+			p.asProtobuf = "repeated bytes"
+			break
+		}
+		if gosrc.IsList(t2) {
+			return g.setListType(p, t2, gosrc.Tag{Wire: wire})
+		}
+		switch {
+		case kind(t2) == types.Uint8:
+			p.asProtobuf = "bytes"
+		case kind(t2) == types.Uint64:
+			p.asProtobuf = "repeated " + int64_txt // this is what setEncAndDec does
+		case isScalar(t2):
+			p.asProtobuf = "repeated " + scalar(t2)
+		case isStruct(t2):
+			if err := g.setStype(p, t2, "repeated "); err != nil {
+				return err
+			}
+		default:
+			switch u2 := t2.Underlying().(type) {
+			case *types.Pointer:
+				if gosrc.IsByteArray(u2.Elem()) {
+					p.asProtobuf = "repeated bytes"
+					break
+				}
+				if !isStruct(u2.Elem()) {
+					return fmt.Errorf("no ptr encoder for %s", t)
+				}
+				p.custom = isCustom(t2)
+				if err := g.setStype(p, u2.Elem(), "repeated "); err != nil {
+					return err
+				}
+			case *types.Slice:
+				if kind(u2.Elem()) != types.Uint8 {
+					return fmt.Errorf("no slice elem encoder for %s", t)
+				}
+				p.asProtobuf = "repeated bytes"
+			default:
+				return fmt.Errorf("no slice encoder for %s", t)
+			}
+		}
+
+	case *types.Array:
+		if u.Len() == 0 {
+			break // a zero-length array always encodes as nothing
+		}
+		t2 := u.Elem()
+		if isCustom(types.NewPointer(t2)) {
+			p.custom = true
+			return g.setStype(p, t2, "repeated ")
+		}
+		if gosrc.IsByteArray(t2) {
+			p.asProtobuf = "repeated bytes"
+			break
+		}
+		if gosrc.IsList(t2) {
+			return g.setListType(p, t2, gosrc.Tag{Wire: wire})
+		}
+		switch {
+		case kind(t2) == types.Uint8:
+			p.asProtobuf = "bytes"
+		case isScalar(t2):
+			p.asProtobuf = "repeated " + scalar(t2)
+		case isStruct(t2):
+			if err := g.setStype(p, t2, "repeated "); err != nil {
+				return err
+			}
+		default:
+			u2, ok := t2.Underlying().(*types.Pointer)
+			if !ok || !isStruct(u2.Elem()) {
+				return fmt.Errorf("no array encoder for %s", t)
+			}
+			p.custom = isCustom(t2)
+			if err := g.setStype(p, u2.Elem(), "repeated "); err != nil {
+				return err
+			}
+		}
+
+	case *types.Map:
+		if wire != "bytes" {
+			return fmt.Errorf("map %s wiretype is not \"bytes\"", t)
+		}
+		key := prop{name: "Key"}
+		val := prop{name: "Value"}
+		for _, kv := range []struct {
+			p    *prop
+			tag  string
+			id   uint32
+			typ  types.Type
+			name string
+		}{
+			{&key, stag.Get("protobuf_key"), 1, u.Key(), "protobuf_key"},
+			{&val, stag.Get("protobuf_val"), 2, u.Elem(), "protobuf_val"},
+		} {
+			pt, skip, err := gosrc.ParseTag(kv.tag)
+			if err != nil || skip || pt.ID != kv.id {
+				return fmt.Errorf("bad %s tag %q", kv.name, kv.tag)
+			}
+			if mf, _ := pt.MarshalFormat(); mf != "" && kv.id == 1 {
+				return fmt.Errorf("%s tag cannot have option %s", kv.name, mf)
+			}
+			if kv.id == 2 && gosrc.IsMapWrapped(kv.typ) {
+				if err := g.setMapValueType(kv.p, kv.typ, pt, stag); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := g.setTagType(kv.p, kv.typ, pt, ""); err != nil {
+				return err
+			}
+		}
+		if gosrc.IsNetKey(u.Key()) {
+			// protobuf map keys cannot be bytes, so the netip types are strings
+			key.asProtobuf = "string"
+		}
+		// note that like setEncAndDec we don't set p.stype. the value type is discovered through p.mval
+		p.mkey, p.mval = &key, &val
+		p.asProtobuf = fmt.Sprintf("map<%s, %s>", key.asProtobuf, val.asProtobuf)
+	}
+
+	// if the type overrides the protobuf definition, use that instead
+	if g.isAsProtobuf3er(ptr_t) {
+		name, definition, _, err := g.asProtobuf3(ptr_t)
+		if err != nil {
+			return err
+		}
+		if name != "" {
+			p.asProtobuf = name
+		}
+		if definition != "" {
+			p.stype = t
+		}
+	}
+
+	return nil
+}
+
+// setTimeType sets p.asProtobuf for a time.Time field with time format option tf, mirroring setTimeEncAndDec()
+func setTimeType(p *prop, t types.Type, wire, tf string) error {
+	var prefix string
+	elem := t
+	switch u := t.(type) {
+	case *types.Pointer:
+		elem = u.Elem()
+	case *types.Slice:
+		elem = u.Elem()
+		prefix = "repeated "
+	}
+	if !gosrc.IsTime(elem) {
+		return fmt.Errorf("%s cannot have option %s, which only time.Time, *time.Time and []time.Time can have", t, tf)
+	}
+
+	if tf == "rfc3339" {
+		if wire != "bytes" {
+			return fmt.Errorf("time.Time with option %s cannot have wiretype %s", tf, wire)
+		}
+		p.asProtobuf = prefix + "string"
+		return nil
+	}
+	_, _, int64_txt, _ := intTexts(wire)
+	if int64_txt == "" {
+		return fmt.Errorf("time.Time with option %s cannot have wiretype %s", tf, wire)
+	}
+	p.asProtobuf = prefix + int64_txt
+	return nil
+}
+
+// setEncodingType sets p.asProtobuf for a field with marshal format option mf, mirroring setMarshalEncAndDec()
+func setEncodingType(p *prop, t types.Type, wire, mf string) error {
+	_, repeated, ok := gosrc.EncodingElem(t, mf)
+	if !ok {
+		return fmt.Errorf("%s with option %s does not implement the encoding interfaces", t, mf)
+	}
+	if wire != "bytes" {
+		return fmt.Errorf("%s cannot have wiretype %s", t, wire)
+	}
+	p.asProtobuf = "bytes"
+	if mf == "text" {
+		p.asProtobuf = "string"
+	}
+	if repeated {
+		p.asProtobuf = "repeated " + p.asProtobuf
+	}
+	return nil
+}
+
+// setListType sets p.asProtobuf for a slice or array of lists of type t2 in a field with protobuf tag pt, mirroring
+// setListEncAndDec(), and notes the definition of the wrapper message. p.stype is set to the type of the items, so
+// that it is discovered.
+func (g *generator) setListType(p *prop, t2 types.Type, pt gosrc.Tag) error {
+	// the items have the field's wiretype and options, and id 1
+	items := prop{name: "Items", wire: strings.Join(append([]string{pt.Wire, "1"}, pt.Options...), ","), tag: 1}
+	pt.ID = 1
+	if err := g.setTagType(&items, t2, pt, ""); err != nil {
+		return err
+	}
+	name, err := g.wrapper(t2, items)
+	if err != nil {
+		return err
+	}
+	p.stype = items.stype
+	p.custom = items.custom
+	p.asProtobuf = "repeated " + name
+	return nil
+}
+
+// setMapValueType sets p.asProtobuf for a map value of type t which is a list or a map, with protobuf_val tag pt
+// in struct tag stag, mirroring getMapValueWrapperLocked(), and notes the definition of the wrapper message.
+func (g *generator) setMapValueType(p *prop, t types.Type, pt gosrc.Tag, stag reflect.StructTag) error {
+	// the items have the value's wiretype and options, and id 1
+	items := prop{name: "Items", wire: strings.Join(append([]string{pt.Wire, "1"}, pt.Options...), ","), tag: 1}
+	pt.ID = 1
+	if err := g.setTagType(&items, t, pt, gosrc.MapValueTag(stag, t)); err != nil {
+		return err
+	}
+	name, err := g.wrapper(t, items)
+	if err != nil {
+		return err
+	}
+	p.stype = items.stype
+	p.custom = items.custom
+	p.mval = items.mval
+	p.asProtobuf = name
+	return nil
+}
+
+// wrapper notes the definition of the wrapper message of list or map type t, whose Items field is items, and
+// returns its name, which like getWrapperLocked() is made from the protobuf types of the items (or keys and values)
+func (g *generator) wrapper(t types.Type, items prop) (string, error) {
+	var parts []string
+	if items.mval != nil {
+		parts = []string{items.mkey.asProtobuf, items.mval.asProtobuf, "Map"}
+	} else {
+		parts = []string{strings.TrimPrefix(items.asProtobuf, "repeated "), "List"}
+	}
+	var name string
+	for _, part := range parts {
+		part = part[strings.LastIndexAny(part, ".\n")+1:]
+		if part == "" {
+			return "", fmt.Errorf("no protobuf name for the wrapper of %s", t)
+		}
+		r := []rune(part)
+		r[0] = unicode.ToUpper(r[0])
+		name += string(r)
+	}
+	sp := &structProps{props: []prop{items}, wrapped: []types.Type{t}}
+	if old, ok := g.wrappers[name]; ok {
+		sp.wrapped = append(old.wrapped, t)
+	}
+	g.wrappers[name] = sp
+	return name, nil
+}
+
+// setStype sets p.stype to t and p.asProtobuf to prefix + the name of t, like stypeAsProtobuf()
+func (g *generator) setStype(p *prop, t types.Type, prefix string) error {
+	p.stype = t
+	if !p.custom && gosrc.IsTime(t) {
+		p.asProtobuf = prefix + "google.protobuf.Timestamp"
+		return nil
+	}
+
+	var name string
+	if ptr_t := types.NewPointer(t); g.isAsProtobuf3er(ptr_t) {
+		var err error
+		name, _, _, err = g.asProtobuf3(ptr_t)
+		if err != nil {
+			return err
+		}
+	}
+	if name == "" {
+		name = g.makeTypeName(t, p.name)
+	}
+
+	if s, ok := t.(*types.Struct); ok {
+		// an anonymous struct. define it inline with the enclosing message
+		sp, err := g.structProps(s, "<anonymous struct>")
+		if err != nil {
+			return err
+		}
+		str := g.asProtobuf(sp, name) + "\n" + name
+		name = strings.Replace(str, "\n", "\n  ", -1)
+	}
+
+	p.asProtobuf = prefix + name
+	return nil
+}
+
+// isAsProtobuf3er returns true if t has an AsProtobuf3() method of either the current or the V1 signature
+func (g *generator) isAsProtobuf3er(t types.Type) bool {
+	return asProtobuf3Method(t) != nil
+}
+
+func asProtobuf3Method(t types.Type) *types.Func {
+	obj, _, _ := types.LookupFieldOrMethod(t, true, nil, "AsProtobuf3")
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return nil
+	}
+	sig := fn.Type().(*types.Signature)
+	if sig.Params().Len() != 0 || (sig.Results().Len() != 2 && sig.Results().Len() != 3) {
+		return nil
+	}
+	return fn
+}
+
+// asProtobuf3 evaluates t's AsProtobuf3() method, if it is simple enough
+func (g *generator) asProtobuf3(t types.Type) (name, definition string, imports []string, err error) {
+	fn := asProtobuf3Method(t)
+	src, ok := g.funcs[g.funcKey(fn)]
+	if !ok || src.decl.Body == nil || len(src.decl.Body.List) != 1 {
+		return "", "", nil, fmt.Errorf("protogen: cannot evaluate %s.AsProtobuf3() statically", deref(t))
+	}
+	ret, ok := src.decl.Body.List[0].(*ast.ReturnStmt)
+	if !ok || (len(ret.Results) != 2 && len(ret.Results) != 3) {
+		return "", "", nil, fmt.Errorf("protogen: cannot evaluate %s.AsProtobuf3() statically", deref(t))
+	}
+
+	str := func(e ast.Expr) (string, bool) {
+		tv, ok := src.info.Types[e]
+		if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+			return "", false
+		}
+		return constant.StringVal(tv.Value), true
+	}
+
+	var ok1, ok2 bool
+	name, ok1 = str(ret.Results[0])
+	definition, ok2 = str(ret.Results[1])
+	if !ok1 || !ok2 {
+		return "", "", nil, fmt.Errorf("protogen: cannot evaluate %s.AsProtobuf3() statically", deref(t))
+	}
+	if len(ret.Results) == 3 {
+		switch e := ret.Results[2].(type) {
+		case *ast.Ident:
+			if e.Name != "nil" {
+				return "", "", nil, fmt.Errorf("protogen: cannot evaluate %s.AsProtobuf3() statically", deref(t))
+			}
+		case *ast.CompositeLit:
+			for _, elt := range e.Elts {
+				imp, ok := str(elt)
+				if !ok {
+					return "", "", nil, fmt.Errorf("protogen: cannot evaluate %s.AsProtobuf3() statically", deref(t))
+				}
+				imports = append(imports, imp)
+			}
+		default:
+			return "", "", nil, fmt.Errorf("protogen: cannot evaluate %s.AsProtobuf3() statically", deref(t))
+		}
+	}
+	return name, definition, imports, nil
+}
+
+// isCustom returns true if t implements protobuf3.Appender or protobuf3.Marshaler
+func isCustom(t types.Type) bool {
+	return gosrc.IsAppender(t) || gosrc.IsMarshaler(t)
+}
+
+func isStruct(t types.Type) bool {
+	_, ok := t.Underlying().(*types.Struct)
+	return ok
+}
+
+func deref(t types.Type) types.Type {
+	for {
+		p, ok := t.(*types.Pointer)
+		if !ok {
+			return t
+		}
+		t = p.Elem()
+	}
+}
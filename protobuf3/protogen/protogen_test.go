@@ -0,0 +1,157 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package protogen_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mistsys/protobuf3/protobuf3"
+	"github.com/mistsys/protobuf3/protobuf3/internal/gosrc"
+	"github.com/mistsys/protobuf3/protobuf3/protogen"
+)
+
+// load types_test.go the way the reflect package sees it
+func load(t *testing.T) []*gosrc.Package {
+	pkg, err := gosrc.LoadFiles("github.com/mistsys/protobuf3/protobuf3/protogen_test", "types_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []*gosrc.Package{pkg}
+}
+
+// without comments the output must be identical to AsProtobufFull2's
+func TestGenerateMatchesReflect(t *testing.T) {
+	pkgs := load(t)
+	device, err := protogen.Lookup(pkgs, "Device")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := protogen.Lookup(pkgs, "Port")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	headers := []string{`option go_package = "protogen_test";`}
+	expected, err := protobuf3.AsProtobufFull2(reflect.TypeOf(Device{}), headers, reflect.TypeOf(Port{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := protogen.Generate(pkgs, protogen.Options{ExtraHeaders: headers, NoComments: true}, device, port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != expected {
+		t.Errorf("got\n%s\nexpected\n%s", got, expected)
+	}
+
+	// and the file options are the same as a Generator's
+	options := map[string]string{"java_package": "com.example.devices", "csharp_namespace": "Example.Devices"}
+	rg := protobuf3.Generator{Headers: headers, FileOptions: options}
+	expected, err = rg.AsProtobufFull(reflect.TypeOf(Device{}), reflect.TypeOf(Port{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = protogen.Generate(pkgs, protogen.Options{ExtraHeaders: headers, FileOptions: options, NoComments: true}, device, port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != expected {
+		t.Errorf("got\n%s\nexpected\n%s", got, expected)
+	}
+}
+
+// a wrapper named the same as a message is the same error as AsProtobufFull's
+func TestWrapperNameCollision(t *testing.T) {
+	pkgs := load(t)
+	device, err := protogen.Lookup(pkgs, "Device")
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := protogen.Lookup(pkgs, "PortList")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, expected := protobuf3.AsProtobufFull(reflect.TypeOf(Device{}), reflect.TypeOf(PortList{}))
+	if expected == nil || !strings.Contains(expected.Error(), "protogen_test.PortList and the wrapper of [2]protogen_test.Port are both named PortList") {
+		t.Fatalf("AsProtobufFull returned %v", expected)
+	}
+	_, err = protogen.Generate(pkgs, protogen.Options{}, device, list)
+	if err == nil || err.Error() != expected.Error() {
+		t.Errorf("Generate returned %v, expected %v", err, expected)
+	}
+}
+
+func TestGenerateComments(t *testing.T) {
+	pkgs := load(t)
+	device, err := protogen.Lookup(pkgs, "Device")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := protogen.Generate(pkgs, protogen.Options{}, device)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(got)
+
+	for _, want := range []string{
+		"\n// Device is a network device.\n//\n// It has a lot of fields.\nmessage Device {\n",
+		"\n  uint64 id = 1; // the unique id\n",
+		"\n  string site = 2; // where the device is\n",
+		"\n  // Name is the name of the device\n  string name = 10;\n",
+		"\n  float temp = 12; // degrees C\n",
+		"\n  // Location is where the device is\n  message Location {\n    double lat = 1; // latitude\n",
+		"\n  repeated PairOfSliceOfUint8AndArray2OfString pairs = 74;\n",
+		"\n// Page is a generic envelope\nmessage PageOfPtrPort {\n",
+		"\n  CollideConfig remote = 76;\n",
+		"\n// Link is only used as the value of maps, which AsProtobufFull discovers as well as fields\nmessage Link {\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output lacks %q", want)
+		}
+	}
+}
+
+func TestStructs(t *testing.T) {
+	pkgs := load(t)
+	var names []string
+	for _, n := range protogen.Structs(pkgs[0]) {
+		names = append(names, n.Obj().Name())
+	}
+	if strings.Join(names, " ") != "Base Config Device Header Link Port PortList" {
+		t.Errorf("Structs returned %v", names)
+	}
+}
@@ -0,0 +1,196 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package protogen_test
+
+// the types in this file are loaded from source by TestGenerate, so it must contain only types and their methods
+
+import (
+	"net"
+	"net/netip"
+	"net/url"
+	"time"
+
+	"github.com/mistsys/protobuf3/protobuf3"
+	"github.com/mistsys/protobuf3/protobuf3/internal/unit_tests/collide"
+)
+
+// Device is a network device.
+//
+// It has a lot of fields.
+type Device struct {
+	Base    `protobuf:"embedded"`
+	*Header `protobuf:"embedded"`
+
+	// Name is the name of the device
+	Name  string  `protobuf:"bytes,10"`
+	Model *string `protobuf:"bytes,11,optional"`
+	Temp  float32 `protobuf:"fixed32,12"` // degrees C
+	Load  float64 `protobuf:"fixed64,13"`
+	Flags uint32  `protobuf:"fixed32,14"`
+	Delta int64   `protobuf:"zigzag64,15"`
+	Small int8    `protobuf:"zigzag32,16"`
+	On    bool    `protobuf:"varint,17"`
+	Count *uint16 `protobuf:"varint,18"`
+
+	Seen    time.Time                       `protobuf:"bytes,20"`
+	PSeen   *time.Time                      `protobuf:"bytes,21"`
+	Uptime  time.Duration                   `protobuf:"bytes,22"`
+	Timeout time.Duration                   `protobuf:"varint,23"`
+	Periods []time.Duration                 `protobuf:"bytes,24"`
+	Born    time.Time                       `protobuf:"fixed64,25,unixnano"`
+	Stamps  []time.Time                     `protobuf:"bytes,26,rfc3339"`
+	PMilli  *time.Time                      `protobuf:"zigzag64,27,unixmilli"`
+	OptTemp protobuf3.Optional[float32]     `protobuf:"fixed32,28"`
+	OptName protobuf3.Optional[string]      `protobuf:"bytes,29"`
+	Addr    netip.Addr                      `protobuf:"bytes,43"`
+	Subnets []netip.Prefix                  `protobuf:"bytes,44"`
+	MACs    map[netip.Addr]net.HardwareAddr `protobuf:"bytes,45" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+	Gateway *net.IP                         `protobuf:"bytes,46"`
+	Home    *url.URL                        `protobuf:"bytes,47,binary"`
+	Ticks   []time.Time                     `protobuf:"bytes,48,text"`
+	Expires map[string]time.Time            `protobuf:"bytes,49" protobuf_key:"bytes,1" protobuf_val:"bytes,2,text"`
+
+	Ports    []Port                                `protobuf:"bytes,30"`
+	PPorts   []*Port                               `protobuf:"bytes,31"`
+	APorts   [2]Port                               `protobuf:"bytes,32"`
+	Counters map[string]int64                      `protobuf:"bytes,33" protobuf_key:"bytes,1" protobuf_val:"zigzag64,2"`
+	Links    map[uint32]*Link                      `protobuf:"bytes,34" protobuf_key:"varint,1" protobuf_val:"bytes,2"`
+	Temps    []int32                               `protobuf:"zigzag32,35"`
+	Uints    []uint64                              `protobuf:"varint,36"`
+	AUints   [3]uint64                             `protobuf:"varint,37"`
+	MAC      [6]byte                               `protobuf:"bytes,38"`
+	Blobs    [][]byte                              `protobuf:"bytes,39"`
+	Nothing  [0]int                                `protobuf:"varint,40"`
+	Strings  []string                              `protobuf:"bytes,41"`
+	Bools    [2]bool                               `protobuf:"varint,42"`
+	Matrix   [][]int32                             `protobuf:"zigzag32,56"`
+	PortsBy  [][2]Port                             `protobuf:"bytes,57"`
+	Cube     [2][][]string                         `protobuf:"bytes,58"`
+	Groups   map[string][]Port                     `protobuf:"bytes,59" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+	Nested   map[string]map[uint32][]int64         `protobuf:"bytes,62" protobuf_key:"bytes,1" protobuf_val:"bytes,2" protobuf_val_key:"varint,1" protobuf_val_val:"zigzag64,2"`
+	Deeper   map[int32]map[string]map[string]*Link `protobuf:"bytes,63" protobuf_key:"varint,1" protobuf_val:"bytes,2" protobuf_val_key:"bytes,1" protobuf_val_val:"bytes,2" protobuf_val_val_key:"bytes,1" protobuf_val_val_val:"bytes,2"`
+	Members  map[string]struct{}                   `protobuf:"bytes,64"`
+	Enabled  map[Port]bool                         `protobuf:"bytes,65,set"`
+	Vlans    map[uint16]struct{}                   `protobuf:"varint,66"`
+	Hashes   [][16]byte                            `protobuf:"bytes,67"`
+	Keys     [2][4]byte                            `protobuf:"bytes,68"`
+	Digests  []*[8]byte                            `protobuf:"bytes,69,resize"`
+	ByDigest map[string][][8]byte                  `protobuf:"bytes,70" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+	Page     Page[Port]                            `protobuf:"bytes,71"`
+	PPages   []*Page[*Port]                        `protobuf:"bytes,72"`
+	Pair     Pair[string, Page[Port]]              `protobuf:"bytes,73"`
+	Pairs    []Pair[[]byte, [2]string]             `protobuf:"bytes,74"`
+	Config   Config                                `protobuf:"bytes,75"`
+	Remote   *collide.Config                       `protobuf:"bytes,76"`
+	Remotes  [][]collide.Config                    `protobuf:"bytes,77"`
+	Epochs   [][]time.Time                         `protobuf:"varint,78,unixnano"`
+	Sites    [2][]url.URL                          `protobuf:"bytes,79,binary"`
+
+	// Location is where the device is
+	Location struct {
+		Lat float64 `protobuf:"fixed64,1"` // latitude
+		Lon float64 `protobuf:"fixed64,2"`
+	} `protobuf:"bytes,50"`
+
+	Renamed int      `protobuf:"varint,51,name=other"`
+	Custom  Custom   `protobuf:"bytes,52"`
+	PCustom *Custom  `protobuf:"bytes,53"`
+	Customs []Custom `protobuf:"bytes,54"`
+	Opaque  Opaque   `protobuf:"bytes,55"`
+	Ignored chan int `protobuf:"-"`
+
+	_ protobuf3.Reserved `protobuf:"60,61"`
+}
+
+// Base is embedded in Device
+type Base struct {
+	ID uint64 `protobuf:"varint,1"` // the unique id
+}
+
+// Header is embedded in Device by pointer
+type Header struct {
+	Site string `protobuf:"bytes,2"` // where the device is
+
+	_ protobuf3.Reserved `protobuf:"3"`
+}
+
+// Page is a generic envelope
+type Page[T any] struct {
+	Items []T    `protobuf:"bytes,1"`
+	Next  string `protobuf:"bytes,2"`
+}
+
+// Pair is a generic pair
+type Pair[K, V any] struct {
+	Key K `protobuf:"bytes,1"`
+	Val V `protobuf:"bytes,2"`
+}
+
+// Config has the same name as collide.Config
+type Config struct {
+	Host string `protobuf:"bytes,1"`
+}
+
+type Port struct {
+	Num int `protobuf:"varint,1"`
+}
+
+// PortList has the name of the wrapper of Device.PortsBy
+type PortList struct {
+	Items []Port `protobuf:"bytes,1"`
+}
+
+// Link is only used as the value of maps, which AsProtobufFull discovers as well as fields
+type Link struct {
+	Speed uint64 `protobuf:"varint,1"`
+}
+
+// Custom marshals itself and describes its own protobuf definition
+type Custom struct {
+	x uint32
+}
+
+func (c *Custom) AppendProtobuf3(buf []byte) ([]byte, error) { return append(buf, byte(c.x)), nil }
+func (c *Custom) UnmarshalProtobuf3(buf []byte) error        { return nil }
+func (*Custom) AsProtobuf3() (string, string, []string) {
+	return "CustomMsg", "message CustomMsg {\n  uint32 x = 1;\n}", []string{"custom.proto"}
+}
+
+// Opaque marshals itself but doesn't describe itself
+type Opaque struct {
+	x uint32
+}
+
+func (o *Opaque) MarshalProtobuf3() ([]byte, error)   { return []byte{byte(o.x)}, nil }
+func (o *Opaque) UnmarshalProtobuf3(buf []byte) error { return nil }
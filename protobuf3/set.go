@@ -0,0 +1,174 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package protobuf3
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"unsafe"
+)
+
+// Go sets are usually maps with empty values. A field of type map[K]struct{} which has no protobuf_key tag is a set,
+// and is encoded like a []K field with the same tag: as a repeated K, packed if K is numeric. A map[K]bool field
+// whose tag has the "set" option is a set too, of the keys whose values are true. Decoding adds the elements to the
+// map. (A map[K]struct{} field which does have protobuf_key and protobuf_val tags is encoded as a map, as it always
+// has been. A map type which marshals itself is never a set.)
+
+// a set field
+type setField struct {
+	elems *Properties  // the properties of a []K of the elements, with offset 0
+	slice reflect.Type // []K
+	mtype reflect.Type // the map type
+	bool  bool         // the map is a map[K]bool
+}
+
+// isSetType returns true if t is a map[K]struct{} or a map[K]bool
+func isSetType(t reflect.Type) bool {
+	if t.Kind() != reflect.Map {
+		return false
+	}
+	v := t.Elem()
+	return v.Kind() == reflect.Bool || (v.Kind() == reflect.Struct && v.NumField() == 0)
+}
+
+// isSet returns true if field f of type t is a set, either because of its tag's "set" option or because it is a
+// map[K]struct{} without a protobuf_key tag
+func (p *Properties) isSet(t reflect.Type, f *reflect.StructField) bool {
+	if p.setOption {
+		return true
+	}
+	return isSetType(t) && t.Elem().Kind() == reflect.Struct && f != nil && f.Tag.Get("protobuf_key") == ""
+}
+
+// setSetEncAndDec is the part of setEncAndDec which handles sets
+func (p *Properties) setSetEncAndDec(t1 reflect.Type, wire *WireType, f *reflect.StructField, name string, int_encoder IntEncoder) error {
+	if !isSetType(t1) {
+		return fmt.Errorf("protobuf3: %q %s: the set option requires a map[K]struct{} or map[K]bool", name, t1)
+	}
+
+	st := reflect.SliceOf(t1.Key())
+	v := *p // same tag, same everything
+	v.offset = 0
+	v.setOption = false
+	if err := v.setEncAndDec(st, f, name, int_encoder); err != nil {
+		return err
+	}
+	p.set = &setField{
+		elems: &v,
+		slice: st,
+		mtype: t1,
+		bool:  t1.Elem().Kind() == reflect.Bool,
+	}
+	p.enc = (*Buffer).enc_set
+	p.dec = (*Buffer).dec_set
+	p.asProtobuf = v.asProtobuf
+	p.stype = v.stype // in case K is a struct or a registered enum
+	*wire = v.WireType
+	return nil
+}
+
+// Encode a set as a repeated field of its elements
+func (o *Buffer) enc_set(p *Properties, base unsafe.Pointer) {
+	m := reflect.NewAt(p.set.mtype, unsafe.Pointer(uintptr(base)+p.offset)).Elem()
+	if m.Len() == 0 {
+		return
+	}
+
+	keys := make([]reflect.Value, 0, m.Len())
+	for i := m.MapRange(); i.Next(); {
+		if p.set.bool && !i.Value().Bool() {
+			continue // not a member of the set
+		}
+		keys = append(keys, i.Key())
+	}
+	if o.Deterministic {
+		sortKeys(keys)
+	}
+	elems := reflect.New(p.set.slice) // *[]K
+	elems.Elem().Set(reflect.Append(reflect.MakeSlice(p.set.slice, 0, len(keys)), keys...))
+
+	e := p.set.elems
+	e.enc(o, e, unsafe.Pointer(elems.Pointer()))
+}
+
+// Decode elements of a set, and add them to the map
+func (o *Buffer) dec_set(p *Properties, base unsafe.Pointer) error {
+	elems := reflect.New(p.set.slice) // *[]K
+	e := p.set.elems
+	if err := e.dec(o, e, unsafe.Pointer(elems.Pointer())); err != nil {
+		return err
+	}
+
+	m := reflect.NewAt(p.set.mtype, unsafe.Pointer(uintptr(base)+p.offset)).Elem()
+	if m.IsNil() {
+		m.Set(reflect.MakeMap(p.set.mtype))
+	}
+	member := reflect.Zero(p.set.mtype.Elem()) // struct{}{}
+	if p.set.bool {
+		member = reflect.ValueOf(true).Convert(p.set.mtype.Elem())
+	}
+	s := elems.Elem()
+	for i := 0; i < s.Len(); i++ {
+		if err := o.addMapEntry(m, s.Index(i), p.set.mtype.Key().Size()); err != nil {
+			return err
+		}
+		m.SetMapIndex(s.Index(i), member)
+	}
+	return nil
+}
+
+// sortKeys sorts map keys, so that Deterministic encoding has an order to follow
+func sortKeys(keys []reflect.Value) {
+	if len(keys) < 2 {
+		return
+	}
+	var less func(a, b reflect.Value) bool
+	switch keys[0].Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		less = func(a, b reflect.Value) bool { return a.Int() < b.Int() }
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		less = func(a, b reflect.Value) bool { return a.Uint() < b.Uint() }
+	case reflect.Float32, reflect.Float64:
+		less = func(a, b reflect.Value) bool { return a.Float() < b.Float() }
+	case reflect.String:
+		less = func(a, b reflect.Value) bool { return a.String() < b.String() }
+	case reflect.Bool:
+		less = func(a, b reflect.Value) bool { return !a.Bool() && b.Bool() }
+	default:
+		// arrays, structs and the like have no natural order, but their formatted values do
+		less = func(a, b reflect.Value) bool { return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface()) }
+	}
+	sort.Slice(keys, func(i, j int) bool { return less(keys[i], keys[j]) })
+}
@@ -0,0 +1,315 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+/*
+Package tagassign adds missing protobuf tags to the fields of Go structs.
+
+Picking the next tag id by hand in a big struct is error prone, since the ids used by
+`protobuf:"embedded"` structs and those listed in protobuf3.Reserved fields are easily
+overlooked. Assign finds the fields lacking a protobuf tag in every struct which has at least
+one protobuf tag, and tags each of them with the wiretype suited to the field's Go type and the
+next free id, which is one more than the highest id used or reserved by the struct (including its
+embedded structs). Gaps in the ids are never filled, since they may belong to deleted fields.
+Existing tags are left untouched.
+
+See cmd/protobuf3-tags for a command line wrapper.
+*/
+package tagassign
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/mistsys/protobuf3/protobuf3/internal/gosrc"
+)
+
+// XXXHack mirrors protobuf3.XXXHack: when set, untagged fields whose names start with XXX_ are left alone
+var XXXHack = false
+
+// Warning describes a field which Assign could not tag
+type Warning struct {
+	Pos     token.Position
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s", w.Pos, w.Message)
+}
+
+// Assign adds protobuf tags to the untagged fields of the tagged structs in file f, which must be one of pkg's files.
+// It returns the number of tags added, and warnings about any fields it could not tag. f is modified in place;
+// print it with go/format.
+func Assign(pkg *gosrc.Package, f *ast.File) (int, []Warning) {
+	a := assigner{pkg: pkg}
+	ast.Inspect(f, func(n ast.Node) bool {
+		if st, ok := n.(*ast.StructType); ok {
+			a.assignStruct(st)
+		}
+		return true
+	})
+	return a.added, a.warnings
+}
+
+type assigner struct {
+	pkg      *gosrc.Package
+	added    int
+	warnings []Warning
+}
+
+func (a *assigner) warnf(pos token.Pos, format string, args ...interface{}) {
+	a.warnings = append(a.warnings, Warning{
+		Pos:     a.pkg.Fset.Position(pos),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (a *assigner) assignStruct(st *ast.StructType) {
+	s, ok := a.pkg.Info.Types[st].Type.(*types.Struct)
+	if !ok {
+		return
+	}
+
+	// only structs which are already (partly) tagged are meant for protobuf
+	tagged := false
+	for i := 0; i < s.NumFields(); i++ {
+		if _, ok := reflect.StructTag(s.Tag(i)).Lookup("protobuf"); ok {
+			tagged = true
+			break
+		}
+	}
+	if !tagged {
+		return
+	}
+
+	used, reserved := gosrc.StructIDs(s)
+	var next uint32
+	for _, id := range append(used, reserved...) {
+		if next < id {
+			next = id
+		}
+	}
+
+	for _, field := range st.Fields.List {
+		var stag reflect.StructTag
+		if field.Tag != nil {
+			s, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				continue // can't happen in a type checked file
+			}
+			stag = reflect.StructTag(s)
+		}
+		if _, ok := stag.Lookup("protobuf"); ok {
+			continue
+		}
+
+		name := types.ExprString(field.Type) // embedded fields are named after their type
+		if len(field.Names) != 0 {
+			name = field.Names[0].Name
+		}
+		if len(field.Names) > 1 {
+			a.warnf(field.Pos(), "fields %s share one declaration and hence one tag. Declare them separately", joinNames(field.Names))
+			continue
+		}
+		if XXXHack && strings.HasPrefix(name, "XXX_") {
+			continue
+		}
+
+		typ := a.pkg.Info.TypeOf(field.Type)
+		if gosrc.IsReserved(typ) {
+			a.warnf(field.Pos(), "protobuf3.Reserved field lacks a list of reserved ids")
+			continue
+		}
+		wire, ok := Wire(typ)
+		if !ok {
+			a.warnf(field.Pos(), "%s has type %s, which protobuf3 cannot encode. Mark it `protobuf:\"-\"` if it isn't meant to be marshaled", name, types.TypeString(typ, types.RelativeTo(a.pkg.Types)))
+			continue
+		}
+
+		next++
+		tags := []string{fmt.Sprintf(`protobuf:"%s,%d"`, wire, next)}
+		// maps of maps also need protobuf_val_key and protobuf_val_val tags, and so on
+		ok = true
+		for prefix, t := "protobuf_", typ; ok; prefix += "val_" {
+			m, isMap := t.Underlying().(*types.Map)
+			if !isMap || gosrc.IsSet(t, gosrc.Tag{}, "") {
+				break // sets have no key and value tags
+			}
+			kwire, kok := Wire(m.Key())
+			vwire, vok := Wire(m.Elem())
+			if ok = kok && vok; ok {
+				tags = append(tags, fmt.Sprintf(`%skey:"%s,1"`, prefix, kwire), fmt.Sprintf(`%sval:"%s,2"`, prefix, vwire))
+			}
+			t = m.Elem()
+		}
+		if !ok {
+			next--
+			a.warnf(field.Pos(), "%s has map type %s, which protobuf3 cannot encode", name, types.TypeString(typ, types.RelativeTo(a.pkg.Types)))
+			continue
+		}
+
+		setTag(field, string(stag), strings.Join(tags, " "))
+		a.added++
+	}
+}
+
+// setTag appends the new tags to the existing struct tag of field
+func setTag(field *ast.Field, old, add string) {
+	tag := add
+	if old != "" {
+		tag = old + " " + add
+	}
+	var lit string
+	if strconv.CanBackquote(tag) {
+		lit = "`" + tag + "`"
+	} else {
+		lit = strconv.Quote(tag)
+	}
+	if field.Tag == nil {
+		field.Tag = &ast.BasicLit{
+			ValuePos: field.Type.End(),
+			Kind:     token.STRING,
+		}
+	}
+	field.Tag.Value = lit
+}
+
+func joinNames(names []*ast.Ident) string {
+	s := make([]string, len(names))
+	for i, n := range names {
+		s[i] = n.Name
+	}
+	return strings.Join(s, ", ")
+}
+
+// Wire returns the wiretype a field of type t would normally be tagged with, and false if protobuf3 can't encode t.
+// Signed integers are tagged "varint" like the rest of the integers; switch them to "zigzag32" or "zigzag64" by hand
+// if they are often negative.
+func Wire(t types.Type) (string, bool) {
+	if gosrc.IsAppender(types.NewPointer(t)) || gosrc.IsMarshaler(types.NewPointer(t)) ||
+		gosrc.IsAppender(t) || gosrc.IsMarshaler(t) {
+		return "bytes", true
+	}
+	if gosrc.IsDuration(t) {
+		return "bytes", true
+	}
+	if elem, ok := gosrc.OptionalElem(t); ok {
+		if !gosrc.IsOptionalElem(elem) {
+			return "", false
+		}
+		return Wire(elem)
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		return basicWire(u)
+	case *types.Struct:
+		return "bytes", true
+	case *types.Map:
+		if gosrc.IsSet(t, gosrc.Tag{}, "") {
+			// a map[K]struct{} is a set, encoded like a []K
+			elem, _ := gosrc.SetElem(t)
+			return elemWire(elem, true)
+		}
+		return "bytes", true
+	case *types.Pointer:
+		switch eu := u.Elem().Underlying().(type) {
+		case *types.Basic:
+			if gosrc.IsDuration(u.Elem()) {
+				return "bytes", true
+			}
+			return basicWire(eu)
+		case *types.Struct:
+			return "bytes", true
+		}
+	case *types.Slice:
+		return elemWire(u.Elem(), true)
+	case *types.Array:
+		return elemWire(u.Elem(), false)
+	}
+	return "", false
+}
+
+// wiretype of the elements of a slice or array
+func elemWire(elem types.Type, slice bool) (string, bool) {
+	if gosrc.IsAppender(types.NewPointer(elem)) || gosrc.IsMarshaler(types.NewPointer(elem)) || gosrc.IsDuration(elem) {
+		return "bytes", true
+	}
+	switch eu := elem.Underlying().(type) {
+	case *types.Basic:
+		if eu.Kind() == types.Uint8 {
+			return "bytes", true // []byte and [N]byte
+		}
+		return basicWire(eu)
+	case *types.Struct:
+		return "bytes", true
+	case *types.Pointer:
+		if _, ok := eu.Elem().Underlying().(*types.Struct); ok || gosrc.IsByteArray(eu.Elem()) {
+			return "bytes", true
+		}
+	case *types.Slice:
+		if b, ok := eu.Elem().Underlying().(*types.Basic); ok && b.Kind() == types.Uint8 && slice {
+			return "bytes", true // [][]byte
+		}
+		if gosrc.IsList(elem) {
+			return Wire(elem) // the wiretype of the items of the inner lists
+		}
+	case *types.Array:
+		if gosrc.IsByteArray(elem) {
+			return "bytes", true // [][N]byte and [M][N]byte
+		}
+		if gosrc.IsList(elem) {
+			return Wire(elem)
+		}
+	}
+	return "", false
+}
+
+func basicWire(b *types.Basic) (string, bool) {
+	switch b.Kind() {
+	case types.Bool,
+		types.Int, types.Int8, types.Int16, types.Int32, types.Int64,
+		types.Uint, types.Uint8, types.Uint16, types.Uint32, types.Uint64:
+		return "varint", true
+	case types.Float32:
+		return "fixed32", true
+	case types.Float64:
+		return "fixed64", true
+	case types.String:
+		return "bytes", true
+	}
+	return "", false
+}
@@ -0,0 +1,88 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package tagassign_test
+
+import (
+	"bytes"
+	"go/format"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mistsys/protobuf3/protobuf3/internal/gosrc"
+	"github.com/mistsys/protobuf3/protobuf3/tagassign"
+)
+
+func TestAssign(t *testing.T) {
+	pkg, err := gosrc.LoadFiles("untagged", "testdata/untagged.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, warnings := tagassign.Assign(pkg, pkg.Files[0])
+	if n != 15 {
+		t.Errorf("added %d tags, expected 15", n)
+	}
+	if len(warnings) != 2 {
+		t.Errorf("expected 2 warnings, got %v", warnings)
+	} else {
+		if !strings.Contains(warnings[0].Message, "Bad has type func()") {
+			t.Errorf("unexpected warning %s", warnings[0])
+		}
+		if !strings.Contains(warnings[1].Message, "fields A, B share one declaration") {
+			t.Errorf("unexpected warning %s", warnings[1])
+		}
+	}
+
+	var got bytes.Buffer
+	if err := format.Node(&got, pkg.Fset, pkg.Files[0]); err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile("testdata/untagged.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != string(want) {
+		t.Errorf("got\n%s\nexpected\n%s", got.Bytes(), want)
+	}
+
+	// running it again on the result ought to change nothing
+	pkg, err = gosrc.LoadFiles("untagged", "testdata/untagged.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := tagassign.Assign(pkg, pkg.Files[0]); n != 0 {
+		t.Errorf("added %d tags to already tagged source", n)
+	}
+}
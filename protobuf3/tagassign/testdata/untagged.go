@@ -0,0 +1,50 @@
+package untagged
+
+import (
+	"time"
+
+	"github.com/mistsys/protobuf3/protobuf3"
+)
+
+type Base struct {
+	ID   uint64 `protobuf:"varint,1"`
+	Name string `protobuf:"bytes,2"`
+
+	_ protobuf3.Reserved `protobuf:"20"`
+}
+
+type Device struct {
+	Base `protobuf:"embedded"`
+
+	Model string `protobuf:"bytes,10"`
+	Seen  time.Time
+
+	// documented fields keep their comments
+	Temp    float32 `json:"temp"`
+	Load    []float64
+	Uptime  time.Duration // trailing comment
+	Ports   map[string]*Port
+	Flags   []bool
+	MAC     [6]byte
+	Parent  *Device
+	Blobs   [][]byte
+	Limit   protobuf3.Optional[int32]
+	Matrix  [][]float32
+	Counts  map[string]map[string][]int
+	Members map[int32]struct{}
+	Hashes  [][32]byte
+	Ignored chan int `protobuf:"-"`
+	Bad     func()
+	A, B    int
+
+	_ protobuf3.Reserved `protobuf:"11,30"`
+}
+
+type Port struct {
+	Num   int `protobuf:"varint,1"`
+	Speed int
+}
+
+type NotProtobuf struct {
+	X int
+}
@@ -0,0 +1,493 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+/*
+Package tagcheck statically checks the protobuf, protobuf_key and protobuf_val struct tags of Go types.
+
+protobuf3 only discovers mistakes in the tags the first time GetProperties() is called on a type, which is
+often at runtime in production. tagcheck finds the same mistakes from the source code, so they can be caught
+by CI or a vet-like step before the code is ever run. It reports
+
+  - unknown wiretypes and invalid tag ids
+  - wiretypes which don't suit the Go type of the field (ex. a float32 which isn't fixed32)
+  - fields whose Go type protobuf3 has no encoder for
+  - duplicate tag ids, and tag ids which are reserved, including those of fields merged in from `protobuf:"embedded"` structs
+  - fields which lack a protobuf tag, in structs which have at least one protobuf tag
+  - map fields whose protobuf_key and protobuf_val tags are missing, or don't use ids 1 and 2, including the
+    protobuf_val_key and protobuf_val_val tags of maps of maps
+
+See cmd/protobuf3-vet for a command line wrapper.
+*/
+package tagcheck
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/mistsys/protobuf3/protobuf3/internal/gosrc"
+)
+
+// XXXHack mirrors protobuf3.XXXHack: when set, untagged fields whose names start with XXX_ are ignored
+var XXXHack = false
+
+// Diagnostic is a problem found in a protobuf struct tag
+type Diagnostic struct {
+	Pos     token.Position
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s", d.Pos, d.Message)
+}
+
+// CheckPackages loads the packages matching patterns (as accepted by the go command) and checks them
+func CheckPackages(patterns ...string) ([]Diagnostic, error) {
+	pkgs, err := gosrc.Load(patterns...)
+	if err != nil {
+		return nil, err
+	}
+	var diags []Diagnostic
+	for _, pkg := range pkgs {
+		diags = append(diags, Check(pkg)...)
+	}
+	return diags, nil
+}
+
+// Check checks every struct type, named or anonymous, declared in pkg. The diagnostics are returned in source order.
+func Check(pkg *gosrc.Package) []Diagnostic {
+	c := checker{
+		pkg: pkg,
+	}
+	for _, f := range pkg.Files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			if st, ok := n.(*ast.StructType); ok {
+				if s, ok := pkg.Info.Types[st].Type.(*types.Struct); ok {
+					c.checkStruct(s)
+				}
+			}
+			return true
+		})
+	}
+	sort.SliceStable(c.diags, func(i, j int) bool {
+		a, b := c.diags[i].Pos, c.diags[j].Pos
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Offset < b.Offset
+	})
+	return c.diags
+}
+
+type checker struct {
+	pkg   *gosrc.Package
+	diags []Diagnostic
+}
+
+func (c *checker) errorf(pos token.Pos, format string, args ...interface{}) {
+	c.diags = append(c.diags, Diagnostic{
+		Pos:     c.pkg.Fset.Position(pos),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// a tag id used by a field, possibly one merged in from an embedded struct
+type use struct {
+	id   uint32
+	name string
+	pos  token.Pos
+}
+
+// isTagged returns true if any field of s has a protobuf tag. Untagged structs aren't meant for protobuf and are ignored.
+func isTagged(s *types.Struct) bool {
+	for i := 0; i < s.NumFields(); i++ {
+		if _, ok := reflect.StructTag(s.Tag(i)).Lookup("protobuf"); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// check the fields of one struct
+func (c *checker) checkStruct(s *types.Struct) {
+	if !isTagged(s) {
+		return
+	}
+
+	var uses []use
+	reserved := make(map[uint32]bool)
+	for i := 0; i < s.NumFields(); i++ {
+		f := s.Field(i)
+		stag := reflect.StructTag(s.Tag(i))
+		tag := stag.Get("protobuf")
+
+		if tag == "embedded" && f.Anonymous() {
+			embedded, ok := gosrc.EmbeddedStruct(f.Type())
+			if !ok {
+				c.errorf(f.Pos(), "embedded field %s must be a struct or a pointer to a struct, not %s", f.Name(), c.typeString(f.Type()))
+				continue
+			}
+			// errors inside the embedded struct are reported where it is declared. here we only need its tag ids
+			for _, u := range fieldIDs(embedded) {
+				uses = append(uses, use{u.id, f.Name() + "." + u.name, f.Pos()})
+			}
+			_, ids := gosrc.StructIDs(embedded)
+			for _, id := range ids {
+				reserved[id] = true
+			}
+			continue
+		}
+
+		if gosrc.IsReserved(f.Type()) {
+			ids, err := gosrc.ParseReserved(tag)
+			if err != nil {
+				c.errorf(f.Pos(), "protobuf3.Reserved field %s: %v", f.Name(), err)
+			}
+			for _, id := range ids {
+				reserved[id] = true
+			}
+			continue
+		}
+
+		if tag == "" {
+			if XXXHack && strings.HasPrefix(f.Name(), "XXX_") {
+				continue
+			}
+			c.errorf(f.Pos(), "field %s (%s) lacks a protobuf tag. Tag it, or mark it with `protobuf:\"-\"` if it isn't intended to be marshaled to/from protobuf", f.Name(), c.typeString(f.Type()))
+			continue
+		}
+
+		t, skip, err := gosrc.ParseTag(tag)
+		if err != nil {
+			c.errorf(f.Pos(), "field %s: %v", f.Name(), err)
+			continue
+		}
+		if skip {
+			continue
+		}
+		uses = append(uses, use{t.ID, f.Name(), f.Pos()})
+
+		if msg := c.checkTagType(f.Type(), t, stag, true); msg != "" {
+			c.errorf(f.Pos(), "field %s: %s", f.Name(), msg)
+		}
+	}
+
+	// look for duplicate and reserved ids the same way getPropertiesLocked does
+	sort.SliceStable(uses, func(i, j int) bool { return uses[i].id < uses[j].id })
+	for i, u := range uses {
+		if i > 0 && uses[i-1].id == u.id {
+			c.errorf(u.pos, "duplicate tag id %d assigned to %s, already used by %s", u.id, u.name, uses[i-1].name)
+		} else if reserved[u.id] {
+			c.errorf(u.pos, "reserved tag id %d assigned to %s", u.id, u.name)
+		}
+	}
+}
+
+// fieldIDs returns the tag ids used by the fields of s, including those of embedded structs. Fields with bad tags are ignored.
+func fieldIDs(s *types.Struct) []use {
+	var uses []use
+	for i := 0; i < s.NumFields(); i++ {
+		f := s.Field(i)
+		tag := reflect.StructTag(s.Tag(i)).Get("protobuf")
+		if tag == "embedded" && f.Anonymous() {
+			if embedded, ok := gosrc.EmbeddedStruct(f.Type()); ok {
+				for _, u := range fieldIDs(embedded) {
+					uses = append(uses, use{u.id, f.Name() + "." + u.name, f.Pos()})
+				}
+			}
+			continue
+		}
+		if gosrc.IsReserved(f.Type()) {
+			continue
+		}
+		if t, skip, err := gosrc.ParseTag(tag); err == nil && !skip {
+			uses = append(uses, use{t.ID, f.Name(), f.Pos()})
+		}
+	}
+	return uses
+}
+
+// checkTagType checks that type t can be encoded as tag says, including any time or marshal format option
+func (c *checker) checkTagType(t types.Type, tag gosrc.Tag, stag reflect.StructTag, top bool) string {
+	mf, err := tag.MarshalFormat()
+	if err != nil {
+		return err.Error()
+	}
+	if mf != "" {
+		return c.checkEncoding(t, tag.Wire, mf)
+	}
+	if elem, ok := gosrc.OptionalElem(t); ok {
+		// mirrors setOptionalEncAndDec()
+		if !gosrc.IsOptionalElem(elem) {
+			return fmt.Sprintf("%s: Optional supports only scalar, string and []byte types", c.typeString(t))
+		}
+		t = elem
+	}
+	tf, err := tag.TimeFormat()
+	if err != nil {
+		return err.Error()
+	}
+	if tf != "" {
+		return c.checkTime(t, tag.Wire, tf)
+	}
+	if top && gosrc.IsSet(t, tag, stag) {
+		// mirrors setSetEncAndDec(), which encodes the elements like a []K
+		elem, ok := gosrc.SetElem(t)
+		if !ok {
+			return fmt.Sprintf("%s: the set option requires a map[K]struct{} or map[K]bool", c.typeString(t))
+		}
+		return c.checkType(types.NewSlice(elem), tag.Wire, "", false)
+	}
+	return c.checkType(t, tag.Wire, stag, top)
+}
+
+// checkTime checks a time.Time field with an alternate encoding, mirroring setTimeEncAndDec()
+func (c *checker) checkTime(t types.Type, wire, tf string) string {
+	elem := t
+	switch u := t.(type) {
+	case *types.Pointer:
+		elem = u.Elem()
+	case *types.Slice:
+		elem = u.Elem()
+	}
+	if !gosrc.IsTime(elem) {
+		return fmt.Sprintf("%s cannot have option %s, which only time.Time, *time.Time and []time.Time can have", c.typeString(t), tf)
+	}
+	switch {
+	case tf == "rfc3339" && wire != "bytes",
+		tf != "rfc3339" && wire != "varint" && wire != "fixed64" && wire != "zigzag64":
+		return fmt.Sprintf("time.Time with option %s cannot have wiretype %s", tf, wire)
+	}
+	return ""
+}
+
+// checkEncoding checks a field encoded through the encoding package's interfaces, mirroring setMarshalEncAndDec()
+func (c *checker) checkEncoding(t types.Type, wire, mf string) string {
+	if _, _, ok := gosrc.EncodingElem(t, mf); !ok {
+		iface := "Binary"
+		if mf == "text" {
+			iface = "Text"
+		}
+		return fmt.Sprintf("%s with option %s does not implement encoding.%sMarshaler and encoding.%sUnmarshaler", c.typeString(t), mf, iface, iface)
+	}
+	return c.needBytes(t, wire)
+}
+
+// checkType checks that type t can be encoded with wiretype wire, mirroring the rules of protobuf3's setEncAndDec().
+// It returns a description of the problem, or "". stag is the complete struct tag, needed by maps.
+// top is true for a field's own type and for map values which are maps, and false for the other key and value
+// types of a map.
+func (c *checker) checkType(t types.Type, wire string, stag reflect.StructTag, top bool) string {
+	if _, _, ok := gosrc.NetElem(t); ok {
+		// the net types are encoded by the codecs in net.go
+		return c.needBytes(t, wire)
+	}
+	if gosrc.IsAppender(types.NewPointer(t)) || gosrc.IsMarshaler(types.NewPointer(t)) {
+		return ""
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		return c.checkScalar(t, u, wire)
+
+	case *types.Struct:
+		return c.needBytes(t, wire)
+
+	case *types.Pointer:
+		if gosrc.IsAppender(t) || gosrc.IsMarshaler(t) {
+			return ""
+		}
+		switch eu := u.Elem().Underlying().(type) {
+		case *types.Basic:
+			return c.checkScalar(u.Elem(), eu, wire)
+		case *types.Struct:
+			return c.needBytes(t, wire)
+		}
+
+	case *types.Slice:
+		if elem := u.Elem(); gosrc.IsAppender(types.NewPointer(elem)) || gosrc.IsMarshaler(types.NewPointer(elem)) {
+			return ""
+		}
+		if gosrc.IsByteArray(u.Elem()) {
+			return c.needBytes(t, wire) // [][N]byte
+		}
+		if gosrc.IsList(u.Elem()) {
+			// each inner list is encoded in a wrapper message, mirroring setListEncAndDec()
+			return c.checkType(u.Elem(), wire, "", false)
+		}
+		switch eu := u.Elem().Underlying().(type) {
+		case *types.Basic:
+			if eu.Kind() == types.Uint8 {
+				return "" // []byte can have any wiretype
+			}
+			return c.checkScalar(u.Elem(), eu, wire)
+		case *types.Struct:
+			return c.needBytes(t, wire)
+		case *types.Pointer:
+			if _, ok := eu.Elem().Underlying().(*types.Struct); ok || gosrc.IsByteArray(eu.Elem()) {
+				return c.needBytes(t, wire)
+			}
+		case *types.Slice:
+			if b, ok := eu.Elem().Underlying().(*types.Basic); ok && b.Kind() == types.Uint8 {
+				return "" // [][]byte
+			}
+		}
+
+	case *types.Array:
+		if u.Len() == 0 {
+			return "" // always encodes as nothing
+		}
+		if elem := u.Elem(); gosrc.IsAppender(types.NewPointer(elem)) || gosrc.IsMarshaler(types.NewPointer(elem)) {
+			return ""
+		}
+		if gosrc.IsByteArray(u.Elem()) {
+			return c.needBytes(t, wire) // [M][N]byte
+		}
+		if gosrc.IsList(u.Elem()) {
+			return c.checkType(u.Elem(), wire, "", false)
+		}
+		switch eu := u.Elem().Underlying().(type) {
+		case *types.Basic:
+			if eu.Kind() == types.Uint8 {
+				return "" // [N]byte can have any wiretype
+			}
+			return c.checkScalar(u.Elem(), eu, wire)
+		case *types.Struct:
+			return c.needBytes(t, wire)
+		case *types.Pointer:
+			if _, ok := eu.Elem().Underlying().(*types.Struct); ok {
+				return c.needBytes(t, wire)
+			}
+		}
+
+	case *types.Map:
+		if !top {
+			break
+		}
+		return c.checkMap(t, u, wire, stag)
+	}
+
+	return fmt.Sprintf("no encoder for type %s", c.typeString(t))
+}
+
+// check a map's wiretype and its protobuf_key and protobuf_val tags
+func (c *checker) checkMap(t types.Type, m *types.Map, wire string, stag reflect.StructTag) string {
+	if wire != "bytes" {
+		return fmt.Sprintf("map %s wiretype is not \"bytes\"", c.typeString(t))
+	}
+	var msgs []string
+	for _, kv := range []struct {
+		name string
+		id   uint32
+		typ  types.Type
+	}{
+		{"protobuf_key", 1, m.Key()},
+		{"protobuf_val", 2, m.Elem()},
+	} {
+		tag := stag.Get(kv.name)
+		if tag == "" {
+			msgs = append(msgs, fmt.Sprintf("lacks a %s tag", kv.name))
+			continue
+		}
+		t, skip, err := gosrc.ParseTag(tag)
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("%s %v", kv.name, err))
+			continue
+		}
+		if skip {
+			msgs = append(msgs, fmt.Sprintf("%s tag cannot be \"-\"", kv.name))
+			continue
+		}
+		if t.ID != kv.id {
+			msgs = append(msgs, fmt.Sprintf("%s tag (%s) doesn't use id %d", kv.name, tag, kv.id))
+		}
+		if mf, _ := t.MarshalFormat(); mf != "" && kv.id == 1 {
+			msgs = append(msgs, fmt.Sprintf("%s tag cannot have option %s", kv.name, mf))
+			continue
+		}
+		vtag, top := reflect.StructTag(""), false
+		if _, ok := kv.typ.Underlying().(*types.Map); ok && kv.id == 2 {
+			// a map value which is a map is encoded in a wrapper message, and its tags come from protobuf_val_... tags
+			vtag, top = gosrc.MapValueTag(stag, kv.typ), true
+		}
+		if msg := c.checkTagType(kv.typ, t, vtag, top); msg != "" {
+			msgs = append(msgs, fmt.Sprintf("%s: %s", kv.name, msg))
+		}
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// check the wiretype of a scalar type
+func (c *checker) checkScalar(t types.Type, b *types.Basic, wire string) string {
+	switch b.Kind() {
+	case types.Bool,
+		types.Int, types.Int8, types.Int16, types.Int32, types.Int64,
+		types.Uint, types.Uint8, types.Uint16, types.Uint32, types.Uint64:
+		if wire == "bytes" && !gosrc.IsDuration(t) {
+			return c.badWire(t, wire)
+		}
+	case types.Float32:
+		if wire != "fixed32" {
+			return c.badWire(t, wire)
+		}
+	case types.Float64:
+		if wire != "fixed64" {
+			return c.badWire(t, wire)
+		}
+	case types.String:
+		return c.needBytes(t, wire)
+	default:
+		return fmt.Sprintf("no encoder for type %s", c.typeString(t))
+	}
+	return ""
+}
+
+func (c *checker) needBytes(t types.Type, wire string) string {
+	if wire != "bytes" {
+		return c.badWire(t, wire)
+	}
+	return ""
+}
+
+func (c *checker) badWire(t types.Type, wire string) string {
+	return fmt.Sprintf("%s cannot have wiretype %s", c.typeString(t), wire)
+}
+
+// format t relative to the package being checked
+func (c *checker) typeString(t types.Type) string {
+	return types.TypeString(t, types.RelativeTo(c.pkg.Types))
+}
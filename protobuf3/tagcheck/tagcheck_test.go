@@ -0,0 +1,101 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package tagcheck_test
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mistsys/protobuf3/protobuf3/internal/gosrc"
+	"github.com/mistsys/protobuf3/protobuf3/tagcheck"
+)
+
+// TestCheck checks that the diagnostics reported for testdata/bad.go are exactly those expected by
+// the `// want` comments in the file
+func TestCheck(t *testing.T) {
+	pkg, err := gosrc.LoadFiles("bad", "testdata/bad.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// gather the expected diagnostics by line number
+	want := make(map[int]*regexp.Regexp)
+	for _, f := range pkg.Files {
+		for _, cg := range f.Comments {
+			for _, c := range cg.List {
+				text := strings.TrimPrefix(c.Text, "//")
+				text = strings.TrimSpace(text)
+				if !strings.HasPrefix(text, "want ") {
+					continue
+				}
+				pat, err := strconv.Unquote(strings.TrimSpace(text[5:]))
+				if err != nil {
+					t.Fatalf("%s: bad want comment: %v", pkg.Fset.Position(c.Pos()), err)
+				}
+				want[pkg.Fset.Position(c.Pos()).Line] = regexp.MustCompile(pat)
+			}
+		}
+	}
+	if len(want) == 0 {
+		t.Fatal("no want comments found")
+	}
+
+	for _, d := range tagcheck.Check(pkg) {
+		re, ok := want[d.Pos.Line]
+		if !ok {
+			t.Errorf("unexpected diagnostic %s", d)
+			continue
+		}
+		if !re.MatchString(d.Message) {
+			t.Errorf("diagnostic %s doesn't match %q", d, re)
+		}
+		delete(want, d.Pos.Line)
+	}
+	for line, re := range want {
+		t.Errorf("testdata/bad.go:%d: missing diagnostic matching %q", line, re)
+	}
+}
+
+// TestCheckSelf checks protobuf3's own package, which ought to be clean
+func TestCheckSelf(t *testing.T) {
+	diags, err := tagcheck.CheckPackages("github.com/mistsys/protobuf3/protobuf3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range diags {
+		t.Error(d)
+	}
+}
@@ -0,0 +1,115 @@
+package bad
+
+import (
+	"math/big"
+	"net"
+	"net/netip"
+	"net/url"
+	"time"
+
+	"github.com/mistsys/protobuf3/protobuf3"
+)
+
+type Good struct {
+	I      int32                       `protobuf:"zigzag32,1"`
+	F      float32                     `protobuf:"fixed32,2"`
+	D      float64                     `protobuf:"fixed64,3"`
+	S      string                      `protobuf:"bytes,4"`
+	B      []byte                      `protobuf:"varint,5"`
+	T      time.Time                   `protobuf:"bytes,6"`
+	Dur    time.Duration               `protobuf:"bytes,7"`
+	M      map[string]*Inner           `protobuf:"bytes,8" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+	Nano   time.Time                   `protobuf:"fixed64,9,unixnano"`
+	Strs   []time.Time                 `protobuf:"bytes,11,rfc3339"`
+	Opt    protobuf3.Optional[int64]   `protobuf:"zigzag64,12"`
+	OptB   protobuf3.Optional[[]byte]  `protobuf:"bytes,13"`
+	Addr   netip.Addr                  `protobuf:"bytes,14"`
+	IPs    []net.IP                    `protobuf:"bytes,15"`
+	Route  map[netip.Prefix]string     `protobuf:"bytes,16" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+	URL    *url.URL                    `protobuf:"bytes,17,binary"`
+	Stamps []time.Time                 `protobuf:"bytes,18,text"`
+	Limits map[string]*big.Int         `protobuf:"bytes,19" protobuf_key:"bytes,1" protobuf_val:"bytes,2,text"`
+	Matrix [][3]int32                  `protobuf:"zigzag32,22"`
+	Words  [2][][]string               `protobuf:"bytes,23"`
+	Counts map[string]map[string]int64 `protobuf:"bytes,24" protobuf_key:"bytes,1" protobuf_val:"bytes,2" protobuf_val_key:"bytes,1" protobuf_val_val:"zigzag64,2"`
+	Tags   map[string][]string         `protobuf:"bytes,25" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+	Hashes [][16]byte                  `protobuf:"bytes,26,resize"`
+	Keys   []*[4]byte                  `protobuf:"bytes,27"`
+	X      chan int                    `protobuf:"-"`
+	Inner  `protobuf:"embedded"`
+	*Extra `protobuf:"embedded"`
+
+	_ protobuf3.Reserved `protobuf:"20,21"`
+}
+
+type Inner struct {
+	A uint64 `protobuf:"varint,10"`
+}
+
+type Extra struct {
+	E string `protobuf:"bytes,28"`
+
+	_ protobuf3.Reserved `protobuf:"29"`
+}
+
+type Untagged struct {
+	X float32
+}
+
+type Bad struct {
+	Wire     int32    `protobuf:"fixed,1"`  // want `unknown wire type`
+	Float    float32  `protobuf:"varint,2"` // want `float32 cannot have wiretype varint`
+	Str      string   `protobuf:"varint,3"` // want `string cannot have wiretype varint`
+	Zero     int      `protobuf:"varint,0"` // want `tag id out of range`
+	Chan     chan int `protobuf:"bytes,4"`  // want `no encoder for type chan int`
+	Untagged          // want `lacks a protobuf tag`
+	Dup      int      `protobuf:"varint,2"` // want `duplicate tag id 2 assigned to Dup, already used by Float`
+	Res      int      `protobuf:"varint,7"` // want `reserved tag id 7 assigned to Res`
+	Inner    `protobuf:"embedded"`
+	Same     int        `protobuf:"varint,10"`  // want `duplicate tag id 10 assigned to Same, already used by Inner.A`
+	PInner   *Inner     `protobuf:"varint,11"`  // want `\*Inner cannot have wiretype varint`
+	Slice    []struct{} `protobuf:"fixed64,12"` // want `\[\]struct{} cannot have wiretype fixed64`
+
+	MapWire  map[int]int     `protobuf:"varint,13" protobuf_key:"varint,1" protobuf_val:"varint,2"` // want `wiretype is not "bytes"`
+	MapNoKey map[int]int     `protobuf:"bytes,14" protobuf_val:"varint,2"`                          // want `lacks a protobuf_key tag`
+	MapIDs   map[int]int     `protobuf:"bytes,15" protobuf_key:"varint,2" protobuf_val:"varint,1"`  // want `protobuf_key tag \(varint,2\) doesn't use id 1; protobuf_val tag \(varint,1\) doesn't use id 2`
+	MapVal   map[int]float64 `protobuf:"bytes,16" protobuf_key:"varint,1" protobuf_val:"-"`         // want `protobuf_val tag cannot be "-"`
+
+	TimeWire time.Time    `protobuf:"fixed32,17,unixmilli"`         // want `time.Time with option unixmilli cannot have wiretype fixed32`
+	TimeStr  []time.Time  `protobuf:"varint,18,rfc3339"`            // want `time.Time with option rfc3339 cannot have wiretype varint`
+	NotTime  int64        `protobuf:"varint,19,unixnano"`           // want `int64 cannot have option unixnano`
+	TwoFmts  *time.Time   `protobuf:"varint,20,unixnano,unixmilli"` // want `more than one time format`
+	PtrTimes []*time.Time `protobuf:"varint,37,unixnano"`           // want `\[\]\*time.Time cannot have option unixnano, which only time.Time, \*time.Time and \[\]time.Time can have`
+	TimeArr  [2]time.Time `protobuf:"bytes,38,rfc3339"`             // want `\[2\]time.Time cannot have option rfc3339`
+
+	OptWire  protobuf3.Optional[string]  `protobuf:"varint,21"`  // want `string cannot have wiretype varint`
+	OptSlice protobuf3.Optional[[]int32] `protobuf:"bytes,22"`   // want `Optional supports only scalar, string and \[\]byte types`
+	IPWire   net.IP                      `protobuf:"varint,23"`  // want `net.IP cannot have wiretype varint`
+	PAddr    *netip.Addr                 `protobuf:"fixed64,24"` // want `\*net/netip.Addr cannot have wiretype fixed64`
+
+	NoText  int               `protobuf:"bytes,25,text"`                                                // want `int with option text does not implement encoding.TextMarshaler and encoding.TextUnmarshaler`
+	URLWire url.URL           `protobuf:"varint,26,binary"`                                             // want `net/url.URL cannot have wiretype varint`
+	TextKey map[time.Time]int `protobuf:"bytes,27" protobuf_key:"bytes,1,text" protobuf_val:"varint,2"` // want `protobuf_key tag cannot have option text`
+	TwoMFs  time.Time         `protobuf:"bytes,28,rfc3339,text"`                                        // want `both time format rfc3339 and marshal format text`
+
+	FloatLists [][]float32  `protobuf:"fixed64,29"` // want `float32 cannot have wiretype fixed64`
+	ChanLists  [][]chan int `protobuf:"bytes,30"`   // want `no encoder for type \[\]chan int`
+
+	MapMapKey  map[int]map[int]int `protobuf:"bytes,31" protobuf_key:"varint,1" protobuf_val:"bytes,2" protobuf_val_val:"varint,2"` // want `protobuf_val: lacks a protobuf_key tag`
+	MapMapWire map[int]map[int]int `protobuf:"bytes,32" protobuf_key:"varint,1" protobuf_val:"varint,2"`                            // want `protobuf_val: map map\[int\]int wiretype is not "bytes"`
+	MapLists   map[int][]float64   `protobuf:"bytes,33" protobuf_key:"varint,1" protobuf_val:"fixed32,2"`                           // want `protobuf_val: float64 cannot have wiretype fixed32`
+
+	SetWire map[float32]struct{} `protobuf:"fixed64,34"`    // want `float32 cannot have wiretype fixed64`
+	SetInts map[int]int          `protobuf:"varint,35,set"` // want `map\[int\]int: the set option requires a map\[K\]struct{} or map\[K\]bool`
+
+	HashWire [][16]byte `protobuf:"varint,36"` // want `\[\]\[16\]byte cannot have wiretype varint`
+
+	_ protobuf3.Reserved `protobuf:"7"`
+	_ protobuf3.Reserved `protobuf:"x"` // want `invalid reserved tag id "x"`
+}
+
+type ExtraBad struct {
+	*Extra   `protobuf:"embedded"`
+	DupExtra int `protobuf:"varint,28"` // want `duplicate tag id 28 assigned to DupExtra, already used by Extra.E`
+	ResExtra int `protobuf:"varint,29"` // want `reserved tag id 29 assigned to ResExtra`
+}
@@ -0,0 +1,379 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package protobuf3
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+	"unsafe"
+)
+
+// timeFormat is the encoding of a time.Time field, selected by an option in its protobuf tag. The options apply to
+// fields of type time.Time, *time.Time and []time.Time, and to the items of lists of those (like [][]time.Time).
+// Other shapes, like []*time.Time and [N]time.Time, can only be encoded as google.protobuf.Timestamp.
+type timeFormat uint8
+
+const (
+	timestampFormat timeFormat = iota // google.protobuf.Timestamp, the default
+	unixNanoFormat                    // `protobuf:"...,unixnano"`: an integer count of nanoseconds since the unix epoch
+	unixMilliFormat                   // `protobuf:"...,unixmilli"`: an integer count of milliseconds since the unix epoch
+	rfc3339Format                     // `protobuf:"bytes,...,rfc3339"`: an RFC 3339 string
+)
+
+// the tag options which select each timeFormat
+var timeFormats = map[string]timeFormat{
+	"unixnano":  unixNanoFormat,
+	"unixmilli": unixMilliFormat,
+	"rfc3339":   rfc3339Format,
+}
+
+func (f timeFormat) String() string {
+	for opt, ff := range timeFormats {
+		if ff == f {
+			return opt
+		}
+	}
+	return "timestamp"
+}
+
+// The alternate encodings map the zero time.Time to 0 or "", like any other scalar type, so that it is
+// omitted from the encoding. The price is that the instant of the unix epoch itself (which is 0 too)
+// decodes as the zero time.Time.
+
+// UnixNanoTime returns t as nanoseconds since the unix epoch, or 0 if t is the zero time.Time
+func UnixNanoTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// UnixMilliTime returns t as milliseconds since the unix epoch, or 0 if t is the zero time.Time
+func UnixMilliTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+// RFC3339Time returns t as an RFC 3339 string with as many fractional digits as needed, or "" if t is the zero time.Time
+func RFC3339Time(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// TimeFromUnixNano is the inverse of UnixNanoTime. The time is in UTC.
+func TimeFromUnixNano(x int64) time.Time {
+	if x == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, x).UTC()
+}
+
+// TimeFromUnixMilli is the inverse of UnixMilliTime. The time is in UTC.
+func TimeFromUnixMilli(x int64) time.Time {
+	if x == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(x).UTC()
+}
+
+// TimeFromRFC3339 is the inverse of RFC3339Time. The time keeps the offset from UTC written in s.
+func TimeFromRFC3339(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("protobuf3: %v", err)
+	}
+	return t, nil
+}
+
+// returns t in the integer format f
+func (f timeFormat) toInt(t time.Time) uint64 {
+	if f == unixMilliFormat {
+		return uint64(UnixMilliTime(t))
+	}
+	return uint64(UnixNanoTime(t))
+}
+
+// returns the time encoded as x in the integer format f
+func (f timeFormat) fromInt(x uint64) time.Time {
+	if f == unixMilliFormat {
+		return TimeFromUnixMilli(int64(x))
+	}
+	return TimeFromUnixNano(int64(x))
+}
+
+// setTimeEncAndDec is the part of setEncAndDec which handles time.Time fields with an alternate timeFormat
+func (p *Properties) setTimeEncAndDec(t1 reflect.Type, wire *WireType, name, int64_encoder_txt string) error {
+	var repeated, ptr bool
+	switch {
+	case t1 == time_Time_type:
+	case t1.Kind() == reflect.Ptr && t1.Elem() == time_Time_type:
+		ptr = true
+	case t1.Kind() == reflect.Slice && t1.Elem() == time_Time_type:
+		repeated = true
+	default:
+		if ok, err := p.setOptionListEncAndDec(t1, wire); ok {
+			return err
+		}
+		return fmt.Errorf("protobuf3: %q %s cannot have option %s, which only time.Time, *time.Time and []time.Time can have", name, t1, p.timeFormat)
+	}
+
+	if p.timeFormat == rfc3339Format {
+		if *wire != WireBytes {
+			return fmt.Errorf("protobuf3: %q %s with option %s cannot have wiretype %s", name, t1, p.timeFormat, *wire)
+		}
+		p.asProtobuf = "string"
+		switch {
+		case ptr:
+			p.enc = (*Buffer).enc_ptr_time_string
+			p.dec = (*Buffer).dec_ptr_time_string
+		case repeated:
+			p.enc = (*Buffer).enc_slice_time_string
+			p.dec = (*Buffer).dec_slice_time_string
+			p.asProtobuf = "repeated string"
+		default:
+			p.enc = (*Buffer).enc_time_string
+			p.dec = (*Buffer).dec_time_string
+		}
+		return nil
+	}
+
+	// the integer formats need 64 bits
+	if p.valEnc == nil || int64_encoder_txt == "" {
+		return fmt.Errorf("protobuf3: %q %s with option %s cannot have wiretype %s", name, t1, p.timeFormat, *wire)
+	}
+	p.asProtobuf = int64_encoder_txt
+	switch {
+	case ptr:
+		p.enc = (*Buffer).enc_ptr_time_int
+		p.dec = (*Buffer).dec_ptr_time_int
+	case repeated:
+		p.enc = (*Buffer).enc_slice_packed_time_int
+		p.dec = (*Buffer).dec_slice_packed_time_int
+		p.asProtobuf = "repeated " + int64_encoder_txt
+		*wire = WireBytes // packed=true...
+	default:
+		p.enc = (*Buffer).enc_time_int
+		p.dec = (*Buffer).dec_time_int
+	}
+	return nil
+}
+
+// Encode a time.Time as an integer
+func (o *Buffer) enc_time_int(p *Properties, base unsafe.Pointer) {
+	x := p.timeFormat.toInt(*(*time.Time)(unsafe.Pointer(uintptr(base) + p.offset)))
+	if x == 0 {
+		return
+	}
+	o.buf = append(o.buf, p.tagcode...)
+	p.valEnc(o, x)
+}
+
+// Encode a *time.Time as an integer
+func (o *Buffer) enc_ptr_time_int(p *Properties, base unsafe.Pointer) {
+	t := *(**time.Time)(unsafe.Pointer(uintptr(base) + p.offset))
+	if t == nil {
+		return
+	}
+	o.buf = append(o.buf, p.tagcode...)
+	p.valEnc(o, p.timeFormat.toInt(*t))
+}
+
+// Encode a []time.Time as packed integers
+func (o *Buffer) enc_slice_packed_time_int(p *Properties, base unsafe.Pointer) {
+	s := *(*[]time.Time)(unsafe.Pointer(uintptr(base) + p.offset))
+	if len(s) == 0 {
+		return
+	}
+	buf := newBuffer(nil)
+	for _, t := range s {
+		p.valEnc(buf, p.timeFormat.toInt(t))
+	}
+
+	o.buf = append(o.buf, p.tagcode...)
+	o.EncodeVarint(uint64(len(buf.buf)))
+	o.buf = append(o.buf, buf.buf...)
+	buf.release()
+}
+
+// Encode a time.Time as an RFC 3339 string
+func (o *Buffer) enc_time_string(p *Properties, base unsafe.Pointer) {
+	s := RFC3339Time(*(*time.Time)(unsafe.Pointer(uintptr(base) + p.offset)))
+	if s == "" {
+		return
+	}
+	o.buf = append(o.buf, p.tagcode...)
+	o.EncodeStringBytes(s)
+}
+
+// Encode a *time.Time as an RFC 3339 string
+func (o *Buffer) enc_ptr_time_string(p *Properties, base unsafe.Pointer) {
+	t := *(**time.Time)(unsafe.Pointer(uintptr(base) + p.offset))
+	if t == nil {
+		return
+	}
+	o.buf = append(o.buf, p.tagcode...)
+	o.EncodeStringBytes(RFC3339Time(*t))
+}
+
+// Encode a []time.Time as repeated RFC 3339 strings
+func (o *Buffer) enc_slice_time_string(p *Properties, base unsafe.Pointer) {
+	s := *(*[]time.Time)(unsafe.Pointer(uintptr(base) + p.offset))
+	for _, t := range s {
+		o.buf = append(o.buf, p.tagcode...)
+		o.EncodeStringBytes(RFC3339Time(t))
+	}
+}
+
+// Decode a time.Time from an integer
+func (o *Buffer) dec_time_int(p *Properties, base unsafe.Pointer) error {
+	u, err := p.valDec(o)
+	if err != nil {
+		return err
+	}
+	*(*time.Time)(unsafe.Pointer(uintptr(base) + p.offset)) = p.timeFormat.fromInt(u)
+	return nil
+}
+
+// Decode a *time.Time from an integer
+func (o *Buffer) dec_ptr_time_int(p *Properties, base unsafe.Pointer) error {
+	u, err := p.valDec(o)
+	if err != nil {
+		return err
+	}
+	t := p.timeFormat.fromInt(u)
+	*(**time.Time)(unsafe.Pointer(uintptr(base) + p.offset)) = &t
+	return nil
+}
+
+// Decode a []time.Time from packed integers
+func (o *Buffer) dec_slice_packed_time_int(p *Properties, base unsafe.Pointer) error {
+	v := (*[]time.Time)(unsafe.Pointer(uintptr(base) + p.offset))
+
+	nn, err := o.DecodeVarint()
+	if err != nil {
+		return err
+	}
+	nb := uint(nn) // number of bytes of encoded integers
+
+	fin := o.index + nb
+	if fin < o.index {
+		return errOverflow
+	}
+	if fin > ulen(o.buf) {
+		return io.ErrUnexpectedEOF
+	}
+
+	y := *v
+	if y == nil || o.limited() {
+		cnt := p.valCnt(o, fin)
+		if err := o.addRepeated(len(y)+cnt, cnt, unsafe.Sizeof(y[0])); err != nil {
+			return err
+		}
+		if y == nil {
+			y = make([]time.Time, 0, cnt)
+		}
+	}
+
+	for o.index < fin {
+		u, err := p.valDec(o)
+		if err != nil {
+			return err
+		}
+		y = append(y, p.timeFormat.fromInt(u))
+	}
+	*v = y
+	return nil
+}
+
+// dec_rfc3339 decodes an RFC 3339 string and parses it. The string is parsed and dropped, so it isn't worth interning
+func (o *Buffer) dec_rfc3339() (time.Time, error) {
+	raw, err := o.DecodeRawBytes()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if max := o.Limits.MaxBytesLength; max != 0 && len(raw) > max {
+		return time.Time{}, &LimitError{"MaxBytesLength", max}
+	}
+	return TimeFromRFC3339(string(raw))
+}
+
+// Decode a time.Time from an RFC 3339 string
+func (o *Buffer) dec_time_string(p *Properties, base unsafe.Pointer) error {
+	t, err := o.dec_rfc3339()
+	if err != nil {
+		return err
+	}
+	*(*time.Time)(unsafe.Pointer(uintptr(base) + p.offset)) = t
+	return nil
+}
+
+// Decode a *time.Time from an RFC 3339 string
+func (o *Buffer) dec_ptr_time_string(p *Properties, base unsafe.Pointer) error {
+	t, err := o.dec_rfc3339()
+	if err != nil {
+		return err
+	}
+	*(**time.Time)(unsafe.Pointer(uintptr(base) + p.offset)) = &t
+	return nil
+}
+
+// Decode a []time.Time from repeated RFC 3339 strings
+func (o *Buffer) dec_slice_time_string(p *Properties, base unsafe.Pointer) error {
+	t, err := o.dec_rfc3339()
+	if err != nil {
+		return err
+	}
+	v := (*[]time.Time)(unsafe.Pointer(uintptr(base) + p.offset))
+	y := *v
+
+	if y == nil {
+		// preallocate what is probably the right sized slice immediately (if it isn't then we'll append later)
+		y = make([]time.Time, 0, o.countAhead(p, unsafe.Sizeof(y[0])))
+	}
+	if err := o.addRepeated(len(y)+1, 1, unsafe.Sizeof(y[0])); err != nil {
+		return err
+	}
+
+	*v = append(y, t)
+	return nil
+}
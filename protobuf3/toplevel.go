@@ -0,0 +1,184 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package protobuf3
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// Marshal and Unmarshal encode a top-level value which isn't a struct (a slice, an array, a map or a scalar) as if it
+// were the single field of a message. Unless Buffer.TopLevelTag says otherwise, the field's id is 1 and its wiretype
+// is the usual one for its type, so a []Event is encoded like a message
+//
+//	message Events {
+//	  repeated Event value = 1;
+//	}
+//
+// and a map[string]Stats is encoded like a message
+//
+//	message StatsByName {
+//	  map<string, Stats> value = 1;
+//	}
+//
+// The message is synthesized as a struct { Value T } whose field is tagged with Buffer.TopLevelTag, or a default tag.
+// It has the same memory layout as T, so the value is encoded and decoded in place.
+
+// the synthesized struct types, by the type of their field and the Buffer.TopLevelTag
+type topLevelKey struct {
+	typ reflect.Type
+	tag reflect.StructTag
+}
+
+var topLevels sync.Map // topLevelKey -> reflect.Type
+
+// isTopLevelWrapped returns true if t, the type of a top-level value, is encoded as the single field of a message
+func isTopLevelWrapped(t reflect.Type) bool {
+	switch k := t.Kind(); {
+	case k == reflect.Bool,
+		k >= reflect.Int && k <= reflect.Uint64,
+		k == reflect.Float32, k == reflect.Float64,
+		k == reflect.String,
+		k == reflect.Slice, k == reflect.Array, k == reflect.Map:
+		return true
+	}
+	return false
+}
+
+// topLevelType returns the struct type whose single field, of type t, is tagged with tag, or with a default tag if tag is ""
+func topLevelType(t reflect.Type, tag reflect.StructTag) (reflect.Type, error) {
+	key := topLevelKey{t, tag}
+	if st, ok := topLevels.Load(key); ok {
+		return st.(reflect.Type), nil
+	}
+
+	if tag == "" {
+		var tags []string
+		if err := appendDefaultTags(&tags, "protobuf", 1, t); err != nil {
+			return nil, err
+		}
+		tag = reflect.StructTag(strings.Join(tags, " "))
+	}
+	st := reflect.StructOf([]reflect.StructField{{
+		Name: "Value",
+		Type: t,
+		Tag:  tag,
+	}})
+	topLevels.Store(key, st)
+	return st, nil
+}
+
+// appendDefaultTags appends to tags the struct tag named name which gives a field of type t id id, and the tags of
+// its keys and values if t is a map
+func appendDefaultTags(tags *[]string, name string, id int, t reflect.Type) error {
+	wire, err := defaultWire(t)
+	if err != nil {
+		return err
+	}
+	*tags = append(*tags, fmt.Sprintf("%s:\"%s,%d\"", name, wire, id))
+	if t.Kind() == reflect.Map && !isDefaultSet(t) {
+		if err := appendDefaultTags(tags, name+"_key", 1, t.Key()); err != nil {
+			return err
+		}
+		return appendDefaultTags(tags, name+"_val", 2, t.Elem())
+	}
+	return nil
+}
+
+// isDefaultSet returns true if map type t is a set when its field has no protobuf_key tag
+func isDefaultSet(t reflect.Type) bool {
+	return isSetType(t) && t.Elem().Kind() == reflect.Struct
+}
+
+// defaultWire returns the usual wiretype of type t
+func defaultWire(t reflect.Type) (string, error) {
+	if t == time_Duration_type {
+		return "bytes", nil
+	}
+	if ptr_t := reflect.PtrTo(t); isMarshaler(ptr_t) || isAppender(ptr_t) {
+		return "bytes", nil
+	}
+	switch k := t.Kind(); {
+	case k == reflect.Bool, k >= reflect.Int && k <= reflect.Uint64:
+		return "varint", nil
+	case k == reflect.Float32:
+		return "fixed32", nil
+	case k == reflect.Float64:
+		return "fixed64", nil
+	case k == reflect.String, k == reflect.Struct:
+		return "bytes", nil
+	case k == reflect.Map:
+		if isDefaultSet(t) {
+			return defaultWire(t.Key())
+		}
+		return "bytes", nil
+	case k == reflect.Slice, k == reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "bytes", nil
+		}
+		return defaultWire(t.Elem())
+	case k == reflect.Ptr:
+		return defaultWire(t.Elem())
+	}
+	return "", fmt.Errorf("protobuf3: no default wiretype for %s", t)
+}
+
+// marshalTopLevel encodes v, a value (or a pointer to a value) which isWrapped, as the single field of a message
+func (o *Buffer) marshalTopLevel(v reflect.Value) error {
+	if v.Kind() != reflect.Ptr {
+		// we need the value's address
+		p := reflect.New(v.Type())
+		p.Elem().Set(v)
+		v = p
+	}
+	base := unsafe.Pointer(v.Pointer())
+	if base == nil {
+		return ErrNil
+	}
+
+	st, err := topLevelType(v.Type().Elem(), o.TopLevelTag)
+	if err != nil {
+		return err
+	}
+	prop, err := GetProperties(st)
+	if err != nil {
+		return err
+	}
+
+	o.enc_struct(prop, base)
+	return o.err
+}
@@ -37,11 +37,17 @@ package protobuf3_test
 
 import (
 	"bytes"
+	"database/sql"
 	"encoding/binary"
 	ehex "encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
+	"math/big"
+	"net"
+	"net/netip"
+	"net/url"
 	"reflect"
 	"regexp"
 	"strings"
@@ -50,6 +56,7 @@ import (
 	"unsafe"
 
 	"github.com/mistsys/protobuf3/protobuf3"
+	"github.com/mistsys/protobuf3/protobuf3/internal/unit_tests/collide"
 	"github.com/mistsys/protobuf3/protobuf3/internal/unit_tests/duration"
 	"github.com/mistsys/protobuf3/protobuf3/internal/unit_tests/proto"
 	pb3 "github.com/mistsys/protobuf3/protobuf3/internal/unit_tests/proto3_proto"
@@ -444,6 +451,37 @@ func TestByteArrayMsg(t *testing.T) {
 	eq("mc", m, mc, t)
 }
 
+// []byte and [N]byte are always encoded as bytes, whatever wiretype their tags give
+type VarintBytesMsg struct {
+	S []uint8 `protobuf:"varint,1"`
+	A [3]byte `protobuf:"varint,2"`
+}
+type WireBytesMsg struct {
+	S []byte `protobuf:"bytes,1"`
+	A []byte `protobuf:"bytes,2"`
+}
+
+func TestByteWireTypes(t *testing.T) {
+	m := VarintBytesMsg{S: []uint8{1, 2}, A: [3]byte{3, 4, 5}}
+	w := WireBytesMsg{S: []byte{1, 2}, A: []byte{3, 4, 5}}
+	b, err := protobuf3.Marshal(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := protobuf3.Marshal(&w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, c) {
+		t.Errorf("Marshal(VarintBytesMsg) = % x\nexpected % x", b, c)
+	}
+	var mb VarintBytesMsg
+	if err := protobuf3.Unmarshal(c, &mb); err != nil {
+		t.Fatal(err)
+	}
+	eq("mb", m, mb, t)
+}
+
 func TestZeroMsgs(t *testing.T) {
 	f := FixedMsg{}
 	check(&f, &f, t)
@@ -979,8 +1017,985 @@ func TestTimeMsg(t *testing.T) {
 	} else {
 		t.Error("failed to unmarshal *time.Duration")
 	}
-	eq("dur3", mb.dur3, m.dur3, t)
-	eq("dur4", mb.dur4, m.dur4, t)
+	eq("dur3", mb.dur3, m.dur3, t)
+	eq("dur4", mb.dur4, m.dur4, t)
+}
+
+type RepeatedTimeMsg struct {
+	ts  []time.Time          `protobuf:"bytes,1"`
+	pts []*time.Time         `protobuf:"bytes,2"`
+	ats [2]time.Time         `protobuf:"bytes,3"`
+	apt [1]*time.Time        `protobuf:"bytes,4"`
+	mts map[string]time.Time `protobuf:"bytes,5" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+}
+
+type OldRepeatedTimeMsg struct {
+	ts  []*timestamp.Timestamp          `protobuf:"bytes,1"`
+	pts []*timestamp.Timestamp          `protobuf:"bytes,2"`
+	ats []*timestamp.Timestamp          `protobuf:"bytes,3"`
+	apt []*timestamp.Timestamp          `protobuf:"bytes,4"`
+	mts map[string]*timestamp.Timestamp `protobuf:"bytes,5" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+}
+
+func (*OldRepeatedTimeMsg) ProtoMessage()    {}
+func (m *OldRepeatedTimeMsg) String() string { return fmt.Sprintf("%+v", *m) }
+func (m *OldRepeatedTimeMsg) Reset()         { *m = OldRepeatedTimeMsg{} }
+
+func TestRepeatedTimeMsg(t *testing.T) {
+	t1 := time.Unix(112233, 445566).UTC()
+	t2 := time.Unix(-1, 999999999).UTC()
+	m := RepeatedTimeMsg{
+		ts:  []time.Time{t1, t2},
+		pts: []*time.Time{&t2, &t1},
+		ats: [2]time.Time{t2, t1},
+		apt: [1]*time.Time{&t1},
+		mts: map[string]time.Time{"x": t1},
+	}
+
+	o := OldRepeatedTimeMsg{
+		ts:  []*timestamp.Timestamp{{Seconds: 112233, Nanos: 445566}, {Seconds: -1, Nanos: 999999999}},
+		pts: []*timestamp.Timestamp{{Seconds: -1, Nanos: 999999999}, {Seconds: 112233, Nanos: 445566}},
+		ats: []*timestamp.Timestamp{{Seconds: -1, Nanos: 999999999}, {Seconds: 112233, Nanos: 445566}},
+		apt: []*timestamp.Timestamp{{Seconds: 112233, Nanos: 445566}},
+		mts: map[string]*timestamp.Timestamp{"x": {Seconds: 112233, Nanos: 445566}},
+	}
+
+	check(&m, &o, t)
+
+	var mb RepeatedTimeMsg
+	var mc OldRepeatedTimeMsg
+	uncheck(&m, &mb, &mc, t)
+	eq("mb", m, mb, t)
+	eq("mc", o, mc, t)
+
+	// and the other direction
+	pb, err := proto.Marshal(&o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var md RepeatedTimeMsg
+	if err := protobuf3.Unmarshal(pb, &md); err != nil {
+		t.Fatal(err)
+	}
+	eq("md", m, md, t)
+
+	s, err := protobuf3.AsProtobuf(reflect.TypeOf(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"repeated google.protobuf.Timestamp ts = 1;", "repeated google.protobuf.Timestamp ats = 3;", "map<string, google.protobuf.Timestamp> mts = 5;"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("AsProtobuf lacks %q:\n%s", want, s)
+		}
+	}
+}
+
+type TimeFormatMsg struct {
+	Nano    time.Time   `protobuf:"fixed64,1,unixnano"`
+	Milli   time.Time   `protobuf:"varint,2,unixmilli"`
+	Str     time.Time   `protobuf:"bytes,3,rfc3339"`
+	PNano   *time.Time  `protobuf:"zigzag64,4,unixnano"`
+	PStr    *time.Time  `protobuf:"bytes,5,rfc3339"`
+	Millis  []time.Time `protobuf:"varint,6,unixmilli"`
+	Strs    []time.Time `protobuf:"bytes,7,rfc3339"`
+	Zero    time.Time   `protobuf:"fixed64,8,unixnano"` // left zero, it encodes to nothing
+	ZeroStr time.Time   `protobuf:"bytes,9,rfc3339"`    // same
+}
+
+// the same wire format, as plain integers and strings
+type TimeFormatWireMsg struct {
+	Nano   int64    `protobuf:"fixed64,1"`
+	Milli  int64    `protobuf:"varint,2"`
+	Str    string   `protobuf:"bytes,3"`
+	PNano  *int64   `protobuf:"zigzag64,4"`
+	PStr   *string  `protobuf:"bytes,5"`
+	Millis []int64  `protobuf:"varint,6"`
+	Strs   []string `protobuf:"bytes,7"`
+}
+
+func TestTimeFormats(t *testing.T) {
+	t1 := time.Unix(1600000000, 123456789).UTC()
+	t2 := time.Date(2021, 2, 3, 4, 5, 6, 7000, time.FixedZone("", -7*3600))
+	t3 := time.UnixMilli(-1234567).UTC()
+	m := TimeFormatMsg{
+		Nano:   t1,
+		Milli:  t3,
+		Str:    t2,
+		PNano:  new(time.Time), // a non-nil pointer is always encoded
+		PStr:   &t1,
+		Millis: []time.Time{t3, {}},
+		Strs:   []time.Time{t1, t2},
+	}
+
+	pnano := int64(0)
+	pstr := "2020-09-13T12:26:40.123456789Z"
+	w := TimeFormatWireMsg{
+		Nano:   1600000000123456789,
+		Milli:  -1234567,
+		Str:    "2021-02-03T04:05:06.000007-07:00",
+		PNano:  &pnano,
+		PStr:   &pstr,
+		Millis: []int64{-1234567, 0},
+		Strs:   []string{pstr, "2021-02-03T04:05:06.000007-07:00"},
+	}
+
+	b, err := protobuf3.Marshal(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := protobuf3.Marshal(&w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, c) {
+		t.Errorf("Marshal(TimeFormatMsg) = % x\nexpected % x", b, c)
+	}
+
+	var mb TimeFormatMsg
+	if err := protobuf3.Unmarshal(b, &mb); err != nil {
+		t.Fatal(err)
+	}
+	eq("Nano", mb.Nano, m.Nano, t)
+	eq("Milli", mb.Milli, m.Milli, t)
+	if !mb.Str.Equal(m.Str) {
+		t.Errorf("Str %v != %v", mb.Str, m.Str)
+	}
+	if mb.PNano == nil || !mb.PNano.IsZero() {
+		t.Errorf("PNano %v", mb.PNano)
+	}
+	if mb.PStr == nil || !mb.PStr.Equal(t1) {
+		t.Errorf("PStr %v", mb.PStr)
+	}
+	eq("Millis", mb.Millis, m.Millis, t)
+	if len(mb.Strs) != 2 || !mb.Strs[0].Equal(t1) || !mb.Strs[1].Equal(t2) {
+		t.Errorf("Strs %v", mb.Strs)
+	}
+
+	s, err := protobuf3.AsProtobufFull(reflect.TypeOf(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(s)
+	for _, want := range []string{
+		"  sfixed64 nano = 1;\n",
+		"  int64 milli = 2;\n",
+		"  string str = 3;\n",
+		"  sint64 pnano = 4;\n",
+		"  repeated int64 millis = 6;\n",
+		"  repeated string strs = 7;\n",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("AsProtobufFull lacks %q", want)
+		}
+	}
+	if strings.Contains(s, "timestamp.proto") {
+		t.Error("AsProtobufFull imports timestamp.proto needlessly")
+	}
+
+	// bad strings are an error
+	w = TimeFormatWireMsg{Str: "yesterday"}
+	c, _ = protobuf3.Marshal(&w)
+	if err := protobuf3.Unmarshal(c, &mb); err == nil {
+		t.Error("Unmarshal of a bad RFC 3339 string should have failed")
+	}
+}
+
+type BadTimeFormatMsg1 struct {
+	T time.Time `protobuf:"fixed32,1,unixnano"`
+}
+
+type BadTimeFormatMsg2 struct {
+	T time.Time `protobuf:"varint,1,rfc3339"`
+}
+
+type BadTimeFormatMsg3 struct {
+	T int64 `protobuf:"varint,1,unixmilli"`
+}
+
+// the time formats don't apply to slices of pointers or to arrays
+type BadTimeFormatMsg4 struct {
+	T []*time.Time `protobuf:"varint,1,unixnano"`
+}
+
+type BadTimeFormatMsg5 struct {
+	T [2]time.Time `protobuf:"bytes,1,rfc3339"`
+}
+
+func TestBadTimeFormats(t *testing.T) {
+	for _, m := range []interface{}{&BadTimeFormatMsg1{}, &BadTimeFormatMsg2{}, &BadTimeFormatMsg3{}, &BadTimeFormatMsg4{}, &BadTimeFormatMsg5{}} {
+		if _, err := protobuf3.Marshal(m); err == nil {
+			t.Errorf("Marshal(%T) should have failed", m)
+		}
+	}
+	for _, m := range []interface{}{&BadTimeFormatMsg3{}, &BadTimeFormatMsg4{}, &BadTimeFormatMsg5{}} {
+		_, err := protobuf3.Marshal(m)
+		if err == nil || !strings.Contains(err.Error(), "which only time.Time, *time.Time and []time.Time can have") {
+			t.Errorf("Marshal(%T) returned %v", m, err)
+		}
+	}
+}
+
+type OptionalMsg struct {
+	B   protobuf3.Optional[bool]    `protobuf:"varint,1"`
+	I   protobuf3.Optional[int32]   `protobuf:"varint,2"`
+	U   protobuf3.Optional[uint64]  `protobuf:"fixed64,3"`
+	Z   protobuf3.Optional[int64]   `protobuf:"zigzag64,4"`
+	F   protobuf3.Optional[float32] `protobuf:"fixed32,5"`
+	D   protobuf3.Optional[float64] `protobuf:"fixed64,6"`
+	S   protobuf3.Optional[string]  `protobuf:"bytes,7"`
+	C   protobuf3.Optional[Color]   `protobuf:"varint,8"`
+	Bs  protobuf3.Optional[[]byte]  `protobuf:"bytes,9"`
+	Off protobuf3.Optional[int32]   `protobuf:"varint,10"` // never set
+}
+
+// the same wire format, using pointers for presence
+type OptionalPtrMsg struct {
+	B   *bool    `protobuf:"varint,1"`
+	I   *int32   `protobuf:"varint,2"`
+	U   *uint64  `protobuf:"fixed64,3"`
+	Z   *int64   `protobuf:"zigzag64,4"`
+	F   *float32 `protobuf:"fixed32,5"`
+	D   *float64 `protobuf:"fixed64,6"`
+	S   *string  `protobuf:"bytes,7"`
+	C   *Color   `protobuf:"varint,8"`
+	Bs  []byte   `protobuf:"bytes,9"`
+	Off *int32   `protobuf:"varint,10"`
+}
+
+func TestOptional(t *testing.T) {
+	// zero values which are set must be encoded
+	var m OptionalMsg
+	m.B = protobuf3.OptionalOf(false)
+	m.I = protobuf3.OptionalOf(int32(0))
+	m.U = protobuf3.OptionalOf(uint64(0))
+	m.Z = protobuf3.OptionalOf(int64(0))
+	m.F = protobuf3.OptionalOf(float32(0))
+	m.D = protobuf3.OptionalOf(0.0)
+	m.S = protobuf3.OptionalOf("")
+	m.Bs = protobuf3.OptionalOf([]byte(nil))
+	m.C = protobuf3.OptionalOf(Color(0))
+
+	var (
+		b  bool
+		i  int32
+		u  uint64
+		z  int64
+		f  float32
+		d  float64
+		s  string
+		c  Color
+		bs = []byte{}
+	)
+	w := OptionalPtrMsg{B: &b, I: &i, U: &u, Z: &z, F: &f, D: &d, S: &s, Bs: bs, C: &c}
+
+	mb, err := protobuf3.Marshal(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wb, err := protobuf3.Marshal(&w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// OptionalPtrMsg omits the empty []byte, so compare everything else
+	wb = append(wb, 9<<3|2, 0)
+	if !bytes.Equal(mb, wb) {
+		t.Errorf("Marshal(OptionalMsg) = % x\nexpected % x", mb, wb)
+	}
+
+	var m2 OptionalMsg
+	if err := protobuf3.Unmarshal(mb, &m2); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m2.Off.Get(); ok {
+		t.Error("Off should not be set")
+	}
+	m2.Bs.Value = nil // the decoder returns an empty, non-nil slice
+	eq("m2", m2, m, t)
+
+	// non-zero values round trip, and unset values are not encoded
+	m = OptionalMsg{
+		I:  protobuf3.OptionalOf(int32(-7)),
+		S:  protobuf3.OptionalOf("hello"),
+		C:  protobuf3.OptionalOf(Color(2)),
+		Bs: protobuf3.OptionalOf([]byte{1, 2}),
+	}
+	mb, err = protobuf3.Marshal(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m2 = OptionalMsg{}
+	if err := protobuf3.Unmarshal(mb, &m2); err != nil {
+		t.Fatal(err)
+	}
+	eq("m2", m2, m, t)
+
+	m.I.Clear()
+	if v, ok := m.I.Get(); ok || v != 0 {
+		t.Errorf("Clear() left %v, %v", v, ok)
+	}
+
+	// an unset Optional, even with a value, encodes nothing
+	m = OptionalMsg{I: protobuf3.Optional[int32]{Value: 3}}
+	mb, err = protobuf3.Marshal(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mb) != 0 {
+		t.Errorf("Marshal of unset Optional = % x", mb)
+	}
+
+	str, err := protobuf3.AsProtobufFull(reflect.TypeOf(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(str)
+	for _, want := range []string{
+		"  optional bool b = 1;\n",
+		"  optional int32 i = 2;\n",
+		"  optional fixed64 u = 3;\n",
+		"  optional sint64 z = 4;\n",
+		"  optional float f = 5;\n",
+		"  optional double d = 6;\n",
+		"  optional string s = 7;\n",
+		"  optional Color c = 8;\n",
+		"  optional bytes bs = 9;\n",
+		"enum Color {",
+	} {
+		if !strings.Contains(str, want) {
+			t.Errorf("AsProtobufFull lacks %q", want)
+		}
+	}
+}
+
+type BadOptionalMsg struct {
+	O protobuf3.Optional[[]int32] `protobuf:"varint,1"`
+}
+
+func TestBadOptional(t *testing.T) {
+	if _, err := protobuf3.Marshal(&BadOptionalMsg{}); err == nil {
+		t.Error("Marshal(BadOptionalMsg) should have failed")
+	}
+}
+
+// LegacyID is a type with no methods, encoded by a registered codec
+type LegacyID struct {
+	v uint64
+}
+
+func init() {
+	protobuf3.RegisterCodec(reflect.TypeOf(big.Int{}), protobuf3.Codec{
+		Wire: protobuf3.WireBytes,
+		Append: func(b []byte, v interface{}) ([]byte, error) {
+			return append(b, v.(*big.Int).Bytes()...), nil
+		},
+		Unmarshal: func(data []byte, v interface{}) error {
+			v.(*big.Int).SetBytes(data)
+			return nil
+		},
+		Name: "bytes",
+	})
+	protobuf3.RegisterCodec(reflect.TypeOf(sql.NullString{}), protobuf3.Codec{
+		Wire: protobuf3.WireBytes,
+		Append: func(b []byte, v interface{}) ([]byte, error) {
+			ns := v.(*sql.NullString)
+			if !ns.Valid {
+				return b, nil
+			}
+			b = append(b, 1<<3|2)
+			return protobuf3.AppendStringBytes(b, ns.String), nil
+		},
+		Unmarshal: func(data []byte, v interface{}) error {
+			var m struct {
+				S string `protobuf:"bytes,1"`
+			}
+			if err := protobuf3.Unmarshal(data, &m); err != nil {
+				return err
+			}
+			*v.(*sql.NullString) = sql.NullString{String: m.S, Valid: true}
+			return nil
+		},
+		Name:    "google.protobuf.StringValue",
+		Imports: []string{"google/protobuf/wrappers.proto"},
+	})
+	protobuf3.RegisterCodec(reflect.TypeOf(LegacyID{}), protobuf3.Codec{
+		Wire: protobuf3.WireFixed64,
+		Append: func(b []byte, v interface{}) ([]byte, error) {
+			id := v.(*LegacyID)
+			if id.v == 0 {
+				return b, nil
+			}
+			return protobuf3.AppendFixed64(b, id.v), nil
+		},
+		Unmarshal: func(data []byte, v interface{}) error {
+			d := protobuf3.MakeBuffer(data)
+			u, err := d.DecodeFixed64()
+			v.(*LegacyID).v = u
+			return err
+		},
+		Name: "fixed64",
+	})
+	// replace the codec protobuf3 registers for net.HardwareAddr with one which encodes the same, but counts its calls
+	protobuf3.RegisterCodec(reflect.TypeOf(net.HardwareAddr{}), protobuf3.Codec{
+		Wire: protobuf3.WireBytes,
+		Append: func(b []byte, v interface{}) ([]byte, error) {
+			macAppends++
+			return append(b, *v.(*net.HardwareAddr)...), nil
+		},
+		Unmarshal: func(data []byte, v interface{}) error {
+			*v.(*net.HardwareAddr) = append(net.HardwareAddr(nil), data...)
+			return nil
+		},
+		Name: "bytes",
+	})
+}
+
+// the number of calls of the net.HardwareAddr codec registered above
+var macAppends int
+
+type CodecMsg struct {
+	N   big.Int             `protobuf:"bytes,1"`
+	PN  *big.Int            `protobuf:"bytes,2"`
+	Ns  []big.Int           `protobuf:"bytes,3"`
+	A   [2]sql.NullString   `protobuf:"bytes,4"`
+	S   sql.NullString      `protobuf:"bytes,5"`
+	IDs map[LegacyID]string `protobuf:"bytes,6" protobuf_key:"fixed64,1" protobuf_val:"bytes,2"`
+	Val map[string]LegacyID `protobuf:"bytes,7" protobuf_key:"bytes,1" protobuf_val:"fixed64,2"`
+	ID  LegacyID            `protobuf:"fixed64,8"`
+}
+
+// the same wire format
+type CodecWireMsg struct {
+	N   []byte            `protobuf:"bytes,1"`
+	PN  []byte            `protobuf:"bytes,2"`
+	Ns  [][]byte          `protobuf:"bytes,3"`
+	A   [2]StringValue    `protobuf:"bytes,4"`
+	S   *StringValue      `protobuf:"bytes,5"`
+	IDs map[uint64]string `protobuf:"bytes,6" protobuf_key:"fixed64,1" protobuf_val:"bytes,2"`
+	Val map[string]uint64 `protobuf:"bytes,7" protobuf_key:"bytes,1" protobuf_val:"fixed64,2"`
+	ID  uint64            `protobuf:"fixed64,8"`
+}
+
+type StringValue struct {
+	S string `protobuf:"bytes,1"`
+}
+
+func TestRegisteredCodec(t *testing.T) {
+	m := CodecMsg{
+		PN:  big.NewInt(0), // a non-nil pointer to a value which encodes to nothing is omitted, like an Appender
+		Ns:  []big.Int{*big.NewInt(1000), {}},
+		A:   [2]sql.NullString{{String: "w", Valid: true}, {String: "x", Valid: true}},
+		S:   sql.NullString{Valid: true},
+		IDs: map[LegacyID]string{{7}: "seven"},
+		Val: map[string]LegacyID{"eight": {8}},
+		ID:  LegacyID{9},
+	}
+	m.N.SetInt64(0x1234)
+
+	w := CodecWireMsg{
+		N:   []byte{0x12, 0x34},
+		Ns:  [][]byte{{0x03, 0xe8}, {}},
+		A:   [2]StringValue{{"w"}, {"x"}},
+		S:   &StringValue{},
+		IDs: map[uint64]string{7: "seven"},
+		Val: map[string]uint64{"eight": 8},
+		ID:  9,
+	}
+
+	b, err := protobuf3.Marshal(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := protobuf3.Marshal(&w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the NullString S is Valid, so it encodes its empty value explicitly, where StringValue encodes to nothing
+	c = bytes.Replace(c, []byte{5<<3 | 2, 0}, []byte{5<<3 | 2, 2, 1<<3 | 2, 0}, 1)
+	if !bytes.Equal(b, c) {
+		t.Errorf("Marshal(CodecMsg) = % x\nexpected % x", b, c)
+	}
+
+	var mb CodecMsg
+	if err := protobuf3.Unmarshal(b, &mb); err != nil {
+		t.Fatal(err)
+	}
+	if mb.N.Cmp(&m.N) != 0 {
+		t.Errorf("N %v != %v", &mb.N, &m.N)
+	}
+	if mb.PN != nil {
+		t.Errorf("PN %v", mb.PN)
+	}
+	if len(mb.Ns) != 2 || mb.Ns[0].Int64() != 1000 || mb.Ns[1].Sign() != 0 {
+		t.Errorf("Ns %v", mb.Ns)
+	}
+	eq("A", mb.A, m.A, t)
+	eq("S", mb.S, m.S, t)
+	eq("IDs", mb.IDs, m.IDs, t)
+	eq("Val", mb.Val, m.Val, t)
+	eq("ID", mb.ID, m.ID, t)
+
+	s, err := protobuf3.AsProtobufFull(reflect.TypeOf(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(s)
+	for _, want := range []string{
+		"import \"google/protobuf/wrappers.proto\";\n",
+		"  bytes n = 1;\n",
+		"  bytes pn = 2;\n",
+		"  repeated bytes ns = 3;\n",
+		"  repeated google.protobuf.StringValue a = 4;\n",
+		"  google.protobuf.StringValue s = 5;\n",
+		"  map<fixed64, string> ids = 6;\n",
+		"  map<string, fixed64> val = 7;\n",
+		"  fixed64 id = 8;\n",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("AsProtobufFull lacks %q", want)
+		}
+	}
+	if strings.Contains(s, "message Int") || strings.Contains(s, "TODO") {
+		t.Error("AsProtobufFull defined a codec type")
+	}
+}
+
+type BadCodecMsg struct {
+	ID LegacyID `protobuf:"varint,1"`
+}
+
+func TestBadCodec(t *testing.T) {
+	if _, err := protobuf3.Marshal(&BadCodecMsg{}); err == nil {
+		t.Error("Marshal(BadCodecMsg) should have failed")
+	}
+}
+
+type NetMsg struct {
+	Addr     netip.Addr              `protobuf:"bytes,1"`
+	Addr6    netip.Addr              `protobuf:"bytes,2"`
+	Prefix   netip.Prefix            `protobuf:"bytes,3"`
+	AddrPort netip.AddrPort          `protobuf:"bytes,4"`
+	IP       net.IP                  `protobuf:"bytes,5"`
+	IPNet    *net.IPNet              `protobuf:"bytes,6"`
+	MAC      net.HardwareAddr        `protobuf:"bytes,7"`
+	Addrs    []netip.Addr            `protobuf:"bytes,8"`
+	PAddr    *netip.Addr             `protobuf:"bytes,9"`
+	Hops     [2]netip.AddrPort       `protobuf:"bytes,10"`
+	IPs      []net.IP                `protobuf:"bytes,11"`
+	ByAddr   map[netip.Addr]uint32   `protobuf:"bytes,12" protobuf_key:"bytes,1" protobuf_val:"varint,2"`
+	Routes   map[netip.Prefix]string `protobuf:"bytes,13" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+	Gateways map[string]netip.Addr   `protobuf:"bytes,14" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+	Zero     netip.Addr              `protobuf:"bytes,15"` // encodes as nothing
+}
+
+// the same wire format
+type NetWireMsg struct {
+	Addr     []byte            `protobuf:"bytes,1"`
+	Addr6    []byte            `protobuf:"bytes,2"`
+	Prefix   []byte            `protobuf:"bytes,3"`
+	AddrPort []byte            `protobuf:"bytes,4"`
+	IP       []byte            `protobuf:"bytes,5"`
+	IPNet    []byte            `protobuf:"bytes,6"`
+	MAC      []byte            `protobuf:"bytes,7"`
+	Addrs    [][]byte          `protobuf:"bytes,8"`
+	PAddr    []byte            `protobuf:"bytes,9"`
+	Hops     [][]byte          `protobuf:"bytes,10"`
+	IPs      [][]byte          `protobuf:"bytes,11"`
+	ByAddr   map[string]uint32 `protobuf:"bytes,12" protobuf_key:"bytes,1" protobuf_val:"varint,2"`
+	Routes   map[string]string `protobuf:"bytes,13" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+	Gateways map[string][]byte `protobuf:"bytes,14" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+}
+
+func TestNetTypes(t *testing.T) {
+	m := NetMsg{
+		Addr:     netip.MustParseAddr("10.1.2.3"),
+		Addr6:    netip.MustParseAddr("fe80::1"),
+		Prefix:   netip.MustParsePrefix("192.168.0.0/16"),
+		AddrPort: netip.MustParseAddrPort("[::1]:443"),
+		IP:       net.ParseIP("10.9.8.7").To4(),
+		IPNet:    &net.IPNet{IP: net.IP{10, 0, 0, 0}, Mask: net.CIDRMask(8, 32)},
+		MAC:      net.HardwareAddr{0, 1, 2, 3, 4, 5},
+		Addrs:    []netip.Addr{netip.MustParseAddr("1.1.1.1"), {}},
+		PAddr:    &netip.Addr{},
+		Hops:     [2]netip.AddrPort{netip.MustParseAddrPort("1.2.3.4:80"), {}},
+		IPs:      []net.IP{net.ParseIP("::2")},
+		ByAddr:   map[netip.Addr]uint32{netip.MustParseAddr("fe80::1%eth0"): 1},
+		Routes:   map[netip.Prefix]string{netip.MustParsePrefix("10.0.0.0/8"): "lan"},
+		Gateways: map[string]netip.Addr{"lan": netip.MustParseAddr("10.0.0.1")},
+	}
+	v6 := netip.MustParseAddr("fe80::1").As16()
+	lo := netip.IPv6Loopback().As16()
+	w := NetWireMsg{
+		Addr:     []byte{10, 1, 2, 3},
+		Addr6:    v6[:],
+		Prefix:   []byte{192, 168, 0, 0, 16},
+		AddrPort: append(lo[:], 443>>8, 443&0xff),
+		IP:       []byte{10, 9, 8, 7},
+		IPNet:    []byte{10, 0, 0, 0, 255, 0, 0, 0},
+		MAC:      []byte{0, 1, 2, 3, 4, 5},
+		Addrs:    [][]byte{{1, 1, 1, 1}, {}},
+		Hops:     [][]byte{{1, 2, 3, 4, 0, 80}, {}},
+		IPs:      [][]byte{net.ParseIP("::2")},
+		ByAddr:   map[string]uint32{"fe80::1%eth0": 1},
+		Routes:   map[string]string{"10.0.0.0/8": "lan"},
+		Gateways: map[string][]byte{"lan": {10, 0, 0, 1}},
+	}
+
+	b, err := protobuf3.Marshal(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := protobuf3.Marshal(&w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, c) {
+		t.Errorf("Marshal(NetMsg) = % x\nexpected % x", b, c)
+	}
+
+	var mb NetMsg
+	if err := protobuf3.Unmarshal(b, &mb); err != nil {
+		t.Fatal(err)
+	}
+	m.PAddr = nil // a pointer to the zero value encodes as nothing
+	eq("mb", mb, m, t)
+
+	s, err := protobuf3.AsProtobufFull(reflect.TypeOf(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(s)
+	for _, want := range []string{
+		"  bytes addr = 1;\n",
+		"  bytes ipnet = 6;\n",
+		"  repeated bytes addrs = 8;\n",
+		"  repeated bytes hops = 10;\n",
+		"  map<string, uint32> by_addr = 12;\n",
+		"  map<string, bytes> gateways = 14;\n",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("AsProtobufFull lacks %q", want)
+		}
+	}
+	if strings.Contains(s, "message Addr") || strings.Contains(s, "TODO") {
+		t.Error("AsProtobufFull defined a net type")
+	}
+
+	// bad lengths are errors
+	for _, w := range []NetWireMsg{{Addr: []byte{1, 2, 3}}, {Prefix: []byte{1, 2, 3, 4, 33}}, {IPNet: []byte{1, 2, 3, 4}}} {
+		c, _ := protobuf3.Marshal(&w)
+		if err := protobuf3.Unmarshal(c, &mb); err == nil {
+			t.Errorf("Unmarshal of % x should have failed", c)
+		}
+	}
+	// except for net.IP, which decodes any bytes, as it did when it was decoded as a []byte
+	c, _ = protobuf3.Marshal(&NetWireMsg{IP: []byte{1, 2, 3}})
+	var mi NetMsg
+	if err := protobuf3.Unmarshal(c, &mi); err != nil || !bytes.Equal(mi.IP, []byte{1, 2, 3}) {
+		t.Errorf("Unmarshal of % x returned %v, %v", c, mi.IP, err)
+	}
+
+	// the codec registered by our init() replaced protobuf3's
+	n := macAppends
+	if _, err := protobuf3.Marshal(&m); err != nil || macAppends != n+1 {
+		t.Errorf("the registered net.HardwareAddr codec was called %d times (error %v)", macAppends-n, err)
+	}
+}
+
+type MarshalFormatMsg struct {
+	URL    url.URL             `protobuf:"bytes,1,binary"`
+	PURL   *url.URL            `protobuf:"bytes,2,binary"`
+	URLs   []url.URL           `protobuf:"bytes,3,binary"`
+	Ns     [2]big.Int          `protobuf:"bytes,4,text"` // the option takes precedence over the registered codec
+	When   time.Time           `protobuf:"bytes,5,text"`
+	Limits map[string]*big.Int `protobuf:"bytes,6" protobuf_key:"bytes,1" protobuf_val:"bytes,2,text"`
+	Empty  url.URL             `protobuf:"bytes,7,binary"` // encodes as nothing
+}
+
+// the same wire format
+type MarshalFormatWireMsg struct {
+	URL    []byte            `protobuf:"bytes,1"`
+	PURL   []byte            `protobuf:"bytes,2"`
+	URLs   [][]byte          `protobuf:"bytes,3"`
+	Ns     [2]string         `protobuf:"bytes,4"`
+	When   string            `protobuf:"bytes,5"`
+	Limits map[string]string `protobuf:"bytes,6" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+}
+
+func TestMarshalFormats(t *testing.T) {
+	u, _ := url.Parse("https://example.com/x?y=1")
+	m := MarshalFormatMsg{
+		URL:    *u,
+		PURL:   &url.URL{Scheme: "mailto", Opaque: "ops@example.com"},
+		URLs:   []url.URL{{Path: "a"}, {}},
+		When:   time.Date(2026, 10, 18, 12, 0, 0, 5, time.UTC),
+		Limits: map[string]*big.Int{"max": big.NewInt(-7)},
+	}
+	m.Ns[0].SetInt64(1000)
+
+	w := MarshalFormatWireMsg{
+		URL:    []byte("https://example.com/x?y=1"),
+		PURL:   []byte("mailto:ops@example.com"),
+		URLs:   [][]byte{[]byte("a"), {}},
+		Ns:     [2]string{"1000", "0"},
+		When:   "2026-10-18T12:00:00.000000005Z",
+		Limits: map[string]string{"max": "-7"},
+	}
+
+	b, err := protobuf3.Marshal(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := protobuf3.Marshal(&w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, c) {
+		t.Errorf("Marshal(MarshalFormatMsg) = % x\nexpected % x", b, c)
+	}
+
+	var mb MarshalFormatMsg
+	if err := protobuf3.Unmarshal(b, &mb); err != nil {
+		t.Fatal(err)
+	}
+	eq("URL", mb.URL, m.URL, t)
+	eq("PURL", mb.PURL, m.PURL, t)
+	eq("URLs", mb.URLs, m.URLs, t)
+	if mb.Ns[0].Cmp(&m.Ns[0]) != 0 || mb.Ns[1].Sign() != 0 {
+		t.Errorf("Ns %v", mb.Ns)
+	}
+	if !mb.When.Equal(m.When) {
+		t.Errorf("When %v != %v", mb.When, m.When)
+	}
+	if len(mb.Limits) != 1 || mb.Limits["max"].Int64() != -7 {
+		t.Errorf("Limits %v", mb.Limits)
+	}
+
+	s, err := protobuf3.AsProtobufFull(reflect.TypeOf(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(s)
+	for _, want := range []string{
+		"  bytes url = 1;\n",
+		"  repeated bytes urls = 3;\n",
+		"  repeated string ns = 4;\n",
+		"  string when = 5;\n",
+		"  map<string, string> limits = 6;\n",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("AsProtobufFull lacks %q", want)
+		}
+	}
+	if strings.Contains(s, "message URL") || strings.Contains(s, "TODO") || strings.Contains(s, "timestamp.proto") {
+		t.Error("AsProtobufFull defined a type encoded by its own methods")
+	}
+
+	// a failure to unmarshal is an error
+	c, _ = protobuf3.Marshal(&MarshalFormatWireMsg{When: "yesterday"})
+	if err := protobuf3.Unmarshal(c, &mb); err == nil {
+		t.Errorf("Unmarshal of % x should have failed", c)
+	}
+}
+
+func TestBadMarshalFormat(t *testing.T) {
+	for _, m := range []interface{}{
+		&struct {
+			N int `protobuf:"bytes,1,text"` // int has no MarshalText method
+		}{},
+		&struct {
+			U url.URL `protobuf:"varint,1,binary"`
+		}{},
+		&struct {
+			T time.Time `protobuf:"bytes,1,text,binary"`
+		}{},
+		&struct {
+			T time.Time `protobuf:"bytes,1,rfc3339,text"`
+		}{},
+		&struct {
+			M map[time.Time]int `protobuf:"bytes,1" protobuf_key:"bytes,1,text" protobuf_val:"varint,2"`
+		}{},
+	} {
+		if _, err := protobuf3.Marshal(m); err == nil {
+			t.Errorf("Marshal(%T) should have failed", m)
+		}
+	}
+}
+
+type ListItem struct {
+	N int32 `protobuf:"varint,1"`
+}
+
+type ListMsg struct {
+	Matrix [][]int32     `protobuf:"zigzag32,1"`
+	Words  [][]string    `protobuf:"bytes,2"`
+	Grid   [2][3]float64 `protobuf:"fixed64,3"`
+	Items  [][]ListItem  `protobuf:"bytes,4"`
+	Cube   [][][]uint64  `protobuf:"varint,5"`
+	Pairs  [][2]int32    `protobuf:"zigzag32,6"` // shares Sint32List with Matrix
+	Blobs  [][][]byte    `protobuf:"bytes,7"`
+}
+
+// the same wire format, using the wrapper messages
+type Sint32List struct {
+	Items []int32 `protobuf:"zigzag32,1"`
+}
+type StringList struct {
+	Items []string `protobuf:"bytes,1"`
+}
+type DoubleList struct {
+	Items []float64 `protobuf:"fixed64,1"`
+}
+type ListItemList struct {
+	Items []ListItem `protobuf:"bytes,1"`
+}
+type Uint64List struct {
+	Items []uint64 `protobuf:"varint,1"`
+}
+type Uint64ListList struct {
+	Items []Uint64List `protobuf:"bytes,1"`
+}
+type BytesList struct {
+	Items [][]byte `protobuf:"bytes,1"`
+}
+type ListWireMsg struct {
+	Matrix []Sint32List     `protobuf:"bytes,1"`
+	Words  []StringList     `protobuf:"bytes,2"`
+	Grid   []DoubleList     `protobuf:"bytes,3"`
+	Items  []ListItemList   `protobuf:"bytes,4"`
+	Cube   []Uint64ListList `protobuf:"bytes,5"`
+	Pairs  []Sint32List     `protobuf:"bytes,6"`
+	Blobs  []BytesList      `protobuf:"bytes,7"`
+}
+
+func TestNestedLists(t *testing.T) {
+	m := ListMsg{
+		Matrix: [][]int32{{1, -2}, nil, {3}},
+		Words:  [][]string{{"a", "b"}, {"c"}},
+		Grid:   [2][3]float64{{1, 2, 3}, {4, 5, 6}},
+		Items:  [][]ListItem{{{1}, {2}}},
+		Cube:   [][][]uint64{{{1, 2}, {3}}, {{4}}},
+		Pairs:  [][2]int32{{5, 6}, {0, 7}},
+		Blobs:  [][][]byte{{[]byte("x"), {}}},
+	}
+	w := ListWireMsg{
+		Matrix: []Sint32List{{[]int32{1, -2}}, {}, {[]int32{3}}},
+		Words:  []StringList{{[]string{"a", "b"}}, {[]string{"c"}}},
+		Grid:   []DoubleList{{[]float64{1, 2, 3}}, {[]float64{4, 5, 6}}},
+		Items:  []ListItemList{{[]ListItem{{1}, {2}}}},
+		Cube:   []Uint64ListList{{[]Uint64List{{[]uint64{1, 2}}, {[]uint64{3}}}}, {[]Uint64List{{[]uint64{4}}}}},
+		Pairs:  []Sint32List{{[]int32{5, 6}}, {[]int32{0, 7}}},
+		Blobs:  []BytesList{{[][]byte{[]byte("x"), {}}}},
+	}
+
+	b, err := protobuf3.Marshal(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := protobuf3.Marshal(&w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, c) {
+		t.Errorf("Marshal(ListMsg) = % x\nexpected % x", b, c)
+	}
+
+	var mb ListMsg
+	if err := protobuf3.Unmarshal(b, &mb); err != nil {
+		t.Fatal(err)
+	}
+	eq("mb", mb, m, t)
+
+	s, err := protobuf3.AsProtobufFull(reflect.TypeOf(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(s)
+	for _, want := range []string{
+		"  repeated Sint32List matrix = 1;\n",
+		"  repeated DoubleList grid = 3;\n",
+		"  repeated Int64ListList cube = 5;\n", // []uint64 is described as repeated int64
+		"  repeated Sint32List pairs = 6;\n",
+		"message Sint32List {\n  repeated sint32 items = 1;\n}",
+		"message ListItemList {\n  repeated ListItem items = 1;\n}",
+		"message Int64ListList {\n  repeated Int64List items = 1;\n}",
+		"message Int64List {\n  repeated int64 items = 1;\n}",
+		"message BytesList {\n  repeated bytes items = 1;\n}",
+		"message ListItem {\n",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("AsProtobufFull lacks %q", want)
+		}
+	}
+	if strings.Count(s, "message Sint32List") != 1 {
+		t.Error("AsProtobufFull defined Sint32List more than once")
+	}
+}
+
+// lists of lists whose tag options apply to the items of the lists
+type OptionListMsg struct {
+	Epochs [][]time.Time  `protobuf:"varint,1,unixnano"`
+	Dates  [2][]time.Time `protobuf:"bytes,2,rfc3339"`
+	Links  [][]url.URL    `protobuf:"bytes,3,binary"`
+}
+
+type Int64List struct {
+	Items []int64 `protobuf:"varint,1"`
+}
+type OptionListWireMsg struct {
+	Epochs []Int64List  `protobuf:"bytes,1"`
+	Dates  []StringList `protobuf:"bytes,2"`
+	Links  []StringList `protobuf:"bytes,3"`
+}
+
+func TestNestedOptionLists(t *testing.T) {
+	t1 := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Second)
+	m := OptionListMsg{
+		Epochs: [][]time.Time{{t1, t2}, {t2}},
+		Dates:  [2][]time.Time{{t1}, {t1, t2}},
+		Links:  [][]url.URL{{{Scheme: "https", Host: "example.com"}}},
+	}
+	w := OptionListWireMsg{
+		Epochs: []Int64List{{[]int64{t1.UnixNano(), t2.UnixNano()}}, {[]int64{t2.UnixNano()}}},
+		Dates:  []StringList{{[]string{t1.Format(time.RFC3339Nano)}}, {[]string{t1.Format(time.RFC3339Nano), t2.Format(time.RFC3339Nano)}}},
+		Links:  []StringList{{[]string{"https://example.com"}}},
+	}
+
+	b, err := protobuf3.Marshal(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := protobuf3.Marshal(&w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, c) {
+		t.Errorf("Marshal(OptionListMsg) = % x\nexpected % x", b, c)
+	}
+
+	var mb OptionListMsg
+	if err := protobuf3.Unmarshal(b, &mb); err != nil {
+		t.Fatal(err)
+	}
+	eq("mb", mb, m, t)
+
+	s, err := protobuf3.AsProtobufFull(reflect.TypeOf(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(s)
+	for _, want := range []string{
+		"  repeated Int64List epochs = 1;\n",
+		"  repeated StringList dates = 2;\n",
+		"  repeated BytesList links = 3;\n",
+		"message Int64List {\n  repeated int64 items = 1;\n}",
+		"message StringList {\n  repeated string items = 1;\n}",
+		"message BytesList {\n  repeated bytes items = 1;\n}",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("AsProtobufFull lacks %q", want)
+		}
+	}
 }
 
 type CustomMarshalerMsg struct {
@@ -1672,93 +2687,733 @@ func TestEmbeddedMsg(t *testing.T) {
 			t.Error("BadEmbeddedMsg should have caused an error")
 		}
 	}
-}
-
-type BadMapMsg struct {
-	A struct {
-		m map[string]int32 `protobuf:"varint,1" protobuf_key:"bytes,1" protobuf_val:"varint,2"` // must use bytes wiretype for maps
+}
+
+type EmbeddedPtrMsg struct {
+	X                  uint32                `protobuf:"varint,2"`
+	*InnerEmbeddedMsg  `protobuf:"embedded"` // marshals as part of the outer struct's fields, when it isn't nil
+	*MiddleEmbeddedMsg `protobuf:"embedded"`
+}
+
+type MiddleEmbeddedMsg struct {
+	Y                    int32 `protobuf:"zigzag32,3"`
+	*InnerEmbeddedPtrMsg `protobuf:"embedded"`
+
+	_ protobuf3.Reserved `protobuf:"6"`
+}
+
+type InnerEmbeddedPtrMsg struct {
+	Z []string `protobuf:"bytes,4"`
+}
+
+// the same wire format, without embedding
+type EmbeddedPtrWireMsg struct {
+	S string   `protobuf:"bytes,1"`
+	X uint32   `protobuf:"varint,2"`
+	Y int32    `protobuf:"zigzag32,3"`
+	Z []string `protobuf:"bytes,4"`
+}
+
+func TestEmbeddedPtrMsg(t *testing.T) {
+	for _, c := range []struct {
+		m EmbeddedPtrMsg
+		w EmbeddedPtrWireMsg
+	}{
+		{EmbeddedPtrMsg{X: 1}, EmbeddedPtrWireMsg{X: 1}},
+		{EmbeddedPtrMsg{X: 1, InnerEmbeddedMsg: &InnerEmbeddedMsg{S: "abc"}}, EmbeddedPtrWireMsg{S: "abc", X: 1}},
+		{EmbeddedPtrMsg{MiddleEmbeddedMsg: &MiddleEmbeddedMsg{Y: -2}}, EmbeddedPtrWireMsg{Y: -2}},
+		{
+			EmbeddedPtrMsg{InnerEmbeddedMsg: &InnerEmbeddedMsg{S: "x"}, MiddleEmbeddedMsg: &MiddleEmbeddedMsg{Y: 3, InnerEmbeddedPtrMsg: &InnerEmbeddedPtrMsg{Z: []string{"a", "b"}}}},
+			EmbeddedPtrWireMsg{S: "x", Y: 3, Z: []string{"a", "b"}},
+		},
+	} {
+		b, err := protobuf3.Marshal(&c.m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wb, err := protobuf3.Marshal(&c.w)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, wb) {
+			t.Errorf("Marshal(%+v) = % x\nexpected % x", c.m, b, wb)
+		}
+
+		// decoding allocates the embedded structs whose fields arrive
+		var m EmbeddedPtrMsg
+		if err := protobuf3.Unmarshal(b, &m); err != nil {
+			t.Fatal(err)
+		}
+		eq("m", m, c.m, t)
+	}
+
+	var bad struct {
+		*InnerEmbeddedMsg `protobuf:"embedded"`
+		*EmbeddedMsg      `protobuf:"embedded"` // InnerEmbeddedMsg.S, embedded twice
+	}
+	if _, err := protobuf3.Marshal(&bad); err == nil || !strings.Contains(err.Error(), "duplicate tag id 1") {
+		t.Errorf("Marshal of a duplicate embedded tag returned %v", err)
+	}
+	var reserved struct {
+		*MiddleEmbeddedMsg `protobuf:"embedded"`
+		W                  int `protobuf:"varint,6"`
+	}
+	if _, err := protobuf3.Marshal(&reserved); err == nil || !strings.Contains(err.Error(), "reserved tag id 6") {
+		t.Errorf("Marshal of a tag reserved by an embedded struct returned %v", err)
+	}
+}
+
+type BadMapMsg struct {
+	A struct {
+		m map[string]int32 `protobuf:"varint,1" protobuf_key:"bytes,1" protobuf_val:"varint,2"` // must use bytes wiretype for maps
+	}
+	B struct {
+		m map[string]int32 `protobuf:"bytes,1" protobuf_key:"-" protobuf_val:"varint,2"` // can't skip keys
+	}
+	C struct {
+		m map[string]int32 `protobuf:"bytes,1" protobuf_key:"bytes,3" protobuf_val:"varint,2"` // keys must use tag 1
+	}
+	D struct {
+		m map[string]int32 `protobuf:"bytes,1" protobuf_key:"bytes,1" protobuf_val:"-"` // can't skip values (we could support it if we ever needed it. for now if all the values are zero-values we're effectively skipping them)
+	}
+	E struct {
+		m map[string]int32 `protobuf:"bytes,1" protobuf_key:"bytes,1" protobuf_val:"varint,3"` // values must use tag 2
+	}
+}
+
+func TestBadMapMsg(t *testing.T) {
+	var m BadMapMsg
+	_, err := protobuf3.Marshal(&m.A)
+	t.Log(err)
+	if err == nil {
+		t.Error("BadMapMsg.A should have caused an error")
+	}
+
+	_, err = protobuf3.Marshal(&m.B)
+	t.Log(err)
+	if err == nil {
+		t.Error("BadMapMsg.B should have caused an error")
+	}
+
+	_, err = protobuf3.Marshal(&m.C)
+	t.Log(err)
+	if err == nil {
+		t.Error("BadMapMsg.C should have caused an error")
+	}
+
+	_, err = protobuf3.Marshal(&m.D)
+	t.Log(err)
+	if err == nil {
+		t.Error("BadMapMsg.D should have caused an error")
+	}
+
+	_, err = protobuf3.Marshal(&m.E)
+	t.Log(err)
+	if err == nil {
+		t.Error("BadMapMsg.E should have caused an error")
+	}
+}
+
+type MapOfSliceOfStruct struct {
+	m map[int][]StructForMap `protobuf:"bytes,1" protobuf_key:"varint,1" protobuf_val:"bytes,2"`
+}
+
+type StructForMap struct {
+	s string `protobuf:"bytes,1"`
+	t bool   `protobuf:"varint,2"`
+}
+
+func TestMapOfSliceOfStruct(t *testing.T) {
+	var m = MapOfSliceOfStruct{
+		m: make(map[int][]StructForMap),
+	}
+	m.m[0] = nil
+	m.m[1] = []StructForMap{StructForMap{s: "one.0", t: false}, StructForMap{s: "one.1", t: true}, StructForMap{s: "one.2", t: false}}
+	m.m[2] = []StructForMap{StructForMap{s: "two.3", t: true}}
+
+	b, err := protobuf3.Marshal(&m)
+	if err != nil {
+		t.Error(err)
+	}
+
+	var m2 MapOfSliceOfStruct
+	err = protobuf3.Unmarshal(b, &m2)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if !reflect.DeepEqual(&m, &m2) {
+		t.Error("unmarshal(marshal(x)) != x")
+		t.Errorf("x = %+v", &m)
+		t.Errorf("unmarshal(marshal(x)) = %+v", &m2)
+	}
+	checkslices(m2, t)
+}
+
+type MapOfWrappedValues struct {
+	Tags    map[string][]string          `protobuf:"bytes,1" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+	Counts  map[string]map[string]int    `protobuf:"bytes,2" protobuf_key:"bytes,1" protobuf_val:"bytes,2" protobuf_val_key:"bytes,1" protobuf_val_val:"varint,2"`
+	Deep    map[int32]map[string][]int64 `protobuf:"bytes,3" protobuf_key:"varint,1" protobuf_val:"bytes,2" protobuf_val_key:"bytes,1" protobuf_val_val:"zigzag64,2"`
+	Grids   map[string][][2]int32        `protobuf:"bytes,4" protobuf_key:"bytes,1" protobuf_val:"zigzag32,2"`
+	Levels  map[uint32][3]float32        `protobuf:"bytes,5" protobuf_key:"varint,1" protobuf_val:"fixed32,2"`
+	Structs map[int][]StructForMap       `protobuf:"bytes,6" protobuf_key:"varint,1" protobuf_val:"bytes,2"`
+}
+
+// the same wire format, using the wrapper messages
+type StringInt32Map struct {
+	Items map[string]int `protobuf:"bytes,1" protobuf_key:"bytes,1" protobuf_val:"varint,2"`
+}
+type Sint64List struct {
+	Items []int64 `protobuf:"zigzag64,1"`
+}
+type StringSint64ListMap struct {
+	Items map[string]Sint64List `protobuf:"bytes,1" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+}
+type FloatList struct {
+	Items []float32 `protobuf:"fixed32,1"`
+}
+type StructForMapList struct {
+	Items []StructForMap `protobuf:"bytes,1"`
+}
+type MapOfWrappedValuesWire struct {
+	Tags   map[string]StringList         `protobuf:"bytes,1" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+	Counts map[string]StringInt32Map     `protobuf:"bytes,2" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+	Deep   map[int32]StringSint64ListMap `protobuf:"bytes,3" protobuf_key:"varint,1" protobuf_val:"bytes,2"`
+	Grids  map[string]struct {
+		Items []Sint32List `protobuf:"bytes,1"`
+	} `protobuf:"bytes,4" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+	Levels  map[uint32]FloatList     `protobuf:"bytes,5" protobuf_key:"varint,1" protobuf_val:"bytes,2"`
+	Structs map[int]StructForMapList `protobuf:"bytes,6" protobuf_key:"varint,1" protobuf_val:"bytes,2"`
+}
+
+func TestMapOfWrappedValues(t *testing.T) {
+	// one key per map, so the encoding is deterministic
+	m := MapOfWrappedValues{
+		Tags:    map[string][]string{"a": {"x", "y"}},
+		Counts:  map[string]map[string]int{"b": {"c": 3}},
+		Deep:    map[int32]map[string][]int64{-4: {"d": {-1, 2}}},
+		Grids:   map[string][][2]int32{"e": {{1, 2}, {3, -4}}},
+		Levels:  map[uint32][3]float32{5: {0.5, 1, 2}},
+		Structs: map[int][]StructForMap{6: {{s: "six", t: true}}},
+	}
+	w := MapOfWrappedValuesWire{
+		Tags:   map[string]StringList{"a": {[]string{"x", "y"}}},
+		Counts: map[string]StringInt32Map{"b": {map[string]int{"c": 3}}},
+		Deep:   map[int32]StringSint64ListMap{-4: {map[string]Sint64List{"d": {[]int64{-1, 2}}}}},
+		Grids: map[string]struct {
+			Items []Sint32List `protobuf:"bytes,1"`
+		}{"e": {[]Sint32List{{[]int32{1, 2}}, {[]int32{3, -4}}}}},
+		Levels:  map[uint32]FloatList{5: {[]float32{0.5, 1, 2}}},
+		Structs: map[int]StructForMapList{6: {[]StructForMap{{s: "six", t: true}}}},
+	}
+
+	b, err := protobuf3.Marshal(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := protobuf3.Marshal(&w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, c) {
+		t.Errorf("Marshal(MapOfWrappedValues) = % x\nexpected % x", b, c)
+	}
+
+	var mb MapOfWrappedValues
+	if err := protobuf3.Unmarshal(b, &mb); err != nil {
+		t.Fatal(err)
+	}
+	eq("mb", mb, m, t)
+
+	s, err := protobuf3.AsProtobufFull(reflect.TypeOf(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(s)
+	for _, want := range []string{
+		"  map<string, StringList> tags = 1;\n",
+		"  map<string, StringInt32Map> counts = 2;\n",
+		"  map<int32, StringSint64ListMap> deep = 3;\n",
+		"  map<string, Sint32ListList> grids = 4;\n",
+		"  map<uint32, FloatList> levels = 5;\n",
+		"  map<int32, StructForMapList> structs = 6;\n",
+		"message StringInt32Map {\n  map<string, int32> items = 1;\n}",
+		"message StringSint64ListMap {\n  map<string, Sint64List> items = 1;\n}",
+		"message Sint64List {\n  repeated sint64 items = 1;\n}",
+		"message Sint32ListList {\n  repeated Sint32List items = 1;\n}",
+		"message StructForMapList {\n  repeated StructForMap items = 1;\n}",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("AsProtobufFull lacks %q", want)
+		}
+	}
+}
+
+type SetMsg struct {
+	Ints  map[int32]struct{}  `protobuf:"zigzag32,1"`
+	Names map[string]struct{} `protobuf:"bytes,2"`
+	Flags map[uint32]bool     `protobuf:"varint,3,set"`
+	Pairs map[int]struct{}    `protobuf:"bytes,4" protobuf_key:"varint,1" protobuf_val:"bytes,2"` // a map, not a set
+}
+
+// the same wire format, with the elements in order
+type SetWireMsg struct {
+	Ints  []int32          `protobuf:"zigzag32,1"`
+	Names []string         `protobuf:"bytes,2"`
+	Flags []uint32         `protobuf:"varint,3"`
+	Pairs map[int]struct{} `protobuf:"bytes,4" protobuf_key:"varint,1" protobuf_val:"bytes,2"`
+}
+
+func TestSets(t *testing.T) {
+	m := SetMsg{
+		Ints:  map[int32]struct{}{3: {}, -1: {}, 2: {}, 0: {}},
+		Names: map[string]struct{}{"b": {}, "c": {}, "a": {}},
+		Flags: map[uint32]bool{9: true, 7: false, 8: true},
+		Pairs: map[int]struct{}{2: {}, 1: {}},
+	}
+	w := SetWireMsg{
+		Ints:  []int32{-1, 0, 2, 3},
+		Names: []string{"a", "b", "c"},
+		Flags: []uint32{8, 9},
+		Pairs: map[int]struct{}{2: {}, 1: {}},
+	}
+
+	b, err := protobuf3.MarshalDeterministic(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := protobuf3.MarshalDeterministic(&w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, c) {
+		t.Errorf("MarshalDeterministic(SetMsg) = % x\nexpected % x", b, c)
+	}
+
+	var mb SetMsg
+	if err := protobuf3.Unmarshal(b, &mb); err != nil {
+		t.Fatal(err)
+	}
+	delete(m.Flags, 7) // false isn't a member of the set
+	eq("mb", mb, m, t)
+
+	s, err := protobuf3.AsProtobuf(reflect.TypeOf(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"  repeated sint32 ints = 1;\n",
+		"  repeated string names = 2;\n",
+		"  repeated uint32 flags = 3;\n",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("AsProtobuf lacks %q\n%s", want, s)
+		}
+	}
+
+	var bad struct {
+		M map[int]int `protobuf:"varint,1,set"`
+	}
+	if _, err := protobuf3.Marshal(&bad); err == nil {
+		t.Error("Marshal of a map[int]int with the set option should have failed")
+	}
+}
+
+type DeterministicMapMsg struct {
+	Names map[string]int32 `protobuf:"bytes,1" protobuf_key:"bytes,1" protobuf_val:"varint,2"`
+	IDs   map[int32]string `protobuf:"bytes,2" protobuf_key:"zigzag32,1" protobuf_val:"bytes,2"`
+}
+
+// MarshalDeterministic sorts the entries of maps, not only the members of sets
+func TestDeterministicMaps(t *testing.T) {
+	m := DeterministicMapMsg{
+		Names: map[string]int32{"c": 3, "a": 1, "b": 2},
+		IDs:   map[int32]string{5: "p", -1: "m", 0: "z"},
+	}
+	expected := []byte{
+		0x0a, 0x05, 0x0a, 0x01, 'a', 0x10, 0x01,
+		0x0a, 0x05, 0x0a, 0x01, 'b', 0x10, 0x02,
+		0x0a, 0x05, 0x0a, 0x01, 'c', 0x10, 0x03,
+		0x12, 0x05, 0x08, 0x01, 0x12, 0x01, 'm', // -1
+		0x12, 0x03, 0x12, 0x01, 'z', // 0, which like any zero key is omitted
+		0x12, 0x05, 0x08, 0x0a, 0x12, 0x01, 'p', // 5
+	}
+	// go randomizes the order of iterating over maps, so one lucky encoding proves little
+	for i := 0; i < 10; i++ {
+		b, err := protobuf3.MarshalDeterministic(&m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, expected) {
+			t.Fatalf("MarshalDeterministic(DeterministicMapMsg) = % x\nexpected % x", b, expected)
+		}
+	}
+}
+
+type ByteArraysMsg struct {
+	Hashes [][4]byte            `protobuf:"bytes,1"`
+	Keys   [2][3]byte           `protobuf:"bytes,2"`
+	Ptrs   []*[2]byte           `protobuf:"bytes,3"`
+	Groups map[string][][2]byte `protobuf:"bytes,4" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+	Nested [][][2]byte          `protobuf:"bytes,5"`
+}
+
+// the same wire format, using []byte
+type ByteArraysWireMsg struct {
+	Hashes [][]byte             `protobuf:"bytes,1"`
+	Keys   [][]byte             `protobuf:"bytes,2"`
+	Ptrs   [][]byte             `protobuf:"bytes,3"`
+	Groups map[string]BytesList `protobuf:"bytes,4" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+	Nested []BytesList          `protobuf:"bytes,5"`
+}
+
+func TestRepeatedByteArrays(t *testing.T) {
+	m := ByteArraysMsg{
+		Hashes: [][4]byte{{1, 2, 3, 4}, {}, {5, 6, 7, 8}},
+		Keys:   [2][3]byte{{1, 2, 3}, {4, 5, 6}},
+		Ptrs:   []*[2]byte{{9, 8}, {0, 7}},
+		Groups: map[string][][2]byte{"a": {{1, 2}, {3, 4}}},
+		Nested: [][][2]byte{{{1, 1}}, nil, {{2, 2}, {3, 3}}},
+	}
+	w := ByteArraysWireMsg{
+		Hashes: [][]byte{{1, 2, 3, 4}, {0, 0, 0, 0}, {5, 6, 7, 8}},
+		Keys:   [][]byte{{1, 2, 3}, {4, 5, 6}},
+		Ptrs:   [][]byte{{9, 8}, {0, 7}},
+		Groups: map[string]BytesList{"a": {[][]byte{{1, 2}, {3, 4}}}},
+		Nested: []BytesList{{[][]byte{{1, 1}}}, {}, {[][]byte{{2, 2}, {3, 3}}}},
+	}
+
+	b, err := protobuf3.Marshal(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := protobuf3.Marshal(&w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, c) {
+		t.Errorf("Marshal(ByteArraysMsg) = % x\nexpected % x", b, c)
+	}
+
+	var mb ByteArraysMsg
+	if err := protobuf3.Unmarshal(b, &mb); err != nil {
+		t.Fatal(err)
+	}
+	eq("mb", mb, m, t)
+
+	s, err := protobuf3.AsProtobufFull(reflect.TypeOf(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"  repeated bytes hashes = 1;\n",
+		"  repeated bytes keys = 2;\n",
+		"  repeated bytes ptrs = 3;\n",
+		"  map<string, BytesList> groups = 4;\n",
+		"  repeated BytesList nested = 5;\n",
+		"message BytesList {\n  repeated bytes items = 1;\n}",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("AsProtobufFull lacks %q\n%s", want, s)
+		}
+	}
+
+	if _, err := protobuf3.Marshal(&struct {
+		Ptrs []*[2]byte `protobuf:"bytes,1"`
+	}{Ptrs: []*[2]byte{nil}}); err != protobuf3.ErrRepeatedHasNil {
+		t.Errorf("Marshal of a nil *[2]byte returned %v", err)
+	}
+
+	// elements of the wrong length are an error, unless the field has the resize option
+	b, err = protobuf3.Marshal(&ByteArraysWireMsg{Hashes: [][]byte{{1, 2, 3, 4, 5}}, Keys: [][]byte{{1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := protobuf3.Unmarshal(b, &mb); err == nil || !strings.Contains(err.Error(), "5 bytes long, not 4") {
+		t.Errorf("Unmarshal of a 5 byte [4]byte returned %v", err)
+	}
+	var rb struct {
+		Hashes [][4]byte  `protobuf:"bytes,1,resize"`
+		Keys   [2][3]byte `protobuf:"bytes,2,resize"`
+	}
+	rb.Keys[0] = [3]byte{7, 7, 7}
+	if err := protobuf3.Unmarshal(b, &rb); err != nil {
+		t.Fatal(err)
+	}
+	if rb.Hashes[0] != [4]byte{1, 2, 3, 4} || rb.Keys[0] != [3]byte{1, 0, 0} {
+		t.Errorf("Unmarshal with the resize option = %v", rb)
+	}
+}
+
+type Page[T any] struct {
+	Items []T    `protobuf:"bytes,1"`
+	Next  string `protobuf:"bytes,2"`
+}
+
+type Pair[K, V any] struct {
+	Key K `protobuf:"bytes,1"`
+	Val V `protobuf:"bytes,2"`
+}
+
+type GenericItem struct {
+	N int32 `protobuf:"varint,1"`
+}
+
+type GenericsMsg struct {
+	Items  Page[GenericItem]                `protobuf:"bytes,1"`
+	Ptrs   *Page[*GenericItem]              `protobuf:"bytes,2"`
+	Nested Pair[string, Page[GenericItem]]  `protobuf:"bytes,3"`
+	Lists  []Pair[[]string, [2]GenericItem] `protobuf:"bytes,4"`
+}
+
+// a generic type only ever named by a replacement MakeGenericTypeName
+type Box[T any] struct {
+	V T `protobuf:"bytes,1"`
+}
+
+func TestGenericTypeNames(t *testing.T) {
+	for _, c := range []struct {
+		v    interface{}
+		name string
+	}{
+		{Page[SetMsg]{}, "PageOfSetMsg"},
+		{Page[*SetMsg]{}, "PageOfPtrSetMsg"},
+		{Pair[string, Page[SetMsg]]{}, "PairOfStringAndPageOfSetMsg"},
+		{Pair[[]byte, [4]uint8]{}, "PairOfSliceOfUint8AndArray4OfUint8"},
+		{Pair[map[string][]int, time.Duration]{}, "PairOfMapOfStringToSliceOfIntAndDuration"},
+		{Page[struct {
+			X int `protobuf:"varint,1,[x]"`
+		}]{}, "PageOfStruct"},
+		{Pair[interface{}, func(int, string) error]{}, "PairOfInterfaceAndFunc"},
+		{Pair[chan<- int, error]{}, "PairOfChanAndError"},
+	} {
+		if name := protobuf3.MakeOfGenericTypeName(reflect.TypeOf(c.v)); name != c.name {
+			t.Errorf("MakeOfGenericTypeName(%T) = %q, expected %q", c.v, name, c.name)
+		}
+	}
+
+	m := GenericsMsg{
+		Items:  Page[GenericItem]{Items: []GenericItem{{1}, {2}}, Next: "a"},
+		Ptrs:   &Page[*GenericItem]{Items: []*GenericItem{{3}}},
+		Nested: Pair[string, Page[GenericItem]]{Key: "b", Val: Page[GenericItem]{Next: "c"}},
+		Lists:  []Pair[[]string, [2]GenericItem]{{Key: []string{"d", "e"}, Val: [2]GenericItem{{4}, {5}}}},
+	}
+	b, err := protobuf3.Marshal(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mb GenericsMsg
+	if err := protobuf3.Unmarshal(b, &mb); err != nil {
+		t.Fatal(err)
+	}
+	eq("mb", mb, m, t)
+
+	// every message and every type of a field must be a valid protobuf identifier
+	s, err := protobuf3.AsProtobufFull(reflect.TypeOf(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(s)
+	ident := regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+	field := regexp.MustCompile(`^\s*(?:repeated |optional )?(\S+) \w+ = \d+;$`)
+	message := regexp.MustCompile(`^\s*message (\S+) \{$`)
+	var messages []string
+	for _, line := range strings.Split(s, "\n") {
+		if sm := message.FindStringSubmatch(line); sm != nil {
+			if !ident.MatchString(sm[1]) {
+				t.Errorf("invalid message name %q", sm[1])
+			}
+			messages = append(messages, sm[1])
+		} else if sm := field.FindStringSubmatch(line); sm != nil && !strings.HasPrefix(sm[1], "map<") && !ident.MatchString(sm[1]) {
+			t.Errorf("invalid type %q in %q", sm[1], line)
+		}
+	}
+	for _, want := range []string{"PageOfGenericItem", "PageOfPtrGenericItem", "PairOfStringAndPageOfGenericItem", "PairOfSliceOfStringAndArray2OfGenericItem"} {
+		found := false
+		for _, name := range messages {
+			found = found || name == want
+		}
+		if !found {
+			t.Errorf("AsProtobufFull lacks message %s", want)
+		}
 	}
-	B struct {
-		m map[string]int32 `protobuf:"bytes,1" protobuf_key:"-" protobuf_val:"varint,2"` // can't skip keys
+	if !strings.Contains(s, "  PageOfPtrGenericItem ptrs = 2;\n") {
+		t.Errorf("AsProtobufFull lacks the ptrs field\n%s", s)
 	}
-	C struct {
-		m map[string]int32 `protobuf:"bytes,1" protobuf_key:"bytes,3" protobuf_val:"varint,2"` // keys must use tag 1
+
+	// the names can be chosen by replacing MakeGenericTypeName
+	defer func(f func(reflect.Type) string) { protobuf3.MakeGenericTypeName = f }(protobuf3.MakeGenericTypeName)
+	protobuf3.MakeGenericTypeName = func(t reflect.Type) string {
+		return "Generic_" + protobuf3.MakeOfGenericTypeName(t)
 	}
-	D struct {
-		m map[string]int32 `protobuf:"bytes,1" protobuf_key:"bytes,1" protobuf_val:"-"` // can't skip values (we could support it if we ever needed it. for now if all the values are zero-values we're effectively skipping them)
+	s, err = protobuf3.AsProtobufFull(reflect.TypeOf(Box[Box[GenericItem]]{}))
+	if err != nil {
+		t.Fatal(err)
 	}
-	E struct {
-		m map[string]int32 `protobuf:"bytes,1" protobuf_key:"bytes,1" protobuf_val:"varint,3"` // values must use tag 2
+	if !strings.Contains(s, "message Generic_BoxOfBoxOfGenericItem {\n") || !strings.Contains(s, "  Generic_BoxOfGenericItem v = 1;\n") {
+		t.Errorf("AsProtobufFull didn't use MakeGenericTypeName\n%s", s)
 	}
 }
 
-func TestBadMapMsg(t *testing.T) {
-	var m BadMapMsg
-	_, err := protobuf3.Marshal(&m.A)
-	t.Log(err)
-	if err == nil {
-		t.Error("BadMapMsg.A should have caused an error")
-	}
+type Config struct {
+	Host string `protobuf:"bytes,1"`
+}
 
-	_, err = protobuf3.Marshal(&m.B)
-	t.Log(err)
-	if err == nil {
-		t.Error("BadMapMsg.B should have caused an error")
-	}
+// a message using types from two packages which have the same names
+type CollidingMsg struct {
+	Local  Config                       `protobuf:"bytes,1"`
+	Other  collide.Config               `protobuf:"bytes,2"`
+	Locals [][]Config                   `protobuf:"bytes,3"`
+	Others [][]collide.Config           `protobuf:"bytes,4"`
+	ByName map[string]collide.Config    `protobuf:"bytes,5" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+	Color  Color                        `protobuf:"varint,6"`
+	Colors []collide.Color              `protobuf:"varint,7"`
+	Deep   map[string][]*collide.Config `protobuf:"bytes,8" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+	Inline struct {
+		C collide.Config `protobuf:"bytes,1"`
+	} `protobuf:"bytes,9"`
+}
 
-	_, err = protobuf3.Marshal(&m.C)
-	t.Log(err)
-	if err == nil {
-		t.Error("BadMapMsg.C should have caused an error")
+// CollideConfig has the name collide.Config is renamed to
+type CollideConfig struct {
+	Other collide.Config `protobuf:"bytes,1"`
+	Local Config         `protobuf:"bytes,2"`
+}
+
+func TestNameCollisions(t *testing.T) {
+	s, err := protobuf3.AsProtobufFull(reflect.TypeOf(CollidingMsg{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(s)
+	for _, want := range []string{
+		"\nmessage CollidingMsg {\n" +
+			"  Config local = 1;\n" +
+			"  CollideConfig other = 2;\n" +
+			"  repeated ConfigList locals = 3;\n" +
+			"  repeated CollideConfigList others = 4;\n" +
+			"  map<string, CollideConfig> by_name = 5;\n" +
+			"  Color color = 6;\n" +
+			"  repeated CollideColor colors = 7;\n" +
+			"  map<string, CollideConfigList> deep = 8;\n" +
+			"  message Inline {\n" +
+			"    CollideConfig c = 1;\n" +
+			"  }\n" +
+			"  Inline inline = 9;\n" +
+			"}\n",
+		"\nmessage CollideConfig {\n  string name = 1;\n  uint32 retries = 2;\n}\n",
+		"\nmessage CollideConfigList {\n  repeated CollideConfig items = 1;\n}\n",
+		"\nmessage Config {\n  string host = 1;\n}\n",
+		"\nmessage ConfigList {\n  repeated Config items = 1;\n}",
+		"\nenum CollideColor {\n  BLACK = 0;\n",
+		"\nenum Color {\n  option allow_alias = true;\n  NONE = 0;\n",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("AsProtobufFull lacks %q", want)
+		}
 	}
 
-	_, err = protobuf3.Marshal(&m.D)
-	t.Log(err)
-	if err == nil {
-		t.Error("BadMapMsg.D should have caused an error")
+	// when collide.Config is the type of the package, it keeps its name and Config is renamed
+	s, err = protobuf3.AsProtobufFull(reflect.TypeOf(collide.Config{}), reflect.TypeOf(CollidingMsg{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(s, "\nmessage Config {\n  string name = 1;\n") || !strings.Contains(s, "\nmessage Protobuf3_testConfig {\n  string host = 1;\n") {
+		t.Errorf("AsProtobufFull didn't rename Config\n%s", s)
 	}
 
-	_, err = protobuf3.Marshal(&m.E)
-	t.Log(err)
-	if err == nil {
-		t.Error("BadMapMsg.E should have caused an error")
+	// collisions which renaming doesn't resolve are errors
+	_, err = protobuf3.AsProtobufFull(reflect.TypeOf(CollideConfig{}))
+	if err == nil || !strings.Contains(err.Error(), "are both named CollideConfig") {
+		t.Errorf("AsProtobufFull of CollideConfig returned %v", err)
 	}
-}
 
-type MapOfSliceOfStruct struct {
-	m map[int][]StructForMap `protobuf:"bytes,1" protobuf_key:"varint,1" protobuf_val:"bytes,2"`
+	// as are wrappers named the same as a message, like StringList and the wrapper of ListMsg.Words
+	_, err = protobuf3.AsProtobufFull(reflect.TypeOf(ListMsg{}), reflect.TypeOf(StringList{}))
+	if err == nil || !strings.Contains(err.Error(), "protobuf3_test.StringList and the wrapper of []string are both named StringList") {
+		t.Errorf("AsProtobufFull of ListMsg and StringList returned %v", err)
+	}
 }
 
-type StructForMap struct {
-	s string `protobuf:"bytes,1"`
-	t bool   `protobuf:"varint,2"`
-}
+func TestGenerator(t *testing.T) {
+	typ := reflect.TypeOf(CollidingMsg{})
+	expected, err := protobuf3.AsProtobufFull(typ)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-func TestMapOfSliceOfStruct(t *testing.T) {
-	var m = MapOfSliceOfStruct{
-		m: make(map[int][]StructForMap),
+	// the zero Generator is the same as AsProtobufFull
+	var zero protobuf3.Generator
+	s, err := zero.AsProtobufFull(typ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != expected {
+		t.Errorf("Generator{}.AsProtobufFull() =\n%s\nexpected\n%s", s, expected)
 	}
-	m.m[0] = nil
-	m.m[1] = []StructForMap{StructForMap{s: "one.0", t: false}, StructForMap{s: "one.1", t: true}, StructForMap{s: "one.2", t: false}}
-	m.m[2] = []StructForMap{StructForMap{s: "two.3", t: true}}
 
-	b, err := protobuf3.Marshal(&m)
+	g := protobuf3.Generator{
+		FieldName: func(f string, t reflect.Type) string { return strings.ToUpper(f) },
+		TypeName: func(t reflect.Type, f string) string {
+			if t.Name() != "" {
+				return "T" + t.Name()
+			}
+			return "Anon" + f
+		},
+		PackageName: func(pkgpath string) string { return "devices.v1" },
+		Headers:     []string{"// a header"},
+		FileOptions: map[string]string{"java_package": "com.example.devices", "go_package": "example.com/devices"},
+		NoComments:  true,
+	}
+	s, err = g.AsProtobufFull(typ)
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
+	}
+	t.Log(s)
+	if !strings.HasPrefix(s, "syntax = \"proto3\";\n\npackage devices.v1;\noption go_package = \"example.com/devices\";\noption java_package = \"com.example.devices\";\n// a header\n") {
+		t.Errorf("Generator.AsProtobufFull() headers are wrong\n%s", s)
+	}
+	for _, want := range []string{
+		"\nmessage TCollidingMsg {\n" +
+			"  TConfig LOCAL = 1;\n" +
+			"  Devicesv1TConfig OTHER = 2;\n" +
+			"  repeated TConfigList LOCALS = 3;\n" +
+			"  repeated Devicesv1TConfigList OTHERS = 4;\n" +
+			"  map<string, Devicesv1TConfig> BYNAME = 5;\n" +
+			"  Color COLOR = 6;\n" +
+			"  repeated Devicesv1Color COLORS = 7;\n" +
+			"  map<string, Devicesv1TConfigList> DEEP = 8;\n" +
+			"  message AnonInline {\n" +
+			"    Devicesv1TConfig C = 1;\n" +
+			"  }\n" +
+			"  AnonInline INLINE = 9;\n" +
+			"}\n",
+		"\nmessage Devicesv1TConfig {\n  string NAME = 1;\n  uint32 RETRIES = 2;\n}\n",
+		"\nmessage Devicesv1TConfigList {\n  repeated Devicesv1TConfig ITEMS = 1;\n}\n",
+		"\nmessage TConfig {\n  string HOST = 1;\n}\n",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("Generator.AsProtobufFull lacks %q", want)
+		}
 	}
 
-	var m2 MapOfSliceOfStruct
-	err = protobuf3.Unmarshal(b, &m2)
+	g = protobuf3.Generator{GenericTypeName: func(t reflect.Type) string { return "G_" + protobuf3.MakeOfGenericTypeName(t) }}
+	s, err = g.AsProtobufFull(reflect.TypeOf(Page[Box[GenericItem]]{}))
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
+	}
+	if !strings.Contains(s, "\nmessage G_PageOfBoxOfGenericItem {\n  repeated G_BoxOfGenericItem items = 1;\n") || !strings.Contains(s, "\nmessage G_BoxOfGenericItem {\n") {
+		t.Errorf("Generator.AsProtobufFull didn't use GenericTypeName\n%s", s)
 	}
 
-	if !reflect.DeepEqual(&m, &m2) {
-		t.Error("unmarshal(marshal(x)) != x")
-		t.Errorf("x = %+v", &m)
-		t.Errorf("unmarshal(marshal(x)) = %+v", &m2)
+	// the generator didn't change the output of AsProtobufFull
+	s, err = protobuf3.AsProtobufFull(typ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != expected {
+		t.Errorf("AsProtobufFull() after using a Generator =\n%s\nexpected\n%s", s, expected)
 	}
-	checkslices(m2, t)
 }
 
 type MapOfPtrToStruct struct {
@@ -2020,6 +3675,182 @@ func TestCustomEnum(t *testing.T) {
 	}
 }
 
+type Color int8
+
+const (
+	Color_NONE = Color(iota)
+	Color_RED
+	Color_GREEN
+	Color_BLUE
+)
+
+func (c Color) String() string                { return protobuf3.EnumString(c) }
+func (c Color) MarshalText() ([]byte, error)  { return protobuf3.MarshalEnumText(c) }
+func (c *Color) UnmarshalText(b []byte) error { return protobuf3.UnmarshalEnumText(c, b) }
+
+// an enum lacking a zero value, which protobuf v3 doesn't allow
+type Level uint16
+
+// an enum whose value names clash with Color's
+type Shade int8
+
+func init() {
+	protobuf3.RegisterEnum(reflect.TypeOf(Color(0)), map[string]int32{
+		"NONE":  int32(Color_NONE),
+		"RED":   int32(Color_RED),
+		"GREEN": int32(Color_GREEN),
+		"BLUE":  int32(Color_BLUE),
+		"AZURE": int32(Color_BLUE), // an alias
+	})
+	protobuf3.RegisterEnum(reflect.TypeOf(Level(0)), map[string]int32{
+		"LOW":  1,
+		"HIGH": 2,
+	})
+	protobuf3.RegisterEnum(reflect.TypeOf(Shade(0)), map[string]int32{
+		"NONE": 0,
+		"DARK": 1,
+	})
+}
+
+type ColorMsg struct {
+	C       Color           `protobuf:"varint,1"`
+	PC      *Color          `protobuf:"varint,2"`
+	Cs      []Color         `protobuf:"varint,3"`
+	A       [2]Color        `protobuf:"varint,4"`
+	ByColor map[Color]Color `protobuf:"bytes,5" protobuf_key:"varint,1" protobuf_val:"varint,2"`
+}
+
+func TestRegisteredEnum(t *testing.T) {
+	green := Color_GREEN
+	m := ColorMsg{
+		C:       Color_RED,
+		PC:      &green,
+		Cs:      []Color{Color_BLUE, Color_NONE},
+		A:       [2]Color{Color_GREEN, Color_RED},
+		ByColor: map[Color]Color{Color_RED: Color_BLUE},
+	}
+	b, err := protobuf3.Marshal(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m2 ColorMsg
+	buf := protobuf3.NewBuffer(b)
+	buf.StrictEnums = true
+	if err := buf.Unmarshal(&m2); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&m, &m2) {
+		t.Errorf("unmarshal(marshal(x)) != x: %v != %v", m2, m)
+	}
+
+	// undefined values decode unless StrictEnums is set
+	for _, bad := range []ColorMsg{
+		{C: 7},
+		{PC: new(Color)}, // a defined value, so fine
+		{Cs: []Color{Color_RED, -1}},
+		{A: [2]Color{9, Color_RED}},
+		{ByColor: map[Color]Color{Color_RED: 5}},
+		{ByColor: map[Color]Color{5: Color_RED}},
+	} {
+		b, err := protobuf3.Marshal(&bad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var m3 ColorMsg
+		if err := protobuf3.Unmarshal(b, &m3); err != nil {
+			t.Errorf("Unmarshal(%v) failed: %v", bad, err)
+		}
+		buf := protobuf3.NewBuffer(b)
+		buf.StrictEnums = true
+		err = buf.Unmarshal(&m3)
+		if bad.PC != nil {
+			if err != nil {
+				t.Errorf("strict Unmarshal(%v) failed: %v", bad, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), "is not a defined value of enum Color") {
+			t.Errorf("strict Unmarshal(%v) returned error %v", bad, err)
+		}
+	}
+
+	s, err := protobuf3.AsProtobufFull(reflect.TypeOf(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(s)
+	for _, want := range []string{
+		"  Color c = 1;\n",
+		"  Color pc = 2;\n",
+		"  repeated Color cs = 3;\n",
+		"  repeated Color a = 4;\n",
+		"  map<int32, Color> by_color = 5;\n",
+		"enum Color {\n  option allow_alias = true;\n  NONE = 0;\n  RED = 1;\n  GREEN = 2;\n  AZURE = 3;\n  BLUE = 3;\n}",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("AsProtobufFull lacks %q", want)
+		}
+	}
+
+	j, err := json.Marshal(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(j, []byte(`"C":"RED"`)) || !bytes.Contains(j, []byte(`"ByColor":{"RED":"AZURE"}`)) {
+		t.Errorf("json didn't use the enum names: %s", j)
+	}
+	var m4 ColorMsg
+	if err := json.Unmarshal(j, &m4); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&m, &m4) {
+		t.Errorf("json round trip failed: %v != %v", m4, m)
+	}
+	if s := Color(7).String(); s != "7" {
+		t.Errorf("Color(7).String() = %q", s)
+	}
+	var c Color
+	if err := c.UnmarshalText([]byte("PURPLE")); err == nil {
+		t.Error("UnmarshalText(PURPLE) should have failed")
+	}
+}
+
+type LevelMsg struct {
+	L Level `protobuf:"varint,1"`
+}
+
+type ZigzagColorMsg struct {
+	C Color `protobuf:"zigzag32,1"`
+}
+
+type ShadedColorMsg struct {
+	C Color `protobuf:"varint,1"`
+	S Shade `protobuf:"varint,2"`
+}
+
+func TestBadEnum(t *testing.T) {
+	_, err := protobuf3.AsProtobufFull(reflect.TypeOf(LevelMsg{}))
+	if err == nil || !strings.Contains(err.Error(), "enum Level has no value 0") {
+		t.Errorf("AsProtobufFull(LevelMsg) returned error %v", err)
+	}
+
+	_, err = protobuf3.Marshal(&ZigzagColorMsg{})
+	if err == nil || !strings.Contains(err.Error(), "must have wiretype varint") {
+		t.Errorf("Marshal(ZigzagColorMsg) returned error %v", err)
+	}
+
+	// protoc would reject two enums in the same package which both define NONE
+	_, err = protobuf3.AsProtobufFull(reflect.TypeOf(ShadedColorMsg{}))
+	if err == nil || !strings.Contains(err.Error(), "value NONE of enum Shade is also a value of enum Color") {
+		t.Errorf("AsProtobufFull(ShadedColorMsg) returned error %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("RegisterEnum(string) should have panicked")
+		}
+	}()
+	protobuf3.RegisterEnum(reflect.TypeOf(""), map[string]int32{"X": 0})
+}
+
 func TestVarint(t *testing.T) {
 	var pb, pba []byte
 	var err error
@@ -2894,7 +4725,7 @@ func TestPackedMsg(t *testing.T) {
 	err = protobuf3.Unmarshal(pb2, &m3)
 	if err == nil {
 		t.Errorf("Unmarshal(unpacked) should have failed")
-	} else if err.Error() != "protobuf3: bad wiretype for field protobuf3_test.PackedMsg.F: got wiretype varint, wanted bytes" {
+	} else if err.Error() != "protobuf3: can't decode PackedMsg.F (id 1, wiretype varint) at offset 0: bad wiretype, wanted bytes" {
 		t.Errorf("Unmarshal() failed: %v", err)
 	}
 }
@@ -2946,3 +4777,341 @@ func TestSliceCapAndMaxRecursion(t *testing.T) {
 		m.B = append(m.B, byte(i/3))
 	}
 }
+
+type TopLevelEvent struct {
+	Name string `protobuf:"bytes,1"`
+	N    int    `protobuf:"varint,2"`
+}
+
+// the messages which top-level values are encoded as
+type TopLevelEventsMsg struct {
+	Value []TopLevelEvent `protobuf:"bytes,1"`
+}
+
+type TopLevelStatsMsg struct {
+	Value map[string]TopLevelEvent `protobuf:"bytes,1" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+}
+
+type TopLevelIntMsg struct {
+	I int `protobuf:"varint,1"`
+	Z int `protobuf:"zigzag64,3"`
+}
+
+type TopLevelStringMsg struct {
+	S string `protobuf:"bytes,1"`
+}
+
+type TopLevelFloatMsg struct {
+	F float64 `protobuf:"fixed64,1"`
+}
+
+func TestTopLevel(t *testing.T) {
+	events := []TopLevelEvent{{"a", 1}, {"b", 2}}
+	stats := map[string]TopLevelEvent{"x": {"c", 3}}
+
+	// marshal v, either as a value or a pointer, compare the result with the message it should be equivalent to,
+	// and unmarshal it into ptr
+	check := func(name string, v, ptr, msg interface{}, tag reflect.StructTag) {
+		expected, err := protobuf3.MarshalDeterministic(msg)
+		if err != nil {
+			t.Fatalf("Marshal(%s message) failed: %v", name, err)
+		}
+		for _, x := range []interface{}{v, ptr} {
+			buf := protobuf3.NewBuffer(nil)
+			buf.TopLevelTag = tag
+			buf.Deterministic = true
+			if err := buf.Marshal(x); err != nil {
+				t.Errorf("Marshal(%s %T) failed: %v", name, x, err)
+				continue
+			}
+			if !bytes.Equal(buf.Bytes(), expected) {
+				t.Errorf("Marshal(%s %T) = % x\nexpected % x", name, x, buf.Bytes(), expected)
+			}
+		}
+
+		got := reflect.New(reflect.TypeOf(ptr).Elem())
+		buf := protobuf3.NewBuffer(expected)
+		buf.TopLevelTag = tag
+		if err := buf.Unmarshal(got.Interface()); err != nil {
+			t.Errorf("Unmarshal(%s) failed: %v", name, err)
+		} else if !reflect.DeepEqual(got.Elem().Interface(), reflect.ValueOf(ptr).Elem().Interface()) {
+			t.Errorf("Unmarshal(%s) = %v, expected %v", name, got.Elem().Interface(), v)
+		}
+	}
+
+	check("slice", events, &events, &TopLevelEventsMsg{events}, "")
+	check("map", stats, &stats, &TopLevelStatsMsg{stats}, "")
+	i, s, f := 300, "hello", 1.5
+	check("int", i, &i, &TopLevelIntMsg{I: i}, "")
+	check("string", s, &s, &TopLevelStringMsg{s}, "")
+	check("float", f, &f, &TopLevelFloatMsg{f}, "")
+	z := -7
+	check("tagged int", z, &z, &TopLevelIntMsg{Z: z}, `protobuf:"zigzag64,3"`)
+
+	// an Appender is the entire message
+	a := TestAppender{1, 2, 3, 4}
+	pb, err := protobuf3.Marshal(&a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pb, a[:]) {
+		t.Errorf("Marshal(Appender) = % x", pb)
+	}
+	var a2 TestAppender
+	if err := protobuf3.Unmarshal(pb, &a2); err != nil || a2 != a {
+		t.Errorf("Unmarshal(Appender) = %v, %v", a2, err)
+	}
+
+	// types which can't be wrapped are still errors
+	var ch chan int
+	if _, err := protobuf3.Marshal(ch); err == nil {
+		t.Error("Marshal(chan) should have failed")
+	}
+	if _, err := protobuf3.Marshal([]chan int{}); err == nil {
+		t.Error("Marshal([]chan) should have failed")
+	}
+	p := &TopLevelEvent{}
+	if err := protobuf3.Unmarshal(pb, &p); err != protobuf3.ErrNotPointerToStruct {
+		t.Errorf("Unmarshal(**struct) returned %v", err)
+	}
+}
+
+type DecodeErrInner struct {
+	Name string `protobuf:"bytes,1"`
+	N    int    `protobuf:"varint,2"`
+}
+
+type DecodeErrOuter struct {
+	ID    int               `protobuf:"varint,1"`
+	Items []DecodeErrInner  `protobuf:"bytes,2"`
+	Ptrs  []*DecodeErrInner `protobuf:"bytes,3"`
+	One   *DecodeErrInner   `protobuf:"bytes,4"`
+}
+
+type BadTagMsg struct {
+	X int `protobuf:"varint,1"`
+	Y int
+}
+
+type BadTagOuterMsg struct {
+	In *BadTagMsg `protobuf:"bytes,1"`
+}
+
+func TestDecodeErrors(t *testing.T) {
+	good, err := protobuf3.Marshal(&DecodeErrOuter{
+		ID:    1,
+		Items: []DecodeErrInner{{"a", 1}, {"b", 2}, {"c", 3}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad := []byte{0x12, 1, 'x'} // DecodeErrInner.N with wiretype bytes
+
+	for _, c := range []struct {
+		tag  byte
+		path string
+	}{
+		{2, "DecodeErrOuter.Items[3].N"},
+		{3, "DecodeErrOuter.Ptrs[0].N"},
+		{4, "DecodeErrOuter.One.N"},
+	} {
+		pb := append(append([]byte{}, good...), c.tag<<3|byte(protobuf3.WireBytes), byte(len(bad)))
+		pb = append(pb, bad...)
+		var m DecodeErrOuter
+		err := protobuf3.Unmarshal(pb, &m)
+		var de *protobuf3.DecodeError
+		if !errors.As(err, &de) {
+			t.Errorf("Unmarshal(%s) returned %v", c.path, err)
+			continue
+		}
+		if de.Path != c.path || de.Type != reflect.TypeOf(DecodeErrInner{}) || de.Tag != 2 || de.WireType != protobuf3.WireBytes || de.Offset != len(good)+2 {
+			t.Errorf("Unmarshal(%s) returned %+v", c.path, *de)
+		}
+	}
+
+	// the path of a top-level slice starts with its type
+	var s []DecodeErrInner
+	err = protobuf3.Unmarshal(append([]byte{0x0a, byte(len(bad))}, bad...), &s)
+	var de *protobuf3.DecodeError
+	if !errors.As(err, &de) || de.Path != "[]protobuf3_test.DecodeErrInner[0].N" || de.Offset != 2 {
+		t.Errorf("Unmarshal([]DecodeErrInner) returned %v", err)
+	}
+
+	// a bad key isn't blamed on the field before it
+	for _, key := range [][]byte{{0x80}, {0x80, 0x80, 0x00}} {
+		var m DecodeErrOuter
+		err := protobuf3.Unmarshal(append(append([]byte{}, good...), key...), &m)
+		if !errors.As(err, &de) || de.Path != "DecodeErrOuter" || de.Tag != 0 || de.Offset != len(good) {
+			t.Errorf("Unmarshal of key % x returned %v", key, err)
+		}
+		if strings.Contains(err.Error(), "protobuf3: protobuf3:") {
+			t.Errorf("Unmarshal of key % x returned %q", key, err)
+		}
+	}
+
+	// errors in tags are TagErrors, and are passed to the ErrorLogger
+	var logged []error
+	protobuf3.ErrorLogger = func(err error) { logged = append(logged, err) }
+	defer func() { protobuf3.ErrorLogger = nil }()
+	_, err = protobuf3.Marshal(&BadTagMsg{})
+	var te *protobuf3.TagError
+	if !errors.As(err, &te) || te.Type != reflect.TypeOf(BadTagMsg{}) || te.Field != "Y" {
+		t.Errorf("Marshal(BadTagMsg) returned %v", err)
+	}
+	if len(logged) != 1 || logged[0] != err {
+		t.Errorf("ErrorLogger was called with %v", logged)
+	}
+
+	// the error in the nested type is logged once, where it was found
+	logged = nil
+	_, err = protobuf3.Marshal(&BadTagOuterMsg{})
+	if !errors.As(err, &te) || te.Type != reflect.TypeOf(BadTagOuterMsg{}) || te.Field != "In" {
+		t.Errorf("Marshal(BadTagOuterMsg) returned %v", err)
+	}
+	if len(logged) != 1 || !errors.As(logged[0], &te) || te.Type != reflect.TypeOf(BadTagMsg{}) {
+		t.Errorf("ErrorLogger was called with %v", logged)
+	}
+	if strings.Count(err.Error(), "protobuf3:") != 1 {
+		t.Errorf("Marshal(BadTagOuterMsg) returned %q", err)
+	}
+}
+
+type StringsMsg struct {
+	S  string            `protobuf:"bytes,1"`
+	P  *string           `protobuf:"bytes,2"`
+	SS []string          `protobuf:"bytes,3"`
+	A  [2]string         `protobuf:"bytes,4"`
+	M  map[string]string `protobuf:"bytes,5" protobuf_key:"bytes,1" protobuf_val:"bytes,2"`
+}
+
+func TestImmutableStrings(t *testing.T) {
+	p := "pointed"
+	m := StringsMsg{
+		S:  "direct",
+		P:  &p,
+		SS: []string{"slice0", "slice1"},
+		A:  [2]string{"array0", "array1"},
+		M:  map[string]string{"key": "value"},
+	}
+	pb, err := protobuf3.Marshal(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, immutable := range []bool{false, true} {
+		var m2 StringsMsg
+		buf := protobuf3.NewBuffer(pb)
+		buf.Immutable = immutable
+		if err := buf.Unmarshal(&m2); err != nil {
+			t.Fatal(err)
+		}
+		eq("StringsMsg", m, m2, t)
+
+		// all the strings point into pb only when the buffer is Immutable
+		strs := []string{m2.S, *m2.P, m2.SS[0], m2.SS[1], m2.A[0], m2.A[1]}
+		for k, v := range m2.M {
+			strs = append(strs, k, v)
+		}
+		start := uintptr(unsafe.Pointer(&pb[0]))
+		for _, s := range strs {
+			d := uintptr(unsafe.Pointer(unsafe.StringData(s)))
+			if aliased := d >= start && d < start+uintptr(len(pb)); aliased != immutable {
+				t.Errorf("Immutable=%v: string %q aliased=%v", immutable, s, aliased)
+			}
+		}
+	}
+}
+
+// a Buffer which is Rewound can decode again into the same value, arrays included
+func TestRewind(t *testing.T) {
+	m := StringsMsg{S: "s", A: [2]string{"a0", "a1"}}
+	pb, err := protobuf3.Marshal(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := protobuf3.NewBuffer(pb)
+	var m2 StringsMsg
+	for i := 0; i < 2; i++ {
+		m2 = StringsMsg{}
+		if err := buf.Unmarshal(&m2); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(&m, &m2) {
+			t.Errorf("#%d: decoded %+v", i, m2)
+		}
+		buf.Rewind()
+	}
+}
+
+func TestInterner(t *testing.T) {
+	p := "pointed"
+	m := StringsMsg{
+		S:  "direct",
+		P:  &p,
+		SS: []string{"slice0", "direct"},
+		A:  [2]string{"array0", "array1"},
+		M:  map[string]string{"key": "value"},
+	}
+	pb, err := protobuf3.Marshal(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := protobuf3.NewInterner(100, 16)
+	var ms [2]StringsMsg
+	for i := range ms {
+		buf := protobuf3.NewBuffer(pb)
+		buf.Interner = in
+		if err := buf.Unmarshal(&ms[i]); err != nil {
+			t.Fatal(err)
+		}
+		eq("StringsMsg", m, ms[i], t)
+	}
+
+	// equal strings share storage, within a message and between messages
+	same := func(what, a, b string) {
+		if unsafe.StringData(a) != unsafe.StringData(b) {
+			t.Errorf("%s %q don't share storage", what, a)
+		}
+	}
+	same("S and SS[1]", ms[0].S, ms[0].SS[1])
+	same("S", ms[0].S, ms[1].S)
+	same("P", *ms[0].P, *ms[1].P)
+	same("SS", ms[0].SS[0], ms[1].SS[0])
+	same("A", ms[0].A[1], ms[1].A[1])
+	for k0, v0 := range ms[0].M {
+		for k1, v1 := range ms[1].M {
+			same("M key", k0, k1)
+			same("M value", v0, v1)
+		}
+	}
+
+	// the Interner copies the strings, so they count against MaxAllocation even when the Buffer is Immutable
+	long := StringsMsg{SS: []string{strings.Repeat("x", 600), strings.Repeat("y", 600)}}
+	pb, err = protobuf3.Marshal(&long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, in := range []protobuf3.Interner{nil, protobuf3.NewInterner(100, 1000)} {
+		var m2 StringsMsg
+		buf := protobuf3.NewBuffer(pb)
+		buf.Immutable = true
+		buf.Interner = in
+		buf.Limits = protobuf3.DecodeLimits{MaxAllocation: 1000}
+		err := buf.Unmarshal(&m2)
+		var le *protobuf3.LimitError
+		if limited := errors.As(err, &le) && le.Limit == "MaxAllocation"; limited != (in != nil) {
+			t.Errorf("Immutable, interning=%v: Unmarshal returned %v", in != nil, err)
+		}
+	}
+
+	// a small interner still returns the right strings
+	in = protobuf3.NewInterner(2, 4)
+	for i := 0; i < 3; i++ {
+		for _, s := range []string{"a", "bb", "ccc", "dddd", "eeeee", ""} {
+			if got := in.Intern([]byte(s)); got != s {
+				t.Errorf("Intern(%q) = %q", s, got)
+			}
+		}
+	}
+}
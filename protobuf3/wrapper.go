@@ -0,0 +1,241 @@
+// Go support for Protocol Buffers - Google's data interchange format
+//
+// Copyright 2026 Mist Systems. All rights reserved.
+//
+// Unlike most files, this one is entirely by Mist, and not derived
+// from any earlier code.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//     * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//     * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//     * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package protobuf3
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// protobuf has no repeated repeated fields, so slices and arrays of lists ([][]T, [N][]T, [][M]T, ...) encode
+// each inner list as a wrapper message whose field 1 holds the list. For example a [][]int32 field tagged
+// `protobuf:"zigzag32,4"` encodes as a repeated Sint32List, where
+//
+//	message Sint32List {
+//	  repeated sint32 items = 1;
+//	}
+//
+// The wrapper is named after the protobuf type of its items, so its definition is the same for every list
+// which shares the name, and deeper nesting nests the names ([][][]string uses StringListList). []byte and
+// [N]byte are not lists; they are bytes.
+//
+// The wrapper of a list type L is the synthesized struct { Items L }, which has the same memory layout as L,
+// so a slice or array of L is encoded and decoded as if it were a slice or array of the wrapper struct.
+
+// a synthesized wrapper struct type
+type wrapper struct {
+	typ  reflect.Type
+	name string // the name of the wrapper message
+}
+
+// the wrappers, by the list or map type and the struct tag of the Items field
+type wrapperKey struct {
+	typ reflect.Type
+	tag string
+}
+
+var (
+	wrappersMu   sync.RWMutex
+	wrappers     = make(map[wrapperKey]*wrapper)
+	wrapperNames = make(map[reflect.Type]string) // the message names of the wrapper types
+)
+
+// isList returns true if t is a slice or array which needs a wrapper message when it is itself an element of a slice or array
+func isList(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return t.Elem().Kind() != reflect.Uint8
+	}
+	return false
+}
+
+// returns the message name of t if it is a wrapper type, or ""
+func wrapperName(t reflect.Type) string {
+	wrappersMu.RLock()
+	name := wrapperNames[t]
+	wrappersMu.RUnlock()
+	return name
+}
+
+// getWrapperLocked returns the wrapper of list or map type t, whose Items field has struct tag tag.
+// It requires that propertiesMu is held.
+func getWrapperLocked(t reflect.Type, tag string) (*wrapper, error) {
+	key := wrapperKey{t, tag}
+	wrappersMu.RLock()
+	w := wrappers[key]
+	wrappersMu.RUnlock()
+	if w != nil {
+		return w, nil
+	}
+
+	typ := reflect.StructOf([]reflect.StructField{{
+		Name: "Items",
+		Type: t,
+		Tag:  reflect.StructTag(tag),
+	}})
+	sprop, err := getPropertiesLocked(typ)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := wrapperMessageName(t, &sprop.props[0], nil)
+	if err != nil {
+		return nil, err
+	}
+	w = &wrapper{
+		typ:  typ,
+		name: name,
+	}
+
+	wrappersMu.Lock()
+	wrappers[key] = w
+	wrapperNames[typ] = w.name
+	wrappersMu.Unlock()
+	return w, nil
+}
+
+// wrapperMessageName names the wrapper of list or map type t after the protobuf types of its items (or keys and values),
+// as named by n
+func wrapperMessageName(t reflect.Type, items *Properties, n *namer) (string, error) {
+	var parts []string
+	if t.Kind() == reflect.Map {
+		parts = []string{items.mkeyprop.protobufType(n), items.mvalprop.protobufType(n), "Map"}
+	} else {
+		parts = []string{strings.TrimPrefix(items.protobufType(n), "repeated "), "List"}
+	}
+	var name string
+	for _, part := range parts {
+		part = part[strings.LastIndexAny(part, ".\n")+1:] // (an anonymous struct's type is preceeded by its definition)
+		if part == "" {
+			return "", fmt.Errorf("protobuf3: no protobuf name for the wrapper of %s", t)
+		}
+		r := []rune(part)
+		r[0] = unicode.ToUpper(r[0])
+		name += string(r)
+	}
+	return name, nil
+}
+
+// setListEncAndDec is the part of setEncAndDec which handles slices and arrays of lists. t2 is the list type.
+func (p *Properties) setListEncAndDec(t2 reflect.Type, wire *WireType, array bool) error {
+	// like getMapValueWrapperLocked(), the items have the wiretype and options of the field's tag, with id 1
+	parts := strings.SplitN(p.Wire, ",", 3)
+	items := parts[0] + ",1"
+	if len(parts) == 3 {
+		items += "," + parts[2]
+	}
+	w, err := getWrapperLocked(t2, fmt.Sprintf("protobuf:%q", items))
+	if err != nil {
+		return err
+	}
+	p.stype = w.typ
+	p.sprop, err = getPropertiesLocked(w.typ)
+	if err != nil {
+		return err
+	}
+	if array {
+		p.enc = (*Buffer).enc_array_struct_message
+		p.dec = (*Buffer).dec_array_struct_message
+	} else {
+		p.enc = (*Buffer).enc_slice_struct_message
+		p.dec = (*Buffer).dec_slice_struct_message
+	}
+	p.asProtobuf = "repeated " + w.name
+	*wire = WireBytes // the wrapper messages are always length delimited, whatever the wiretype of the items
+	return nil
+}
+
+// setOptionListEncAndDec handles t1 if it is a slice or array of lists, whose time format or marshal format option
+// applies to the items of the lists. It returns false if t1 is some other type.
+func (p *Properties) setOptionListEncAndDec(t1 reflect.Type, wire *WireType) (bool, error) {
+	if t1.Kind() != reflect.Slice && (t1.Kind() != reflect.Array || t1.Len() == 0) {
+		return false, nil
+	}
+	t2 := t1.Elem()
+	if !isList(t2) || isAppender(reflect.PtrTo(t2)) || isMarshaler(reflect.PtrTo(t2)) {
+		return false, nil
+	}
+	if t1.Kind() == reflect.Array {
+		p.length = uint(t1.Len())
+	}
+	return true, p.setListEncAndDec(t2, wire, t1.Kind() == reflect.Array)
+}
+
+// protobuf map values cannot be repeated fields or maps either, so map values which are lists or maps
+// (map[K][]V, map[K]map[K2]V, ...) are encoded as wrapper messages too. The protobuf_val tag of a map of lists
+// gives the wiretype of the lists' items, so a map[string][]string field tagged
+// `protobuf:"bytes,3" protobuf_key:"bytes,1" protobuf_val:"bytes,2"` is described as map<string, StringList>.
+// A map of maps is tagged protobuf_val:"bytes,2", and the key and value tags of the inner map are given by
+// protobuf_val_key and protobuf_val_val tags (and protobuf_val_val_key and protobuf_val_val_val tags for the
+// map inside that, and so on). A map<string, map<string, int64>> is described as map<string, StringInt64Map>,
+// where
+//
+//	message StringInt64Map {
+//	  map<string, int64> items = 1;
+//	}
+
+// isMapWrapped returns true if t, the value type of a map, needs a wrapper message
+func isMapWrapped(t reflect.Type) bool {
+	return isList(t) || t.Kind() == reflect.Map
+}
+
+// getMapValueWrapperLocked returns the wrapper of t, the value type of a map field with struct tag ftag whose
+// protobuf_val tag is valTag, and the protobuf_val tag of the wrapper. It requires that propertiesMu is held.
+func getMapValueWrapperLocked(t reflect.Type, ftag reflect.StructTag, valTag string) (*wrapper, string, error) {
+	// the items have the wiretype and options of the value tag, with id 1, and the wrapper has the value's id
+	parts := strings.SplitN(valTag, ",", 3)
+	if len(parts) < 2 {
+		return nil, "", fmt.Errorf("protobuf3: bad protobuf_val tag %q", valTag)
+	}
+	items := parts[0] + ",1"
+	if len(parts) == 3 {
+		items += "," + parts[2]
+	}
+	tag := fmt.Sprintf("protobuf:%q", items)
+
+	// the inner maps' tags are the protobuf_val_... tags, less one "val_"
+	for t2, prefix := t, ""; t2.Kind() == reflect.Map; t2, prefix = t2.Elem(), prefix+"val_" {
+		for _, kv := range []string{"key", "val"} {
+			if v, ok := ftag.Lookup("protobuf_val_" + prefix + kv); ok {
+				tag += fmt.Sprintf(" protobuf_%s%s:%q", prefix, kv, v)
+			}
+		}
+	}
+
+	w, err := getWrapperLocked(t, tag)
+	return w, "bytes," + parts[1], err
+}